@@ -0,0 +1,107 @@
+package gocachex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+)
+
+// SetAlias points alias at targetKey, replacing any previous target. Reads
+// through GetByAlias then return the value stored at targetKey. Aliases live
+// in their own namespace: they never expire and do not collide with keys.
+// Redis stores each alias in a key prefixed with "gocachex:alias:"; Keys,
+// Export and DeleteByPattern skip these keys, but Size counts them.
+//
+// Aliases let a full dataset be rebuilt under a new key and switched to in
+// one step:
+//
+//	cache.Set(ctx, "data:v2", dataset, 0)
+//	previous, _ := cache.PromoteAlias(ctx, "data", "data:v2")
+//	cache.Delete(ctx, previous)
+func (c *CacheClient) SetAlias(ctx context.Context, alias, targetKey string) error {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.set_alias")
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	backend, err := c.backendFor(alias)
+	if err != nil {
+		return err
+	}
+	aliaser, ok := backend.(backends.Aliaser)
+	if !ok {
+		return fmt.Errorf("aliases not supported by backend")
+	}
+	return c.guard(func() error {
+		return aliaser.SetAlias(ctx, alias, targetKey)
+	})
+}
+
+// PromoteAlias atomically repoints an existing alias at targetKey and
+// returns the key it pointed at before, so the old version can be deleted
+// once readers have moved on. Readers see either the old or the new target,
+// never a missing alias. It returns backends.ErrNotFound if alias is not set.
+func (c *CacheClient) PromoteAlias(ctx context.Context, alias, targetKey string) (string, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.promote_alias")
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return "", ErrReadOnly
+	}
+
+	backend, err := c.backendFor(alias)
+	if err != nil {
+		return "", err
+	}
+	aliaser, ok := backend.(backends.Aliaser)
+	if !ok {
+		return "", fmt.Errorf("aliases not supported by backend")
+	}
+
+	var previous string
+	err = c.guard(func() error {
+		var err error
+		previous, err = aliaser.SwapAlias(ctx, alias, targetKey)
+		return err
+	})
+	return previous, err
+}
+
+// ResolveAlias returns the key alias currently points at, or
+// backends.ErrNotFound if alias is not set.
+func (c *CacheClient) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.resolve_alias")
+	defer span.End()
+
+	backend, err := c.backendFor(alias)
+	if err != nil {
+		return "", err
+	}
+	aliaser, ok := backend.(backends.Aliaser)
+	if !ok {
+		return "", fmt.Errorf("aliases not supported by backend")
+	}
+
+	var target string
+	err = c.guard(func() error {
+		var err error
+		target, err = aliaser.ResolveAlias(ctx, alias)
+		return err
+	})
+	return target, err
+}
+
+// GetByAlias resolves alias and returns the value stored at its target.
+func (c *CacheClient) GetByAlias(ctx context.Context, alias string) (interface{}, error) {
+	target, err := c.ResolveAlias(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+	return c.Get(ctx, target)
+}
@@ -0,0 +1,78 @@
+//go:build integration
+
+package gocachex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisClientSetNX(t *testing.T) {
+	clients := []*CacheClient{newRedisClient(t, config.LockConfig{}), newRedisClient(t, config.LockConfig{})}
+	ctx := context.Background()
+	key := fmt.Sprintf("setnx:%d", time.Now().UnixNano())
+	defer clients[0].Delete(ctx, key)
+
+	// Callers on separate connections race; the backend lets exactly one win
+	var wins atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			stored, err := clients[i%2].SetNX(ctx, key, i, time.Minute)
+			assert.NoError(t, err)
+			if stored {
+				wins.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), wins.Load())
+}
+
+func TestRedisClientGetSet(t *testing.T) {
+	clients := []*CacheClient{newRedisClient(t, config.LockConfig{}), newRedisClient(t, config.LockConfig{})}
+	ctx := context.Background()
+	key := fmt.Sprintf("getset:%d", time.Now().UnixNano())
+	defer clients[0].Delete(ctx, key)
+
+	require.NoError(t, clients[0].Set(ctx, key, "initial", time.Minute))
+
+	// Each swap returns the value of exactly one earlier swap
+	const writers = 50
+	olds := make([]interface{}, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			old, err := clients[i%2].GetSet(ctx, key, fmt.Sprintf("v%d", i))
+			assert.NoError(t, err)
+			olds[i] = old
+		}(i)
+	}
+	wg.Wait()
+
+	final, err := clients[0].Get(ctx, key)
+	require.NoError(t, err)
+
+	seen := map[interface{}]int{final: 1}
+	for _, old := range olds {
+		seen[old]++
+	}
+	assert.Len(t, seen, writers+1)
+	for value, count := range seen {
+		assert.Equal(t, 1, count, value)
+	}
+	assert.Equal(t, 1, seen["initial"])
+}
@@ -27,24 +27,79 @@ package gocachex
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/chmenegatti/gocachex/pkg/backends"
+	"github.com/chmenegatti/gocachex/pkg/breaker"
 	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/chmenegatti/gocachex/pkg/hashing"
+	"github.com/chmenegatti/gocachex/pkg/metrics"
+	"github.com/chmenegatti/gocachex/pkg/sharding"
+	"github.com/chmenegatti/gocachex/pkg/tracing"
+	"golang.org/x/sync/singleflight"
 )
 
+// ErrNoShards is returned when a distributed client has no shard to route a
+// key to.
+var ErrNoShards = errors.New("no shards available")
+
+// ErrNilPointer is returned when a nil pointer is stored with
+// DereferencePointers enabled.
+var ErrNilPointer = errors.New("cannot cache a nil pointer")
+
+// ErrReadOnly is returned by the writes of a client configured with
+// ReadOnly.
+var ErrReadOnly = errors.New("cache is read-only")
+
+// ErrMultiple is returned when a write to a hierarchical client fails in
+// some of its tiers. Failed maps each failed tier, "L1" or "L2", to its
+// error; a tier missing from it holds the written value.
+type ErrMultiple struct {
+	Failed map[string]error
+}
+
+func (e *ErrMultiple) Error() string {
+	tiers := make([]string, 0, len(e.Failed))
+	for tier := range e.Failed {
+		tiers = append(tiers, tier)
+	}
+	sort.Strings(tiers)
+
+	msgs := make([]string, len(tiers))
+	for i, tier := range tiers {
+		msgs[i] = fmt.Sprintf("failed to set in %s cache: %v", tier, e.Failed[tier])
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the error of each failed tier, so errors.Is and errors.As
+// match any of them.
+func (e *ErrMultiple) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, err := range e.Failed {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 // Cache represents the main cache interface that all backends must implement.
 // It provides a unified API for cache operations across different storage backends.
 type Cache interface {
 	// Basic operations
 	Get(ctx context.Context, key string) (interface{}, error)
+	GetOK(ctx context.Context, key string) (interface{}, bool, error)
 	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
 
 	// Batch operations
-	GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error)
+	GetMulti(ctx context.Context, keys []string, opts ...GetMultiOption) (map[string]interface{}, error)
 	SetMulti(ctx context.Context, items map[string]interface{}, ttl time.Duration) error
 	DeleteMulti(ctx context.Context, keys []string) error
 
@@ -57,14 +112,151 @@ type Cache interface {
 	GetSet(ctx context.Context, key string, value interface{}) (interface{}, error)
 	Expire(ctx context.Context, key string, ttl time.Duration) error
 	TTL(ctx context.Context, key string) (time.Duration, error)
+	GetMultiTTL(ctx context.Context, keys []string) (map[string]time.Duration, error)
+	ProbeMulti(ctx context.Context, keys []string) (map[string]backends.KeyProbe, error)
+	GetOrSet(ctx context.Context, key string, ttl time.Duration, loader LoaderFunc, opts ...GetOrSetOption) (interface{}, error)
+	GetOrSetWithTTL(ctx context.Context, key string, ttl time.Duration, loader LoaderFunc, opts ...GetOrSetOption) (GetOrSetResult, error)
+
+	// Key enumeration operations
+	Keys(ctx context.Context, pattern string) ([]string, error)
+	DeleteByPattern(ctx context.Context, pattern string) (int, error)
+	ExistsPattern(ctx context.Context, pattern string) (bool, error)
+
+	// Backup operations
+	Export(ctx context.Context, w io.Writer) (int, error)
+	Import(ctx context.Context, r io.Reader) (int, error)
 
 	// Management operations
 	Clear(ctx context.Context) error
+	Size(ctx context.Context) (int64, error)
 	Stats(ctx context.Context) (*Stats, error)
+	ResetStats(ctx context.Context) error
 	Health(ctx context.Context) error
+	HealthAll(ctx context.Context) []ComponentHealth
 	Close() error
 }
 
+// CodecErrorHook is called when a value fails to serialize, compress,
+// decompress or deserialize. errorType is one of "serialize", "compress",
+// "decompress" or "deserialize".
+type CodecErrorHook func(errorType, key string, err error)
+
+// LargeValueHook is called when a value whose encoded size exceeds
+// LargeValueThreshold is written, with the key and the size in bytes.
+type LargeValueHook func(key string, size int)
+
+// SetOption configures a single SetWithOptions call.
+type SetOption func(*setOptions)
+
+// setOptions holds the per-call overrides applied by SetOption.
+type setOptions struct {
+	compression *bool
+	serializer  string
+}
+
+// WithCompression overrides the client's compression setting for one entry.
+// Entries are compressed with the configured algorithm, or gzip when
+// compression is disabled globally.
+func WithCompression(enabled bool) SetOption {
+	return func(o *setOptions) {
+		o.compression = &enabled
+	}
+}
+
+// WithSerializer overrides the client's serializer for one entry, e.g. "gob"
+// for values that encode far better with it. The entry records its
+// serializer, so Get decodes it correctly whatever the client default.
+func WithSerializer(name string) SetOption {
+	return func(o *setOptions) {
+		o.serializer = name
+	}
+}
+
+// LoaderFunc loads a value from the source of truth on a cache miss.
+type LoaderFunc func(ctx context.Context) (interface{}, error)
+
+// GetOrSetOption configures a single GetOrSet or GetOrSetLocked call.
+type GetOrSetOption func(*getOrSetOptions)
+
+// getOrSetOptions holds the per-call behaviour applied by GetOrSetOption.
+type getOrSetOptions struct {
+	shouldCache      func(value interface{}) bool
+	maxCacheableSize int
+}
+
+// WithShouldCache caches a loaded value only when shouldCache reports true
+// for it, e.g. to skip degraded or partial responses. Rejected values are
+// still returned to the caller, and the next call runs the loader again.
+func WithShouldCache(shouldCache func(value interface{}) bool) GetOrSetOption {
+	return func(o *getOrSetOptions) {
+		o.shouldCache = shouldCache
+	}
+}
+
+// WithMaxCacheableSize caches a loaded value only when its encoded entry is
+// at most maxSize bytes, so a single huge result cannot evict the rest of the
+// cache. Larger values are still returned to the caller, counted in the
+// oversized loads metric, and the next call runs the loader again. Checking
+// the size encodes the value once more before it is stored.
+func WithMaxCacheableSize(maxSize int) GetOrSetOption {
+	return func(o *getOrSetOptions) {
+		o.maxCacheableSize = maxSize
+	}
+}
+
+// newGetOrSetOptions applies opts to the default options.
+func newGetOrSetOptions(opts []GetOrSetOption) getOrSetOptions {
+	var o getOrSetOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// cacheable reports whether a loaded value should be stored.
+func (o getOrSetOptions) cacheable(value interface{}) bool {
+	return o.shouldCache == nil || o.shouldCache(value)
+}
+
+// GetOrSetResult is the outcome of a GetOrSetWithTTL call.
+type GetOrSetResult struct {
+	// Value is the cached or loaded value
+	Value interface{}
+
+	// TTL is the remaining time to live of the cached entry, -1 if it has no
+	// expiration, or zero if the value was not cached or its TTL could not
+	// be read
+	TTL time.Duration
+
+	// Hit reports whether Value was found in the cache rather than loaded
+	Hit bool
+}
+
+// Missing is the value GetMulti reports for keys that are not cached when
+// called with WithMisses. Compare against it with ==.
+var Missing = missing{}
+
+// missing is the type of Missing.
+type missing struct{}
+
+// GetMultiOption configures a single GetMulti call.
+type GetMultiOption func(*getMultiOptions)
+
+// getMultiOptions holds the per-call behaviour applied by GetMultiOption.
+type getMultiOptions struct {
+	includeMisses bool
+}
+
+// WithMisses makes GetMulti report every requested key, mapping keys that
+// are not cached to Missing instead of omitting them. Since a key GetMulti
+// failed to read is not known to be missing, failures are returned as
+// errors instead, except for undecodable values EvictUndecodable removed.
+func WithMisses() GetMultiOption {
+	return func(o *getMultiOptions) {
+		o.includeMisses = true
+	}
+}
+
 // Stats represents cache statistics and metrics.
 type Stats struct {
 	Hits        int64 `json:"hits"`
@@ -86,61 +278,171 @@ func (s *Stats) HitRatio() float64 {
 	return float64(s.Hits) / float64(total)
 }
 
+// ExtendedStats is Stats plus backend-specific extras, such as Redis server
+// counters or memory access distributions. Extra is keyed by component:
+// "primary", "l1", "l2" or "shard-N".
+type ExtendedStats struct {
+	Stats
+	Extra map[string]map[string]interface{} `json:"extra,omitempty"`
+}
+
+// ComponentHealth is the health of a single backend, tier or shard.
+type ComponentHealth struct {
+	Component string `json:"component"`
+	Backend   string `json:"backend"`
+	Healthy   bool   `json:"healthy"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ShardInfo describes a single shard of a distributed client.
+type ShardInfo struct {
+	Index     int      `json:"index"`
+	Backend   string   `json:"backend"`
+	Addresses []string `json:"addresses,omitempty"`
+	Healthy   bool     `json:"healthy"`
+	Error     string   `json:"error,omitempty"`
+}
+
 // CacheClient is the main implementation of the Cache interface.
 // It provides a unified interface to different cache backends with additional features
 // like compression, serialization, monitoring, and distributed operations.
 type CacheClient struct {
-	backend backends.Backend
-	config  *config.Config
-	// metrics    *metrics.Collector
-	// tracer     *tracing.Tracer
-	shards     []backends.Backend
-	l1Cache    Cache
-	l2Cache    Cache
-	serializer backends.Serializer
-	compressor backends.Compressor
+	backend     backends.Backend
+	config      *config.Config
+	metrics     *metrics.Collector
+	tracer      *tracing.Tracer // nil when tracing is disabled
+	shards      []backends.Backend
+	hash        hashing.Func
+	sharder     sharding.Sharder // nil unless loads are bounded
+	l1Cache     Cache
+	l2Cache     Cache
+	invalidator *invalidator // nil unless an invalidation channel is set
+	serializer  backends.Serializer
+	compressor  backends.Compressor // nil when compression is disabled
+	stopStats   chan struct{}
+	statsDone   sync.WaitGroup // tracks the stats poller so Close can wait for it
+	breaker     *breaker.Breaker
+	loads       singleflight.Group // deduplicates GetOrSet loads when SingleFlight is set
+	loaders     *loaderLimiter     // nil when the loader limit is disabled
+	closeOnce   sync.Once
+
+	// entryCompressor handles every compressed entry, including entries that
+	// opt in with WithCompression while compression is disabled
+	entryCompressor backends.Compressor
+
+	// serializers holds every serializer by id, for entries stored with
+	// WithSerializer
+	serializers map[byte]backends.Serializer
+
+	codecErrorHook   CodecErrorHook
+	reportCodecError func(operation, errorType, key string, err error)
+
+	largeValueHook    LargeValueHook
+	reportPayloadSize func(key string, size int)
+
+	reportUndecodableEviction func()
+	reportCompression         func(algorithm, direction string, duration time.Duration)
+}
+
+// RegisterType registers the concrete type of value with the "typed"
+// serializer, so Get returns values of that type instead of generic maps.
+func RegisterType(value interface{}) {
+	backends.RegisterType(value)
 }
 
 // New creates a new cache client with the given configuration.
 // It initializes the appropriate backend, sets up monitoring, and configures
 // additional features like compression and hierarchical caching.
 func New(cfg config.Config) (Cache, error) {
+	return newClient(cfg, nil)
+}
+
+// NewWithBackend creates a cache client that stores its entries in backend,
+// such as a fake from the gocachextest package. cfg configures everything
+// but the backend; its Backend field only labels metrics and defaults to
+// "memory". Hierarchical and distributed modes are not supported.
+func NewWithBackend(cfg config.Config, backend backends.Backend) (Cache, error) {
+	if cfg.Hierarchical || cfg.Distributed {
+		return nil, fmt.Errorf("custom backends are not supported in hierarchical or distributed mode")
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = "memory"
+	}
+	return newClient(cfg, backend)
+}
+
+// newClient creates a cache client, initializing the configured backend
+// unless one is given.
+func newClient(cfg config.Config, backend backends.Backend) (Cache, error) {
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	if cfg.StrictCodecs {
+		if err := checkStrictCodecs(cfg); err != nil {
+			return nil, err
+		}
+	}
+
 	client := &CacheClient{
 		config: &cfg,
 	}
 
 	// Initialize metrics collector
-	// if cfg.Prometheus.Enabled {
-	// 	client.metrics = metrics.New(cfg.Prometheus)
-	// }
+	if cfg.Prometheus.Enabled {
+		client.metrics = metrics.NewForBackend(cfg.Prometheus, cfg.Backend)
+	}
 
 	// Initialize tracer
-	// if cfg.Tracing.Enabled {
-	// 	tracer, err := tracing.New(cfg.Tracing)
-	// 	if err != nil {
-	// 		return nil, fmt.Errorf("failed to initialize tracer: %w", err)
-	// 	}
-	// 	client.tracer = tracer
-	// }
+	if cfg.Tracing.Enabled {
+		tracer, err := tracing.New(cfg.Tracing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize tracer: %w", err)
+		}
+		client.tracer = tracer
+	}
+
+	// Initialize circuit breaker
+	if cfg.CircuitBreaker.Enabled {
+		client.breaker = breaker.New(cfg.CircuitBreaker.FailureThreshold, cfg.CircuitBreaker.Cooldown)
+	}
+
+	// Initialize loader limiter
+	if cfg.LoaderLimit.MaxConcurrent > 0 {
+		client.loaders = newLoaderLimiter(cfg.LoaderLimit.MaxConcurrent)
+	}
 
 	// Initialize serializer
-	serializer, err := backends.NewSerializer(cfg.Serializer)
+	serializer, err := newSerializer(cfg.Serializer, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize serializer: %w", err)
 	}
 	client.serializer = serializer
 
-	// Initialize compressor if enabled
-	if cfg.Compression {
-		compressor, err := backends.NewCompressor(cfg.CompressionAlgorithm)
-		if err != nil {
-			return nil, fmt.Errorf("failed to initialize compressor: %w", err)
+	// Initialize the serializers entries may override it with
+	client.serializers = make(map[byte]backends.Serializer, len(serializerIDs))
+	for name, id := range serializerIDs {
+		if client.serializers[id], err = newSerializer(name, cfg); err != nil {
+			return nil, fmt.Errorf("failed to initialize serializer: %w", err)
 		}
+	}
+
+	// Initialize compressor. It is created even when compression is
+	// disabled, since single entries may still opt into it
+	algorithm, maxSize := cfg.CompressionAlgorithm, cfg.MaxDecompressedSize
+	if !cfg.Compression {
+		algorithm = "gzip"
+		if maxSize == 0 {
+			maxSize = backends.DefaultMaxDecompressedSize
+		}
+	}
+	compressor, err := backends.NewCompressorWithLevel(algorithm, cfg.CompressionLevel, maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize compressor: %w", err)
+	}
+	client.entryCompressor = compressor
+	if cfg.Compression {
 		client.compressor = compressor
 	}
 
@@ -149,13 +451,34 @@ func New(cfg config.Config) (Cache, error) {
 		if err := client.initHierarchicalCache(); err != nil {
 			return nil, fmt.Errorf("failed to initialize hierarchical cache: %w", err)
 		}
+		if err := client.startInvalidation(); err != nil {
+			client.Close()
+			return nil, err
+		}
+		if err := client.startMetrics(); err != nil {
+			client.Close()
+			return nil, err
+		}
+		if err := client.warmCriticalKeys(); err != nil {
+			client.Close()
+			return nil, err
+		}
 		return client, nil
 	}
 
 	// Initialize main backend
-	backend, err := backends.New(cfg.Backend, cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize backend: %w", err)
+	if backend == nil {
+		if backend, err = backends.New(cfg.Backend, cfg); err != nil {
+			return nil, fmt.Errorf("failed to initialize backend: %w", err)
+		}
+	}
+	if cfg.Failover.Enabled {
+		standby, err := newStandbyBackend(cfg.Failover.Standby)
+		if err != nil {
+			backend.Close()
+			return nil, fmt.Errorf("failed to initialize standby backend: %w", err)
+		}
+		backend = backends.NewFailoverBackend(backend, standby, cfg.Failover.CheckInterval)
 	}
 	client.backend = backend
 
@@ -166,15 +489,29 @@ func New(cfg config.Config) (Cache, error) {
 		}
 	}
 
+	if err := client.startMetrics(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	// Load critical keys before serving traffic
+	if err := client.warmCriticalKeys(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
 	return client, nil
 }
 
 // Get retrieves a value from the cache.
-func (c *CacheClient) Get(ctx context.Context, key string) (interface{}, error) {
+func (c *CacheClient) Get(ctx context.Context, key string) (value interface{}, err error) {
 	// Start tracing span
-	ctx, span := c.startSpan(ctx, "cache.get")
+	ctx, span := c.startKeySpan(ctx, "cache.get", key)
 	defer span.End()
 
+	c.metrics.RecordKeyGroup("get", key)
+	defer func(start time.Time) { c.recordOperation(span, "get", start, err) }(time.Now())
+
 	// Hierarchical cache check
 	if c.config.Hierarchical {
 		return c.getHierarchical(ctx, key)
@@ -189,32 +526,66 @@ func (c *CacheClient) Get(ctx context.Context, key string) (interface{}, error)
 	return c.getSingle(ctx, key)
 }
 
+// GetOK retrieves a value from the cache like Get, but reports a miss as
+// found == false with a nil error. The error is reserved for real failures,
+// such as an unreachable backend or an undecodable value.
+func (c *CacheClient) GetOK(ctx context.Context, key string) (interface{}, bool, error) {
+	value, err := c.Get(ctx, key)
+	if errors.Is(err, backends.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
 // Set stores a value in the cache with the specified TTL.
 func (c *CacheClient) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.SetWithOptions(ctx, key, value, ttl)
+}
+
+// SetWithOptions stores a value like Set, applying per-call options such as
+// WithCompression to this entry only.
+func (c *CacheClient) SetWithOptions(ctx context.Context, key string, value interface{}, ttl time.Duration, opts ...SetOption) (err error) {
 	// Start tracing span
-	ctx, span := c.startSpan(ctx, "cache.set")
+	ctx, span := c.startKeySpan(ctx, "cache.set", key)
 	defer span.End()
 
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	c.metrics.RecordKeyGroup("set", key)
+	defer func(start time.Time) { c.recordOperation(span, "set", start, err) }(time.Now())
+
 	// Hierarchical cache set
 	if c.config.Hierarchical {
-		return c.setHierarchical(ctx, key, value, ttl)
+		return c.setHierarchical(ctx, key, value, ttl, opts...)
 	}
 
 	// Distributed cache set
 	if c.config.Distributed {
-		return c.setDistributed(ctx, key, value, ttl)
+		return c.setDistributed(ctx, key, value, ttl, opts...)
 	}
 
 	// Single backend set
-	return c.setSingle(ctx, key, value, ttl)
+	return c.setSingle(ctx, key, value, ttl, opts...)
 }
 
 // Delete removes a value from the cache.
-func (c *CacheClient) Delete(ctx context.Context, key string) error {
+func (c *CacheClient) Delete(ctx context.Context, key string) (err error) {
 	// Start tracing span
-	ctx, span := c.startSpan(ctx, "cache.delete")
+	ctx, span := c.startKeySpan(ctx, "cache.delete", key)
 	defer span.End()
 
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	c.metrics.RecordKeyGroup("delete", key)
+	defer func(start time.Time) { c.recordOperation(span, "delete", start, err) }(time.Now())
+
 	// Hierarchical cache delete
 	if c.config.Hierarchical {
 		return c.deleteHierarchical(ctx, key)
@@ -232,7 +603,7 @@ func (c *CacheClient) Delete(ctx context.Context, key string) error {
 // Exists checks if a key exists in the cache.
 func (c *CacheClient) Exists(ctx context.Context, key string) (bool, error) {
 	// Start tracing span
-	ctx, span := c.startSpan(ctx, "cache.exists")
+	ctx, span := c.startKeySpan(ctx, "cache.exists", key)
 	defer span.End()
 
 	// Hierarchical cache check
@@ -246,44 +617,78 @@ func (c *CacheClient) Exists(ctx context.Context, key string) (bool, error) {
 	}
 
 	// Single backend check
-	return c.backend.Exists(ctx, key)
+	var exists bool
+	err := c.guard(func() error {
+		var err error
+		exists, err = c.backend.Exists(ctx, key)
+		return err
+	})
+	return exists, err
 }
 
-// GetMulti retrieves multiple values from the cache.
-func (c *CacheClient) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+// GetMulti retrieves multiple values from the cache. Keys that are not
+// cached are omitted from the result unless WithMisses is given.
+func (c *CacheClient) GetMulti(ctx context.Context, keys []string, opts ...GetMultiOption) (map[string]interface{}, error) {
 	// Start tracing span
 	ctx, span := c.startSpan(ctx, "cache.get_multi")
 	defer span.End()
 
+	var options getMultiOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	result := make(map[string]interface{})
 
 	// For hierarchical or distributed cache, we need to handle each key individually
 	if c.config.Hierarchical || c.config.Distributed {
 		for _, key := range keys {
 			value, err := c.Get(ctx, key)
+			if errors.Is(err, ErrNoShards) {
+				return nil, err
+			}
+			if err != nil && !errors.Is(err, backends.ErrNotFound) && options.includeMisses {
+				return nil, fmt.Errorf("failed to get key %s: %w", key, err)
+			}
 			if err == nil {
 				result[key] = value
 			}
 		}
-		return result, nil
-	}
+	} else {
+		// Single backend get multi
+		var rawResult map[string][]byte
+		err := c.guard(func() error {
+			var err error
+			rawResult, err = c.backend.GetMulti(ctx, keys)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
 
-	// Single backend get multi
-	rawResult, err := c.backend.GetMulti(ctx, keys)
-	if err != nil {
-		return nil, err
+		for key, value := range rawResult {
+			// Decode each value
+			decodedValue, err := c.decode(key, value)
+			if err != nil {
+				err = c.evictUndecodable(ctx, key, err)
+				if !errors.Is(err, backends.ErrNotFound) && options.includeMisses {
+					return nil, fmt.Errorf("failed to get key %s: %w", key, err)
+				}
+				continue
+			}
+			result[key] = decodedValue
+		}
 	}
 
-	resultMap := make(map[string]interface{})
-	for key, value := range rawResult {
-		// Deserialize each value
-		var deserializedValue interface{}
-		if err := c.serializer.Deserialize(value, &deserializedValue); err == nil {
-			resultMap[key] = deserializedValue
+	if options.includeMisses {
+		for _, key := range keys {
+			if _, found := result[key]; !found {
+				result[key] = Missing
+			}
 		}
 	}
 
-	return resultMap, nil
+	return result, nil
 }
 
 // SetMulti stores multiple values in the cache.
@@ -292,6 +697,10 @@ func (c *CacheClient) SetMulti(ctx context.Context, items map[string]interface{}
 	ctx, span := c.startSpan(ctx, "cache.set_multi")
 	defer span.End()
 
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
 	// For hierarchical or distributed cache, we need to handle each item individually
 	if c.config.Hierarchical || c.config.Distributed {
 		for key, value := range items {
@@ -303,15 +712,17 @@ func (c *CacheClient) SetMulti(ctx context.Context, items map[string]interface{}
 	}
 
 	// Single backend set multi
-	serializedItems := make(map[string][]byte)
+	encodedItems := make(map[string][]byte)
 	for key, value := range items {
-		serializedValue, err := c.serializer.Serialize(value)
+		encodedValue, err := c.encode(key, value)
 		if err != nil {
 			return err
 		}
-		serializedItems[key] = serializedValue
+		encodedItems[key] = encodedValue
 	}
-	return c.backend.SetMulti(ctx, serializedItems, ttl)
+	return c.guard(func() error {
+		return c.backend.SetMulti(ctx, encodedItems, ttl)
+	})
 }
 
 // DeleteMulti removes multiple values from the cache.
@@ -320,8 +731,12 @@ func (c *CacheClient) DeleteMulti(ctx context.Context, keys []string) error {
 	ctx, span := c.startSpan(ctx, "cache.delete_multi")
 	defer span.End()
 
-	// For hierarchical or distributed cache, we need to handle each key individually
-	if c.config.Hierarchical || c.config.Distributed {
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	// For hierarchical cache, we need to handle each key individually
+	if c.config.Hierarchical {
 		for _, key := range keys {
 			if err := c.Delete(ctx, key); err != nil {
 				return err
@@ -330,116 +745,556 @@ func (c *CacheClient) DeleteMulti(ctx context.Context, keys []string) error {
 		return nil
 	}
 
+	// Distributed cache issues one batched delete per shard
+	if c.config.Distributed {
+		return c.deleteMultiDistributed(ctx, keys)
+	}
+
 	// Single backend delete multi
-	return c.backend.DeleteMulti(ctx, keys)
+	return c.guard(func() error {
+		return c.backend.DeleteMulti(ctx, keys)
+	})
 }
 
 // Increment atomically increments a numeric value.
 func (c *CacheClient) Increment(ctx context.Context, key string, delta int64) (int64, error) {
 	// Start tracing span
-	ctx, span := c.startSpan(ctx, "cache.increment")
+	ctx, span := c.startKeySpan(ctx, "cache.increment", key)
 	defer span.End()
 
+	if c.config.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
 	// For hierarchical cache, use L2 for atomic operations
 	if c.config.Hierarchical {
-		return c.l2Cache.Increment(ctx, key, delta)
+		value, err := c.l2Cache.Increment(ctx, key, delta)
+		if err != nil {
+			return 0, err
+		}
+		c.invalidateL1(ctx, key)
+		return value, nil
 	}
 
 	// For distributed cache, use the appropriate shard
+	backend := c.backend
 	if c.config.Distributed {
-		shard := c.getShard(key)
-		return shard.Increment(ctx, key, delta)
+		shard, err := c.getShard(key)
+		if err != nil {
+			return 0, err
+		}
+		backend = shard
 	}
 
-	// Single backend increment
-	return c.backend.Increment(ctx, key, delta)
+	var value int64
+	err := c.guard(func() error {
+		var err error
+		value, err = backend.Increment(ctx, key, delta)
+		return err
+	})
+	return value, err
 }
 
 // Decrement atomically decrements a numeric value.
 func (c *CacheClient) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
 	// Start tracing span
-	ctx, span := c.startSpan(ctx, "cache.decrement")
+	ctx, span := c.startKeySpan(ctx, "cache.decrement", key)
 	defer span.End()
 
+	if c.config.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
 	// For hierarchical cache, use L2 for atomic operations
 	if c.config.Hierarchical {
-		return c.l2Cache.Decrement(ctx, key, delta)
+		value, err := c.l2Cache.Decrement(ctx, key, delta)
+		if err != nil {
+			return 0, err
+		}
+		c.invalidateL1(ctx, key)
+		return value, nil
 	}
 
 	// For distributed cache, use the appropriate shard
+	backend := c.backend
 	if c.config.Distributed {
-		shard := c.getShard(key)
-		return shard.Decrement(ctx, key, delta)
+		shard, err := c.getShard(key)
+		if err != nil {
+			return 0, err
+		}
+		backend = shard
+	}
+
+	var value int64
+	err := c.guard(func() error {
+		var err error
+		value, err = backend.Decrement(ctx, key, delta)
+		return err
+	})
+	return value, err
+}
+
+// IncrementWithTTLOnCreate atomically increments a numeric value and, only
+// if the increment creates it, sets its TTL. Later increments leave the TTL
+// alone, which makes it suitable for fixed-window counters:
+//
+//	count, err := cache.IncrementWithTTLOnCreate(ctx, "logins:"+userID, 1, time.Hour)
+func (c *CacheClient) IncrementWithTTLOnCreate(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	// Start tracing span
+	ctx, span := c.startKeySpan(ctx, "cache.increment_with_ttl_on_create", key)
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	backend, err := c.backendFor(key)
+	if err != nil {
+		return 0, err
+	}
+	incrementer, ok := backend.(backends.CreateTTLIncrementer)
+	if !ok {
+		return 0, fmt.Errorf("increment with TTL not supported by backend")
 	}
 
-	// Single backend decrement
-	return c.backend.Decrement(ctx, key, delta)
+	var value int64
+	err = c.guard(func() error {
+		var err error
+		value, err = incrementer.IncrementWithTTLOnCreate(ctx, key, delta, ttl)
+		return err
+	})
+	if err == nil && c.config.Hierarchical {
+		c.invalidateL1(ctx, key)
+	}
+	return value, err
 }
 
-// SetNX sets a value only if the key doesn't exist.
+// SetNX sets a value only if the key doesn't exist. The check and the write
+// are a single atomic backend operation, so of several concurrent callers
+// exactly one succeeds.
 func (c *CacheClient) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
 	// Start tracing span
-	ctx, span := c.startSpan(ctx, "cache.setnx")
+	ctx, span := c.startKeySpan(ctx, "cache.setnx", key)
 	defer span.End()
 
-	// Check if key exists first
-	exists, err := c.Exists(ctx, key)
+	if c.config.ReadOnly {
+		return false, ErrReadOnly
+	}
+
+	// Hierarchical cache set
+	if c.config.Hierarchical {
+		return c.setNXHierarchical(ctx, key, value, ttl)
+	}
+
+	data, err := c.encode(key, value)
 	if err != nil {
 		return false, err
 	}
-	if exists {
-		return false, nil
+
+	// Distributed cache sets in the shard owning key
+	backend := c.backend
+	if c.config.Distributed {
+		shard, err := c.getShard(key)
+		if err != nil {
+			return false, err
+		}
+		backend = shard
 	}
 
-	// Set the value
-	err = c.Set(ctx, key, value, ttl)
-	return err == nil, err
+	var stored bool
+	err = c.guard(func() error {
+		var err error
+		stored, err = backend.SetNX(ctx, key, data, ttl)
+		return err
+	})
+	return stored, err
 }
 
-// GetSet atomically sets a value and returns the old value.
+// GetSet atomically sets a value and returns the old value, or nil if the
+// key did not exist. The key keeps its remaining TTL; a new key is stored as
+// by Set with a zero TTL. Memcached does not support GetSet.
 func (c *CacheClient) GetSet(ctx context.Context, key string, value interface{}) (interface{}, error) {
 	// Start tracing span
-	ctx, span := c.startSpan(ctx, "cache.getset")
+	ctx, span := c.startKeySpan(ctx, "cache.getset", key)
 	defer span.End()
 
-	// Get the old value
-	oldValue, _ := c.Get(ctx, key)
+	if c.config.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	// Hierarchical cache swap
+	if c.config.Hierarchical {
+		return c.getSetHierarchical(ctx, key, value)
+	}
+
+	data, err := c.encode(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := c.backend
+	if c.config.Distributed {
+		if backend, err = c.getShard(key); err != nil {
+			return nil, err
+		}
+	}
 
-	// Set the new value
-	err := c.Set(ctx, key, value, 0) // Use default TTL
+	var old []byte
+	err = c.guard(func() error {
+		var err error
+		old, err = backend.GetSet(ctx, key, data)
+		return err
+	})
+	if errors.Is(err, backends.ErrNotFound) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return oldValue, nil
+	return c.decode(key, old)
+}
+
+// GetOrSet returns the cached value for key, or calls loader on a miss and
+// caches its result with the given TTL. Only a missing key is a miss: other
+// read errors, such as an unreachable backend or an open breaker, are
+// returned without calling loader. A loaded value that cannot be cached is
+// still returned, and the failure is counted as a "store" error of the
+// get_or_set operation. The whole operation, including the
+// loader, is bounded by ctx: the loader receives ctx, GetOrSet returns as soon
+// as ctx is done, and a result produced after that point is not cached. With
+// SingleFlight enabled, concurrent misses for key run loader only once. With
+// LoaderLimit.MaxConcurrent set, loaders of keys in the same group wait for
+// one of the group's slots before running.
+func (c *CacheClient) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader LoaderFunc, opts ...GetOrSetOption) (interface{}, error) {
+	// Start tracing span
+	ctx, span := c.startKeySpan(ctx, "cache.get_or_set", key)
+	defer span.End()
+
+	value, _, err := c.getOrSet(ctx, key, ttl, loader, opts)
+	return value, err
 }
 
-// Expire sets a timeout on a key.
+// GetOrSetWithTTL is like GetOrSet, but also reports whether the value was a
+// cache hit and the remaining TTL of the cached entry, so callers can refresh
+// entries that are close to expiring in the background. The TTL is read with
+// a separate call after the value; failing to read it does not fail the
+// call, and TTL is then reported as zero.
+func (c *CacheClient) GetOrSetWithTTL(ctx context.Context, key string, ttl time.Duration, loader LoaderFunc, opts ...GetOrSetOption) (GetOrSetResult, error) {
+	// Start tracing span
+	ctx, span := c.startKeySpan(ctx, "cache.get_or_set_with_ttl", key)
+	defer span.End()
+
+	value, hit, err := c.getOrSet(ctx, key, ttl, loader, opts)
+	if err != nil {
+		return GetOrSetResult{}, err
+	}
+
+	result := GetOrSetResult{Value: value, Hit: hit}
+	if remaining, err := c.TTL(ctx, key); err == nil {
+		result.TTL = remaining
+	}
+	return result, nil
+}
+
+// getOrSet implements GetOrSet, also reporting whether the value was found
+// in the cache.
+func (c *CacheClient) getOrSet(ctx context.Context, key string, ttl time.Duration, loader LoaderFunc, opts []GetOrSetOption) (interface{}, bool, error) {
+	value, err := c.Get(ctx, key)
+	if err == nil {
+		return value, true, nil
+	}
+	if !errors.Is(err, backends.ErrNotFound) {
+		return nil, false, err
+	}
+
+	load := func() (interface{}, error) {
+		value, err := loadWithContext(ctx, c.limitLoader(key, loader))
+		if err != nil {
+			return nil, err
+		}
+
+		c.storeLoaded(ctx, key, value, ttl, newGetOrSetOptions(opts))
+		return value, nil
+	}
+
+	if !c.config.SingleFlight {
+		value, err := load()
+		return value, false, err
+	}
+
+	// Concurrent misses share the load started by the first caller, which
+	// runs with that caller's context and options
+	select {
+	case result := <-c.loads.DoChan(key, load):
+		return result.Val, false, result.Err
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// Expire sets a timeout on a key. In hierarchical mode the timeout is set in
+// L2 and the L1 copy, which could outlive it, is dropped.
 func (c *CacheClient) Expire(ctx context.Context, key string, ttl time.Duration) error {
 	// Start tracing span
-	ctx, span := c.startSpan(ctx, "cache.expire")
+	ctx, span := c.startKeySpan(ctx, "cache.expire", key)
 	defer span.End()
 
-	// For hierarchical or distributed cache, this operation might not be supported
-	if c.config.Hierarchical || c.config.Distributed {
-		return fmt.Errorf("expire operation not supported in hierarchical/distributed mode")
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	if c.config.Hierarchical {
+		if err := c.l2Cache.Expire(ctx, key, ttl); err != nil {
+			return err
+		}
+		c.invalidateL1(ctx, key)
+		return nil
+	}
+
+	// Distributed cache expire
+	if c.config.Distributed {
+		return c.expireDistributed(ctx, key, ttl)
 	}
 
-	return c.backend.Expire(ctx, key, ttl)
+	return c.guard(func() error {
+		return c.backend.Expire(ctx, key, ttl)
+	})
 }
 
 // TTL returns the remaining time to live of a key.
 func (c *CacheClient) TTL(ctx context.Context, key string) (time.Duration, error) {
 	// Start tracing span
-	ctx, span := c.startSpan(ctx, "cache.ttl")
+	ctx, span := c.startKeySpan(ctx, "cache.ttl", key)
 	defer span.End()
 
-	// For hierarchical or distributed cache, this operation might not be supported
-	if c.config.Hierarchical || c.config.Distributed {
-		return 0, fmt.Errorf("ttl operation not supported in hierarchical/distributed mode")
+	// For hierarchical cache, this operation might not be supported
+	if c.config.Hierarchical {
+		return 0, fmt.Errorf("ttl operation not supported in hierarchical mode")
+	}
+
+	// Distributed cache TTL
+	if c.config.Distributed {
+		return c.ttlDistributed(ctx, key)
+	}
+
+	var ttl time.Duration
+	err := c.guard(func() error {
+		var err error
+		ttl, err = c.backend.TTL(ctx, key)
+		return err
+	})
+	return ttl, err
+}
+
+// GetMultiTTL returns the remaining time to live of many keys at once. Missing
+// keys are omitted from the result and keys without an expiration report -1.
+// In distributed mode keys are grouped by shard and each shard is queried
+// once; in hierarchical mode the L2 tier is queried.
+func (c *CacheClient) GetMultiTTL(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.get_multi_ttl")
+	defer span.End()
+
+	// The L2 tier holds the authoritative expirations in hierarchical mode
+	if c.config.Hierarchical {
+		return c.l2Cache.GetMultiTTL(ctx, keys)
+	}
+
+	if !c.config.Distributed {
+		var result map[string]time.Duration
+		err := c.guard(func() error {
+			var err error
+			result, err = getMultiTTL(ctx, c.backend, keys)
+			return err
+		})
+		return result, err
+	}
+
+	// Group keys by shard so each shard is queried once
+	shardKeys, err := c.groupByShard(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]time.Duration, len(keys))
+	for shard, keys := range shardKeys {
+		var ttls map[string]time.Duration
+		err := c.guard(func() error {
+			var err error
+			ttls, err = getMultiTTL(ctx, shard, keys)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for key, ttl := range ttls {
+			result[key] = ttl
+		}
+	}
+
+	return result, nil
+}
+
+// ProbeMulti reports, for every key in keys, whether it exists and its
+// remaining time to live, in as few round trips as the backend allows: one
+// pipeline in Redis and one locked pass in memory. Keys without an
+// expiration report a TTL of -1. In distributed mode keys are grouped by
+// shard and each shard is probed once; in hierarchical mode the L2 tier is
+// probed.
+func (c *CacheClient) ProbeMulti(ctx context.Context, keys []string) (map[string]backends.KeyProbe, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.probe_multi")
+	defer span.End()
+
+	// The L2 tier holds the authoritative entries in hierarchical mode
+	if c.config.Hierarchical {
+		return c.l2Cache.ProbeMulti(ctx, keys)
+	}
+
+	if !c.config.Distributed {
+		var result map[string]backends.KeyProbe
+		err := c.guard(func() error {
+			var err error
+			result, err = probeMulti(ctx, c.backend, keys)
+			return err
+		})
+		return result, err
+	}
+
+	// Group keys by shard so each shard is probed once
+	shardKeys, err := c.groupByShard(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]backends.KeyProbe, len(keys))
+	for shard, keys := range shardKeys {
+		var probes map[string]backends.KeyProbe
+		err := c.guard(func() error {
+			var err error
+			probes, err = probeMulti(ctx, shard, keys)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for key, probe := range probes {
+			result[key] = probe
+		}
+	}
+
+	return result, nil
+}
+
+// Keys returns all keys matching a Redis-style glob pattern such as "user:*".
+// In distributed mode every shard is queried. Memcached does not support
+// key enumeration.
+func (c *CacheClient) Keys(ctx context.Context, pattern string) ([]string, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.keys")
+	defer span.End()
+
+	// The L2 tier holds the complete key set in hierarchical mode
+	if c.config.Hierarchical {
+		return c.l2Cache.Keys(ctx, pattern)
 	}
 
-	return c.backend.TTL(ctx, key)
+	var keys []string
+	for _, backend := range c.dataBackends() {
+		backendKeys, err := backend.Keys(ctx, pattern)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, backendKeys...)
+	}
+
+	return keys, nil
+}
+
+// DeleteByPattern deletes all keys matching a Redis-style glob pattern and
+// returns the number of keys deleted. The memory backend deletes under a
+// single lock and Redis deletes one SCAN page at a time, so the matching keys
+// are never all collected first. In hierarchical mode both tiers are cleared
+// and the L2 count is returned.
+func (c *CacheClient) DeleteByPattern(ctx context.Context, pattern string) (int, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.delete_by_pattern")
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	// Hierarchical cache delete
+	if c.config.Hierarchical {
+		if _, err := c.l1Cache.DeleteByPattern(ctx, pattern); err != nil {
+			return 0, fmt.Errorf("failed to delete from L1 cache: %w", err)
+		}
+		deleted, err := c.l2Cache.DeleteByPattern(ctx, pattern)
+		if err != nil {
+			return deleted, err
+		}
+		c.publishPatternInvalidation(ctx, pattern)
+		return deleted, nil
+	}
+
+	deleted := 0
+	for _, backend := range c.dataBackends() {
+		n, err := deleteByPattern(ctx, backend, pattern)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	return deleted, nil
+}
+
+// ExistsPattern reports whether at least one key matches a Redis-style glob
+// pattern, stopping at the first match.
+func (c *CacheClient) ExistsPattern(ctx context.Context, pattern string) (bool, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.exists_pattern")
+	defer span.End()
+
+	// The L2 tier holds the complete key set in hierarchical mode
+	if c.config.Hierarchical {
+		return c.l2Cache.ExistsPattern(ctx, pattern)
+	}
+
+	for _, backend := range c.dataBackends() {
+		if checker, ok := backend.(backends.PatternChecker); ok {
+			found, err := checker.ExistsPattern(ctx, pattern)
+			if err != nil || found {
+				return found, err
+			}
+			continue
+		}
+
+		// Fall back to enumerating entries, stopping at the first match
+		iterator, ok := backend.(backends.EntryIterator)
+		if !ok {
+			return false, fmt.Errorf("exists pattern operation not supported by %s backend", c.config.Backend)
+		}
+
+		found := false
+		err := iterator.Iterate(ctx, func(key string, value []byte, ttl time.Duration) error {
+			if backends.MatchPattern(pattern, key) {
+				found = true
+				return errStopIteration
+			}
+			return nil
+		})
+		if found {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
 }
 
 // Clear removes all keys from the cache.
@@ -448,6 +1303,10 @@ func (c *CacheClient) Clear(ctx context.Context) error {
 	ctx, span := c.startSpan(ctx, "cache.clear")
 	defer span.End()
 
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
 	// Hierarchical cache clear
 	if c.config.Hierarchical {
 		if err := c.l1Cache.Clear(ctx); err != nil {
@@ -504,6 +1363,119 @@ func (c *CacheClient) Stats(ctx context.Context) (*Stats, error) {
 	}, nil
 }
 
+// ResetStats zeroes the hit, miss, set, delete and eviction counters reported
+// by Stats, so hit ratios can be measured over a window. The key count and
+// memory usage keep reflecting the stored entries. In hierarchical mode both
+// tiers are reset; in distributed mode every shard is. Redis resets the
+// server statistics, which are shared by every client of the server, and
+// Memcached does not support resetting.
+func (c *CacheClient) ResetStats(ctx context.Context) error {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.reset_stats")
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	if c.config.Hierarchical {
+		if err := c.l1Cache.ResetStats(ctx); err != nil {
+			return fmt.Errorf("failed to reset L1 stats: %w", err)
+		}
+		if err := c.l2Cache.ResetStats(ctx); err != nil {
+			return fmt.Errorf("failed to reset L2 stats: %w", err)
+		}
+		return nil
+	}
+
+	if !c.config.Distributed {
+		return resetStats(ctx, c.backend)
+	}
+
+	for i, shard := range c.shards {
+		if err := resetStats(ctx, shard); err != nil {
+			return fmt.Errorf("failed to reset stats of shard %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// PurgeExpired removes expired entries that the backend has not removed yet,
+// such as those of the memory backend waiting for its next cleanup, and
+// returns how many were removed. Backends that expire entries themselves,
+// like Redis, do not support it. In hierarchical mode both tiers are
+// purged; in distributed mode the shards are.
+func (c *CacheClient) PurgeExpired(ctx context.Context) (int, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.purge_expired")
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	if c.config.Hierarchical {
+		l1, ok1 := c.l1Cache.(*CacheClient)
+		l2, ok2 := c.l2Cache.(*CacheClient)
+		if !ok1 || !ok2 {
+			return 0, fmt.Errorf("purging expired entries not supported by cache tiers")
+		}
+		removed, err := l1.PurgeExpired(ctx)
+		if err != nil {
+			return removed, fmt.Errorf("failed to purge L1: %w", err)
+		}
+		l2Removed, err := l2.PurgeExpired(ctx)
+		if err != nil {
+			return removed + l2Removed, fmt.Errorf("failed to purge L2: %w", err)
+		}
+		return removed + l2Removed, nil
+	}
+
+	if !c.config.Distributed {
+		return purgeExpired(ctx, c.backend)
+	}
+
+	total := 0
+	for i, shard := range c.shards {
+		removed, err := purgeExpired(ctx, shard)
+		total += removed
+		if err != nil {
+			return total, fmt.Errorf("failed to purge shard %d: %w", i, err)
+		}
+	}
+	return total, nil
+}
+
+// Size returns the number of stored keys using the cheapest count each
+// backend offers, such as DBSIZE for Redis, instead of collecting full Stats.
+// Keys that have expired but not yet been removed may be included. In
+// hierarchical mode the L2 tier is counted; in distributed mode the shards
+// are summed.
+func (c *CacheClient) Size(ctx context.Context) (int64, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.size")
+	defer span.End()
+
+	// L2 holds every key in hierarchical mode
+	if c.config.Hierarchical {
+		return c.l2Cache.Size(ctx)
+	}
+
+	if !c.config.Distributed {
+		return backendSize(ctx, c.backend)
+	}
+
+	var total int64
+	for _, shard := range c.shards {
+		size, err := backendSize(ctx, shard)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
 // Health checks the health of the cache backend.
 func (c *CacheClient) Health(ctx context.Context) error {
 	// Start tracing span
@@ -532,10 +1504,204 @@ func (c *CacheClient) Health(ctx context.Context) error {
 	return c.backend.Health(ctx)
 }
 
-// Close closes the cache client and releases resources.
+// ExtendedStats returns the common Stats along with backend-specific extras
+// for every backend, tier or shard able to report them.
+func (c *CacheClient) ExtendedStats(ctx context.Context) (*ExtendedStats, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.extended_stats")
+	defer span.End()
+
+	stats, err := c.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	extended := &ExtendedStats{
+		Stats: *stats,
+		Extra: make(map[string]map[string]interface{}),
+	}
+	for _, comp := range c.components() {
+		if comp.extra == nil {
+			continue
+		}
+		extra, err := comp.extra.ExtendedStats(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get extended stats for %s: %w", comp.level, err)
+		}
+		extended.Extra[comp.level] = extra
+	}
+
+	return extended, nil
+}
+
+// HealthAll checks every backend, tier and shard and reports the health of
+// each one. Unlike Health it does not stop at the first failure.
+func (c *CacheClient) HealthAll(ctx context.Context) []ComponentHealth {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.health_all")
+	defer span.End()
+
+	components := c.components()
+	report := make([]ComponentHealth, 0, len(components))
+	for _, comp := range components {
+		health := ComponentHealth{
+			Component: comp.level,
+			Backend:   comp.backend,
+			Healthy:   true,
+		}
+		if err := comp.health(ctx); err != nil {
+			health.Healthy = false
+			health.Error = err.Error()
+		}
+		report = append(report, health)
+	}
+
+	return report
+}
+
+// ClockSkew reports, for every backend, tier or shard that can read the clock
+// its expirations are measured against, how far that clock is ahead of the
+// local one; negative values are behind. It is keyed by component, as in
+// HealthAll. Components whose clock cannot be read are left out and their
+// errors joined.
+//
+// TTLs are sent to every backend as durations and counted down by the
+// backend's own clock, so a constant skew does not change when entries
+// expire. Skew matters where absolute times cross clocks, such as Memcached
+// expirations beyond 30 days, which are computed from the local clock.
+func (c *CacheClient) ClockSkew(ctx context.Context) (map[string]time.Duration, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.clock_skew")
+	defer span.End()
+
+	skews := make(map[string]time.Duration)
+	var errs []error
+	for _, comp := range c.components() {
+		if comp.clock == nil {
+			continue
+		}
+		skew, err := measureSkew(ctx, comp.clock)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read the %s clock: %w", comp.level, err))
+			continue
+		}
+		skews[comp.level] = skew
+	}
+
+	return skews, errors.Join(errs...)
+}
+
+// ShardInfo returns the index, backend type, server addresses and health of
+// every shard, in shard order. It is only available in distributed mode.
+func (c *CacheClient) ShardInfo(ctx context.Context) ([]ShardInfo, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.shard_info")
+	defer span.End()
+
+	if !c.config.Distributed {
+		return nil, fmt.Errorf("shard info requires distributed mode")
+	}
+
+	shards := make([]ShardInfo, 0, len(c.shards))
+	for i, shard := range c.shards {
+		info := ShardInfo{
+			Index:   i,
+			Backend: c.config.Backend,
+			Healthy: true,
+		}
+		if reporter, ok := shard.(backends.AddressReporter); ok {
+			info.Addresses = reporter.Addresses()
+		}
+		if err := shard.Health(ctx); err != nil {
+			info.Healthy = false
+			info.Error = err.Error()
+		}
+		shards = append(shards, info)
+	}
+
+	return shards, nil
+}
+
+// SetKeyGroupFunc replaces the function deriving key group metric labels
+// from keys. It has no effect unless Prometheus key groups are enabled.
+func (c *CacheClient) SetKeyGroupFunc(fn metrics.KeyGroupFunc) {
+	c.metrics.SetKeyGroupFunc(fn)
+}
+
+// OnCodecError sets a hook called on every serialization or compression
+// failure. It must be set before the client is used concurrently.
+func (c *CacheClient) OnCodecError(hook CodecErrorHook) {
+	c.codecErrorHook = hook
+}
+
+// OnLargeValue sets the hook called for every written value larger than
+// LargeValueThreshold, replacing the default that logs the key with the
+// standard logger. It must be set before the client is used concurrently.
+func (c *CacheClient) OnLargeValue(hook LargeValueHook) {
+	c.largeValueHook = hook
+}
+
+// BreakerState returns the state of the circuit breaker guarding the
+// backend. It reports Closed when the circuit breaker is disabled.
+func (c *CacheClient) BreakerState() breaker.State {
+	if c.breaker == nil {
+		return breaker.Closed
+	}
+	return c.breaker.State()
+}
+
+// ResetBreaker force-closes the circuit breaker so the next operation is
+// sent to the backend immediately instead of waiting for the cooldown.
+func (c *CacheClient) ResetBreaker() {
+	if c.breaker != nil {
+		c.breaker.Reset()
+	}
+}
+
+// RecommendPoolSize returns a connection pool size recommendation for
+// backends that track pool usage (currently Redis).
+func (c *CacheClient) RecommendPoolSize() (backends.PoolRecommendation, error) {
+	sizer, ok := c.backend.(interface {
+		RecommendPoolSize() backends.PoolRecommendation
+	})
+	if !ok {
+		return backends.PoolRecommendation{}, fmt.Errorf("pool size recommendation not supported by %s backend", c.config.Backend)
+	}
+	return sizer.RecommendPoolSize(), nil
+}
+
+// Close closes the cache client and releases resources. It is safe to call
+// more than once; calls after the first return nil.
 func (c *CacheClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.close()
+	})
+	return err
+}
+
+// close stops background work and closes every backend.
+func (c *CacheClient) close() error {
 	var errors []error
 
+	// Stop the metrics stats poller and wait for it to exit
+	if c.stopStats != nil {
+		close(c.stopStats)
+		c.statsDone.Wait()
+	}
+
+	// Stop serving metrics
+	if err := c.metrics.Close(); err != nil {
+		errors = append(errors, err)
+	}
+
+	// Stop receiving invalidations before the tiers close
+	if c.invalidator != nil {
+		if err := c.invalidator.stop(); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
 	// Close hierarchical caches
 	if c.config.Hierarchical {
 		if err := c.l1Cache.Close(); err != nil {
@@ -562,12 +1728,10 @@ func (c *CacheClient) Close() error {
 		}
 	}
 
-	// Close tracer
-	// if c.tracer != nil {
-	// 	if err := c.tracer.Close(); err != nil {
-	// 		errors = append(errors, err)
-	// 	}
-	// }
+	// Flush pending spans
+	if err := c.tracer.Close(); err != nil {
+		errors = append(errors, err)
+	}
 
 	if len(errors) > 0 {
 		return fmt.Errorf("errors closing cache: %v", errors)
@@ -2,12 +2,21 @@ package gocachex
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/chmenegatti/gocachex/pkg/backends"
 	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/chmenegatti/gocachex/pkg/hashing"
 	"github.com/chmenegatti/gocachex/pkg/sharding"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // NoOpSpan is a no-operation span for when tracing is disabled.
@@ -15,48 +24,116 @@ type NoOpSpan struct{}
 
 func (n NoOpSpan) End() {}
 
+// errStopIteration ends an entry iteration early once a result is found.
+var errStopIteration = errors.New("stop iteration")
+
 // Helper methods for CacheClient
 
 // initHierarchicalCache initializes hierarchical (L1/L2) cache.
 func (c *CacheClient) initHierarchicalCache() error {
 	// Initialize L1 cache
-	l1Cache, err := New(config.Config{
-		Backend:     c.config.L1.Backend,
-		Memory:      c.config.L1.Memory,
-		Redis:       c.config.L1.Redis,
-		Memcached:   c.config.L1.Memcached,
-		Serializer:  c.config.Serializer,
-		Compression: c.config.Compression,
-	})
+	l1Cache, err := New(c.tierConfig(c.config.L1))
 	if err != nil {
 		return fmt.Errorf("failed to initialize L1 cache: %w", err)
 	}
 	c.l1Cache = l1Cache
 
 	// Initialize L2 cache
-	l2Cache, err := New(config.Config{
-		Backend:     c.config.L2.Backend,
-		Memory:      c.config.L2.Memory,
-		Redis:       c.config.L2.Redis,
-		Memcached:   c.config.L2.Memcached,
-		Serializer:  c.config.Serializer,
-		Compression: c.config.Compression,
-	})
+	l2Cache, err := New(c.tierConfig(c.config.L2))
 	if err != nil {
 		return fmt.Errorf("failed to initialize L2 cache: %w", err)
 	}
 	c.l2Cache = l2Cache
 
+	// Tiers report codec errors, payload sizes, undecodable evictions and
+	// compression timings through this client's metrics and hooks
+	for _, tier := range []Cache{l1Cache, l2Cache} {
+		if client, ok := tier.(*CacheClient); ok {
+			client.reportCodecError = c.codecError
+			client.reportPayloadSize = c.payloadSize
+			client.reportUndecodableEviction = c.undecodableEvicted
+			client.reportCompression = c.compressionTimed
+		}
+	}
+
+	return nil
+}
+
+// tierConfig builds the configuration of a hierarchical tier. Codec settings
+// are inherited from the client unless the tier overrides compression. The
+// tiers of a read-only client are read-only too, so neither tier is ever
+// written, not even to evict an undecodable value or promote one to L1.
+func (c *CacheClient) tierConfig(tier config.CacheConfig) config.Config {
+	cfg := config.Config{
+		Backend:              tier.Backend,
+		Memory:               tier.Memory,
+		Redis:                tier.Redis,
+		Memcached:            tier.Memcached,
+		Serializer:           c.config.Serializer,
+		PreserveIntegers:     c.config.PreserveIntegers,
+		CanonicalJSON:        c.config.CanonicalJSON,
+		DereferencePointers:  c.config.DereferencePointers,
+		Compression:          c.config.Compression,
+		CompressionAlgorithm: c.config.CompressionAlgorithm,
+		CompressionLevel:     c.config.CompressionLevel,
+		CompressionMinSize:   c.config.CompressionMinSize,
+		MaxDecompressedSize:  c.config.MaxDecompressedSize,
+		EvictUndecodable:     c.config.EvictUndecodable,
+		ReadOnly:             c.config.ReadOnly,
+	}
+
+	if tier.Compression != nil {
+		cfg.Compression = *tier.Compression
+	}
+	if tier.CompressionAlgorithm != "" {
+		cfg.CompressionAlgorithm = tier.CompressionAlgorithm
+	}
+
+	return cfg
+}
+
+// newStandbyBackend creates the failover standby backend, applying the same
+// defaults as a top-level backend configuration.
+func newStandbyBackend(standby config.CacheConfig) (backends.Backend, error) {
+	cfg := config.Config{
+		Backend:   standby.Backend,
+		Memory:    standby.Memory,
+		Redis:     standby.Redis,
+		Memcached: standby.Memcached,
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return backends.New(cfg.Backend, cfg)
+}
+
+// checkStrictCodecs returns an error if the configured serializer or
+// compression algorithm is a placeholder for another codec.
+func checkStrictCodecs(cfg config.Config) error {
+	if fallback, ok := backends.PlaceholderFallback(cfg.Serializer); ok {
+		return fmt.Errorf("serializer %s is not implemented and falls back to %s", cfg.Serializer, fallback)
+	}
+	if cfg.Compression {
+		if fallback, ok := backends.PlaceholderFallback(cfg.CompressionAlgorithm); ok {
+			return fmt.Errorf("compression algorithm %s is not implemented and falls back to %s", cfg.CompressionAlgorithm, fallback)
+		}
+	}
 	return nil
 }
 
 // initSharding initializes distributed cache sharding.
 func (c *CacheClient) initSharding() error {
+	hash, err := hashing.New(c.config.Sharding.HashFunction)
+	if err != nil {
+		return err
+	}
+	c.hash = hash
+
 	sharder := sharding.NewSharder(c.config.Sharding)
 
 	// Create shards based on configuration
 	shardCount := c.config.Sharding.Shards
-	if shardCount <= 0 {
+	if shardCount == 0 {
 		shardCount = 3 // Default shard count
 	}
 
@@ -71,74 +148,676 @@ func (c *CacheClient) initSharding() error {
 		}
 	}
 
+	// Fail fast rather than on every operation
+	if len(c.shards) == 0 {
+		return ErrNoShards
+	}
+
+	// Bounded loads need the ring; other keys are placed by hash
+	if c.config.Sharding.LoadFactor > 0 {
+		c.sharder = sharder
+	}
+
 	return nil
 }
 
-// startSpan starts a tracing span if tracing is enabled.
-func (c *CacheClient) startSpan(ctx context.Context, operationName string) (context.Context, interface{ End() }) {
-	return ctx, NoOpSpan{}
+// component is a backend, tier or shard that reports its own stats and health.
+type component struct {
+	backend string
+	level   string
+	stats   func(ctx context.Context) (*Stats, error)
+	health  func(ctx context.Context) error
+	conns   backends.ConnectionReporter
+	extra   backends.ExtendedStatsReporter
+	clock   backends.ClockReporter
 }
 
-// getSingle gets a value from a single backend.
-func (c *CacheClient) getSingle(ctx context.Context, key string) (interface{}, error) {
-	// Get raw data from backend
-	data, err := c.backend.Get(ctx, key)
+// components lists every backend, tier or shard of the client, labelled with
+// its backend type and level ("primary", "l1", "l2" or "shard-N").
+func (c *CacheClient) components() []component {
+	if c.config.Hierarchical {
+		components := []component{
+			{backend: c.config.L1.Backend, level: "l1", stats: c.l1Cache.Stats, health: c.l1Cache.Health},
+			{backend: c.config.L2.Backend, level: "l2", stats: c.l2Cache.Stats, health: c.l2Cache.Health},
+		}
+		for i, tier := range []Cache{c.l1Cache, c.l2Cache} {
+			if client, ok := tier.(*CacheClient); ok {
+				components[i].conns, _ = client.backend.(backends.ConnectionReporter)
+				components[i].extra, _ = client.backend.(backends.ExtendedStatsReporter)
+				components[i].clock, _ = client.backend.(backends.ClockReporter)
+			}
+		}
+		return components
+	}
+
+	if c.config.Distributed {
+		components := make([]component, 0, len(c.shards))
+		for i, shard := range c.shards {
+			components = append(components, backendComponent(c.config.Backend, fmt.Sprintf("shard-%d", i), shard))
+		}
+		return components
+	}
+
+	return []component{backendComponent(c.config.Backend, "primary", c.backend)}
+}
+
+// measureSkew returns how far the clock of reporter is ahead of the local
+// clock, taking the local time halfway through the read.
+func measureSkew(ctx context.Context, reporter backends.ClockReporter) (time.Duration, error) {
+	before := time.Now()
+	remote, err := reporter.ServerTime(ctx)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	after := time.Now()
 
-	// Decompress if needed
-	if c.compressor != nil {
-		data, err = c.compressor.Decompress(data)
+	return remote.Sub(before.Add(after.Sub(before) / 2)), nil
+}
+
+// backendComponent describes a single backend as a component.
+func backendComponent(name, level string, backend backends.Backend) component {
+	comp := component{
+		backend: name,
+		level:   level,
+		stats:   backendStats(backend),
+		health:  backend.Health,
+	}
+	comp.conns, _ = backend.(backends.ConnectionReporter)
+	comp.extra, _ = backend.(backends.ExtendedStatsReporter)
+	comp.clock, _ = backend.(backends.ClockReporter)
+	return comp
+}
+
+// backendStats adapts a backend's Stats to the client Stats type.
+func backendStats(backend backends.Backend) func(ctx context.Context) (*Stats, error) {
+	return func(ctx context.Context) (*Stats, error) {
+		stats, err := backend.Stats(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to decompress data: %w", err)
+			return nil, err
 		}
+		return &Stats{
+			KeyCount:    stats.KeyCount,
+			MemoryUsage: stats.MemoryUsage,
+		}, nil
 	}
+}
 
-	// Deserialize
-	var result interface{}
-	if err := c.serializer.Deserialize(data, &result); err != nil {
-		return nil, fmt.Errorf("failed to deserialize data: %w", err)
+// startMetrics starts the metrics HTTP server when a port is configured and
+// the stats poller. It does nothing when metrics are disabled.
+func (c *CacheClient) startMetrics() error {
+	if c.metrics == nil {
+		return nil
 	}
 
-	return result, nil
+	if c.config.Prometheus.Port > 0 {
+		if err := c.metrics.StartMetricsServer(); err != nil {
+			return err
+		}
+	}
+
+	c.startStatsFlush()
+	return nil
 }
 
-// setSingle sets a value in a single backend.
-func (c *CacheClient) setSingle(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	// Serialize
-	data, err := c.serializer.Serialize(value)
+// startStatsFlush starts a goroutine that periodically copies backend stats
+// into the metric gauges. It does nothing when metrics are disabled.
+func (c *CacheClient) startStatsFlush() {
+	if c.metrics == nil || c.config.Prometheus.StatsInterval <= 0 {
+		return
+	}
+
+	c.stopStats = make(chan struct{})
+	c.statsDone.Add(1)
+	go func() {
+		defer c.statsDone.Done()
+		ticker := time.NewTicker(c.config.Prometheus.StatsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.flushStats(context.Background())
+			case <-c.stopStats:
+				return
+			}
+		}
+	}()
+}
+
+// flushStats polls every stats source once and updates the metric gauges.
+func (c *CacheClient) flushStats(ctx context.Context) {
+	for _, source := range c.components() {
+		stats, err := source.stats(ctx)
+		if err != nil {
+			c.metrics.RecordError("stats", source.backend, "stats")
+			continue
+		}
+
+		c.metrics.UpdateCacheSize(source.backend, source.level, stats.MemoryUsage)
+		c.metrics.UpdateKeyCount(source.backend, source.level, stats.KeyCount)
+		if source.conns != nil {
+			c.metrics.UpdateActiveConnections(source.backend, source.conns.ActiveConnections())
+		}
+		if source.clock != nil {
+			if skew, err := measureSkew(ctx, source.clock); err == nil {
+				c.metrics.UpdateClockSkew(source.backend, source.level, skew)
+			}
+		}
+	}
+}
+
+// startSpan starts a tracing span if tracing is enabled, carrying the
+// operation and backend as attributes. Otherwise it returns the span already
+// in ctx, a no-op span if there is none.
+func (c *CacheClient) startSpan(ctx context.Context, operationName string) (context.Context, trace.Span) {
+	if !c.tracer.IsEnabled() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	return c.tracer.StartSpan(ctx, operationName, trace.WithAttributes(
+		attribute.String("cache.operation", strings.TrimPrefix(operationName, "cache.")),
+		attribute.String("cache.backend", c.config.Backend),
+	))
+}
+
+// startKeySpan starts a tracing span like startSpan for an operation on a
+// single key, adding the key as an attribute.
+func (c *CacheClient) startKeySpan(ctx context.Context, operationName, key string) (context.Context, trace.Span) {
+	ctx, span := c.startSpan(ctx, operationName)
+	if c.tracer.IsEnabled() {
+		span.SetAttributes(attribute.String("cache.key", key))
+	}
+	return ctx, span
+}
+
+// traceResult records the outcome of an operation on its span: a hit or miss
+// event for reads, and the error for failures. Misses are not errors. Spans
+// are left alone when tracing is disabled, since span is then the caller's.
+func (c *CacheClient) traceResult(span trace.Span, operation string, err error) {
+	if !c.tracer.IsEnabled() {
+		return
+	}
+
+	switch {
+	case errors.Is(err, backends.ErrNotFound):
+		if operation == "get" {
+			span.AddEvent("cache.miss")
+		}
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case operation == "get":
+		span.AddEvent("cache.hit")
+	}
+}
+
+// loadWithContext runs loader and waits for it until ctx is done. Results
+// that arrive after ctx is done are discarded, so they never get cached.
+func loadWithContext(ctx context.Context, loader LoaderFunc) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type loadResult struct {
+		value interface{}
+		err   error
+	}
+
+	done := make(chan loadResult, 1)
+	go func() {
+		value, err := loader(ctx)
+		done <- loadResult{value: value, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return nil, result.err
+		}
+		// The loader may have finished right as the deadline passed
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return result.value, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Stored values may start with a small header describing how the payload was
+// encoded. Serializer output never starts with a zero byte, so values without
+// the header are read as plain serialized data.
+const (
+	headerMarker byte = 0x00
+	headerSize        = 2
+
+	// flagString marks a payload holding a Go string verbatim
+	flagString byte = 1 << 0
+
+	// flagCompressed and flagUncompressed mark entries whose compression
+	// differs from the client's setting; the header wraps the whole payload
+	flagCompressed   byte = 1 << 1
+	flagUncompressed byte = 1 << 2
+
+	// flagSerializer marks a payload serialized with a serializer other than
+	// the client's; the header is followed by the serializer id
+	flagSerializer byte = 1 << 3
+)
+
+// serializerIDs identifies serializers in the header of entries stored with
+// WithSerializer. Ids are persisted, so they must never change.
+var serializerIDs = map[string]byte{
+	"json":    1,
+	"gob":     2,
+	"msgpack": 3,
+	"typed":   4,
+}
+
+// dereference follows the pointers value is stored through, returning the
+// value they point to.
+func dereference(value interface{}) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Ptr {
+		return value, nil
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, ErrNilPointer
+		}
+		v = v.Elem()
+	}
+	return v.Interface(), nil
+}
+
+// newSerializer creates the named serializer with the options of cfg.
+func newSerializer(name string, cfg config.Config) (backends.Serializer, error) {
+	serializer, err := backends.NewSerializer(name)
 	if err != nil {
-		return fmt.Errorf("failed to serialize data: %w", err)
+		return nil, err
+	}
+	if jsonSerializer, ok := serializer.(*backends.JSONSerializer); ok {
+		jsonSerializer.PreserveIntegers = cfg.PreserveIntegers
+		jsonSerializer.Canonical = cfg.CanonicalJSON
+	}
+	return serializer, nil
+}
+
+// encode turns a value written to the backend into the bytes stored, and
+// records their size.
+func (c *CacheClient) encode(key string, value interface{}, opts ...SetOption) ([]byte, error) {
+	data, err := c.encodeValue(key, value, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.payloadSize(key, len(data))
+	return data, nil
+}
+
+// encodeValue turns a value into the bytes stored in the backend. Strings
+// skip the serializer and are stored verbatim behind a header. Entries whose
+// compression is overridden by opts carry an outer header recording it.
+func (c *CacheClient) encodeValue(key string, value interface{}, opts ...SetOption) ([]byte, error) {
+	var options setOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if c.config.DereferencePointers {
+		var err error
+		if value, err = dereference(value); err != nil {
+			return nil, err
+		}
+	}
+
+	var data []byte
+	jsonPayload := false
+	if str, ok := value.(string); ok {
+		data = make([]byte, headerSize+len(str))
+		data[0] = headerMarker
+		data[1] = flagString
+		copy(data[headerSize:], str)
+	} else {
+		serializer, id := c.serializer, byte(0)
+		if options.serializer != "" && options.serializer != c.config.Serializer {
+			var ok bool
+			if id, ok = serializerIDs[options.serializer]; !ok {
+				return nil, fmt.Errorf("unsupported serializer type: %s", options.serializer)
+			}
+			serializer = c.serializers[id]
+		}
+
+		serialized, err := serializer.Serialize(value)
+		if err != nil {
+			c.codecError("set", "serialize", key, err)
+			return nil, fmt.Errorf("failed to serialize data: %w", err)
+		}
+		data = serialized
+		_, jsonPayload = serializer.(*backends.JSONSerializer)
+
+		// Record the serializer so decode reads the entry back with it
+		if id != 0 {
+			data = append([]byte{headerMarker, flagSerializer, id}, serialized...)
+		}
+	}
+
+	// Values under the size threshold, or already compressed, are stored as
+	// is unless the entry explicitly asks for compression
+	compress := c.compressor != nil && backends.ShouldCompress(data, c.config.CompressionMinSize)
+	if options.compression != nil {
+		compress = *options.compression
 	}
 
 	// Compress if needed
-	if c.compressor != nil {
-		data, err = c.compressor.Compress(data)
+	if compress {
+		start := time.Now()
+		compressed, err := c.entryCompressor.Compress(data)
+		c.compressionTimed(c.entryCompressor.Algorithm(), "compress", time.Since(start))
 		if err != nil {
-			return fmt.Errorf("failed to compress data: %w", err)
+			c.codecError("set", "compress", key, err)
+			return nil, fmt.Errorf("failed to compress data: %w", err)
 		}
+		data = compressed
+	}
+
+	// Record overrides and skipped compression so decode reads the entry
+	// back correctly. Counters are stored as plain decimals and read back as
+	// JSON numbers, so other payloads that look like one are marked too.
+	if compress != (c.compressor != nil) || (isCounter(data) && (compress || !jsonPayload)) {
+		flag := flagUncompressed
+		if compress {
+			flag = flagCompressed
+		}
+		data = append([]byte{headerMarker, flag}, data...)
+	}
+
+	return data, nil
+}
+
+// decode turns bytes read from the backend back into a value.
+func (c *CacheClient) decode(key string, data []byte) (interface{}, error) {
+	var result interface{}
+	if err := c.decodeInto(key, data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// decodeInto turns bytes read from the backend back into a value stored in
+// target, which must be a pointer. Strings stored verbatim can be decoded
+// into a *string or *interface{} only.
+func (c *CacheClient) decodeInto(key string, data []byte, target interface{}) error {
+	compressed, counter := c.compressor != nil, false
+	if len(data) >= headerSize && data[0] == headerMarker && data[1]&(flagCompressed|flagUncompressed) != 0 {
+		compressed = data[1]&flagCompressed != 0
+		data = data[headerSize:]
+	} else if isCounter(data) {
+		// Increment stores counters as plain decimals the backend can update
+		// atomically, whatever the serializer, so they are never compressed
+		// and are read as JSON numbers
+		compressed, counter = false, true
+	}
+
+	// Decompress if needed
+	if compressed {
+		start := time.Now()
+		decompressed, err := c.entryCompressor.Decompress(data)
+		c.compressionTimed(c.entryCompressor.Algorithm(), "decompress", time.Since(start))
+		if err != nil {
+			c.codecError("get", "decompress", key, err)
+			return undecodableError{fmt.Errorf("failed to decompress data: %w", err)}
+		}
+		data = decompressed
+	}
+
+	if len(data) >= headerSize && data[0] == headerMarker && data[1]&flagString != 0 {
+		switch out := target.(type) {
+		case *interface{}:
+			*out = string(data[headerSize:])
+		case *string:
+			*out = string(data[headerSize:])
+		default:
+			return fmt.Errorf("cannot decode string value into %T", target)
+		}
+		return nil
+	}
+
+	serializer := c.serializer
+	if counter {
+		serializer = c.serializers[serializerIDs["json"]]
+	}
+	if len(data) > headerSize && data[0] == headerMarker && data[1]&flagSerializer != 0 {
+		var ok bool
+		if serializer, ok = c.serializers[data[headerSize]]; !ok {
+			err := fmt.Errorf("unknown serializer id %d", data[headerSize])
+			c.codecError("get", "deserialize", key, err)
+			return undecodableError{fmt.Errorf("failed to deserialize data: %w", err)}
+		}
+		data = data[headerSize+1:]
+	}
+
+	// Deserialize
+	if err := serializer.Deserialize(data, target); err != nil {
+		c.codecError("get", "deserialize", key, err)
+		return undecodableError{fmt.Errorf("failed to deserialize data: %w", err)}
+	}
+
+	return nil
+}
+
+// undecodableError reports a stored value that cannot be decompressed or
+// deserialized, as opposed to one that does not fit the requested type.
+type undecodableError struct {
+	error
+}
+
+func (e undecodableError) Unwrap() error {
+	return e.error
+}
+
+// evictUndecodable deletes key when err reports that its stored value cannot
+// be decoded and EvictUndecodable is set on a writable client, returning
+// ErrNotFound so the value is reloaded. Other errors, or a failed delete,
+// leave err unchanged.
+func (c *CacheClient) evictUndecodable(ctx context.Context, key string, err error) error {
+	var undecodable undecodableError
+	if !c.config.EvictUndecodable || c.config.ReadOnly || !errors.As(err, &undecodable) {
+		return err
+	}
+
+	backend := c.backend
+	if c.config.Distributed {
+		shard, shardErr := c.getShard(key)
+		if shardErr != nil {
+			return err
+		}
+		backend = shard
+	}
+
+	if delErr := backend.Delete(ctx, key); delErr != nil {
+		return err
+	}
+	c.undecodableEvicted()
+	return backends.ErrNotFound
+}
+
+// undecodableEvicted counts a value deleted by evictUndecodable.
+func (c *CacheClient) undecodableEvicted() {
+	if c.reportUndecodableEviction != nil {
+		c.reportUndecodableEviction()
+		return
+	}
+	c.metrics.RecordUndecodableEviction()
+}
+
+// compressionTimed records the duration of a compression or decompression
+// in the compression metric.
+func (c *CacheClient) compressionTimed(algorithm, direction string, duration time.Duration) {
+	if c.reportCompression != nil {
+		c.reportCompression(algorithm, direction, duration)
+		return
+	}
+	c.metrics.RecordCompression(algorithm, direction, duration)
+}
+
+// isCounter reports whether data is a counter written by Increment: a plain
+// decimal int64. encodeValue marks other payloads of that shape, such as a
+// small integer in msgpack, with a header.
+func isCounter(data []byte) bool {
+	if len(data) == 0 || len(data) > 20 {
+		return false
+	}
+	_, err := strconv.ParseInt(string(data), 10, 64)
+	return err == nil
+}
+
+// recordOperation records the duration and outcome of an operation started
+// at start in the metrics, and its outcome on span. Misses are reported with
+// a "miss" status rather than as errors.
+func (c *CacheClient) recordOperation(span trace.Span, operation string, start time.Time, err error) {
+	c.traceResult(span, operation, err)
+	if c.metrics == nil {
+		return
+	}
+
+	status := "success"
+	if errors.Is(err, backends.ErrNotFound) {
+		status = "miss"
+	} else if err != nil {
+		status = "error"
+	}
+	c.metrics.RecordOperationWithBackend(operation, c.config.Backend, status, time.Since(start))
+}
+
+// codecError counts a serialization or compression failure in the errors
+// metric and reports it to the codec error hook, if set.
+func (c *CacheClient) codecError(operation, errorType, key string, err error) {
+	if c.reportCodecError != nil {
+		c.reportCodecError(operation, errorType, key, err)
+		return
+	}
+
+	c.metrics.RecordError(operation, c.config.Backend, errorType)
+	if c.codecErrorHook != nil {
+		c.codecErrorHook(errorType, key, err)
+	}
+}
+
+// payloadSize records the encoded size of a value written under key in the
+// payload size metric, and reports it to the large value hook when it
+// exceeds LargeValueThreshold.
+func (c *CacheClient) payloadSize(key string, size int) {
+	if c.reportPayloadSize != nil {
+		c.reportPayloadSize(key, size)
+		return
+	}
+
+	c.metrics.RecordPayloadSize(c.config.Backend, size)
+	if threshold := c.config.LargeValueThreshold; threshold > 0 && size > threshold {
+		if c.largeValueHook != nil {
+			c.largeValueHook(key, size)
+		} else {
+			log.Printf("gocachex: value of key %s is %d bytes, over the large value threshold of %d bytes", key, size, threshold)
+		}
+	}
+}
+
+// getSingle gets a value from a single backend.
+func (c *CacheClient) getSingle(ctx context.Context, key string) (interface{}, error) {
+	// Get raw data from backend
+	var data []byte
+	err := c.guard(func() error {
+		var err error
+		data, err = c.backend.Get(ctx, key)
+		c.recordLookup("get", c.config.Backend, "primary", err)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := c.decode(key, data)
+	if err != nil {
+		return nil, c.evictUndecodable(ctx, key, err)
+	}
+	return value, nil
+}
+
+// setSingle sets a value in a single backend.
+func (c *CacheClient) setSingle(ctx context.Context, key string, value interface{}, ttl time.Duration, opts ...SetOption) error {
+	data, err := c.encode(key, value, opts...)
+	if err != nil {
+		return err
 	}
 
 	// Store in backend
-	return c.backend.Set(ctx, key, data, ttl)
+	return c.guard(func() error {
+		err := c.backend.Set(ctx, key, data, ttl)
+		c.recordBackendError("set", c.config.Backend, err)
+		return err
+	})
 }
 
 // deleteSingle deletes a value from a single backend.
 func (c *CacheClient) deleteSingle(ctx context.Context, key string) error {
-	return c.backend.Delete(ctx, key)
+	return c.guard(func() error {
+		err := c.backend.Delete(ctx, key)
+		c.recordBackendError("delete", c.config.Backend, err)
+		return err
+	})
+}
+
+// recordLookup counts a read against backend at level as a hit or a miss,
+// or as a backend error when it failed for another reason.
+func (c *CacheClient) recordLookup(operation, backend, level string, err error) {
+	switch {
+	case err == nil:
+		c.metrics.RecordHit(backend, level)
+	case errors.Is(err, backends.ErrNotFound):
+		c.metrics.RecordMiss(backend, level)
+	default:
+		c.recordBackendError(operation, backend, err)
+	}
+}
+
+// recordBackendError counts a failed backend call in the errors metric.
+// Misses are not failures and are left out.
+func (c *CacheClient) recordBackendError(operation, backend string, err error) {
+	if err == nil || errors.Is(err, backends.ErrNotFound) {
+		return
+	}
+	c.metrics.RecordError(operation, backend, "backend")
+}
+
+// guard runs a backend call through the circuit breaker, if enabled. Misses,
+// exceeded quotas and caller cancellations are not counted as backend
+// failures.
+func (c *CacheClient) guard(call func() error) error {
+	if c.breaker == nil {
+		return call()
+	}
+
+	if err := c.breaker.Allow(); err != nil {
+		return err
+	}
+
+	err := call()
+	c.breaker.Record(err != nil && !errors.Is(err, backends.ErrNotFound) &&
+		!errors.Is(err, backends.ErrQuotaExceeded) && !errors.Is(err, context.Canceled))
+	return err
 }
 
 // getHierarchical gets a value from hierarchical cache (L1/L2).
 func (c *CacheClient) getHierarchical(ctx context.Context, key string) (interface{}, error) {
 	// Try L1 cache first
 	value, err := c.l1Cache.Get(ctx, key)
+	c.recordLookup("get", c.config.L1.Backend, "l1", err)
 	if err == nil {
 		return value, nil
 	}
 
 	// Try L2 cache
 	value, err = c.l2Cache.Get(ctx, key)
+	c.recordLookup("get", c.config.L2.Backend, "l2", err)
 	if err != nil {
 		return nil, err
 	}
@@ -159,19 +838,72 @@ func (c *CacheClient) getHierarchical(ctx context.Context, key string) (interfac
 }
 
 // setHierarchical sets a value in hierarchical cache (L1/L2).
-func (c *CacheClient) setHierarchical(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	// Set in both L1 and L2
-	if err := c.l1Cache.Set(ctx, key, value, ttl); err != nil {
-		return fmt.Errorf("failed to set in L1 cache: %w", err)
+func (c *CacheClient) setHierarchical(ctx context.Context, key string, value interface{}, ttl time.Duration, opts ...SetOption) error {
+	// Set in both L1 and L2, attempting L2 even when L1 fails
+	failed := make(map[string]error)
+	if err := setTier(ctx, c.l1Cache, key, value, ttl, opts); err != nil {
+		failed["L1"] = err
+		// Do not leave an older value in L1 to shadow the new one in L2
+		_ = c.l1Cache.Delete(ctx, key)
 	}
 
-	if err := c.l2Cache.Set(ctx, key, value, ttl); err != nil {
-		return fmt.Errorf("failed to set in L2 cache: %w", err)
+	if err := setTier(ctx, c.l2Cache, key, value, ttl, opts); err != nil {
+		failed["L2"] = err
+	} else {
+		c.publishInvalidation(ctx, key)
 	}
 
+	if len(failed) > 0 {
+		return &ErrMultiple{Failed: failed}
+	}
 	return nil
 }
 
+// setNXHierarchical decides SetNX in L2, the tier shared between clients,
+// and copies a stored value to L1.
+func (c *CacheClient) setNXHierarchical(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	stored, err := c.l2Cache.SetNX(ctx, key, value, ttl)
+	if err != nil {
+		return false, fmt.Errorf("failed to set in L2 cache: %w", err)
+	}
+	if stored {
+		c.publishInvalidation(ctx, key)
+		if err := c.l1Cache.Set(ctx, key, value, ttl); err != nil {
+			return true, fmt.Errorf("failed to set in L1 cache: %w", err)
+		}
+	}
+
+	return stored, nil
+}
+
+// getSetHierarchical swaps the value in L2 and drops the L1 copy, which is
+// reloaded with the L1 TTL on the next read.
+func (c *CacheClient) getSetHierarchical(ctx context.Context, key string, value interface{}) (interface{}, error) {
+	old, err := c.l2Cache.GetSet(ctx, key, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set in L2 cache: %w", err)
+	}
+	c.invalidateL1(ctx, key)
+
+	return old, nil
+}
+
+// invalidateL1 drops key from L1 after a write that changed it only in L2,
+// and tells the other clients on the invalidation channel to drop it too.
+func (c *CacheClient) invalidateL1(ctx context.Context, key string) {
+	_ = c.l1Cache.Delete(ctx, key)
+	c.publishInvalidation(ctx, key)
+}
+
+// setTier stores a value in a hierarchical tier, passing opts along when the
+// tier supports them.
+func setTier(ctx context.Context, tier Cache, key string, value interface{}, ttl time.Duration, opts []SetOption) error {
+	if client, ok := tier.(*CacheClient); ok {
+		return client.SetWithOptions(ctx, key, value, ttl, opts...)
+	}
+	return tier.Set(ctx, key, value, ttl)
+}
+
 // deleteHierarchical deletes a value from hierarchical cache (L1/L2).
 func (c *CacheClient) deleteHierarchical(ctx context.Context, key string) error {
 	// Delete from both L1 and L2
@@ -182,6 +914,7 @@ func (c *CacheClient) deleteHierarchical(ctx context.Context, key string) error
 	if err1 != nil && err2 != nil {
 		return fmt.Errorf("failed to delete from both caches: L1=%v, L2=%v", err1, err2)
 	}
+	c.publishInvalidation(ctx, key)
 
 	return nil
 }
@@ -199,92 +932,273 @@ func (c *CacheClient) existsHierarchical(ctx context.Context, key string) (bool,
 
 // getDistributed gets a value from distributed cache.
 func (c *CacheClient) getDistributed(ctx context.Context, key string) (interface{}, error) {
-	shard := c.getShard(key)
-	if shard == nil {
-		return nil, fmt.Errorf("no shard available for key: %s", key)
+	shard, err := c.getShard(key)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get raw data from shard
-	data, err := shard.Get(ctx, key)
+	var data []byte
+	err = c.guard(func() error {
+		var err error
+		data, err = shard.Get(ctx, key)
+		if c.metrics != nil {
+			c.recordLookup("get", c.config.Backend, fmt.Sprintf("shard-%d", c.shardIndex(key)), err)
+		}
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Decompress if needed
-	if c.compressor != nil {
-		data, err = c.compressor.Decompress(data)
+	value, err := c.decode(key, data)
+	if err != nil {
+		return nil, c.evictUndecodable(ctx, key, err)
+	}
+	return value, nil
+}
+
+// setDistributed sets a value in distributed cache.
+func (c *CacheClient) setDistributed(ctx context.Context, key string, value interface{}, ttl time.Duration, opts ...SetOption) error {
+	shard, err := c.getShard(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := c.encode(key, value, opts...)
+	if err != nil {
+		return err
+	}
+
+	// Store in shard
+	return c.guard(func() error {
+		return shard.Set(ctx, key, data, ttl)
+	})
+}
+
+// deleteDistributed deletes a value from distributed cache.
+func (c *CacheClient) deleteDistributed(ctx context.Context, key string) error {
+	shard, err := c.getShard(key)
+	if err != nil {
+		return err
+	}
+
+	return c.guard(func() error {
+		return shard.Delete(ctx, key)
+	})
+}
+
+// existsDistributed checks if a key exists in distributed cache.
+func (c *CacheClient) existsDistributed(ctx context.Context, key string) (bool, error) {
+	shard, err := c.getShard(key)
+	if err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err = c.guard(func() error {
+		var err error
+		exists, err = shard.Exists(ctx, key)
+		return err
+	})
+	return exists, err
+}
+
+// expireDistributed sets a timeout on a key in its shard.
+func (c *CacheClient) expireDistributed(ctx context.Context, key string, ttl time.Duration) error {
+	shard, err := c.getShard(key)
+	if err != nil {
+		return err
+	}
+
+	return c.guard(func() error {
+		return shard.Expire(ctx, key, ttl)
+	})
+}
+
+// ttlDistributed returns the remaining time to live of a key in its shard.
+func (c *CacheClient) ttlDistributed(ctx context.Context, key string) (time.Duration, error) {
+	shard, err := c.getShard(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var ttl time.Duration
+	err = c.guard(func() error {
+		var err error
+		ttl, err = shard.TTL(ctx, key)
+		return err
+	})
+	return ttl, err
+}
+
+// getMultiTTL reads the TTLs of keys from backend, falling back to one TTL
+// call per key when the backend has no bulk read.
+func getMultiTTL(ctx context.Context, backend backends.Backend, keys []string) (map[string]time.Duration, error) {
+	if reader, ok := backend.(backends.MultiTTLReader); ok {
+		return reader.GetMultiTTL(ctx, keys)
+	}
+
+	result := make(map[string]time.Duration, len(keys))
+	for _, key := range keys {
+		ttl, err := backend.TTL(ctx, key)
+		if errors.Is(err, backends.ErrNotFound) {
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to decompress data: %w", err)
+			return nil, err
 		}
+		result[key] = ttl
 	}
 
-	// Deserialize
-	var result interface{}
-	if err := c.serializer.Deserialize(data, &result); err != nil {
-		return nil, fmt.Errorf("failed to deserialize data: %w", err)
+	return result, nil
+}
+
+// probeMulti probes keys in backend, falling back to reading their TTLs with
+// getMultiTTL when the backend cannot probe them natively.
+func probeMulti(ctx context.Context, backend backends.Backend, keys []string) (map[string]backends.KeyProbe, error) {
+	if prober, ok := backend.(backends.MultiProber); ok {
+		return prober.ProbeMulti(ctx, keys)
+	}
+
+	ttls, err := getMultiTTL(ctx, backend, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]backends.KeyProbe, len(keys))
+	for _, key := range keys {
+		ttl, exists := ttls[key]
+		result[key] = backends.KeyProbe{Exists: exists, TTL: ttl}
 	}
 
 	return result, nil
 }
 
-// setDistributed sets a value in distributed cache.
-func (c *CacheClient) setDistributed(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	shard := c.getShard(key)
-	if shard == nil {
-		return fmt.Errorf("no shard available for key: %s", key)
+// deleteByPattern deletes the keys of backend matching pattern, falling back
+// to Keys and DeleteMulti when the backend cannot delete by pattern itself.
+func deleteByPattern(ctx context.Context, backend backends.Backend, pattern string) (int, error) {
+	if deleter, ok := backend.(backends.PatternDeleter); ok {
+		return deleter.DeleteByPattern(ctx, pattern)
+	}
+
+	keys, err := backend.Keys(ctx, pattern)
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+	if err := backend.DeleteMulti(ctx, keys); err != nil {
+		return 0, err
+	}
+	return len(keys), nil
+}
+
+// resetStats resets the statistics of backend, if it supports it.
+func resetStats(ctx context.Context, backend backends.Backend) error {
+	resetter, ok := backend.(backends.StatsResetter)
+	if !ok {
+		return fmt.Errorf("resetting stats not supported by backend")
+	}
+	return resetter.ResetStats(ctx)
+}
+
+// purgeExpired removes the expired entries of backend.
+func purgeExpired(ctx context.Context, backend backends.Backend) (int, error) {
+	purger, ok := backend.(backends.ExpiredPurger)
+	if !ok {
+		return 0, fmt.Errorf("purging expired entries not supported by backend")
+	}
+	return purger.PurgeExpired(ctx)
+}
+
+// backendSize counts the keys in backend, falling back to the key count in
+// its Stats when the backend has no cheaper way.
+func backendSize(ctx context.Context, backend backends.Backend) (int64, error) {
+	if sizer, ok := backend.(backends.Sizer); ok {
+		return sizer.Size(ctx)
 	}
 
-	// Serialize
-	data, err := c.serializer.Serialize(value)
+	stats, err := backend.Stats(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to serialize data: %w", err)
+		return 0, err
 	}
+	return stats.KeyCount, nil
+}
 
-	// Compress if needed
-	if c.compressor != nil {
-		data, err = c.compressor.Compress(data)
+// deleteMultiDistributed deletes keys with one DeleteMulti per owning shard.
+// Every shard is attempted; failures are joined into a single error.
+func (c *CacheClient) deleteMultiDistributed(ctx context.Context, keys []string) error {
+	shardKeys, err := c.groupByShard(keys)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for shard, keys := range shardKeys {
+		err := c.guard(func() error {
+			return shard.DeleteMulti(ctx, keys)
+		})
 		if err != nil {
-			return fmt.Errorf("failed to compress data: %w", err)
+			errs = append(errs, err)
 		}
 	}
 
-	// Store in shard
-	return shard.Set(ctx, key, data, ttl)
+	return errors.Join(errs...)
 }
 
-// deleteDistributed deletes a value from distributed cache.
-func (c *CacheClient) deleteDistributed(ctx context.Context, key string) error {
-	shard := c.getShard(key)
-	if shard == nil {
-		return fmt.Errorf("no shard available for key: %s", key)
+// groupByShard groups keys by the shard that owns them.
+func (c *CacheClient) groupByShard(keys []string) (map[backends.Backend][]string, error) {
+	shardKeys := make(map[backends.Backend][]string)
+	for _, key := range keys {
+		shard, err := c.getShard(key)
+		if err != nil {
+			return nil, err
+		}
+		shardKeys[shard] = append(shardKeys[shard], key)
 	}
-
-	return shard.Delete(ctx, key)
+	return shardKeys, nil
 }
 
-// existsDistributed checks if a key exists in distributed cache.
-func (c *CacheClient) existsDistributed(ctx context.Context, key string) (bool, error) {
-	shard := c.getShard(key)
-	if shard == nil {
-		return false, fmt.Errorf("no shard available for key: %s", key)
+// backendFor returns the backend holding key: the key's shard in distributed
+// mode and, in hierarchical mode, the backend of the L2 tier, which is shared
+// between nodes. It returns a nil backend when L2 is not a *CacheClient.
+// Callers type-assert the result to the optional interface they need.
+func (c *CacheClient) backendFor(key string) (backends.Backend, error) {
+	if c.config.Hierarchical {
+		l2, ok := c.l2Cache.(*CacheClient)
+		if !ok {
+			return nil, nil
+		}
+		return l2.backendFor(key)
 	}
 
-	return shard.Exists(ctx, key)
+	if c.config.Distributed {
+		return c.getShard(key)
+	}
+	return c.backend, nil
 }
 
-// getShard returns the appropriate shard for a given key.
-func (c *CacheClient) getShard(key string) backends.Backend {
+// getShard returns the appropriate shard for a given key, or ErrNoShards
+// when the client has none.
+func (c *CacheClient) getShard(key string) (backends.Backend, error) {
 	if len(c.shards) == 0 {
-		return nil
+		return nil, ErrNoShards
+	}
+
+	return c.shards[c.shardIndex(key)], nil
+}
+
+// shardIndex returns the index of the shard that owns key.
+func (c *CacheClient) shardIndex(key string) int {
+	if len(c.shards) <= 1 {
+		return 0
 	}
 
-	if len(c.shards) == 1 {
-		return c.shards[0]
+	if c.sharder != nil {
+		return c.sharder.GetShardIndex(key)
 	}
 
 	// Simple hash-based sharding
-	index := sharding.ShardKey(key, len(c.shards))
-	return c.shards[index]
+	return sharding.ShardKeyWithHash(key, len(c.shards), c.hash)
 }
 
 // statsHierarchical returns stats for hierarchical cache.
@@ -350,3 +1264,29 @@ type TracingInterface interface {
 	StartSpan(ctx context.Context, operationName string) (context.Context, interface{ End() })
 	IsEnabled() bool
 }
+
+// storeLoaded caches a value returned by a loader unless the client is
+// read-only or opts reject it, either through WithShouldCache or for
+// exceeding WithMaxCacheableSize. The loaded value is valid whether or not
+// it is cached, so a failure to cache it is only counted as a store error.
+func (c *CacheClient) storeLoaded(ctx context.Context, key string, value interface{}, ttl time.Duration, opts getOrSetOptions) {
+	if c.config.ReadOnly || !opts.cacheable(value) {
+		return
+	}
+
+	if opts.maxCacheableSize > 0 {
+		data, err := c.encodeValue(key, value)
+		if err != nil {
+			c.metrics.RecordError("get_or_set", c.config.Backend, "store")
+			return
+		}
+		if len(data) > opts.maxCacheableSize {
+			c.metrics.RecordOversizedLoad()
+			return
+		}
+	}
+
+	if err := c.Set(ctx, key, value, ttl); err != nil {
+		c.metrics.RecordError("get_or_set", c.config.Backend, "store")
+	}
+}
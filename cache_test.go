@@ -1,13 +1,37 @@
 package gocachex
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/chmenegatti/gocachex/pkg/backends"
+	"github.com/chmenegatti/gocachex/pkg/breaker"
 	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/chmenegatti/gocachex/pkg/gocachextest"
+	"github.com/chmenegatti/gocachex/pkg/sharding"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestMemoryBackend(t *testing.T) {
@@ -175,6 +199,189 @@ func TestMemoryBackendStats(t *testing.T) {
 	assert.True(t, stats.Deletes > 0)
 }
 
+func TestPurgeExpired(t *testing.T) {
+	memory := config.MemoryConfig{DisableCleanup: true}
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend: "memory",
+			Memory:  memory,
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory", Memory: memory},
+			L2:           config.CacheConfig{Backend: "memory", Memory: memory},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Memory:      memory,
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 3},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			for i := 0; i < 4; i++ {
+				require.NoError(t, cache.Set(ctx, fmt.Sprintf("short%d", i), "value", 20*time.Millisecond))
+			}
+			require.NoError(t, cache.Set(ctx, "long", "value", time.Hour))
+			time.Sleep(50 * time.Millisecond)
+
+			client := cache.(*CacheClient)
+			removed, err := client.PurgeExpired(ctx)
+			require.NoError(t, err)
+
+			// Each hierarchical tier holds its own copy
+			want := 4
+			if cfg.Hierarchical {
+				want = 8
+			}
+			assert.Equal(t, want, removed)
+
+			size, err := cache.Size(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), size)
+		})
+	}
+}
+
+func TestSetIfNewer(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 3},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			client := cache.(*CacheClient)
+
+			// Writers arrive out of order; the highest version wins
+			for key := 0; key < 5; key++ {
+				key := fmt.Sprintf("user:%d", key)
+				var wg sync.WaitGroup
+				var wins atomic.Int32
+				for _, version := range rand.Perm(20) {
+					wg.Add(1)
+					go func(version int) {
+						defer wg.Done()
+						stored, err := client.SetIfNewer(ctx, key, fmt.Sprintf("v%d", version), int64(version), time.Minute)
+						assert.NoError(t, err)
+						if stored {
+							wins.Add(1)
+						}
+					}(version)
+				}
+				wg.Wait()
+
+				assert.GreaterOrEqual(t, wins.Load(), int32(1))
+				value, err := cache.Get(ctx, key)
+				require.NoError(t, err)
+				assert.Equal(t, "v19", value, key)
+			}
+
+			// Older and equal versions are rejected
+			stored, err := client.SetIfNewer(ctx, "user:0", "stale", 19, time.Minute)
+			require.NoError(t, err)
+			assert.False(t, stored)
+
+			stored, err = client.SetIfNewer(ctx, "user:0", "v20", 20, time.Minute)
+			require.NoError(t, err)
+			assert.True(t, stored)
+			value, err := cache.Get(ctx, "user:0")
+			require.NoError(t, err)
+			assert.Equal(t, "v20", value)
+		})
+	}
+}
+
+func TestResetStats(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 3},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			for i := 0; i < 5; i++ {
+				require.NoError(t, cache.Set(ctx, fmt.Sprintf("key%d", i), "value", time.Minute))
+			}
+			_, err = cache.Get(ctx, "key1")
+			require.NoError(t, err)
+			_, err = cache.Get(ctx, "missing")
+			require.ErrorIs(t, err, backends.ErrNotFound)
+			require.NoError(t, cache.Delete(ctx, "key4"))
+
+			stats, err := cache.Stats(ctx)
+			require.NoError(t, err)
+			require.Positive(t, stats.Hits)
+			require.Positive(t, stats.Misses)
+
+			require.NoError(t, cache.ResetStats(ctx))
+
+			// Counters start over while the keys remain
+			stats, err = cache.Stats(ctx)
+			require.NoError(t, err)
+			assert.Zero(t, stats.Hits)
+			assert.Zero(t, stats.Misses)
+			assert.Zero(t, stats.Sets)
+			assert.Zero(t, stats.Deletes)
+			assert.Zero(t, stats.Evictions)
+			assert.Positive(t, stats.KeyCount)
+
+			value, err := cache.Get(ctx, "key0")
+			require.NoError(t, err)
+			assert.Equal(t, "value", value)
+
+			stats, err = cache.Stats(ctx)
+			require.NoError(t, err)
+			assert.Positive(t, stats.Hits)
+		})
+	}
+}
+
 func TestMemoryBackendHealth(t *testing.T) {
 	cache, err := New(config.Config{
 		Backend:    "memory",
@@ -232,6 +439,25 @@ func TestConfigValidation(t *testing.T) {
 	})
 	assert.Error(t, err)
 
+	// Test invalid memory hash function
+	_, err = New(config.Config{
+		Backend: "memory",
+		Memory:  config.MemoryConfig{HashFunction: "sha1"},
+	})
+	assert.ErrorContains(t, err, "invalid memory hash function")
+
+	// Test invalid tracing provider and sample rate
+	_, err = New(config.Config{
+		Backend: "memory",
+		Tracing: config.TracingConfig{Enabled: true, Provider: "datadog"},
+	})
+	assert.ErrorContains(t, err, "invalid tracing provider")
+	_, err = New(config.Config{
+		Backend: "memory",
+		Tracing: config.TracingConfig{Enabled: true, Provider: "otlp", SampleRate: 1.5},
+	})
+	assert.ErrorContains(t, err, "sample rate must be between 0 and 1")
+
 	// Test valid config
 	_, err = New(config.Config{
 		Backend:    "memory",
@@ -240,48 +466,4329 @@ func TestConfigValidation(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func BenchmarkMemorySet(b *testing.B) {
+func TestReadOnly(t *testing.T) {
+	configs := map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 3},
+		},
+	}
+
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+			client := cache.(*CacheClient)
+
+			ctx := context.Background()
+			require.NoError(t, client.Set(ctx, "key", "value", time.Minute))
+			_, err = client.Increment(ctx, "counter", 1)
+			require.NoError(t, err)
+
+			client.config.ReadOnly = true
+
+			writes := map[string]func() error{
+				"Set":    func() error { return client.Set(ctx, "key", "other", time.Minute) },
+				"Delete": func() error { return client.Delete(ctx, "key") },
+				"Clear":  func() error { return client.Clear(ctx) },
+				"Increment": func() error {
+					_, err := client.Increment(ctx, "counter", 1)
+					return err
+				},
+				"Decrement": func() error {
+					_, err := client.Decrement(ctx, "counter", 1)
+					return err
+				},
+				"SetMulti": func() error {
+					return client.SetMulti(ctx, map[string]interface{}{"key": "other"}, time.Minute)
+				},
+				"DeleteMulti": func() error { return client.DeleteMulti(ctx, []string{"key"}) },
+				"SetNX": func() error {
+					_, err := client.SetNX(ctx, "new", "value", time.Minute)
+					return err
+				},
+				"GetSet": func() error {
+					_, err := client.GetSet(ctx, "key", "other")
+					return err
+				},
+				"Expire": func() error { return client.Expire(ctx, "key", time.Second) },
+				"DeleteByPattern": func() error {
+					_, err := client.DeleteByPattern(ctx, "*")
+					return err
+				},
+				"SetIfNewer": func() error {
+					_, err := client.SetIfNewer(ctx, "key", "other", 1, time.Minute)
+					return err
+				},
+				"ListPushCapped": func() error { return client.ListPushCapped(ctx, "list", "value", 10, time.Minute) },
+				"Import": func() error {
+					_, err := client.Import(ctx, strings.NewReader(""))
+					return err
+				},
+			}
+			for op, write := range writes {
+				assert.ErrorIs(t, write(), ErrReadOnly, op)
+			}
+
+			// Reads see the values stored before, untouched by the writes
+			value, err := client.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, "value", value)
+			exists, err := client.Exists(ctx, "counter")
+			require.NoError(t, err)
+			assert.True(t, exists)
+			exists, err = client.Exists(ctx, "new")
+			require.NoError(t, err)
+			assert.False(t, exists)
+			_, err = client.Stats(ctx)
+			assert.NoError(t, err)
+
+			// GetOrSet returns loaded values without storing them
+			value, err = client.GetOrSet(ctx, "loaded", time.Minute, func(ctx context.Context) (interface{}, error) {
+				return "loaded", nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "loaded", value)
+			exists, err = client.Exists(ctx, "loaded")
+			require.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+
+	t.Run("hierarchical undecodable", func(t *testing.T) {
+		cache, err := New(config.Config{
+			Backend:          "memory",
+			Serializer:       "json",
+			Hierarchical:     true,
+			L1:               config.CacheConfig{Backend: "memory"},
+			L2:               config.CacheConfig{Backend: "memory"},
+			EvictUndecodable: true,
+			ReadOnly:         true,
+		})
+		require.NoError(t, err)
+		defer cache.Close()
+
+		// The L2 tier keeps an undecodable value instead of evicting it
+		ctx := context.Background()
+		l2 := cache.(*CacheClient).l2Cache.(*CacheClient).backend
+		require.NoError(t, l2.Set(ctx, "user:1", []byte("{not json"), time.Minute))
+
+		_, err = cache.Get(ctx, "user:1")
+		assert.ErrorContains(t, err, "failed to deserialize data")
+		_, err = l2.Get(ctx, "user:1")
+		assert.NoError(t, err)
+	})
+}
+
+func TestInvalidationChannelConfig(t *testing.T) {
+	// Without hierarchical mode there is no L1 to invalidate
+	cfg := config.Config{Backend: "memory", InvalidationChannel: "invalidations"}
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "invalidation channel requires hierarchical mode")
+
+	// A memory L2 has no pub/sub to carry the invalidations
+	_, err = New(config.Config{
+		Backend:             "memory",
+		Hierarchical:        true,
+		Serializer:          "json",
+		L1:                  config.CacheConfig{Backend: "memory"},
+		L2:                  config.CacheConfig{Backend: "memory"},
+		InvalidationChannel: "invalidations",
+	})
+	assert.ErrorContains(t, err, "invalidation channel not supported by L2 backend")
+}
+
+// recordingBroadcaster records the messages published to it.
+type recordingBroadcaster struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (b *recordingBroadcaster) Publish(ctx context.Context, channel, message string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, message)
+	return nil
+}
+
+func (b *recordingBroadcaster) Subscribe(ctx context.Context, channel string, fn func(message string)) (func() error, error) {
+	return func() error { return nil }, nil
+}
+
+// take returns and clears the recorded messages.
+func (b *recordingBroadcaster) take() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	messages := b.messages
+	b.messages = nil
+	return messages
+}
+
+func TestInvalidationPublishedByEveryWrite(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:      "memory",
+		Hierarchical: true,
+		Serializer:   "json",
+		L1:           config.CacheConfig{Backend: "memory"},
+		L2:           config.CacheConfig{Backend: "memory"},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	client := cache.(*CacheClient)
+	broadcaster := &recordingBroadcaster{}
+	client.invalidator = &invalidator{
+		channel:     "invalidations",
+		origin:      "self",
+		broadcaster: broadcaster,
+		stop:        func() error { return nil },
+	}
+
+	ctx := context.Background()
+	writes := []struct {
+		name     string
+		write    func() error
+		expected string
+	}{
+		{"Set", func() error { return client.Set(ctx, "key", "v1", time.Minute) }, "self key"},
+		{"SetNX", func() error {
+			_, err := client.SetNX(ctx, "nx", "v1", time.Minute)
+			return err
+		}, "self nx"},
+		{"GetSet", func() error {
+			_, err := client.GetSet(ctx, "key", "v2")
+			return err
+		}, "self key"},
+		{"SetIfNewer", func() error {
+			_, err := client.SetIfNewer(ctx, "versioned", "v1", 1, time.Minute)
+			return err
+		}, "self versioned"},
+		{"SetWithExpireCallback", func() error {
+			return client.SetWithExpireCallback(ctx, "callback", "v1", time.Minute, func(string) {})
+		}, "self callback"},
+		{"Expire", func() error { return client.Expire(ctx, "key", time.Hour) }, "self key"},
+		{"Increment", func() error {
+			_, err := client.Increment(ctx, "counter", 1)
+			return err
+		}, "self counter"},
+		{"Decrement", func() error {
+			_, err := client.Decrement(ctx, "counter", 1)
+			return err
+		}, "self counter"},
+		{"IncrementWithTTLOnCreate", func() error {
+			_, err := client.IncrementWithTTLOnCreate(ctx, "counter", 1, time.Minute)
+			return err
+		}, "self counter"},
+		{"Delete", func() error { return client.Delete(ctx, "key") }, "self key"},
+		{"DeleteByPattern", func() error {
+			_, err := client.DeleteByPattern(ctx, "user:*")
+			return err
+		}, "self:pattern user:*"},
+	}
+	for _, w := range writes {
+		require.NoError(t, w.write(), w.name)
+		assert.Equal(t, []string{w.expected}, broadcaster.take(), w.name)
+	}
+
+	// Messages from other clients drop a key or the keys matching a pattern
+	// from L1, while the client's own messages are ignored
+	for _, key := range []string{"user:1", "user:2"} {
+		require.NoError(t, client.Set(ctx, key, "v1", time.Minute))
+	}
+	inL1 := func(key string) bool {
+		exists, err := client.l1Cache.Exists(ctx, key)
+		require.NoError(t, err)
+		return exists
+	}
+	client.dropInvalidated("self", "self user:1")
+	client.dropInvalidated("self", "self:pattern user:*")
+	assert.True(t, inL1("user:1"))
+	client.dropInvalidated("self", "other user:1")
+	assert.False(t, inL1("user:1"))
+	assert.True(t, inL1("user:2"))
+	client.dropInvalidated("self", "other:pattern user:*")
+	assert.False(t, inL1("user:2"))
+}
+
+func TestRedisModeValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		redis     config.RedisConfig
+		expectErr string
+	}{
+		{name: "single", redis: config.RedisConfig{Mode: "single", Addresses: []string{"a:6379", "b:6379"}}},
+		{name: "cluster", redis: config.RedisConfig{Mode: "cluster", Addresses: []string{"a:6379"}}},
+		{name: "sentinel", redis: config.RedisConfig{Mode: "sentinel", Addresses: []string{"a:26379"}, MasterName: "cache"}},
+		{name: "inferred", redis: config.RedisConfig{Addresses: []string{"a:26379", "b:26379"}}},
+		{
+			name:      "unknown mode",
+			redis:     config.RedisConfig{Mode: "replicated"},
+			expectErr: "invalid redis mode",
+		},
+		{
+			name:      "cluster without addresses",
+			redis:     config.RedisConfig{Mode: "cluster"},
+			expectErr: "cluster mode requires at least one address",
+		},
+		{
+			name:      "sentinel without master name",
+			redis:     config.RedisConfig{Mode: "sentinel", Addresses: []string{"a:26379"}},
+			expectErr: "sentinel mode requires a master name",
+		},
+		{
+			name:      "cluster enabled in single mode",
+			redis:     config.RedisConfig{Mode: "single", Cluster: config.RedisClusterConfig{Enabled: true}},
+			expectErr: "conflicts with mode single",
+		},
+		{
+			name:      "client cache in sentinel mode",
+			redis:     config.RedisConfig{Mode: "sentinel", Addresses: []string{"a:26379"}, MasterName: "cache", ClientCache: config.RedisClientCacheConfig{Enabled: true}},
+			expectErr: "only supported for a single instance",
+		},
+		{
+			name:      "tls certificate without key",
+			redis:     config.RedisConfig{TLS: true, TLSCertFile: "client.pem"},
+			expectErr: "must be set together",
+		},
+		{
+			name:  "client cache in single mode with several addresses",
+			redis: config.RedisConfig{Mode: "single", Addresses: []string{"a:6379", "b:6379"}, ClientCache: config.RedisClientCacheConfig{Enabled: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{Backend: "redis", Redis: tt.redis}
+			err := cfg.Validate()
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestStrictCodecs(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		expectErr bool
+	}{
+		{
+			name: "gzip compression",
+			cfg:  config.Config{Serializer: "json", Compression: true, CompressionAlgorithm: "gzip"},
+		},
+		{
+			name: "lz4 compression",
+			cfg:  config.Config{Serializer: "json", Compression: true, CompressionAlgorithm: "lz4"},
+		},
+		{
+			name: "snappy compression",
+			cfg:  config.Config{Serializer: "json", Compression: true, CompressionAlgorithm: "snappy"},
+		},
+		{
+			name: "zstd compression",
+			cfg:  config.Config{Serializer: "json", Compression: true, CompressionAlgorithm: "zstd", CompressionLevel: 19},
+		},
+		{
+			name:      "zstd level out of range",
+			cfg:       config.Config{Serializer: "json", Compression: true, CompressionAlgorithm: "zstd", CompressionLevel: 23},
+			expectErr: true,
+		},
+		{
+			name: "msgpack serializer",
+			cfg:  config.Config{Serializer: "msgpack"},
+		},
+		{
+			name: "lz4 with compression disabled",
+			cfg:  config.Config{Serializer: "gob", CompressionAlgorithm: "lz4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Backend = "memory"
+			tt.cfg.StrictCodecs = true
+
+			cache, err := New(tt.cfg)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			cache.Close()
+		})
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			var loads int
+			loader := func(ctx context.Context) (interface{}, error) {
+				loads++
+				return "loaded", nil
+			}
+
+			// Miss: the loader runs and its result is cached
+			value, err := cache.GetOrSet(ctx, "key", time.Minute, loader)
+			require.NoError(t, err)
+			assert.Equal(t, "loaded", value)
+			assert.Equal(t, 1, loads)
+
+			cached, err := cache.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, "loaded", cached)
+
+			// Hit: the cached value is returned without loading
+			require.NoError(t, cache.Set(ctx, "key", "cached", time.Minute))
+			value, err = cache.GetOrSet(ctx, "key", time.Minute, loader)
+			require.NoError(t, err)
+			assert.Equal(t, "cached", value)
+			assert.Equal(t, 1, loads)
+
+			// Loader errors propagate and nothing is cached
+			errDB := errors.New("database unavailable")
+			_, err = cache.GetOrSet(ctx, "failing", time.Minute, func(ctx context.Context) (interface{}, error) {
+				return nil, errDB
+			})
+			assert.ErrorIs(t, err, errDB)
+
+			exists, err := cache.Exists(ctx, "failing")
+			require.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestGetOrSetSingleFlight(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:      "memory",
+		Serializer:   "json",
+		SingleFlight: true,
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (interface{}, error) {
+		loads.Add(1)
+		<-release
+		return "origin", nil
+	}
+
+	var wg sync.WaitGroup
+	var started sync.WaitGroup
+	results := make([]interface{}, 100)
+	errs := make([]error, 100)
+	for i := range results {
+		wg.Add(1)
+		started.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			results[i], errs[i] = cache.GetOrSet(ctx, "hot", time.Minute, loader)
+		}(i)
+	}
+
+	// Hold the load open until every caller has missed
+	started.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+	for i := range results {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "origin", results[i])
+	}
+
+	value, err := cache.Get(ctx, "hot")
+	require.NoError(t, err)
+	assert.Equal(t, "origin", value)
+}
+
+func TestSetNXConcurrent(t *testing.T) {
+	configs := map[string]config.Config{
+		"single": {Backend: "memory", Serializer: "json"},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 4},
+		},
+	}
+
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			var wins atomic.Int32
+			var winner atomic.Int32
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					stored, err := cache.SetNX(ctx, "lock", i, time.Minute)
+					assert.NoError(t, err)
+					if stored {
+						wins.Add(1)
+						winner.Store(int32(i))
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			require.Equal(t, int32(1), wins.Load())
+			value, err := cache.Get(ctx, "lock")
+			require.NoError(t, err)
+			assert.EqualValues(t, winner.Load(), value)
+		})
+	}
+}
+
+func TestGetSetKeepsTTL(t *testing.T) {
+	configs := map[string]config.Config{
+		"single": {Backend: "memory", Serializer: "json"},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 4},
+		},
+	}
+
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			old, err := cache.GetSet(ctx, "missing", "first")
+			require.NoError(t, err)
+			assert.Nil(t, old)
+
+			require.NoError(t, cache.Set(ctx, "key", "v1", time.Minute))
+			old, err = cache.GetSet(ctx, "key", "v2")
+			require.NoError(t, err)
+			assert.Equal(t, "v1", old)
+
+			value, err := cache.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, "v2", value)
+
+			ttls, err := cache.GetMultiTTL(ctx, []string{"key"})
+			require.NoError(t, err)
+			assert.InDelta(t, time.Minute.Seconds(), ttls["key"].Seconds(), 1)
+		})
+	}
+}
+
+func TestGetOrSetSingleFlightWaiterCancel(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:      "memory",
+		Serializer:   "json",
+		SingleFlight: true,
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	go func() {
+		_, _ = cache.GetOrSet(context.Background(), "slow", time.Minute, func(ctx context.Context) (interface{}, error) {
+			close(loading)
+			<-release
+			return "value", nil
+		})
+	}()
+	<-loading
+
+	// A waiter gives up on its own deadline without affecting the load
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = cache.GetOrSet(ctx, "slow", time.Minute, func(ctx context.Context) (interface{}, error) {
+		t.Error("waiter must not run its own loader")
+		return nil, nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		value, err := cache.Get(context.Background(), "slow")
+		return err == nil && value == "value"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestGetOrSetLoaderDeadline(t *testing.T) {
 	cache, err := New(config.Config{
 		Backend:    "memory",
 		Serializer: "json",
 	})
-	require.NoError(b, err)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	client := cache.(*CacheClient)
+
+	// A loader that ignores its context and overruns the deadline
+	loaderDone := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.GetOrSet(ctx, "slow_key", time.Minute, func(ctx context.Context) (interface{}, error) {
+		defer close(loaderDone)
+		time.Sleep(200 * time.Millisecond)
+		return "late_value", nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+
+	// The late result must not be cached
+	<-loaderDone
+	exists, err := cache.Exists(context.Background(), "slow_key")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// A loader that honours its context sees the cancellation
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+
+	_, err = client.GetOrSet(ctx2, "ctx_key", time.Minute, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return "value", ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	exists, err = cache.Exists(context.Background(), "ctx_key")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestGetOrSetShouldCache(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
 	defer cache.Close()
 
+	client := cache.(*CacheClient)
 	ctx := context.Background()
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		key := "benchmark_key"
-		value := map[string]interface{}{
-			"id":   i,
-			"name": "Benchmark Item",
-			"data": make([]int, 100),
+	var loads int
+	loader := func(ctx context.Context) (interface{}, error) {
+		loads++
+		if loads == 1 {
+			return "partial", nil
 		}
-		_ = cache.Set(ctx, key, value, time.Minute)
+		return "complete", nil
 	}
+	complete := WithShouldCache(func(value interface{}) bool {
+		return value != "partial"
+	})
+
+	value, err := client.GetOrSet(ctx, "report", time.Minute, loader, complete)
+	require.NoError(t, err)
+	assert.Equal(t, "partial", value)
+
+	exists, err := cache.Exists(ctx, "report")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// The rejected value was not stored, so the loader runs again
+	value, err = client.GetOrSet(ctx, "report", time.Minute, loader, complete)
+	require.NoError(t, err)
+	assert.Equal(t, "complete", value)
+	assert.Equal(t, 2, loads)
+
+	value, err = client.GetOrSet(ctx, "report", time.Minute, loader, complete)
+	require.NoError(t, err)
+	assert.Equal(t, "complete", value)
+	assert.Equal(t, 2, loads)
 }
 
-func BenchmarkMemoryGet(b *testing.B) {
+func TestGetOrSetMaxCacheableSize(t *testing.T) {
 	cache, err := New(config.Config{
 		Backend:    "memory",
 		Serializer: "json",
+		Prometheus: config.PrometheusConfig{Enabled: true},
 	})
-	require.NoError(b, err)
+	require.NoError(t, err)
 	defer cache.Close()
 
+	client := cache.(*CacheClient)
 	ctx := context.Background()
 
-	// Pre-populate cache
-	value := map[string]interface{}{
-		"id":   1,
-		"name": "Benchmark Item",
-		"data": make([]int, 100),
+	var loads int
+	blob := strings.Repeat("x", 1024)
+	loader := func(ctx context.Context) (interface{}, error) {
+		loads++
+		return blob, nil
 	}
-	_ = cache.Set(ctx, "benchmark_key", value, time.Minute)
+	limit := WithMaxCacheableSize(512)
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, _ = cache.Get(ctx, "benchmark_key")
+	value, err := client.GetOrSet(ctx, "blob", time.Minute, loader, limit)
+	require.NoError(t, err)
+	assert.Equal(t, blob, value, "oversized values are still returned")
+
+	exists, err := cache.Exists(ctx, "blob")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// The oversized value was not stored, so the loader runs again
+	value, err = client.GetOrSet(ctx, "blob", time.Minute, loader, limit)
+	require.NoError(t, err)
+	assert.Equal(t, blob, value)
+	assert.Equal(t, 2, loads)
+
+	skipped, ok := metricValue(t, client.metrics.GetRegistry(), "gocachex_cache_oversized_loads_total", nil)
+	require.True(t, ok)
+	assert.Equal(t, float64(2), skipped)
+
+	// Values within the limit are cached as usual
+	_, err = client.GetOrSet(ctx, "blob", time.Minute, loader, WithMaxCacheableSize(4096))
+	require.NoError(t, err)
+	_, err = client.GetOrSet(ctx, "blob", time.Minute, loader, WithMaxCacheableSize(4096))
+	require.NoError(t, err)
+	assert.Equal(t, 3, loads)
+}
+
+func TestGetOrSetWithTTL(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+
+	var loads int
+	loader := func(ctx context.Context) (interface{}, error) {
+		loads++
+		return "value", nil
+	}
+
+	result, err := cache.GetOrSetWithTTL(ctx, "key", time.Minute, loader)
+	require.NoError(t, err)
+	assert.Equal(t, "value", result.Value)
+	assert.False(t, result.Hit)
+	assert.LessOrEqual(t, result.TTL, time.Minute)
+	assert.Greater(t, result.TTL, 59*time.Second)
+
+	// Later hits see the entry's TTL running down
+	previous := result.TTL
+	for i := 0; i < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+
+		result, err = cache.GetOrSetWithTTL(ctx, "key", time.Minute, loader)
+		require.NoError(t, err)
+		assert.Equal(t, "value", result.Value)
+		assert.True(t, result.Hit)
+		assert.Less(t, result.TTL, previous)
+		previous = result.TTL
+	}
+	assert.Equal(t, 1, loads)
+
+	// Values that are not cached report no TTL
+	result, err = cache.GetOrSetWithTTL(ctx, "skipped", time.Minute, loader, WithShouldCache(func(interface{}) bool { return false }))
+	require.NoError(t, err)
+	assert.Equal(t, GetOrSetResult{Value: "value"}, result)
+
+	// Loader errors are returned as with GetOrSet
+	_, err = cache.GetOrSetWithTTL(ctx, "failing", time.Minute, func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("source down")
+	})
+	assert.ErrorContains(t, err, "source down")
+}
+
+func TestLoaderLimit(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		LoaderLimit: config.LoaderLimitConfig{MaxConcurrent: 2},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+
+	var mu sync.Mutex
+	running := make(map[string]int)
+	peak := make(map[string]int)
+	loader := func(group string) LoaderFunc {
+		return func(ctx context.Context) (interface{}, error) {
+			mu.Lock()
+			running[group]++
+			if running[group] > peak[group] {
+				peak[group] = running[group]
+			}
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			running[group]--
+			mu.Unlock()
+			return group, nil
+		}
+	}
+
+	// Distinct keys of one group share its slots, while another group
+	// loads alongside them
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, group := range []string{"users", "orders"} {
+			wg.Add(1)
+			go func(key, group string) {
+				defer wg.Done()
+				value, err := cache.GetOrSet(ctx, key, time.Minute, loader(group))
+				assert.NoError(t, err)
+				assert.Equal(t, group, value)
+			}(fmt.Sprintf("%s:%d", group, i), group)
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]int{"users": 2, "orders": 2}, peak)
+	assert.Zero(t, loaderGroups(client.loaders))
+
+	// A custom group function puts every key in the same group
+	client.SetLoaderGroupFunc(func(string) string { return "all" })
+	peak = make(map[string]int)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			_, err := cache.GetOrSet(ctx, key, time.Minute, loader("all"))
+			assert.NoError(t, err)
+		}(fmt.Sprintf("key-%d", i))
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]int{"all": 2}, peak)
+}
+
+func TestLoaderLimitWaiterCancel(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		LoaderLimit: config.LoaderLimitConfig{MaxConcurrent: 1},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	client := cache.(*CacheClient)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := cache.GetOrSet(context.Background(), "report:1", time.Minute, func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-unblock
+			return "first", nil
+		})
+		assert.NoError(t, err)
+	}()
+	<-started
+
+	// A loader waiting for the group's only slot gives up with its context
+	// and never runs
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var ran atomic.Bool
+	_, err = cache.GetOrSet(ctx, "report:2", time.Minute, func(ctx context.Context) (interface{}, error) {
+		ran.Store(true)
+		return "second", nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(unblock)
+	<-done
+	assert.False(t, ran.Load())
+
+	// The abandoned waiter leaves the group in the background
+	assert.Eventually(t, func() bool { return loaderGroups(client.loaders) == 0 }, time.Second, time.Millisecond)
+}
+
+// loaderGroups returns the number of key groups the limiter tracks.
+func loaderGroups(l *loaderLimiter) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.slots)
+}
+
+func TestStringFastPath(t *testing.T) {
+	for _, compression := range []bool{false, true} {
+		cache, err := New(config.Config{
+			Backend:     "memory",
+			Serializer:  "json",
+			Compression: compression,
+		})
+		require.NoError(t, err)
+		defer cache.Close()
+
+		ctx := context.Background()
+		client := cache.(*CacheClient)
+
+		err = cache.Set(ctx, "greeting", `hello "world"`, time.Minute)
+		require.NoError(t, err)
+
+		value, err := cache.Get(ctx, "greeting")
+		assert.NoError(t, err)
+		assert.IsType(t, "", value)
+		assert.Equal(t, `hello "world"`, value)
+
+		// Empty strings round-trip too
+		require.NoError(t, cache.Set(ctx, "empty", "", time.Minute))
+		value, err = cache.Get(ctx, "empty")
+		assert.NoError(t, err)
+		assert.Equal(t, "", value)
+
+		if !compression {
+			// Stored verbatim behind the header, not as a quoted JSON value
+			raw, err := client.backend.Get(ctx, "greeting")
+			require.NoError(t, err)
+			assert.Equal(t, append([]byte{headerMarker, flagString}, `hello "world"`...), raw)
+		}
+
+		// Values written before the fast path (plain JSON) still decode
+		require.NoError(t, client.backend.Set(ctx, "legacy", []byte(`"legacy"`), time.Minute))
+		if !compression {
+			value, err = cache.Get(ctx, "legacy")
+			assert.NoError(t, err)
+			assert.Equal(t, "legacy", value)
+		}
+	}
+}
+
+func TestGetWait(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+	client := cache.(*CacheClient)
+
+	ctx := context.Background()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cache.Set(ctx, "job:result", "done", time.Minute)
+	}()
+
+	start := time.Now()
+	value, err := client.GetWait(ctx, "job:result", 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, "done", value)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// A key that is already present returns immediately
+	value, err = client.GetWait(ctx, "job:result", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "done", value)
+}
+
+func TestGetWaitTimeout(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	value, err := cache.(*CacheClient).GetWait(ctx, "never", 10*time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, value)
+}
+
+func TestGetOrSetLockedExtendsLease(t *testing.T) {
+	newClient := func() *CacheClient {
+		cache, err := New(config.Config{
+			Backend:    "memory",
+			Serializer: "json",
+			Lock: config.LockConfig{
+				Lease:         60 * time.Millisecond,
+				RetryInterval: 10 * time.Millisecond,
+			},
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { cache.Close() })
+		return cache.(*CacheClient)
+	}
+
+	// Two clients sharing one backend stand in for two nodes
+	first := newClient()
+	second := newClient()
+	own := second.backend
+	second.backend = first.backend
+	t.Cleanup(func() { second.backend = own })
+
+	ctx := context.Background()
+	started := make(chan struct{})
+	var loads int32
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		value, err := first.GetOrSetLocked(ctx, "report", time.Minute, func(ctx context.Context) (interface{}, error) {
+			atomic.AddInt32(&loads, 1)
+			close(started)
+			// Outlive the lease several times over
+			time.Sleep(300 * time.Millisecond)
+			return "from-first", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "from-first", value)
+	}()
+
+	<-started
+	value, err := second.GetOrSetLocked(ctx, "report", time.Minute, func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		return "from-second", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "from-first", value)
+
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	// The lease is released once the loader completes
+	exists, err := first.backend.Exists(ctx, lockKeyPrefix+"report")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+// failingBackend is a backend whose reads fail while fail is set.
+type failingBackend struct {
+	backends.Backend
+	fail  atomic.Bool
+	calls atomic.Int32
+}
+
+func (f *failingBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	f.calls.Add(1)
+	if f.fail.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return f.Backend.Get(ctx, key)
+}
+
+func TestPreserveIntegers(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:          "memory",
+		Serializer:       "json",
+		PreserveIntegers: true,
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "count", int64(1)<<60, time.Minute))
+	require.NoError(t, cache.Set(ctx, "order", map[string]interface{}{"qty": 3, "price": 9.99}, time.Minute))
+
+	value, err := cache.Get(ctx, "count")
+	require.NoError(t, err)
+	assert.IsType(t, int64(0), value)
+	assert.Equal(t, int64(1)<<60, value)
+
+	value, err = cache.Get(ctx, "order")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"qty": int64(3), "price": 9.99}, value)
+
+	// Counters read back through Get are integers too
+	_, err = cache.Increment(ctx, "hits", 5)
+	require.NoError(t, err)
+	value, err = cache.Get(ctx, "hits")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:       "memory",
+		Serializer:    "json",
+		CanonicalJSON: true,
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+
+	product := &Product{ID: 1, Name: "Book", Price: 9.5, Tags: []string{"paper"}}
+	require.NoError(t, cache.Set(ctx, "struct", product, time.Minute))
+	require.NoError(t, cache.Set(ctx, "map", map[string]interface{}{
+		"tags":  []string{"paper"},
+		"price": 9.5,
+		"name":  "Book",
+		"id":    1,
+	}, time.Minute))
+
+	// Equal values are stored as identical bytes
+	fromStruct, err := client.backend.Get(ctx, "struct")
+	require.NoError(t, err)
+	fromMap, err := client.backend.Get(ctx, "map")
+	require.NoError(t, err)
+	assert.Equal(t, fromStruct, fromMap)
+
+	read, err := GetTyped[*Product](ctx, cache, "struct")
+	require.NoError(t, err)
+	assert.Equal(t, product, read)
+}
+
+func TestGetMultiWithMisses(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			require.NoError(t, cache.Set(ctx, "a", "1", time.Minute))
+			require.NoError(t, cache.Set(ctx, "null", nil, time.Minute))
+			keys := []string{"a", "missing", "null"}
+
+			// Misses are omitted by default
+			result, err := cache.GetMulti(ctx, keys)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{"a": "1", "null": nil}, result)
+
+			result, err = cache.GetMulti(ctx, keys, WithMisses())
+			require.NoError(t, err)
+			assert.Len(t, result, len(keys))
+			assert.Equal(t, "1", result["a"])
+			assert.Equal(t, Missing, result["missing"])
+			assert.Nil(t, result["null"], "cached nil values are not misses")
+		})
+	}
+}
+
+func TestGetMultiWithMissesReportsFailures(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("undecodable", func(t *testing.T) {
+		for _, evict := range []bool{false, true} {
+			cache, err := New(config.Config{
+				Backend:          "memory",
+				Serializer:       "json",
+				EvictUndecodable: evict,
+			})
+			require.NoError(t, err)
+			defer cache.Close()
+
+			client := cache.(*CacheClient)
+			require.NoError(t, client.backend.Set(ctx, "bad", []byte("{not json"), time.Minute))
+
+			result, err := cache.GetMulti(ctx, []string{"bad"}, WithMisses())
+			if evict {
+				// An evicted value really is missing now
+				require.NoError(t, err)
+				assert.Equal(t, Missing, result["bad"])
+				continue
+			}
+			assert.ErrorContains(t, err, "bad")
+		}
+	})
+
+	t.Run("distributed backend error", func(t *testing.T) {
+		cache, err := New(config.Config{
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 2},
+		})
+		require.NoError(t, err)
+		defer cache.Close()
+
+		client := cache.(*CacheClient)
+		for i, shard := range client.shards {
+			failing := &failingBackend{Backend: shard}
+			failing.fail.Store(true)
+			client.shards[i] = failing
+		}
+
+		// Failures are not reported as misses
+		_, err = cache.GetMulti(ctx, []string{"key"}, WithMisses())
+		assert.ErrorContains(t, err, "connection refused")
+
+		result, err := cache.GetMulti(ctx, []string{"key"})
+		require.NoError(t, err)
+		assert.Empty(t, result)
+	})
+}
+
+func TestGetOK(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			value, found, err := cache.GetOK(ctx, "missing")
+			require.NoError(t, err)
+			assert.False(t, found)
+			assert.Nil(t, value)
+
+			require.NoError(t, cache.Set(ctx, "key", "value", time.Minute))
+			value, found, err = cache.GetOK(ctx, "key")
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, "value", value)
+		})
+	}
+}
+
+func TestGetOKBackendFailure(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+	backend := &failingBackend{Backend: client.backend}
+	client.backend = backend
+
+	backend.fail.Store(true)
+	value, found, err := cache.GetOK(ctx, "key")
+	assert.EqualError(t, err, "connection refused")
+	assert.False(t, found)
+	assert.Nil(t, value)
+
+	// Undecodable values are failures too, not misses
+	backend.fail.Store(false)
+	require.NoError(t, backend.Set(ctx, "corrupt", []byte("{not json"), time.Minute))
+	_, found, err = cache.GetOK(ctx, "corrupt")
+	assert.Error(t, err)
+	assert.False(t, found)
+}
+
+func TestGetOrSetBackendFailure(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+	backend := &failingBackend{Backend: client.backend}
+	client.backend = backend
+
+	var loads int
+	loader := func(ctx context.Context) (interface{}, error) {
+		loads++
+		return "loaded", nil
+	}
+
+	// A failing read is not a miss, so the loader does not run
+	backend.fail.Store(true)
+	_, err = cache.GetOrSet(ctx, "key", time.Minute, loader)
+	assert.EqualError(t, err, "connection refused")
+	_, err = client.GetOrSetLocked(ctx, "key", time.Minute, loader)
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 0, loads)
+
+	// A loaded value that cannot be cached is still returned
+	backend.fail.Store(false)
+	client.backend = failingSetBackend{Backend: backend}
+	value, err := cache.GetOrSet(ctx, "key", time.Minute, loader)
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", value)
+	assert.Equal(t, 1, loads)
+}
+
+func TestNamespaceQuota(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+		Memory: config.MemoryConfig{
+			NamespaceQuotas: map[string]config.NamespaceQuota{
+				"tenant1": {MaxKeys: 2},
+				"tenant2": {MaxKeys: 2},
+			},
+		},
+		CircuitBreaker: config.CircuitBreakerConfig{
+			Enabled:          true,
+			FailureThreshold: 2,
+			Cooldown:         time.Hour,
+		},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+	require.NoError(t, cache.Set(ctx, "tenant1:a", "v", time.Minute))
+	require.NoError(t, cache.Set(ctx, "tenant1:b", "v", time.Minute))
+
+	// A tenant over its quota neither trips the breaker nor affects others
+	for i := 0; i < 3; i++ {
+		err := cache.Set(ctx, fmt.Sprintf("tenant1:%d", i), "v", time.Minute)
+		assert.ErrorIs(t, err, backends.ErrQuotaExceeded)
+	}
+	assert.Equal(t, breaker.Closed, client.BreakerState())
+
+	require.NoError(t, cache.Set(ctx, "tenant2:a", "v", time.Minute))
+	require.NoError(t, cache.Set(ctx, "tenant2:b", "v", time.Minute))
+	value, err := cache.Get(ctx, "tenant1:a")
+	require.NoError(t, err)
+	assert.Equal(t, "v", value)
+}
+
+func TestDistributedBoundedLoads(t *testing.T) {
+	cfg := config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Distributed: true,
+		GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+		Sharding:    config.ShardingConfig{Shards: 4, LoadFactor: 1.25},
+	}
+	newClient := func() *CacheClient {
+		cache, err := New(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { cache.Close() })
+		return cache.(*CacheClient)
+	}
+	first, second := newClient(), newClient()
+
+	// Keys are placed on the bounded ring, the same way by every client
+	ring := sharding.NewSharder(cfg.Sharding)
+	for _, shard := range first.shards {
+		require.NoError(t, ring.AddShard(shard))
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		assert.Equal(t, ring.GetShardIndex(key), first.shardIndex(key), key)
+		assert.Equal(t, first.shardIndex(key), second.shardIndex(key), key)
+
+		require.NoError(t, first.Set(ctx, key, i, time.Minute))
+		exists, err := first.shards[first.shardIndex(key)].Exists(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, exists, key)
+	}
+
+	cfg.Sharding.Algorithm = "hash"
+	_, err := New(cfg)
+	assert.Error(t, err)
+}
+
+func TestDistributedExpireAndTTL(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Distributed: true,
+		GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+		Sharding:    config.ShardingConfig{Shards: 4},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+		require.NoError(t, cache.Set(ctx, keys[i], i, 0))
+	}
+
+	for _, key := range keys {
+		ttl, err := cache.TTL(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(-1), ttl, key)
+
+		require.NoError(t, cache.Expire(ctx, key, time.Minute))
+		ttl, err = cache.TTL(ctx, key)
+		require.NoError(t, err)
+		assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 1, key)
+
+		// The TTL lives on the shard that owns the key
+		owner, err := client.getShard(key)
+		require.NoError(t, err)
+		ttl, err = owner.TTL(ctx, key)
+		require.NoError(t, err)
+		assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 1, key)
+		for _, shard := range client.shards {
+			if shard != owner {
+				_, err := shard.TTL(ctx, key)
+				assert.ErrorIs(t, err, backends.ErrNotFound, key)
+			}
+		}
+	}
+
+	require.NoError(t, cache.Expire(ctx, keys[0], 20*time.Millisecond))
+	time.Sleep(30 * time.Millisecond)
+	_, err = cache.Get(ctx, keys[0])
+	assert.ErrorIs(t, err, backends.ErrNotFound)
+	_, err = cache.Get(ctx, keys[1])
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, cache.Expire(ctx, "missing", time.Minute), backends.ErrNotFound)
+	_, err = cache.TTL(ctx, "missing")
+	assert.ErrorIs(t, err, backends.ErrNotFound)
+}
+
+func TestResetBreaker(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+		CircuitBreaker: config.CircuitBreakerConfig{
+			Enabled:          true,
+			FailureThreshold: 2,
+			Cooldown:         time.Hour,
+		},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+	backend := &failingBackend{Backend: client.backend}
+	client.backend = backend
+
+	// Misses do not count as failures
+	for i := 0; i < 3; i++ {
+		_, err := cache.Get(ctx, "missing")
+		assert.ErrorIs(t, err, backends.ErrNotFound)
+	}
+	assert.Equal(t, breaker.Closed, client.BreakerState())
+
+	// Trip the breaker
+	backend.fail.Store(true)
+	for i := 0; i < 2; i++ {
+		_, err := cache.Get(ctx, "key")
+		assert.EqualError(t, err, "connection refused")
+	}
+	assert.Equal(t, breaker.Open, client.BreakerState())
+
+	// While open, calls are rejected without reaching the backend
+	calls := backend.calls.Load()
+	_, err = cache.Get(ctx, "key")
+	assert.ErrorIs(t, err, breaker.ErrOpen)
+	assert.Equal(t, calls, backend.calls.Load())
+
+	// After a manual reset the next call is attempted immediately
+	backend.fail.Store(false)
+	require.NoError(t, client.backend.Set(ctx, "key", []byte(`"value"`), time.Minute))
+	client.ResetBreaker()
+	assert.Equal(t, breaker.Closed, client.BreakerState())
+
+	value, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+	assert.Equal(t, calls+1, backend.calls.Load())
+}
+
+func TestOpenBreakerRejectsEveryBackendCall(t *testing.T) {
+	for _, distributed := range []bool{false, true} {
+		t.Run(fmt.Sprintf("distributed=%t", distributed), func(t *testing.T) {
+			cfg := config.Config{
+				Backend:    "memory",
+				Serializer: "json",
+				CircuitBreaker: config.CircuitBreakerConfig{
+					Enabled:          true,
+					FailureThreshold: 1,
+					Cooldown:         time.Hour,
+				},
+			}
+			if distributed {
+				cfg.Distributed = true
+				cfg.GRPC = config.GRPCConfig{Peers: []string{"localhost:50051"}}
+				cfg.Sharding = config.ShardingConfig{Shards: 2}
+			}
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			client := cache.(*CacheClient)
+			require.NoError(t, cache.Set(ctx, "key", 1, time.Minute))
+			client.breaker.Record(true)
+			require.Equal(t, breaker.Open, client.BreakerState())
+
+			calls := map[string]func() error{
+				"Get": func() error { _, err := cache.Get(ctx, "key"); return err },
+				"GetInto": func() error {
+					_, err := GetTyped[int](ctx, cache, "key")
+					return err
+				},
+				"Set":         func() error { return cache.Set(ctx, "key", 2, time.Minute) },
+				"Delete":      func() error { return cache.Delete(ctx, "key") },
+				"Exists":      func() error { _, err := cache.Exists(ctx, "key"); return err },
+				"GetMulti":    func() error { _, err := cache.GetMulti(ctx, []string{"key"}); return err },
+				"SetMulti":    func() error { return cache.SetMulti(ctx, map[string]interface{}{"key": 2}, time.Minute) },
+				"DeleteMulti": func() error { return cache.DeleteMulti(ctx, []string{"key"}) },
+				"Increment":   func() error { _, err := cache.Increment(ctx, "key", 1); return err },
+				"Decrement":   func() error { _, err := cache.Decrement(ctx, "key", 1); return err },
+				"SetNX":       func() error { _, err := cache.SetNX(ctx, "other", 1, time.Minute); return err },
+				"GetSet":      func() error { _, err := cache.GetSet(ctx, "key", 2); return err },
+				"Expire":      func() error { return cache.Expire(ctx, "key", time.Hour) },
+				"TTL":         func() error { _, err := cache.TTL(ctx, "key"); return err },
+				"GetMultiTTL": func() error { _, err := cache.GetMultiTTL(ctx, []string{"key"}); return err },
+				"ProbeMulti":  func() error { _, err := cache.ProbeMulti(ctx, []string{"key"}); return err },
+			}
+			for name, call := range calls {
+				err := call()
+				if name == "GetMulti" && distributed {
+					// Per-key failures are omitted from the result
+					continue
+				}
+				assert.ErrorIs(t, err, breaker.ErrOpen, name)
+			}
+
+			// Nothing was written while the breaker was open
+			client.ResetBreaker()
+			value, err := cache.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, float64(1), value)
+		})
+	}
+}
+
+// unhealthyBackend is a backend whose health check always fails.
+type unhealthyBackend struct {
+	backends.Backend
+}
+
+func (unhealthyBackend) Health(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestHealthAll(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Distributed: true,
+		GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+		Sharding:    config.ShardingConfig{Shards: 3},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	client := cache.(*CacheClient)
+	client.shards[1] = unhealthyBackend{client.shards[1]}
+
+	assert.Error(t, cache.Health(context.Background()))
+
+	report := cache.HealthAll(context.Background())
+	require.Len(t, report, 3)
+	for i, health := range report {
+		assert.Equal(t, fmt.Sprintf("shard-%d", i), health.Component)
+		assert.Equal(t, "memory", health.Backend)
+		if i == 1 {
+			assert.False(t, health.Healthy)
+			assert.Equal(t, "connection refused", health.Error)
+		} else {
+			assert.True(t, health.Healthy)
+			assert.Empty(t, health.Error)
+		}
+	}
+}
+
+func TestDistributedRequiresShards(t *testing.T) {
+	_, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Distributed: true,
+		GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+		Sharding:    config.ShardingConfig{Shards: -1},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "at least one shard")
+}
+
+func TestErrNoShards(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Distributed: true,
+		GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+		Sharding:    config.ShardingConfig{Shards: 2},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+	client := cache.(*CacheClient)
+
+	// Simulate a client whose shards are gone
+	shards := client.shards
+	client.shards = nil
+	defer func() { client.shards = shards }()
+
+	ctx := context.Background()
+	_, err = client.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrNoShards)
+	assert.ErrorIs(t, client.Set(ctx, "key", "value", time.Minute), ErrNoShards)
+	assert.ErrorIs(t, client.Delete(ctx, "key"), ErrNoShards)
+	_, err = client.Exists(ctx, "key")
+	assert.ErrorIs(t, err, ErrNoShards)
+	_, err = client.GetMulti(ctx, []string{"key"})
+	assert.ErrorIs(t, err, ErrNoShards)
+	assert.ErrorIs(t, client.SetMulti(ctx, map[string]interface{}{"key": "value"}, time.Minute), ErrNoShards)
+	_, err = client.Increment(ctx, "counter", 1)
+	assert.ErrorIs(t, err, ErrNoShards)
+	_, err = client.Decrement(ctx, "counter", 1)
+	assert.ErrorIs(t, err, ErrNoShards)
+	_, err = client.GetMultiTTL(ctx, []string{"key"})
+	assert.ErrorIs(t, err, ErrNoShards)
+}
+
+type deleteCountingBackend struct {
+	backends.Backend
+	deletes      atomic.Int32
+	deleteMultis atomic.Int32
+	keys         atomic.Int32
+}
+
+func (d *deleteCountingBackend) Delete(ctx context.Context, key string) error {
+	d.deletes.Add(1)
+	return d.Backend.Delete(ctx, key)
+}
+
+func (d *deleteCountingBackend) DeleteMulti(ctx context.Context, keys []string) error {
+	d.deleteMultis.Add(1)
+	d.keys.Add(int32(len(keys)))
+	return d.Backend.DeleteMulti(ctx, keys)
+}
+
+func TestDeleteMultiGroupsByShard(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Distributed: true,
+		GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+		Sharding:    config.ShardingConfig{Shards: 3},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+	client := cache.(*CacheClient)
+
+	counters := make([]*deleteCountingBackend, len(client.shards))
+	for i, shard := range client.shards {
+		counters[i] = &deleteCountingBackend{Backend: shard}
+		client.shards[i] = counters[i]
+	}
+
+	ctx := context.Background()
+	keys := make([]string, 30)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		require.NoError(t, client.Set(ctx, keys[i], i, time.Minute))
+	}
+
+	require.NoError(t, client.DeleteMulti(ctx, keys))
+
+	var total int32
+	for i, counter := range counters {
+		require.Positive(t, counter.keys.Load(), "keys should spread across every shard")
+		assert.Equal(t, int32(1), counter.deleteMultis.Load(), "shard %d", i)
+		assert.Zero(t, counter.deletes.Load(), "shard %d", i)
+		total += counter.keys.Load()
+	}
+	assert.Equal(t, int32(len(keys)), total)
+
+	for _, key := range keys {
+		exists, err := client.Exists(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, exists, key)
+	}
+}
+
+type failingDeleteBackend struct {
+	backends.Backend
+}
+
+func (failingDeleteBackend) DeleteMulti(ctx context.Context, keys []string) error {
+	return errors.New("connection refused")
+}
+
+// failingSetBackend is a backend whose writes fail.
+type failingSetBackend struct {
+	backends.Backend
+}
+
+func (failingSetBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func TestHierarchicalSetPartialFailure(t *testing.T) {
+	for _, failedTier := range []string{"L1", "L2"} {
+		t.Run(failedTier, func(t *testing.T) {
+			cache, err := New(config.Config{
+				Backend:      "memory",
+				Serializer:   "json",
+				Hierarchical: true,
+				L1:           config.CacheConfig{Backend: "memory"},
+				L2:           config.CacheConfig{Backend: "memory"},
+			})
+			require.NoError(t, err)
+			defer cache.Close()
+			client := cache.(*CacheClient)
+
+			tiers := map[string]*CacheClient{
+				"L1": client.l1Cache.(*CacheClient),
+				"L2": client.l2Cache.(*CacheClient),
+			}
+			failing := tiers[failedTier]
+			failing.backend = failingSetBackend{failing.backend}
+
+			ctx := context.Background()
+			err = client.Set(ctx, "key", "value", time.Minute)
+			var multi *ErrMultiple
+			require.ErrorAs(t, err, &multi)
+			assert.Len(t, multi.Failed, 1)
+			assert.ErrorContains(t, multi.Failed[failedTier], "connection refused")
+			assert.ErrorContains(t, err, "failed to set in "+failedTier+" cache")
+
+			// The other tier still received the value
+			for name, tier := range tiers {
+				if name == failedTier {
+					continue
+				}
+				value, err := tier.Get(ctx, "key")
+				require.NoError(t, err, name)
+				assert.Equal(t, "value", value, name)
+			}
+		})
+	}
+
+	// A write failing in both tiers reports both
+	cache, err := New(config.Config{
+		Backend:      "memory",
+		Serializer:   "json",
+		Hierarchical: true,
+		L1:           config.CacheConfig{Backend: "memory"},
+		L2:           config.CacheConfig{Backend: "memory"},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+	client := cache.(*CacheClient)
+	for _, tier := range []Cache{client.l1Cache, client.l2Cache} {
+		tier.(*CacheClient).backend = failingSetBackend{tier.(*CacheClient).backend}
+	}
+
+	err = client.Set(context.Background(), "key", "value", time.Minute)
+	var multi *ErrMultiple
+	require.ErrorAs(t, err, &multi)
+	assert.Len(t, multi.Failed, 2)
+}
+
+func TestDeleteMultiShardErrors(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Distributed: true,
+		GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+		Sharding:    config.ShardingConfig{Shards: 3},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+	client := cache.(*CacheClient)
+
+	ctx := context.Background()
+	keys := make([]string, 30)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		require.NoError(t, client.Set(ctx, keys[i], i, time.Minute))
+	}
+
+	failing := client.shards[0]
+	client.shards[0] = failingDeleteBackend{failing}
+
+	err = client.DeleteMulti(ctx, keys)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+
+	// The healthy shards still deleted their keys
+	for _, key := range keys {
+		shard, err := client.getShard(key)
+		require.NoError(t, err)
+		exists, err := client.Exists(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, shard == client.shards[0], exists, key)
+	}
+}
+
+func TestShardInfo(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Distributed: true,
+		GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+		Sharding:    config.ShardingConfig{Shards: 4},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	client := cache.(*CacheClient)
+	client.shards[2] = unhealthyBackend{client.shards[2]}
+
+	shards, err := client.ShardInfo(context.Background())
+	require.NoError(t, err)
+	require.Len(t, shards, 4)
+	for i, info := range shards {
+		assert.Equal(t, i, info.Index)
+		assert.Equal(t, "memory", info.Backend)
+		assert.Empty(t, info.Addresses)
+		assert.Equal(t, i != 2, info.Healthy)
+	}
+	assert.Equal(t, "connection refused", shards[2].Error)
+
+	// Shard info is only meaningful in distributed mode
+	single, err := New(config.Config{Backend: "memory", Serializer: "json"})
+	require.NoError(t, err)
+	defer single.Close()
+
+	_, err = single.(*CacheClient).ShardInfo(context.Background())
+	assert.Error(t, err)
+}
+
+// offsetClock reads a base clock shifted by a fixed offset, like a node
+// whose clock is skewed.
+type offsetClock struct {
+	base   backends.Clock
+	offset time.Duration
+}
+
+func (c offsetClock) Now() time.Time {
+	return c.base.Now().Add(c.offset)
+}
+
+func TestClockSkew(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Distributed: true,
+		GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+		Sharding:    config.ShardingConfig{Shards: 3},
+		Prometheus:  config.PrometheusConfig{Enabled: true},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	// Give every shard its own skewed clock
+	client := cache.(*CacheClient)
+	clock := gocachextest.NewFakeClock(time.Now())
+	offsets := []time.Duration{-5 * time.Second, 0, 5 * time.Second}
+	for i, offset := range offsets {
+		require.NoError(t, client.shards[i].Close())
+		shard, err := backends.NewMemoryBackendWithClock(config.MemoryConfig{DisableCleanup: true}, offsetClock{clock, offset})
+		require.NoError(t, err)
+		client.shards[i] = shard
+	}
+
+	ctx := context.Background()
+	skews, err := client.ClockSkew(ctx)
+	require.NoError(t, err)
+	require.Len(t, skews, 3)
+	for i, offset := range offsets {
+		assert.InDelta(t, offset.Seconds(), skews[fmt.Sprintf("shard-%d", i)].Seconds(), 0.5, "shard %d", i)
+	}
+
+	client.flushStats(ctx)
+	skew, ok := metricValue(t, client.metrics.GetRegistry(), "gocachex_cache_clock_skew_seconds", map[string]string{
+		"backend": "memory",
+		"level":   "shard-2",
+	})
+	assert.True(t, ok)
+	assert.InDelta(t, 5, skew, 0.5)
+
+	// TTLs are counted down by each shard's own clock, so every shard
+	// expires its keys at the same moment despite the skew
+	keys := make([]string, 30)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		require.NoError(t, cache.Set(ctx, keys[i], i, time.Minute))
+	}
+
+	clock.Advance(59 * time.Second)
+	for _, key := range keys {
+		ttl, err := cache.TTL(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, time.Second, ttl, key)
+	}
+
+	clock.Advance(2 * time.Second)
+	for _, key := range keys {
+		_, err := cache.Get(ctx, key)
+		assert.ErrorIs(t, err, backends.ErrNotFound, key)
+	}
+}
+
+func TestHealthAllHierarchical(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:      "memory",
+		Serializer:   "json",
+		Hierarchical: true,
+		L1:           config.CacheConfig{Backend: "memory"},
+		L2:           config.CacheConfig{Backend: "memory"},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	report := cache.HealthAll(context.Background())
+	assert.Equal(t, []ComponentHealth{
+		{Component: "l1", Backend: "memory", Healthy: true},
+		{Component: "l2", Backend: "memory", Healthy: true},
+	}, report)
+}
+
+func TestCloseTwice(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+			Prometheus: config.PrometheusConfig{Enabled: true, StatsInterval: time.Minute},
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+
+			assert.NotPanics(t, func() {
+				assert.NoError(t, cache.Close())
+				assert.NoError(t, cache.Close())
+			})
+		})
+	}
+}
+
+// expiringIterator is a backend whose only entry is about to expire.
+type expiringIterator struct {
+	backends.Backend
+}
+
+func (expiringIterator) Iterate(ctx context.Context, fn func(key string, value []byte, ttl time.Duration) error) error {
+	return fn("expiring", []byte(`"soon"`), 500*time.Microsecond)
+}
+
+func TestExportSubMillisecondTTL(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	client := cache.(*CacheClient)
+	client.backend = expiringIterator{Backend: client.backend}
+
+	// The entry must not be exported as persistent
+	var buf bytes.Buffer
+	count, err := client.Export(context.Background(), &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var record exportRecord
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, int64(1), record.TTL)
+}
+
+func TestImportKeepsEntriesWithoutExpiry(t *testing.T) {
+	src, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer src.Close()
+
+	dst, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+		Memory:     config.MemoryConfig{DefaultTTL: time.Minute},
+	})
+	require.NoError(t, err)
+	defer dst.Close()
+
+	ctx := context.Background()
+	require.NoError(t, src.Set(ctx, "forever", "value", 0))
+	require.NoError(t, src.Set(ctx, "expiring", "value", time.Hour))
+
+	var buf bytes.Buffer
+	_, err = src.Export(ctx, &buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `"ttl_ms":-1`)
+
+	count, err := dst.Import(ctx, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// The default TTL must not be applied to an entry that never expired
+	ttl, err := dst.TTL(ctx, "forever")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), ttl)
+
+	ttl, err = dst.TTL(ctx, "expiring")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute)
+}
+
+func TestExtendedStats(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+	client := cache.(*CacheClient)
+
+	ctx := context.Background()
+	require.NoError(t, client.Set(ctx, "hot", "value", time.Minute))
+	require.NoError(t, client.Set(ctx, "cold", "value", 0))
+	for i := 0; i < 5; i++ {
+		_, err := client.Get(ctx, "hot")
+		require.NoError(t, err)
+	}
+
+	stats, err := client.ExtendedStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.KeyCount)
+	assert.Equal(t, int64(5), stats.Hits)
+
+	extra := stats.Extra["primary"]
+	require.NotNil(t, extra)
+	assert.Equal(t, int64(5), extra["access_count_max"])
+	assert.Equal(t, int64(1), extra["keys_never_read"])
+	assert.Equal(t, int64(1), extra["keys_with_ttl"])
+
+	// The common fields stay at the top level of the JSON
+	data, err := json.Marshal(stats)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, float64(2), decoded["key_count"])
+	assert.Contains(t, decoded["extra"], "primary")
+}
+
+func TestExtendedStatsHierarchical(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:      "memory",
+		Serializer:   "json",
+		Hierarchical: true,
+		L1:           config.CacheConfig{Backend: "memory"},
+		L2:           config.CacheConfig{Backend: "memory"},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	stats, err := cache.(*CacheClient).ExtendedStats(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, stats.Extra, "l1")
+	assert.Contains(t, stats.Extra, "l2")
+}
+
+func TestHierarchicalTierCompression(t *testing.T) {
+	disabled := false
+	cache, err := New(config.Config{
+		Backend:              "memory",
+		Serializer:           "json",
+		Compression:          true,
+		CompressionAlgorithm: "gzip",
+		CompressionMinSize:   1,
+		Hierarchical:         true,
+		L1:                   config.CacheConfig{Backend: "memory", Compression: &disabled},
+		L2:                   config.CacheConfig{Backend: "memory"},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+	l1 := client.l1Cache.(*CacheClient).backend
+	l2 := client.l2Cache.(*CacheClient).backend
+
+	value := map[string]interface{}{"name": "gocachex", "tags": []interface{}{"a", "b"}}
+	require.NoError(t, cache.Set(ctx, "key", value, time.Minute))
+
+	raw, err := l1.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, json.Valid(raw), "L1 stores raw serialized values")
+
+	raw, err = l2.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x1f, 0x8b}, raw[:2], "L2 stores gzip compressed values")
+
+	got, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, value, got)
+
+	// Values promoted from L2 are stored raw in L1 as well
+	require.NoError(t, l1.Delete(ctx, "key"))
+	got, err = cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, value, got)
+
+	raw, err = l1.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, json.Valid(raw))
+}
+
+func TestHierarchicalTierCompressionAlgorithm(t *testing.T) {
+	enabled := true
+	cache, err := New(config.Config{
+		Backend:            "memory",
+		Serializer:         "json",
+		CompressionMinSize: 1,
+		Hierarchical:       true,
+		L1:                 config.CacheConfig{Backend: "memory"},
+		L2:                 config.CacheConfig{Backend: "memory", Compression: &enabled, CompressionAlgorithm: "zstd"},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+	require.NoError(t, cache.Set(ctx, "key", []interface{}{"x", "y"}, time.Minute))
+
+	raw, err := client.l1Cache.(*CacheClient).backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, json.Valid(raw))
+
+	raw, err = client.l2Cache.(*CacheClient).backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x28, 0xb5, 0x2f, 0xfd}, raw[:4])
+
+	got, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"x", "y"}, got)
+
+	// An invalid tier algorithm is rejected when the tier is created
+	_, err = New(config.Config{
+		Backend:      "memory",
+		Hierarchical: true,
+		L1:           config.CacheConfig{Backend: "memory"},
+		L2:           config.CacheConfig{Backend: "memory", Compression: &enabled, CompressionAlgorithm: "brotli"},
+	})
+	assert.ErrorContains(t, err, "L2")
+}
+
+func TestCounterOverflow(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+
+	value, err := cache.Increment(ctx, "counter", math.MaxInt64-1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64-1), value)
+
+	value, err = cache.Increment(ctx, "counter", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64), value)
+
+	_, err = cache.Increment(ctx, "counter", 1)
+	assert.ErrorIs(t, err, backends.ErrCounterOverflow)
+
+	_, err = cache.Decrement(ctx, "counter", -1)
+	assert.ErrorIs(t, err, backends.ErrCounterOverflow)
+
+	// The stored value is left untouched on overflow
+	value, err = cache.Increment(ctx, "counter", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64), value)
+
+	value, err = cache.Decrement(ctx, "negative", math.MaxInt64)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-math.MaxInt64), value)
+
+	value, err = cache.Decrement(ctx, "negative", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(math.MinInt64), value)
+
+	_, err = cache.Decrement(ctx, "negative", 1)
+	assert.ErrorIs(t, err, backends.ErrCounterOverflow)
+
+	_, err = cache.Increment(ctx, "negative", math.MinInt64)
+	assert.ErrorIs(t, err, backends.ErrCounterOverflow)
+}
+
+func TestIncrementWithCompression(t *testing.T) {
+	for _, algorithm := range []string{"gzip", "lz4", "snappy", "zstd"} {
+		t.Run(algorithm, func(t *testing.T) {
+			cache, err := New(config.Config{
+				Backend:              "memory",
+				Serializer:           "json",
+				Compression:          true,
+				CompressionAlgorithm: algorithm,
+				PreserveIntegers:     true,
+			})
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			_, err = cache.Increment(ctx, "counter", 40)
+			require.NoError(t, err)
+			_, err = cache.Increment(ctx, "counter", 2)
+			require.NoError(t, err)
+
+			value, err := cache.Get(ctx, "counter")
+			require.NoError(t, err)
+			assert.Equal(t, int64(42), value)
+
+			values, err := cache.GetMulti(ctx, []string{"counter"})
+			require.NoError(t, err)
+			assert.Equal(t, int64(42), values["counter"])
+
+			// Regular values are still compressed and read back
+			require.NoError(t, cache.Set(ctx, "value", strings.Repeat("12345", 100), 0))
+			value, err = cache.Get(ctx, "value")
+			require.NoError(t, err)
+			assert.Equal(t, strings.Repeat("12345", 100), value)
+		})
+	}
+}
+
+func TestIncrementWithSerializers(t *testing.T) {
+	for _, serializer := range []string{"json", "gob", "msgpack"} {
+		t.Run(serializer, func(t *testing.T) {
+			cache, err := New(config.Config{
+				Backend:          "memory",
+				Serializer:       serializer,
+				PreserveIntegers: true,
+			})
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			_, err = cache.Increment(ctx, "counter", 12)
+			require.NoError(t, err)
+
+			value, err := cache.Get(ctx, "counter")
+			require.NoError(t, err)
+			assert.Equal(t, int64(12), value)
+
+			// A serialized value shaped like a counter keeps its serializer
+			require.NoError(t, cache.Set(ctx, "value", 49, 0))
+			number, err := GetTyped[int](ctx, cache, "value")
+			require.NoError(t, err)
+			assert.Equal(t, 49, number)
+		})
+	}
+}
+
+func TestMaxDecompressedSize(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:             "memory",
+		Serializer:          "json",
+		Compression:         true,
+		MaxDecompressedSize: 1024,
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "small", "value", time.Minute))
+	require.NoError(t, cache.Set(ctx, "large", strings.Repeat("x", 1<<20), time.Minute))
+
+	value, err := cache.Get(ctx, "small")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	_, err = cache.Get(ctx, "large")
+	assert.ErrorIs(t, err, backends.ErrDecompressedTooLarge)
+}
+
+func TestCompressionMinSize(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Compression: true,
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Compression: true,
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			client := cache.(*CacheClient)
+			assert.Equal(t, 1024, client.config.CompressionMinSize)
+
+			raw := func(key string) []byte {
+				t.Helper()
+				backend := client.backend
+				if client.config.Distributed {
+					backend, err = client.getShard(key)
+					require.NoError(t, err)
+				}
+				data, err := backend.Get(ctx, key)
+				require.NoError(t, err)
+				return data
+			}
+
+			small := map[string]interface{}{"id": float64(1)}
+			large := map[string]interface{}{"body": strings.Repeat("payload ", 512)}
+			require.NoError(t, cache.Set(ctx, "small", small, time.Minute))
+			require.NoError(t, cache.Set(ctx, "large", large, time.Minute))
+
+			// The tiny value is stored uncompressed behind a header
+			data := raw("small")
+			assert.Equal(t, []byte{headerMarker, flagUncompressed}, data[:headerSize])
+			assert.JSONEq(t, `{"id":1}`, string(data[headerSize:]))
+
+			data = raw("large")
+			assert.Equal(t, []byte{0x1f, 0x8b}, data[:2])
+			assert.Less(t, len(data), 1024)
+
+			value, err := cache.Get(ctx, "small")
+			require.NoError(t, err)
+			assert.Equal(t, small, value)
+
+			value, err = cache.Get(ctx, "large")
+			require.NoError(t, err)
+			assert.Equal(t, large, value)
+
+			// An explicit per-entry override still compresses small values
+			require.NoError(t, client.SetWithOptions(ctx, "forced", small, time.Minute, WithCompression(true)))
+			assert.Equal(t, []byte{0x1f, 0x8b}, raw("forced")[:2])
+			value, err = cache.Get(ctx, "forced")
+			require.NoError(t, err)
+			assert.Equal(t, small, value)
+		})
+	}
+
+	_, err := New(config.Config{Backend: "memory", Compression: true, CompressionMinSize: -1})
+	assert.Error(t, err)
+}
+
+func TestExistsPattern(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "user:1", "alice", time.Minute))
+	require.NoError(t, cache.Set(ctx, "session:1", "token", 20*time.Millisecond))
+
+	found, err := cache.ExistsPattern(ctx, "user:*")
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = cache.ExistsPattern(ctx, "order:*")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// A prefix alone is not a match without a wildcard
+	found, err = cache.ExistsPattern(ctx, "user:")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// Expired keys do not count
+	time.Sleep(50 * time.Millisecond)
+	found, err = cache.ExistsPattern(ctx, "session:*")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+type typedOrder struct {
+	ID    int      `json:"id"`
+	Items []string `json:"items"`
+	Total float64  `json:"total"`
+}
+
+func TestTypedSerializer(t *testing.T) {
+	RegisterType(&typedOrder{})
+
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "typed",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	order := &typedOrder{ID: 1, Items: []string{"book", "pen"}, Total: 12.5}
+	require.NoError(t, cache.Set(ctx, "order:1", order, time.Minute))
+	require.NoError(t, cache.Set(ctx, "count", 42, time.Minute))
+
+	value, err := cache.Get(ctx, "order:1")
+	require.NoError(t, err)
+	require.IsType(t, &typedOrder{}, value)
+	assert.Equal(t, order, value.(*typedOrder))
+
+	value, err = cache.Get(ctx, "count")
+	require.NoError(t, err)
+	assert.Equal(t, 42, value)
+}
+
+type Product struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Price float64  `json:"price"`
+	Tags  []string `json:"tags"`
+}
+
+func TestGetTyped(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"json": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"gob": {
+			Backend:    "memory",
+			Serializer: "gob",
+		},
+		"msgpack compressed": {
+			Backend:            "memory",
+			Serializer:         "msgpack",
+			Compression:        true,
+			CompressionMinSize: 1,
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			product := &Product{ID: 1, Name: "book", Price: 12.5, Tags: []string{"paper"}}
+			require.NoError(t, SetTyped(ctx, cache, "product:1", product, time.Minute))
+
+			got, err := GetTyped[*Product](ctx, cache, "product:1")
+			require.NoError(t, err)
+			assert.IsType(t, &Product{}, got)
+			assert.Equal(t, product, got)
+
+			// Values can also be read as non-pointer types
+			value, err := GetTyped[Product](ctx, cache, "product:1")
+			require.NoError(t, err)
+			assert.Equal(t, *product, value)
+
+			require.NoError(t, SetTyped(ctx, cache, "name", "gocachex", time.Minute))
+			str, err := GetTyped[string](ctx, cache, "name")
+			require.NoError(t, err)
+			assert.Equal(t, "gocachex", str)
+
+			_, err = GetTyped[*Product](ctx, cache, "name")
+			assert.Error(t, err)
+
+			_, err = GetTyped[*Product](ctx, cache, "missing")
+			assert.ErrorIs(t, err, backends.ErrNotFound)
+		})
+	}
+}
+
+func TestDereferencePointers(t *testing.T) {
+	RegisterType(Product{})
+
+	for _, serializer := range []string{"json", "gob", "msgpack", "typed"} {
+		t.Run(serializer, func(t *testing.T) {
+			cache, err := New(config.Config{
+				Backend:             "memory",
+				Serializer:          serializer,
+				DereferencePointers: true,
+			})
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			client := cache.(*CacheClient)
+			product := Product{ID: 1, Name: "book", Price: 12.5, Tags: []string{"paper"}}
+
+			// Pointers and values are stored identically
+			require.NoError(t, cache.Set(ctx, "pointer", &product, time.Minute))
+			require.NoError(t, cache.Set(ctx, "value", product, time.Minute))
+			pointerData, err := client.backend.Get(ctx, "pointer")
+			require.NoError(t, err)
+			valueData, err := client.backend.Get(ctx, "value")
+			require.NoError(t, err)
+			assert.Equal(t, valueData, pointerData)
+
+			// GetTyped reconstructs the pointer type
+			got, err := GetTyped[*Product](ctx, cache, "pointer")
+			require.NoError(t, err)
+			require.NotNil(t, got)
+			assert.Equal(t, product, *got)
+
+			value, err := GetTyped[Product](ctx, cache, "pointer")
+			require.NoError(t, err)
+			assert.Equal(t, product, value)
+
+			// Pointers to pointers and strings are followed too
+			pointer := &product
+			require.NoError(t, cache.Set(ctx, "pointer-pointer", &pointer, time.Minute))
+			got, err = GetTyped[*Product](ctx, cache, "pointer-pointer")
+			require.NoError(t, err)
+			assert.Equal(t, product, *got)
+
+			name := "gocachex"
+			require.NoError(t, cache.Set(ctx, "name", &name, time.Minute))
+			str, err := cache.Get(ctx, "name")
+			require.NoError(t, err)
+			assert.Equal(t, "gocachex", str)
+
+			err = cache.Set(ctx, "nil", (*Product)(nil), time.Minute)
+			assert.ErrorIs(t, err, ErrNilPointer)
+			_, err = cache.Get(ctx, "nil")
+			assert.ErrorIs(t, err, backends.ErrNotFound)
+		})
+	}
+
+	// The typed serializer records the value type, so Get returns a value
+	cache, err := New(config.Config{
+		Backend:             "memory",
+		Serializer:          "typed",
+		DereferencePointers: true,
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "product", &Product{ID: 2, Name: "pen"}, time.Minute))
+	value, err := cache.Get(ctx, "product")
+	require.NoError(t, err)
+	assert.Equal(t, Product{ID: 2, Name: "pen"}, value)
+
+	// Both tiers of a hierarchical client dereference pointers
+	hierarchical, err := New(config.Config{
+		Backend:             "memory",
+		Serializer:          "json",
+		DereferencePointers: true,
+		Hierarchical:        true,
+		L1:                  config.CacheConfig{Backend: "memory"},
+		L2:                  config.CacheConfig{Backend: "memory"},
+	})
+	require.NoError(t, err)
+	defer hierarchical.Close()
+	client := hierarchical.(*CacheClient)
+
+	product := Product{ID: 3, Name: "ink"}
+	require.NoError(t, hierarchical.Set(ctx, "pointer", &product, time.Minute))
+	require.NoError(t, hierarchical.Set(ctx, "value", product, time.Minute))
+	for _, tier := range []Cache{client.l1Cache, client.l2Cache} {
+		backend := tier.(*CacheClient).backend
+		pointerData, err := backend.Get(ctx, "pointer")
+		require.NoError(t, err)
+		valueData, err := backend.Get(ctx, "value")
+		require.NoError(t, err)
+		assert.Equal(t, valueData, pointerData)
+	}
+
+	err = hierarchical.Set(ctx, "nil", (*Product)(nil), time.Minute)
+	assert.ErrorIs(t, err, ErrNilPointer)
+}
+
+func TestGetTypedPromotesToL1(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:      "memory",
+		Serializer:   "json",
+		Hierarchical: true,
+		L1:           config.CacheConfig{Backend: "memory"},
+		L2:           config.CacheConfig{Backend: "memory"},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+	product := &Product{ID: 2, Name: "pen"}
+	require.NoError(t, client.l2Cache.Set(ctx, "product:2", product, time.Minute))
+
+	got, err := GetTyped[*Product](ctx, cache, "product:2")
+	require.NoError(t, err)
+	assert.Equal(t, product, got)
+
+	got, err = GetTyped[*Product](ctx, client.l1Cache, "product:2")
+	require.NoError(t, err)
+	assert.Equal(t, product, got)
+}
+
+func TestSetWithCompression(t *testing.T) {
+	for _, global := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compression=%v", global), func(t *testing.T) {
+			cache, err := New(config.Config{
+				Backend:     "memory",
+				Serializer:  "json",
+				Compression: global,
+			})
+			require.NoError(t, err)
+			defer cache.Close()
+			client := cache.(*CacheClient)
+
+			ctx := context.Background()
+			payload := map[string]interface{}{"body": strings.Repeat("compressible ", 100)}
+			require.NoError(t, client.Set(ctx, "default", payload, time.Minute))
+			require.NoError(t, client.SetWithOptions(ctx, "compressed", payload, time.Minute, WithCompression(true)))
+			require.NoError(t, client.SetWithOptions(ctx, "uncompressed", payload, time.Minute, WithCompression(false)))
+			require.NoError(t, client.SetWithOptions(ctx, "text", "plain text", time.Minute, WithCompression(!global)))
+
+			for _, key := range []string{"default", "compressed", "uncompressed"} {
+				value, err := client.Get(ctx, key)
+				require.NoError(t, err, key)
+				assert.Equal(t, payload, value, key)
+			}
+			value, err := client.Get(ctx, "text")
+			require.NoError(t, err)
+			assert.Equal(t, "plain text", value)
+
+			compressed, err := client.backend.Get(ctx, "compressed")
+			require.NoError(t, err)
+			uncompressed, err := client.backend.Get(ctx, "uncompressed")
+			require.NoError(t, err)
+			assert.Less(t, len(compressed), len(uncompressed))
+		})
+	}
+}
+
+func TestSetWithSerializer(t *testing.T) {
+	type report struct {
+		Name   string
+		Counts []int
+	}
+
+	for _, compression := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compression=%v", compression), func(t *testing.T) {
+			cache, err := New(config.Config{
+				Backend:     "memory",
+				Serializer:  "json",
+				Compression: compression,
+			})
+			require.NoError(t, err)
+			defer cache.Close()
+			client := cache.(*CacheClient)
+
+			ctx := context.Background()
+			want := report{Name: "daily", Counts: []int{1, 2, 3}}
+			require.NoError(t, client.SetWithOptions(ctx, "report", want, time.Minute, WithSerializer("gob")))
+			require.NoError(t, client.SetWithOptions(ctx, "tags", map[string]interface{}{"count": 3}, time.Minute, WithSerializer("msgpack")))
+			require.NoError(t, client.Set(ctx, "default", map[string]interface{}{"count": 3}, time.Minute))
+
+			got, err := GetTyped[report](ctx, cache, "report")
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+
+			// msgpack keeps the integer that the JSON default turns into a float
+			value, err := client.Get(ctx, "tags")
+			require.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{"count": int64(3)}, value)
+			value, err = client.Get(ctx, "default")
+			require.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{"count": float64(3)}, value)
+
+			if !compression {
+				raw, err := client.backend.Get(ctx, "report")
+				require.NoError(t, err)
+				assert.Equal(t, []byte{headerMarker, flagSerializer, serializerIDs["gob"]}, raw[:3])
+			}
+
+			err = client.SetWithOptions(ctx, "bad", want, time.Minute, WithSerializer("xml"))
+			assert.ErrorContains(t, err, "unsupported serializer type")
+		})
+	}
+}
+
+func TestGetMultiTTL(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 3},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			expirations := map[string]time.Duration{
+				"key:1": time.Minute,
+				"key:2": 10 * time.Minute,
+				"key:3": time.Hour,
+				"key:4": 24 * time.Hour,
+			}
+			for key, ttl := range expirations {
+				require.NoError(t, cache.Set(ctx, key, "value", ttl))
+			}
+			require.NoError(t, cache.Set(ctx, "key:persistent", "value", 0))
+
+			ttls, err := cache.GetMultiTTL(ctx, []string{"key:1", "key:2", "key:3", "key:4", "key:persistent", "key:missing"})
+			require.NoError(t, err)
+			require.Len(t, ttls, 5)
+
+			for key, ttl := range expirations {
+				assert.LessOrEqual(t, ttls[key], ttl, key)
+				assert.Greater(t, ttls[key], ttl-time.Second, key)
+			}
+			assert.Equal(t, time.Duration(-1), ttls["key:persistent"])
+			assert.NotContains(t, ttls, "key:missing")
+		})
+	}
+}
+
+func TestProbeMulti(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 3},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			require.NoError(t, cache.Set(ctx, "key:minute", "value", time.Minute))
+			require.NoError(t, cache.Set(ctx, "key:hour", "value", time.Hour))
+			require.NoError(t, cache.Set(ctx, "key:persistent", "value", 0))
+			require.NoError(t, cache.Set(ctx, "key:expired", "value", 10*time.Millisecond))
+			time.Sleep(20 * time.Millisecond)
+
+			keys := []string{"key:minute", "key:hour", "key:persistent", "key:expired", "key:missing"}
+			probes, err := cache.ProbeMulti(ctx, keys)
+			require.NoError(t, err)
+			require.Len(t, probes, len(keys))
+
+			for key, ttl := range map[string]time.Duration{"key:minute": time.Minute, "key:hour": time.Hour} {
+				assert.True(t, probes[key].Exists, key)
+				assert.LessOrEqual(t, probes[key].TTL, ttl, key)
+				assert.Greater(t, probes[key].TTL, ttl-time.Second, key)
+			}
+			assert.Equal(t, backends.KeyProbe{Exists: true, TTL: -1}, probes["key:persistent"])
+			assert.Equal(t, backends.KeyProbe{}, probes["key:expired"])
+			assert.Equal(t, backends.KeyProbe{}, probes["key:missing"])
+		})
+	}
+}
+
+func TestCopy(t *testing.T) {
+	newCache := func() Cache {
+		cache, err := New(config.Config{
+			Backend:    "memory",
+			Serializer: "json",
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { cache.Close() })
+		return cache
+	}
+
+	ctx := context.Background()
+	src := newCache()
+
+	require.NoError(t, src.Set(ctx, "user:1", "alice", time.Hour))
+	require.NoError(t, src.Set(ctx, "user:2", map[string]interface{}{"name": "bob"}, 0))
+	require.NoError(t, src.Set(ctx, "session:1", "token", time.Hour))
+	require.NoError(t, src.Set(ctx, "user:expired", "gone", 20*time.Millisecond))
+	time.Sleep(50 * time.Millisecond)
+
+	t.Run("all keys", func(t *testing.T) {
+		dst := newCache()
+		copied, err := Copy(ctx, src, dst, CopyOptions{Concurrency: 4})
+		require.NoError(t, err)
+		assert.Equal(t, 3, copied)
+
+		value, err := dst.Get(ctx, "user:1")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", value)
+
+		value, err = dst.Get(ctx, "user:2")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"name": "bob"}, value)
+
+		exists, err := dst.Exists(ctx, "user:expired")
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		ttl, err := dst.TTL(ctx, "user:1")
+		require.NoError(t, err)
+		assert.InDelta(t, time.Hour.Seconds(), ttl.Seconds(), 5)
+	})
+
+	t.Run("pattern", func(t *testing.T) {
+		dst := newCache()
+		copied, err := Copy(ctx, src, dst, CopyOptions{Pattern: "user:*"})
+		require.NoError(t, err)
+		assert.Equal(t, 2, copied)
+
+		exists, err := dst.Exists(ctx, "session:1")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("pattern applied to the source scan", func(t *testing.T) {
+		client := newCache().(*CacheClient)
+		require.NoError(t, client.Set(ctx, "user:1", "alice", time.Hour))
+		require.NoError(t, client.Set(ctx, "user:2", "bob", time.Hour))
+		require.NoError(t, client.Set(ctx, "session:1", "token", time.Hour))
+		scanning := &patternIteratorBackend{Backend: client.backend}
+		client.backend = scanning
+
+		copied, err := Copy(ctx, client, newCache(), CopyOptions{Pattern: "user:*"})
+		require.NoError(t, err)
+		assert.Equal(t, 2, copied)
+		assert.Equal(t, []string{"user:*"}, scanning.patterns)
+	})
+}
+
+// patternIteratorBackend records the patterns of IteratePattern calls and
+// fails unfiltered iteration.
+type patternIteratorBackend struct {
+	backends.Backend
+	patterns []string
+}
+
+func (b *patternIteratorBackend) Iterate(ctx context.Context, fn func(key string, value []byte, ttl time.Duration) error) error {
+	return errors.New("unfiltered iteration")
+}
+
+func (b *patternIteratorBackend) IteratePattern(ctx context.Context, pattern string, fn func(key string, value []byte, ttl time.Duration) error) error {
+	b.patterns = append(b.patterns, pattern)
+	return b.Backend.(backends.PatternIterator).IteratePattern(ctx, pattern, fn)
+}
+
+// metricValue returns the value of a gauge or counter, or the sample count
+// of a histogram, with the given labels from registry.
+func metricValue(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) (float64, bool) {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			matched := 0
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] == label.GetValue() {
+					matched++
+				}
+			}
+			if matched == len(labels) {
+				if metric.GetCounter() != nil {
+					return metric.GetCounter().GetValue(), true
+				}
+				if metric.GetHistogram() != nil {
+					return float64(metric.GetHistogram().GetSampleCount()), true
+				}
+				return metric.GetGauge().GetValue(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// goroutineRunning reports whether any goroutine is running fn, given as a
+// fully qualified function name.
+func goroutineRunning(fn string) bool {
+	buf := make([]byte, 1<<20)
+	return strings.Contains(string(buf[:runtime.Stack(buf, true)]), fn)
+}
+
+func TestCloseStopsMetrics(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+		Prometheus: config.PrometheusConfig{Enabled: true, Port: port, StatsInterval: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	const poller = "gocachex.(*CacheClient).startStatsFlush.func1"
+	const server = "metrics.(*Collector).StartMetricsServer.func1"
+	assert.True(t, goroutineRunning(poller))
+	assert.True(t, goroutineRunning(server))
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	url := fmt.Sprintf("http://127.0.0.1:%d/metrics", port)
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, cache.Close())
+
+	// Close waits for the poller; the server goroutine returns right after
+	assert.False(t, goroutineRunning(poller))
+	assert.Eventually(t, func() bool { return !goroutineRunning(server) }, time.Second, 10*time.Millisecond)
+	_, err = client.Get(url)
+	assert.Error(t, err)
+}
+
+// flushingProvider is a tracer provider that counts ForceFlush calls, like
+// the OpenTelemetry SDK provider would export pending spans.
+type flushingProvider struct {
+	noop.TracerProvider
+	spans   atomic.Int64
+	flushes atomic.Int64
+}
+
+func (p *flushingProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return &countingTracer{provider: p}
+}
+
+func (p *flushingProvider) ForceFlush(ctx context.Context) error {
+	p.flushes.Add(1)
+	return nil
+}
+
+// countingTracer counts the spans started through it.
+type countingTracer struct {
+	noop.Tracer
+	provider *flushingProvider
+}
+
+func (t *countingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.provider.spans.Add(1)
+	return t.Tracer.Start(ctx, name, opts...)
+}
+
+func TestCloseFlushesTracer(t *testing.T) {
+	provider := &flushingProvider{}
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+		Tracing:    config.TracingConfig{Enabled: true, ServiceName: "test"},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "key", "value", time.Minute))
+	_, err = cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Positive(t, provider.spans.Load())
+	assert.Zero(t, provider.flushes.Load())
+
+	require.NoError(t, cache.Close())
+	assert.Equal(t, int64(1), provider.flushes.Load())
+}
+
+// recordingProvider is a tracer provider keeping every span started through
+// it, like the OpenTelemetry SDK in-memory exporter.
+type recordingProvider struct {
+	noop.TracerProvider
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+func (p *recordingProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{provider: p}
+}
+
+// named returns the recorded spans called name, in start order.
+func (p *recordingProvider) named(name string) []*recordingSpan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var spans []*recordingSpan
+	for _, span := range p.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// recordingTracer starts recording spans.
+type recordingTracer struct {
+	noop.Tracer
+	provider *recordingProvider
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordingSpan{name: name, attributes: make(map[attribute.Key]string)}
+	cfg := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(cfg.Attributes()...)
+
+	t.provider.mu.Lock()
+	t.provider.spans = append(t.provider.spans, span)
+	t.provider.mu.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// recordingSpan records the attributes, events, errors and status set on it.
+type recordingSpan struct {
+	noop.Span
+	name       string
+	attributes map[attribute.Key]string
+	events     []string
+	errs       []error
+	status     codes.Code
+}
+
+func (s *recordingSpan) IsRecording() bool { return true }
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attributes[attr.Key] = attr.Value.Emit()
+	}
+}
+
+func (s *recordingSpan) AddEvent(name string, opts ...trace.EventOption) {
+	s.events = append(s.events, name)
+}
+
+func (s *recordingSpan) RecordError(err error, opts ...trace.EventOption) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, description string) {
+	s.status = code
+}
+
+func TestTracingSpans(t *testing.T) {
+	provider := &recordingProvider{}
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+		Tracing:    config.TracingConfig{Enabled: true, ServiceName: "test"},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "user:1", "Ana", time.Minute))
+	_, err = cache.Get(ctx, "user:1")
+	require.NoError(t, err)
+	_, err = cache.Get(ctx, "user:2")
+	require.ErrorIs(t, err, backends.ErrNotFound)
+
+	gets := provider.named("cache.get")
+	require.Len(t, gets, 2)
+	assert.Equal(t, map[attribute.Key]string{
+		"cache.key":       "user:1",
+		"cache.backend":   "memory",
+		"cache.operation": "get",
+	}, gets[0].attributes)
+	assert.Equal(t, []string{"cache.hit"}, gets[0].events)
+	assert.Equal(t, "user:2", gets[1].attributes["cache.key"])
+	assert.Equal(t, []string{"cache.miss"}, gets[1].events)
+
+	// Misses are not errors
+	for _, span := range gets {
+		assert.Empty(t, span.errs)
+		assert.Equal(t, codes.Unset, span.status)
+	}
+
+	// Failures are recorded on the span; channels cannot be serialized
+	err = cache.Set(ctx, "user:3", make(chan int), time.Minute)
+	require.Error(t, err)
+
+	sets := provider.named("cache.set")
+	require.Len(t, sets, 2)
+	assert.Empty(t, sets[0].errs)
+	assert.Equal(t, "user:3", sets[1].attributes["cache.key"])
+	assert.Equal(t, []error{err}, sets[1].errs)
+	assert.Equal(t, codes.Error, sets[1].status)
+
+	// Operations without a key still carry the operation and backend
+	_, err = cache.Keys(ctx, "user:*")
+	require.NoError(t, err)
+	keys := provider.named("cache.keys")
+	require.Len(t, keys, 1)
+	assert.Equal(t, map[attribute.Key]string{
+		"cache.backend":   "memory",
+		"cache.operation": "keys",
+	}, keys[0].attributes)
+}
+
+func TestCodecErrorMetrics(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Compression: true,
+		Prometheus:  config.PrometheusConfig{Enabled: true},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+
+	type codecError struct {
+		errorType string
+		key       string
+	}
+	var hooked []codecError
+	client.OnCodecError(func(errorType, key string, err error) {
+		assert.Error(t, err)
+		hooked = append(hooked, codecError{errorType, key})
+	})
+
+	// Channels cannot be serialized to JSON
+	err = cache.Set(ctx, "chan", make(chan int), time.Minute)
+	assert.Error(t, err)
+
+	// Bytes that are not gzip cannot be decompressed
+	require.NoError(t, client.backend.Set(ctx, "corrupt", []byte("not gzip"), time.Minute))
+	_, err = cache.Get(ctx, "corrupt")
+	assert.Error(t, err)
+
+	// Valid gzip holding invalid JSON cannot be deserialized
+	compressed, err := client.compressor.Compress([]byte("{not json"))
+	require.NoError(t, err)
+	require.NoError(t, client.backend.Set(ctx, "invalid", compressed, time.Minute))
+	_, err = cache.Get(ctx, "invalid")
+	assert.Error(t, err)
+
+	registry := client.metrics.GetRegistry()
+	for _, tc := range []struct{ operation, errorType string }{
+		{"set", "serialize"},
+		{"get", "decompress"},
+		{"get", "deserialize"},
+	} {
+		count, ok := metricValue(t, registry, "gocachex_cache_errors_total", map[string]string{
+			"operation":  tc.operation,
+			"backend":    "memory",
+			"error_type": tc.errorType,
+		})
+		assert.True(t, ok, tc.errorType)
+		assert.Equal(t, float64(1), count, tc.errorType)
+	}
+
+	assert.Equal(t, []codecError{
+		{"serialize", "chan"},
+		{"decompress", "corrupt"},
+		{"deserialize", "invalid"},
+	}, hooked)
+}
+
+func TestLargeValues(t *testing.T) {
+	ctx := context.Background()
+	large := strings.Repeat("x", 200)
+
+	for name, tc := range map[string]struct {
+		cfg    config.Config
+		writes int // backend writes per Set
+	}{
+		"single": {
+			cfg:    config.Config{Backend: "memory"},
+			writes: 1,
+		},
+		"hierarchical": {
+			cfg: config.Config{
+				Backend:      "memory",
+				Hierarchical: true,
+				L1:           config.CacheConfig{Backend: "memory"},
+				L2:           config.CacheConfig{Backend: "memory"},
+			},
+			writes: 2,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tc.cfg.Prometheus = config.PrometheusConfig{Enabled: true}
+			tc.cfg.LargeValueThreshold = 100
+			cache, err := New(tc.cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			client := cache.(*CacheClient)
+			var hooked []string
+			client.OnLargeValue(func(key string, size int) {
+				assert.Greater(t, size, 100)
+				hooked = append(hooked, key)
+			})
+
+			require.NoError(t, cache.Set(ctx, "small", "value", time.Minute))
+			require.NoError(t, cache.Set(ctx, "large", large, time.Minute))
+
+			count, ok := metricValue(t, client.metrics.GetRegistry(), "gocachex_cache_payload_size_bytes", map[string]string{"backend": "memory"})
+			assert.True(t, ok)
+			assert.Equal(t, float64(2*tc.writes), count)
+
+			want := make([]string, tc.writes)
+			for i := range want {
+				want[i] = "large"
+			}
+			assert.Equal(t, want, hooked)
+		})
+	}
+
+	t.Run("default log", func(t *testing.T) {
+		cache, err := New(config.Config{Backend: "memory", LargeValueThreshold: 100})
+		require.NoError(t, err)
+		defer cache.Close()
+
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		defer log.SetOutput(os.Stderr)
+
+		require.NoError(t, cache.Set(ctx, "small", "value", time.Minute))
+		assert.Empty(t, buf.String())
+
+		require.NoError(t, cache.Set(ctx, "report:large", large, time.Minute))
+		assert.Contains(t, buf.String(), "value of key report:large is 202 bytes")
+	})
+}
+
+func TestEvictUndecodable(t *testing.T) {
+	ctx := context.Background()
+
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend: "memory",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg.Serializer = "json"
+			cfg.EvictUndecodable = true
+			cfg.Prometheus = config.PrometheusConfig{Enabled: true}
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			// The payload of an older, incompatible encoding
+			client := cache.(*CacheClient)
+			backend := client.backend
+			switch {
+			case cfg.Hierarchical:
+				backend = client.l2Cache.(*CacheClient).backend
+			case cfg.Distributed:
+				backend, err = client.getShard("user:1")
+				require.NoError(t, err)
+			}
+			require.NoError(t, backend.Set(ctx, "user:1", []byte("{not json"), time.Minute))
+
+			_, err = cache.Get(ctx, "user:1")
+			assert.ErrorIs(t, err, backends.ErrNotFound)
+			_, err = backend.Get(ctx, "user:1")
+			assert.ErrorIs(t, err, backends.ErrNotFound)
+
+			count, ok := metricValue(t, client.metrics.GetRegistry(), "gocachex_cache_undecodable_evictions_total", nil)
+			assert.True(t, ok)
+			assert.Equal(t, float64(1), count)
+
+			// GetOrSet reloads an undecodable value
+			require.NoError(t, backend.Set(ctx, "user:1", []byte("{not json"), time.Minute))
+			value, err := client.GetOrSet(ctx, "user:1", time.Minute, func(ctx context.Context) (interface{}, error) {
+				return "fresh", nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "fresh", value)
+
+			value, err = cache.Get(ctx, "user:1")
+			require.NoError(t, err)
+			assert.Equal(t, "fresh", value)
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		cache, err := New(config.Config{Backend: "memory", Serializer: "json"})
+		require.NoError(t, err)
+		defer cache.Close()
+
+		client := cache.(*CacheClient)
+		require.NoError(t, client.backend.Set(ctx, "user:1", []byte("{not json"), time.Minute))
+
+		_, err = cache.Get(ctx, "user:1")
+		assert.ErrorContains(t, err, "failed to deserialize data")
+		_, err = client.backend.Get(ctx, "user:1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		cache, err := New(config.Config{Backend: "memory", Serializer: "json", EvictUndecodable: true})
+		require.NoError(t, err)
+		defer cache.Close()
+
+		// A value that decodes, just not into the requested type, is kept
+		require.NoError(t, cache.Set(ctx, "name", "gopher", time.Minute))
+		_, err = GetTyped[int](ctx, cache, "name")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, backends.ErrNotFound)
+
+		value, err := cache.Get(ctx, "name")
+		require.NoError(t, err)
+		assert.Equal(t, "gopher", value)
+	})
+}
+
+func TestHitMissMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("single", func(t *testing.T) {
+		cache, err := New(config.Config{
+			Backend:    "memory",
+			Prometheus: config.PrometheusConfig{Enabled: true},
+		})
+		require.NoError(t, err)
+		defer cache.Close()
+
+		require.NoError(t, cache.Set(ctx, "key", "value", time.Minute))
+		_, err = cache.Get(ctx, "key")
+		require.NoError(t, err)
+		_, err = cache.Get(ctx, "key")
+		require.NoError(t, err)
+		_, err = cache.Get(ctx, "missing")
+		assert.ErrorIs(t, err, backends.ErrNotFound)
+
+		registry := cache.(*CacheClient).metrics.GetRegistry()
+		operations, ok := metricValue(t, registry, "gocachex_cache_operations_total", map[string]string{
+			"operation": "get",
+			"backend":   "memory",
+			"status":    "success",
+		})
+		assert.True(t, ok)
+		assert.Equal(t, float64(2), operations)
+
+		labels := map[string]string{"backend": "memory", "level": "primary"}
+		hits, ok := metricValue(t, registry, "gocachex_cache_hits_total", labels)
+		assert.True(t, ok)
+		assert.Equal(t, float64(2), hits)
+		misses, ok := metricValue(t, registry, "gocachex_cache_misses_total", labels)
+		assert.True(t, ok)
+		assert.Equal(t, float64(1), misses)
+	})
+
+	t.Run("hierarchical", func(t *testing.T) {
+		cache, err := New(config.Config{
+			Backend:      "memory",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+			Prometheus:   config.PrometheusConfig{Enabled: true},
+		})
+		require.NoError(t, err)
+		defer cache.Close()
+
+		// Only L2 holds the key, so the first read misses L1 and promotes it
+		client := cache.(*CacheClient)
+		require.NoError(t, client.l2Cache.Set(ctx, "key", "value", time.Minute))
+		_, err = cache.Get(ctx, "key")
+		require.NoError(t, err)
+		_, err = cache.Get(ctx, "key")
+		require.NoError(t, err)
+
+		registry := client.metrics.GetRegistry()
+		for _, tc := range []struct {
+			name, level string
+			want        float64
+		}{
+			{"gocachex_cache_hits_total", "l1", 1},
+			{"gocachex_cache_misses_total", "l1", 1},
+			{"gocachex_cache_hits_total", "l2", 1},
+		} {
+			count, ok := metricValue(t, registry, tc.name, map[string]string{"backend": "memory", "level": tc.level})
+			assert.True(t, ok, tc.name+" "+tc.level)
+			assert.Equal(t, tc.want, count, tc.name+" "+tc.level)
+		}
+	})
+}
+
+func TestCompressionMetrics(t *testing.T) {
+	for name, tc := range map[string]struct {
+		cfg      config.Config
+		expected map[string]float64
+	}{
+		"single": {
+			cfg:      config.Config{Backend: "memory"},
+			expected: map[string]float64{"compress": 3, "decompress": 4},
+		},
+		"hierarchical": {
+			// Sets are compressed once per tier, and reads hit L1
+			cfg: config.Config{
+				Backend:      "memory",
+				Hierarchical: true,
+				L1:           config.CacheConfig{Backend: "memory"},
+				L2:           config.CacheConfig{Backend: "memory"},
+			},
+			expected: map[string]float64{"compress": 6, "decompress": 4},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := tc.cfg
+			cfg.Serializer = "json"
+			cfg.Compression = true
+			cfg.CompressionAlgorithm = "zstd"
+			cfg.CompressionMinSize = 1
+			cfg.Prometheus = config.PrometheusConfig{Enabled: true}
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			client := cache.(*CacheClient)
+
+			for i := 0; i < 3; i++ {
+				key := fmt.Sprintf("key:%d", i)
+				require.NoError(t, cache.Set(ctx, key, strings.Repeat("payload", 100), time.Minute))
+				_, err := cache.Get(ctx, key)
+				require.NoError(t, err)
+			}
+			_, err = cache.Get(ctx, "key:0")
+			require.NoError(t, err)
+
+			registry := client.metrics.GetRegistry()
+			for direction, expected := range tc.expected {
+				count, ok := metricValue(t, registry, "gocachex_cache_compression_duration_seconds", map[string]string{
+					"algorithm": "zstd",
+					"direction": direction,
+				})
+				assert.True(t, ok, direction)
+				assert.Equal(t, expected, count, direction)
+			}
+		})
+	}
+}
+
+func TestKeyGroupMetrics(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+		Prometheus: config.PrometheusConfig{
+			Enabled:   true,
+			KeyGroups: true,
+		},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+
+	keys := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		keys[key] = true
+		require.NoError(t, cache.Set(ctx, key, i, time.Minute))
+		_, err := cache.Get(ctx, key)
+		require.NoError(t, err)
+	}
+
+	families, err := client.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+
+	groups := make(map[string]bool)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				assert.False(t, keys[label.GetValue()], "raw key %q used as label", label.GetValue())
+				if label.GetName() == "group" {
+					groups[label.GetValue()] = true
+				}
+			}
+		}
+	}
+	assert.Equal(t, map[string]bool{"user": true}, groups)
+}
+
+func TestStatsFlushUpdatesGauges(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+		Prometheus: config.PrometheusConfig{
+			Enabled:       true,
+			StatsInterval: 10 * time.Millisecond,
+		},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, cache.Set(ctx, key, "value", time.Minute))
+	}
+
+	stats, err := cache.Stats(ctx)
+	require.NoError(t, err)
+
+	labels := map[string]string{"backend": "memory", "level": "primary"}
+	assert.Eventually(t, func() bool {
+		count, ok := metricValue(t, client.metrics.GetRegistry(), "gocachex_cache_key_count", labels)
+		return ok && count == 3
+	}, time.Second, 10*time.Millisecond)
+
+	size, ok := metricValue(t, client.metrics.GetRegistry(), "gocachex_cache_size_bytes", labels)
+	assert.True(t, ok)
+	assert.Equal(t, float64(stats.MemoryUsage), size)
+}
+
+func TestStatsFlushHierarchicalTiers(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:      "memory",
+		Serializer:   "json",
+		Hierarchical: true,
+		L1:           config.CacheConfig{Backend: "memory"},
+		L2:           config.CacheConfig{Backend: "memory"},
+		Prometheus: config.PrometheusConfig{
+			Enabled:       true,
+			StatsInterval: time.Hour,
+		},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	client := cache.(*CacheClient)
+
+	require.NoError(t, cache.Set(ctx, "a", "value", time.Minute))
+	require.NoError(t, client.l2Cache.Set(ctx, "only_l2", "value", time.Minute))
+
+	client.flushStats(ctx)
+
+	registry := client.metrics.GetRegistry()
+	l1Count, ok := metricValue(t, registry, "gocachex_cache_key_count", map[string]string{"backend": "memory", "level": "l1"})
+	assert.True(t, ok)
+	assert.Equal(t, float64(1), l1Count)
+
+	l2Count, ok := metricValue(t, registry, "gocachex_cache_key_count", map[string]string{"backend": "memory", "level": "l2"})
+	assert.True(t, ok)
+	assert.Equal(t, float64(2), l2Count)
+}
+
+func BenchmarkStringEncoding(b *testing.B) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(b, err)
+	defer cache.Close()
+
+	client := cache.(*CacheClient)
+	value := "session-token-0123456789abcdefghijklmnopqrstuvwxyz"
+
+	b.Run("fast_path", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			data, _ := client.encode("key", value)
+			_, _ = client.decode("key", data)
+		}
+	})
+
+	b.Run("serializer", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			data, _ := client.serializer.Serialize(value)
+			var result interface{}
+			_ = client.serializer.Deserialize(data, &result)
+		}
+	})
+}
+
+func BenchmarkMemorySet(b *testing.B) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(b, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := "benchmark_key"
+		value := map[string]interface{}{
+			"id":   i,
+			"name": "Benchmark Item",
+			"data": make([]int, 100),
+		}
+		_ = cache.Set(ctx, key, value, time.Minute)
+	}
+}
+
+func BenchmarkMemoryGet(b *testing.B) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(b, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+
+	// Pre-populate cache
+	value := map[string]interface{}{
+		"id":   1,
+		"name": "Benchmark Item",
+		"data": make([]int, 100),
+	}
+	_ = cache.Set(ctx, "benchmark_key", value, time.Minute)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = cache.Get(ctx, "benchmark_key")
+	}
+}
+
+func TestFailoverConfig(t *testing.T) {
+	ctx := context.Background()
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+		Failover: config.FailoverConfig{
+			Enabled: true,
+			Standby: config.CacheConfig{Backend: "memory"},
+		},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	failover, ok := cache.(*CacheClient).backend.(*backends.FailoverBackend)
+	require.True(t, ok)
+	assert.False(t, failover.Promoted())
+
+	require.NoError(t, cache.Set(ctx, "key", "value", 0))
+	value, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	_, err = New(config.Config{
+		Backend:      "memory",
+		Hierarchical: true,
+		L1:           config.CacheConfig{Backend: "memory"},
+		L2:           config.CacheConfig{Backend: "memory"},
+		Failover: config.FailoverConfig{
+			Enabled: true,
+			Standby: config.CacheConfig{Backend: "memory"},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestSetWithExpireCallback(t *testing.T) {
+	ctx := context.Background()
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+		Memory:     config.MemoryConfig{CleanupInterval: 10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	fired := make(chan string, 1)
+	client := cache.(*CacheClient)
+	require.NoError(t, client.SetWithExpireCallback(ctx, "session", "data", 20*time.Millisecond, func(key string) {
+		fired <- key
+	}))
+
+	value, err := cache.Get(ctx, "session")
+	require.NoError(t, err)
+	assert.Equal(t, "data", value)
+
+	select {
+	case key := <-fired:
+		assert.Equal(t, "session", key)
+	case <-time.After(time.Second):
+		t.Fatal("expire callback not called")
+	}
+}
+
+type statsCountingBackend struct {
+	backends.Backend
+	stats atomic.Int32
+}
+
+func (s *statsCountingBackend) Stats(ctx context.Context) (*backends.Stats, error) {
+	s.stats.Add(1)
+	return s.Backend.Stats(ctx)
+}
+
+func (s *statsCountingBackend) Size(ctx context.Context) (int64, error) {
+	return s.Backend.(backends.Sizer).Size(ctx)
+}
+
+func TestSize(t *testing.T) {
+	ctx := context.Background()
+	cache, err := New(config.Config{
+		Backend:     "memory",
+		Serializer:  "json",
+		Distributed: true,
+		GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+		Sharding:    config.ShardingConfig{Shards: 3},
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	client := cache.(*CacheClient)
+	counters := make([]*statsCountingBackend, len(client.shards))
+	for i := range client.shards {
+		counters[i] = &statsCountingBackend{Backend: client.shards[i]}
+		client.shards[i] = counters[i]
+	}
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, cache.Set(ctx, fmt.Sprintf("key-%d", i), i, 0))
+	}
+	require.NoError(t, cache.Delete(ctx, "key-0"))
+
+	size, err := cache.Size(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(19), size)
+
+	// Size never falls back to collecting stats
+	for _, counter := range counters {
+		assert.Zero(t, counter.stats.Load())
+	}
+
+	// Backends without a cheap count fall back to Stats
+	for i := range client.shards {
+		client.shards[i] = &deleteCountingBackend{Backend: counters[i].Backend}
+	}
+	size, err = cache.Size(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(19), size)
+}
+
+func TestWarm(t *testing.T) {
+	cache, err := New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	ctx := context.Background()
+	registry := NewLoaderRegistry()
+	registry.Register("config:flags", func(ctx context.Context) (interface{}, error) {
+		return map[string]interface{}{"beta": true}, nil
+	})
+	registry.Register("config:broken", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("database unavailable")
+	})
+
+	client := cache.(*CacheClient)
+	err = client.Warm(ctx, registry, time.Minute, "config:flags", "config:broken", "config:unknown")
+	assert.ErrorContains(t, err, "database unavailable")
+	assert.ErrorContains(t, err, "no loader registered for key config:unknown")
+
+	value, err := cache.Get(ctx, "config:flags")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"beta": true}, value)
+
+	ttl, err := cache.TTL(ctx, "config:flags")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 50*time.Second)
+}
+
+func TestWarmFrom(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 2},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			products := []Product{
+				{ID: 1, Name: "book", Price: 12.5, Tags: []string{"paper"}},
+				{ID: 2, Name: "pen", Price: 1.5},
+				{ID: 3, Name: "lamp", Price: 30},
+			}
+			keyFn := func(p Product) string { return fmt.Sprintf("product:%d", p.ID) }
+			require.NoError(t, WarmFrom(ctx, cache, products, keyFn, time.Minute))
+
+			for _, product := range products {
+				got, err := GetTyped[Product](ctx, cache, keyFn(product))
+				require.NoError(t, err)
+				assert.Equal(t, product, got)
+
+				if !cfg.Hierarchical {
+					ttl, err := cache.TTL(ctx, keyFn(product))
+					require.NoError(t, err)
+					assert.Greater(t, ttl, 50*time.Second)
+				}
+			}
+
+			err = WarmFrom(ctx, cache, products, func(Product) string { return "" }, time.Minute)
+			assert.ErrorContains(t, err, "empty key")
+			require.NoError(t, WarmFrom[Product](ctx, cache, nil, keyFn, time.Minute))
+		})
+	}
+}
+
+func TestCriticalKeys(t *testing.T) {
+	var loads atomic.Int32
+	RegisterLoader("critical:menu", func(ctx context.Context) (interface{}, error) {
+		time.Sleep(50 * time.Millisecond)
+		loads.Add(1)
+		return "menu", nil
+	})
+	RegisterLoader("critical:prices", func(ctx context.Context) (interface{}, error) {
+		loads.Add(1)
+		return []interface{}{1.5, 2.5}, nil
+	})
+
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend: "memory",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			loads.Store(0)
+			cfg.Warmup = config.WarmupConfig{CriticalKeys: []string{"critical:menu", "critical:prices"}}
+
+			start := time.Now()
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			// New returns only once every critical key is cached
+			assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+			assert.Equal(t, int32(2), loads.Load())
+
+			ctx := context.Background()
+			value, err := cache.Get(ctx, "critical:menu")
+			require.NoError(t, err)
+			assert.Equal(t, "menu", value)
+
+			value, err = cache.Get(ctx, "critical:prices")
+			require.NoError(t, err)
+			assert.Equal(t, []interface{}{1.5, 2.5}, value)
+		})
+	}
+}
+
+func TestCriticalKeysFailure(t *testing.T) {
+	RegisterLoader("critical:failing", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("upstream down")
+	})
+	RegisterLoader("critical:slow", func(ctx context.Context) (interface{}, error) {
+		select {
+		case <-time.After(time.Second):
+			return "late", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+
+	_, err := New(config.Config{
+		Backend: "memory",
+		Warmup:  config.WarmupConfig{CriticalKeys: []string{"critical:failing"}},
+	})
+	assert.ErrorContains(t, err, "upstream down")
+
+	_, err = New(config.Config{
+		Backend: "memory",
+		Warmup:  config.WarmupConfig{CriticalKeys: []string{"critical:unregistered"}},
+	})
+	assert.ErrorContains(t, err, "no loader registered")
+
+	start := time.Now()
+	_, err = New(config.Config{
+		Backend: "memory",
+		Warmup: config.WarmupConfig{
+			CriticalKeys: []string{"critical:slow"},
+			Timeout:      20 * time.Millisecond,
+		},
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestKeysAndDeleteByPattern(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 4},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			var users []string
+			for i := 0; i < 10; i++ {
+				users = append(users, fmt.Sprintf("user:%d", i))
+				require.NoError(t, cache.Set(ctx, users[i], i, time.Minute))
+				require.NoError(t, cache.Set(ctx, fmt.Sprintf("team:%d:profile", i), i, time.Minute))
+			}
+
+			keys, err := cache.Keys(ctx, "user:*")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, users, keys)
+
+			keys, err = cache.Keys(ctx, "*:profile")
+			require.NoError(t, err)
+			assert.Len(t, keys, 10)
+
+			deleted, err := cache.DeleteByPattern(ctx, "user:*")
+			require.NoError(t, err)
+			assert.Equal(t, 10, deleted)
+			_, err = cache.Get(ctx, "user:0")
+			assert.ErrorIs(t, err, backends.ErrNotFound)
+
+			keys, err = cache.Keys(ctx, "*")
+			require.NoError(t, err)
+			assert.Len(t, keys, 10)
+		})
+	}
+}
+
+func TestIncrementWithTTLOnCreate(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 4},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			client := cache.(*CacheClient)
+
+			value, err := client.IncrementWithTTLOnCreate(ctx, "logins:user:1", 1, time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), value)
+			ttls, err := client.GetMultiTTL(ctx, []string{"logins:user:1"})
+			require.NoError(t, err)
+			first := ttls["logins:user:1"]
+			assert.InDelta(t, time.Minute.Seconds(), first.Seconds(), 1, "first increment sets the TTL")
+
+			time.Sleep(20 * time.Millisecond)
+			value, err = client.IncrementWithTTLOnCreate(ctx, "logins:user:1", 1, time.Hour)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), value)
+			ttls, err = client.GetMultiTTL(ctx, []string{"logins:user:1"})
+			require.NoError(t, err)
+			assert.Less(t, ttls["logins:user:1"], first, "later increments do not reset the TTL")
+		})
+	}
+}
+
+func TestRateLimitAllow(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 4},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			client := cache.(*CacheClient)
+			window := 100 * time.Millisecond
+
+			for i := 0; i < 3; i++ {
+				allowed, remaining, err := client.RateLimitAllow(ctx, "api:user:1", 3, window)
+				require.NoError(t, err)
+				assert.True(t, allowed, "request %d", i)
+				assert.Equal(t, 2-i, remaining)
+			}
+
+			allowed, remaining, err := client.RateLimitAllow(ctx, "api:user:1", 3, window)
+			require.NoError(t, err)
+			assert.False(t, allowed)
+			assert.Zero(t, remaining)
+
+			// Other keys have their own window
+			allowed, _, err = client.RateLimitAllow(ctx, "api:user:2", 3, window)
+			require.NoError(t, err)
+			assert.True(t, allowed)
+
+			time.Sleep(window + 20*time.Millisecond)
+			allowed, remaining, err = client.RateLimitAllow(ctx, "api:user:1", 3, window)
+			require.NoError(t, err)
+			assert.True(t, allowed)
+			assert.Equal(t, 2, remaining)
+
+			_, _, err = client.RateLimitAllow(ctx, "api:user:1", 0, window)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestListPushCapped(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 4},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			client := cache.(*CacheClient)
+
+			for i := 0; i < 10; i++ {
+				event := map[string]interface{}{"seq": float64(i)}
+				require.NoError(t, client.ListPushCapped(ctx, "events:user:1", event, 4, time.Minute))
+			}
+
+			// Only the newest four remain, newest first
+			values, err := client.ListRange(ctx, "events:user:1")
+			require.NoError(t, err)
+			assert.Equal(t, []interface{}{
+				map[string]interface{}{"seq": float64(9)},
+				map[string]interface{}{"seq": float64(8)},
+				map[string]interface{}{"seq": float64(7)},
+				map[string]interface{}{"seq": float64(6)},
+			}, values)
+
+			values, err = client.ListRange(ctx, "events:user:2")
+			require.NoError(t, err)
+			assert.Empty(t, values)
+
+			assert.Error(t, client.ListPushCapped(ctx, "events:user:1", "event", 0, time.Minute))
+		})
+	}
+}
+
+func TestAliasPromotion(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"single": {
+			Backend:    "memory",
+			Serializer: "json",
+		},
+		"hierarchical": {
+			Backend:      "memory",
+			Serializer:   "json",
+			Hierarchical: true,
+			L1:           config.CacheConfig{Backend: "memory"},
+			L2:           config.CacheConfig{Backend: "memory"},
+		},
+		"distributed": {
+			Backend:     "memory",
+			Serializer:  "json",
+			Distributed: true,
+			GRPC:        config.GRPCConfig{Peers: []string{"localhost:50051"}},
+			Sharding:    config.ShardingConfig{Shards: 4},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := New(cfg)
+			require.NoError(t, err)
+			defer cache.Close()
+
+			ctx := context.Background()
+			client := cache.(*CacheClient)
+			dataset := func(version int) map[string]interface{} {
+				return map[string]interface{}{"version": float64(version), "rows": []interface{}{"a", "b"}}
+			}
+
+			_, err = client.GetByAlias(ctx, "data")
+			assert.ErrorIs(t, err, backends.ErrNotFound)
+
+			require.NoError(t, cache.Set(ctx, "data:v1", dataset(1), 0))
+			require.NoError(t, client.SetAlias(ctx, "data", "data:v1"))
+
+			// Readers run while v2 is built and promoted
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			var readErrs atomic.Int32
+			for r := 0; r < 4; r++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					seen := float64(1)
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+						}
+						value, err := client.GetByAlias(ctx, "data")
+						if err != nil {
+							readErrs.Add(1)
+							continue
+						}
+						version := value.(map[string]interface{})["version"].(float64)
+						if version < seen {
+							readErrs.Add(1) // went back to an older version
+						}
+						seen = version
+					}
+				}()
+			}
+
+			require.NoError(t, cache.Set(ctx, "data:v2", dataset(2), 0))
+			time.Sleep(10 * time.Millisecond)
+			previous, err := client.PromoteAlias(ctx, "data", "data:v2")
+			require.NoError(t, err)
+			assert.Equal(t, "data:v1", previous)
+			time.Sleep(10 * time.Millisecond)
+			close(stop)
+			wg.Wait()
+
+			assert.Zero(t, readErrs.Load())
+
+			value, err := client.GetByAlias(ctx, "data")
+			require.NoError(t, err)
+			assert.Equal(t, dataset(2), value)
+
+			target, err := client.ResolveAlias(ctx, "data")
+			require.NoError(t, err)
+			assert.Equal(t, "data:v2", target)
+
+			_, err = client.PromoteAlias(ctx, "missing", "data:v2")
+			assert.ErrorIs(t, err, backends.ErrNotFound)
+		})
 	}
 }
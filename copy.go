@@ -0,0 +1,117 @@
+package gocachex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+)
+
+// CopyOptions configures a Copy between two caches.
+type CopyOptions struct {
+	// Pattern restricts the copy to keys matching a Redis-style glob such
+	// as "user:*". An empty pattern copies every key.
+	Pattern string
+
+	// Concurrency is the number of parallel writers into the destination.
+	// Defaults to 1.
+	Concurrency int
+}
+
+// Copy streams every live entry from src into dst, preserving values and
+// remaining TTLs. Entries are read through src.Export and written through
+// dst.Import, so values are transferred as stored and both caches must use
+// the same serializer. Expired entries are skipped. When src is a
+// *CacheClient, the pattern is applied while scanning its backend, so other
+// keys are never read. It returns the number of entries stored in dst.
+func Copy(ctx context.Context, src, dst Cache, opts CopyOptions) (int, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Stream the source export through a pipe so entries are never buffered
+	// in full
+	srcReader, srcWriter := io.Pipe()
+	go func() {
+		var err error
+		if client, ok := src.(*CacheClient); ok {
+			_, err = client.export(ctx, srcWriter, opts.Pattern)
+		} else {
+			_, err = src.Export(ctx, srcWriter)
+		}
+		srcWriter.CloseWithError(err)
+	}()
+	defer srcReader.Close()
+
+	// Each writer imports its own stream into the destination
+	var wg sync.WaitGroup
+	writers := make([]*io.PipeWriter, opts.Concurrency)
+	encoders := make([]*json.Encoder, opts.Concurrency)
+	counts := make([]int, opts.Concurrency)
+	errs := make([]error, opts.Concurrency)
+	for i := range writers {
+		reader, writer := io.Pipe()
+		writers[i] = writer
+		encoders[i] = json.NewEncoder(writer)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			counts[i], errs[i] = dst.Import(ctx, reader)
+			reader.CloseWithError(errs[i])
+		}(i)
+	}
+
+	copyErr := dispatchCopy(srcReader, encoders, opts.Pattern)
+	for _, writer := range writers {
+		writer.CloseWithError(copyErr)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+
+	if copyErr != nil {
+		return total, copyErr
+	}
+	for _, err := range errs {
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// dispatchCopy decodes export records from r and distributes the ones
+// matching pattern across encoders in round-robin order.
+func dispatchCopy(r io.Reader, encoders []*json.Encoder, pattern string) error {
+	decoder := json.NewDecoder(r)
+	next := 0
+	for {
+		var record exportRecord
+		if err := decoder.Decode(&record); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to read source entry: %w", err)
+		}
+
+		if pattern != "" && !backends.MatchPattern(pattern, record.Key) {
+			continue
+		}
+
+		if err := encoders[next].Encode(&record); err != nil {
+			return fmt.Errorf("failed to copy key %s: %w", record.Key, err)
+		}
+		next = (next + 1) % len(encoders)
+	}
+}
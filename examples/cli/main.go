@@ -1,13 +1,17 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/chmenegatti/gocachex"
@@ -25,11 +29,14 @@ func main() {
 	var (
 		configPath = flag.String("config", defaultConfigPath, "Path to cache configuration file")
 		backend    = flag.String("backend", "memory", "Cache backend: memory, redis, memcached")
-		operation  = flag.String("op", "demo", "Operation: demo, get, set, delete, exists, stats, clear")
+		operation  = flag.String("op", "demo", "Operation: demo, get, set, delete, exists, stats, clear, dump, load, keys, delpattern")
 		key        = flag.String("key", defaultKey, "Cache key")
 		value      = flag.String("value", defaultValue, "Cache value (for set operation)")
 		ttlStr     = flag.String("ttl", defaultTTL, "TTL for set operation (e.g., 5m, 1h, 30s)")
 		jsonValue  = flag.Bool("json", false, "Treat value as JSON")
+		file       = flag.String("file", "cache_dump.jsonl", "File for dump and load operations")
+		pattern    = flag.String("pattern", "*", "Key pattern for keys and delpattern operations (e.g., user:*)")
+		yes        = flag.Bool("yes", false, "Skip the confirmation prompt for delpattern")
 		help       = flag.Bool("help", false, "Show help message")
 	)
 	flag.Parse()
@@ -76,6 +83,14 @@ func main() {
 		showStats(ctx, cache)
 	case "clear":
 		clearCache(ctx, cache)
+	case "dump":
+		dumpCache(ctx, cache, *file)
+	case "load":
+		loadCache(ctx, cache, *file)
+	case "keys":
+		listKeys(ctx, cache, *pattern)
+	case "delpattern":
+		deletePattern(ctx, cache, *pattern, *yes, os.Stdin)
 	default:
 		fmt.Printf("Unknown operation: %s\n", *operation)
 		showHelp()
@@ -342,6 +357,86 @@ func clearCache(ctx context.Context, cache gocachex.Cache) {
 	fmt.Println("✓ Cache cleared")
 }
 
+func dumpCache(ctx context.Context, cache gocachex.Cache, path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("Error creating dump file '%s': %v\n", path, err)
+		return
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	count, err := cache.Export(ctx, writer)
+	if err != nil {
+		fmt.Printf("Error dumping cache: %v\n", err)
+		return
+	}
+	if err := writer.Flush(); err != nil {
+		fmt.Printf("Error writing dump file '%s': %v\n", path, err)
+		return
+	}
+	fmt.Printf("✓ Dumped %d entries to '%s'\n", count, path)
+}
+
+func loadCache(ctx context.Context, cache gocachex.Cache, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Error opening dump file '%s': %v\n", path, err)
+		return
+	}
+	defer file.Close()
+
+	count, err := cache.Import(ctx, bufio.NewReader(file))
+	if err != nil {
+		fmt.Printf("Error loading cache after %d entries: %v\n", count, err)
+		return
+	}
+	fmt.Printf("✓ Loaded %d entries from '%s'\n", count, path)
+}
+
+func listKeys(ctx context.Context, cache gocachex.Cache, pattern string) {
+	keys, err := cache.Keys(ctx, pattern)
+	if err != nil {
+		fmt.Printf("Error listing keys matching '%s': %v\n", pattern, err)
+		return
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Println(key)
+	}
+	fmt.Printf("%d keys match '%s'\n", len(keys), pattern)
+}
+
+func deletePattern(ctx context.Context, cache gocachex.Cache, pattern string, skipConfirm bool, input io.Reader) {
+	keys, err := cache.Keys(ctx, pattern)
+	if err != nil {
+		fmt.Printf("Error listing keys matching '%s': %v\n", pattern, err)
+		return
+	}
+	if len(keys) == 0 {
+		fmt.Printf("No keys match '%s'\n", pattern)
+		return
+	}
+
+	if !skipConfirm {
+		fmt.Printf("Delete %d keys matching '%s'? [y/N]: ", len(keys), pattern)
+		answer, _ := bufio.NewReader(input).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			fmt.Println("Aborted")
+			return
+		}
+	}
+
+	count, err := cache.DeleteByPattern(ctx, pattern)
+	if err != nil {
+		fmt.Printf("Error deleting keys matching '%s': %v\n", pattern, err)
+		return
+	}
+	fmt.Printf("✓ Deleted %d keys matching '%s'\n", count, pattern)
+}
+
 func showHelp() {
 	fmt.Println("GoCacheX CLI - Cache Operations Tool")
 	fmt.Println("")
@@ -351,11 +446,14 @@ func showHelp() {
 	fmt.Println("Flags:")
 	fmt.Println("  -config string    Path to config file (default \"cache_config.json\")")
 	fmt.Println("  -backend string   Cache backend: memory, redis, memcached (default \"memory\")")
-	fmt.Println("  -op string        Operation: demo, get, set, delete, exists, stats, clear (default \"demo\")")
+	fmt.Println("  -op string        Operation: demo, get, set, delete, exists, stats, clear, dump, load, keys, delpattern (default \"demo\")")
 	fmt.Println("  -key string       Cache key (default \"example:key\")")
 	fmt.Println("  -value string     Cache value for set operation (default \"example value\")")
 	fmt.Println("  -ttl string       TTL for set operation (default \"5m\")")
 	fmt.Println("  -json            Treat value as JSON")
+	fmt.Println("  -file string      File for dump and load operations (default \"cache_dump.jsonl\")")
+	fmt.Println("  -pattern string   Key pattern for keys and delpattern operations (default \"*\")")
+	fmt.Println("  -yes             Skip the confirmation prompt for delpattern")
 	fmt.Println("  -help            Show this help message")
 	fmt.Println("")
 	fmt.Println("Examples:")
@@ -380,6 +478,16 @@ func showHelp() {
 	fmt.Println("  # Clear cache")
 	fmt.Println("  go run main.go -op clear")
 	fmt.Println("")
+	fmt.Println("  # Dump cache contents to a file, then load them back")
+	fmt.Println("  go run main.go -op dump -file backup.jsonl")
+	fmt.Println("  go run main.go -op load -file backup.jsonl")
+	fmt.Println("")
+	fmt.Println("  # List keys matching a pattern")
+	fmt.Println("  go run main.go -op keys -pattern \"user:*\"")
+	fmt.Println("")
+	fmt.Println("  # Delete keys matching a pattern (asks for confirmation)")
+	fmt.Println("  go run main.go -op delpattern -pattern \"session:*\"")
+	fmt.Println("")
 	fmt.Println("Configuration File Example (cache_config.json):")
 	exampleConfig := config.Config{
 		Backend:              "redis",
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex"
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMemoryCache(t *testing.T) gocachex.Cache {
+	cache, err := gocachex.New(config.Config{
+		Backend:    "memory",
+		Serializer: "json",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+func TestDumpAndLoad(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dump.jsonl")
+
+	source := newMemoryCache(t)
+	require.NoError(t, source.Set(ctx, "user:1", "Alice", time.Hour))
+	require.NoError(t, source.Set(ctx, "user:2", map[string]interface{}{"name": "Bob"}, time.Hour))
+	require.NoError(t, source.Set(ctx, "expiring", "soon", 50*time.Millisecond))
+	time.Sleep(100 * time.Millisecond)
+
+	dumpCache(ctx, source, path)
+
+	target := newMemoryCache(t)
+	loadCache(ctx, target, path)
+
+	value, err := target.Get(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", value)
+
+	value, err = target.Get(ctx, "user:2")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Bob"}, value)
+
+	// Expired entries are not dumped
+	exists, err := target.Exists(ctx, "expiring")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// TTLs are carried over
+	ttl, err := target.TTL(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), ttl.Seconds(), 5)
+}
+
+func TestListAndDeleteByPattern(t *testing.T) {
+	ctx := context.Background()
+	cache := newMemoryCache(t)
+
+	for _, key := range []string{"session:1", "session:2", "user:1"} {
+		require.NoError(t, cache.Set(ctx, key, "value", time.Hour))
+	}
+
+	keys, err := cache.Keys(ctx, "session:*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"session:1", "session:2"}, keys)
+	listKeys(ctx, cache, "session:*")
+
+	// Declining the prompt keeps the keys
+	deletePattern(ctx, cache, "session:*", false, strings.NewReader("n\n"))
+	exists, _ := cache.Exists(ctx, "session:1")
+	assert.True(t, exists)
+
+	// Confirming deletes only the matching keys
+	deletePattern(ctx, cache, "session:*", false, strings.NewReader("y\n"))
+	keys, err = cache.Keys(ctx, "session:*")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	exists, _ = cache.Exists(ctx, "user:1")
+	assert.True(t, exists)
+
+	// Skipping the prompt deletes without reading input
+	deletePattern(ctx, cache, "user:*", true, strings.NewReader(""))
+	exists, _ = cache.Exists(ctx, "user:1")
+	assert.False(t, exists)
+}
@@ -5,6 +5,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -24,6 +25,9 @@ type User struct {
 	Created time.Time `json:"created"`
 }
 
+// errUserNotFound is returned by the user loader for unknown IDs
+var errUserNotFound = errors.New("user not found")
+
 // WebServer represents our web server with cache
 type WebServer struct {
 	cache gocachex.Cache
@@ -93,34 +97,33 @@ func (ws *WebServer) getUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	cacheKey := fmt.Sprintf("user:%d", userID)
 
-	// Try to get from cache first
-	cachedUser, err := ws.cache.Get(ws.ctx, cacheKey)
-	if err == nil {
-		log.Printf("Cache HIT for user %d", userID)
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("X-Cache", "HIT")
-		json.NewEncoder(w).Encode(cachedUser)
+	// On a cache miss, load from the "database" (our map) and cache the
+	// user for 5 minutes
+	result, err := ws.cache.GetOrSetWithTTL(ws.ctx, cacheKey, 5*time.Minute, func(ctx context.Context) (interface{}, error) {
+		user, exists := ws.users[userID]
+		if !exists {
+			return nil, errUserNotFound
+		}
+		return user, nil
+	})
+	if errors.Is(err, errUserNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
-
-	log.Printf("Cache MISS for user %d", userID)
-
-	// Get from "database" (our map)
-	user, exists := ws.users[userID]
-	if !exists {
-		http.Error(w, "User not found", http.StatusNotFound)
+	if err != nil {
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
 		return
 	}
 
-	// Cache the user for 5 minutes
-	err = ws.cache.Set(ws.ctx, cacheKey, user, 5*time.Minute)
-	if err != nil {
-		log.Printf("Failed to cache user %d: %v", userID, err)
+	cacheStatus := "MISS"
+	if result.Hit {
+		cacheStatus = "HIT"
 	}
 
+	log.Printf("Cache %s for user %d", cacheStatus, userID)
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-Cache", "MISS")
-	json.NewEncoder(w).Encode(user)
+	w.Header().Set("X-Cache", cacheStatus)
+	json.NewEncoder(w).Encode(result.Value)
 }
 
 // createUserHandler handles POST /users requests
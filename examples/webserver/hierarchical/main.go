@@ -236,9 +236,9 @@ func (s *MultiBackendServer) updateProductHandler(w http.ResponseWriter, r *http
 
 	// Invalidate caches
 	s.invalidateProduct(id)
-	s.memoryCache.Delete(s.ctx, "products:all")
+	s.memoryCache.DeleteByPattern(s.ctx, "products:*")
 	if s.distributedCache != nil {
-		s.distributedCache.Delete(s.ctx, "products:all")
+		s.distributedCache.DeleteByPattern(s.ctx, "products:*")
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -35,6 +35,9 @@ type MultiBackendServer struct {
 
 // NewMultiBackendServer creates a server with multiple cache backends
 func NewMultiBackendServer() *MultiBackendServer {
+	// Let the typed serializer return cached products as *Product
+	gocachex.RegisterType(&Product{})
+
 	// L1 Cache - Memory (fast, limited size)
 	memoryCache, err := gocachex.New(config.Config{
 		Backend: "memory",
@@ -47,7 +50,7 @@ func NewMultiBackendServer() *MultiBackendServer {
 		},
 		Compression:          true,
 		CompressionAlgorithm: "gzip",
-		Serializer:           "json",
+		Serializer:           "typed",
 	})
 	if err != nil {
 		log.Fatalf("Failed to create memory cache: %v", err)
@@ -69,7 +72,7 @@ func NewMultiBackendServer() *MultiBackendServer {
 		},
 		Compression:          true,
 		CompressionAlgorithm: "gzip",
-		Serializer:           "json",
+		Serializer:           "typed",
 	})
 	if err != nil {
 		log.Printf("Redis not available, using memory cache only: %v", err)
@@ -101,19 +104,19 @@ func (s *MultiBackendServer) getProduct(id int) (*Product, string, error) {
 	cacheKey := fmt.Sprintf("product:%d", id)
 
 	// Try L1 cache first (memory)
-	if product, err := s.memoryCache.Get(s.ctx, cacheKey); err == nil {
+	if product, err := gocachex.GetTyped[*Product](s.ctx, s.memoryCache, cacheKey); err == nil {
 		log.Printf("L1 Cache HIT for product %d", id)
-		return product.(*Product), "L1-HIT", nil
+		return product, "L1-HIT", nil
 	}
 	log.Printf("L1 Cache MISS for product %d", id)
 
 	// Try L2 cache (Redis) if available
 	if s.distributedCache != nil {
-		if product, err := s.distributedCache.Get(s.ctx, cacheKey); err == nil {
+		if product, err := gocachex.GetTyped[*Product](s.ctx, s.distributedCache, cacheKey); err == nil {
 			log.Printf("L2 Cache HIT for product %d", id)
 			// Store in L1 for faster access next time
-			s.memoryCache.Set(s.ctx, cacheKey, product, 2*time.Minute)
-			return product.(*Product), "L2-HIT", nil
+			gocachex.SetTyped(s.ctx, s.memoryCache, cacheKey, product, 2*time.Minute)
+			return product, "L2-HIT", nil
 		}
 		log.Printf("L2 Cache MISS for product %d", id)
 	}
@@ -236,9 +239,9 @@ func (s *MultiBackendServer) updateProductHandler(w http.ResponseWriter, r *http
 
 	// Invalidate caches
 	s.invalidateProduct(id)
-	s.memoryCache.Delete(s.ctx, "products:all")
+	s.memoryCache.DeleteByPattern(s.ctx, "products:*")
 	if s.distributedCache != nil {
-		s.distributedCache.Delete(s.ctx, "products:all")
+		s.distributedCache.DeleteByPattern(s.ctx, "products:*")
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,63 @@
+package gocachex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+)
+
+// SetWithExpireCallback stores a value like Set and calls cb with key once
+// the key expires. Callbacks are best effort: the memory backend runs cb
+// when its cleanup or a read removes the expired key, so it may fire up to
+// a cleanup interval late, and Redis relies on keyspace notifications
+// (KeyspaceNotifications), which are lost if the subscriber disconnects.
+// Deleting or overwriting the key through this client drops the callback.
+// In hierarchical mode the callback is registered with L2.
+func (c *CacheClient) SetWithExpireCallback(ctx context.Context, key string, value interface{}, ttl time.Duration, cb func(key string)) (err error) {
+	// Start tracing span
+	ctx, span := c.startKeySpan(ctx, "cache.set_with_expire_callback", key)
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	c.metrics.RecordKeyGroup("set", key)
+	defer func(start time.Time) { c.recordOperation(span, "set", start, err) }(time.Now())
+
+	if c.config.Hierarchical {
+		if err := c.l1Cache.Set(ctx, key, value, ttl); err != nil {
+			return fmt.Errorf("failed to set in L1 cache: %w", err)
+		}
+		l2, ok := c.l2Cache.(*CacheClient)
+		if !ok {
+			return fmt.Errorf("expire callbacks not supported by L2 cache")
+		}
+		if err := l2.SetWithExpireCallback(ctx, key, value, ttl, cb); err != nil {
+			return err
+		}
+		c.publishInvalidation(ctx, key)
+		return nil
+	}
+
+	backend, err := c.backendFor(key)
+	if err != nil {
+		return err
+	}
+
+	notifier, ok := backend.(backends.ExpireNotifier)
+	if !ok {
+		return fmt.Errorf("expire callbacks not supported by backend")
+	}
+
+	data, err := c.encode(key, value)
+	if err != nil {
+		return err
+	}
+
+	return c.guard(func() error {
+		return notifier.SetWithExpireCallback(ctx, key, data, ttl, cb)
+	})
+}
@@ -0,0 +1,150 @@
+package gocachex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+)
+
+// exportRecord is a single cache entry in an export stream. Values are the
+// raw stored bytes, so an export can be loaded back without re-encoding. TTL
+// is the remaining time to live in milliseconds, or noExpiry for entries
+// that never expire.
+type exportRecord struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+	TTL   int64  `json:"ttl_ms,omitempty"`
+}
+
+// noExpiry is the exported TTL of entries without an expiration.
+const noExpiry = -1
+
+// Export writes every live entry to w as newline-delimited JSON, one entry
+// per line, including its remaining TTL. Entries are streamed as they are
+// read from the backend. It returns the number of entries written.
+func (c *CacheClient) Export(ctx context.Context, w io.Writer) (int, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.export")
+	defer span.End()
+
+	return c.export(ctx, w, "")
+}
+
+// export writes the live entries whose keys match pattern to w like Export;
+// an empty pattern exports every entry. Backends that can filter their scan
+// never read the values of other keys.
+func (c *CacheClient) export(ctx context.Context, w io.Writer, pattern string) (int, error) {
+	// The L2 tier holds the complete data set in hierarchical mode
+	if c.config.Hierarchical {
+		if l2, ok := c.l2Cache.(*CacheClient); ok {
+			return l2.export(ctx, w, pattern)
+		}
+		return c.l2Cache.Export(ctx, w)
+	}
+
+	encoder := json.NewEncoder(w)
+	count := 0
+	for _, backend := range c.dataBackends() {
+		iterate := func(fn func(key string, value []byte, ttl time.Duration) error) error {
+			if iterator, ok := backend.(backends.PatternIterator); ok && pattern != "" {
+				return iterator.IteratePattern(ctx, pattern, fn)
+			}
+			iterator, ok := backend.(backends.EntryIterator)
+			if !ok {
+				return fmt.Errorf("export not supported by %s backend", c.config.Backend)
+			}
+			return iterator.Iterate(ctx, func(key string, value []byte, ttl time.Duration) error {
+				if pattern != "" && !backends.MatchPattern(pattern, key) {
+					return nil
+				}
+				return fn(key, value, ttl)
+			})
+		}
+
+		err := iterate(func(key string, value []byte, ttl time.Duration) error {
+			record := exportRecord{Key: key, Value: value, TTL: ttl.Milliseconds()}
+			switch {
+			case ttl == 0:
+				record.TTL = noExpiry
+			case record.TTL == 0:
+				// Keep entries about to expire expiring
+				record.TTL = 1
+			}
+			if err := encoder.Encode(&record); err != nil {
+				return fmt.Errorf("failed to write entry %s: %w", key, err)
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// Import reads entries written by Export from r and stores them, one at a
+// time, with their recorded TTLs. Entries exported without an expiration are
+// stored without one, even when the backend has a default TTL. It returns the
+// number of entries stored.
+func (c *CacheClient) Import(ctx context.Context, r io.Reader) (int, error) {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.import")
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	if c.config.Hierarchical {
+		return c.l2Cache.Import(ctx, r)
+	}
+
+	decoder := json.NewDecoder(r)
+	count := 0
+	for {
+		var record exportRecord
+		if err := decoder.Decode(&record); err != nil {
+			if err == io.EOF {
+				return count, nil
+			}
+			return count, fmt.Errorf("failed to read entry: %w", err)
+		}
+
+		backend := c.backend
+		if c.config.Distributed {
+			shard, err := c.getShard(record.Key)
+			if err != nil {
+				return count, err
+			}
+			backend = shard
+		}
+
+		var ttl time.Duration
+		if record.TTL > 0 {
+			ttl = time.Duration(record.TTL) * time.Millisecond
+		}
+		if err := backend.Set(ctx, record.Key, record.Value, ttl); err != nil {
+			return count, fmt.Errorf("failed to import key %s: %w", record.Key, err)
+		}
+		if persister, ok := backend.(backends.Persister); ok && record.TTL == noExpiry {
+			if err := persister.Persist(ctx, record.Key); err != nil {
+				return count, fmt.Errorf("failed to import key %s: %w", record.Key, err)
+			}
+		}
+		count++
+	}
+}
+
+// dataBackends returns the backends holding data in single or distributed mode.
+func (c *CacheClient) dataBackends() []backends.Backend {
+	if c.config.Distributed {
+		return c.shards
+	}
+	return []backends.Backend{c.backend}
+}
@@ -0,0 +1,111 @@
+package gocachex
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+)
+
+// invalidator publishes the keys written by a hierarchical client on the
+// invalidation channel and drops the keys written by other clients from L1.
+// Messages are the id of the publishing client, a space and the key, so a
+// client ignores its own writes. Pattern deletes publish the id followed by
+// patternInvalidation, a space and the pattern.
+type invalidator struct {
+	channel     string
+	origin      string
+	broadcaster backends.Broadcaster
+	stop        func() error
+}
+
+// patternInvalidation marks the messages that carry a pattern of keys.
+const patternInvalidation = ":pattern"
+
+// startInvalidation subscribes to the configured invalidation channel of the
+// L2 backend.
+func (c *CacheClient) startInvalidation() error {
+	channel := c.config.InvalidationChannel
+	if channel == "" {
+		return nil
+	}
+
+	l2, ok := c.l2Cache.(*CacheClient)
+	if !ok {
+		return fmt.Errorf("invalidation channel not supported by L2 cache")
+	}
+	broadcaster, ok := l2.backend.(backends.Broadcaster)
+	if !ok {
+		return fmt.Errorf("invalidation channel not supported by L2 backend %s", c.config.L2.Backend)
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("failed to generate invalidation origin: %w", err)
+	}
+	inv := &invalidator{
+		channel:     channel,
+		origin:      hex.EncodeToString(buf),
+		broadcaster: broadcaster,
+	}
+
+	stop, err := broadcaster.Subscribe(context.Background(), channel, func(message string) {
+		c.dropInvalidated(inv.origin, message)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to invalidation channel: %w", err)
+	}
+	inv.stop = stop
+	c.invalidator = inv
+
+	return nil
+}
+
+// dropInvalidated drops the key or pattern of keys carried by a message from
+// another client from L1, ignoring the messages published with origin.
+func (c *CacheClient) dropInvalidated(origin, message string) {
+	sender, target, ok := strings.Cut(message, " ")
+	if !ok {
+		return
+	}
+
+	if sender, isPattern := strings.CutSuffix(sender, patternInvalidation); isPattern {
+		if sender != origin {
+			_, _ = c.l1Cache.DeleteByPattern(context.Background(), target)
+		}
+		return
+	}
+	if sender != origin {
+		_ = c.l1Cache.Delete(context.Background(), target)
+	}
+}
+
+// publishInvalidation tells the other clients on the invalidation channel to
+// drop key from their L1. A failed publish is counted as an error; the write
+// it follows has already succeeded.
+func (c *CacheClient) publishInvalidation(ctx context.Context, key string) {
+	if c.invalidator == nil {
+		return
+	}
+	c.publish(ctx, c.invalidator.origin+" "+key)
+}
+
+// publishPatternInvalidation tells the other clients on the invalidation
+// channel to drop the keys matching pattern from their L1, like
+// publishInvalidation.
+func (c *CacheClient) publishPatternInvalidation(ctx context.Context, pattern string) {
+	if c.invalidator == nil {
+		return
+	}
+	c.publish(ctx, c.invalidator.origin+patternInvalidation+" "+pattern)
+}
+
+// publish sends message on the invalidation channel, counting failures.
+func (c *CacheClient) publish(ctx context.Context, message string) {
+	if err := c.invalidator.broadcaster.Publish(ctx, c.invalidator.channel, message); err != nil {
+		c.metrics.RecordError("invalidate", c.config.L2.Backend, "publish")
+	}
+}
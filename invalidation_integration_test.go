@@ -0,0 +1,72 @@
+//go:build integration
+
+package gocachex
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newInvalidatedClient(t *testing.T, channel string) *CacheClient {
+	addr := os.Getenv("GOCACHEX_TEST_REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	cache, err := New(config.Config{
+		Backend:             "redis",
+		Hierarchical:        true,
+		Serializer:          "json",
+		L1:                  config.CacheConfig{Backend: "memory"},
+		L2:                  config.CacheConfig{Backend: "redis", Redis: config.RedisConfig{Addresses: []string{addr}}},
+		InvalidationChannel: channel,
+	})
+	if err != nil {
+		t.Skipf("redis not available at %s: %v", addr, err)
+	}
+	t.Cleanup(func() { cache.Close() })
+	return cache.(*CacheClient)
+}
+
+func TestRedisInvalidation(t *testing.T) {
+	channel := fmt.Sprintf("invalidations:%d", time.Now().UnixNano())
+	a := newInvalidatedClient(t, channel)
+	b := newInvalidatedClient(t, channel)
+	ctx := context.Background()
+	key := fmt.Sprintf("invalidation:%d", time.Now().UnixNano())
+	defer a.Delete(ctx, key)
+
+	inL1 := func(c *CacheClient) bool {
+		exists, err := c.l1Cache.Exists(ctx, key)
+		require.NoError(t, err)
+		return exists
+	}
+
+	// B caches the value in its L1 on read
+	require.NoError(t, a.Set(ctx, key, "v1", time.Minute))
+	value, err := b.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", value)
+	require.True(t, inL1(b))
+
+	// A write on A drops the copy from B's L1 but not A's own
+	require.NoError(t, a.Set(ctx, key, "v2", time.Minute))
+	assert.Eventually(t, func() bool { return !inL1(b) }, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, inL1(a))
+
+	value, err = b.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", value)
+	require.True(t, inL1(b))
+
+	// So does a delete
+	require.NoError(t, a.Delete(ctx, key))
+	assert.Eventually(t, func() bool { return !inL1(b) }, 2*time.Second, 10*time.Millisecond)
+}
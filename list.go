@@ -0,0 +1,86 @@
+package gocachex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+)
+
+// ListPushCapped prepends value to the list at key and keeps only its newest
+// maxLen values, like a circular buffer of recent events. The push and trim
+// happen atomically in the backend, in Redis as a single script over a list,
+// so concurrent pushes from every client sharing the backend never leave the
+// list longer than maxLen. Every push sets the list to expire after ttl, or
+// never if ttl is 0:
+//
+//	err := cache.ListPushCapped(ctx, "events:"+userID, event, 50, 24*time.Hour)
+//
+// Lists live in their own namespace and do not collide with keys.
+func (c *CacheClient) ListPushCapped(ctx context.Context, key string, value interface{}, maxLen int, ttl time.Duration) error {
+	// Start tracing span
+	ctx, span := c.startKeySpan(ctx, "cache.list_push_capped", key)
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	if maxLen <= 0 {
+		return fmt.Errorf("list length must be positive, got %d", maxLen)
+	}
+
+	backend, err := c.backendFor(key)
+	if err != nil {
+		return err
+	}
+	lister, ok := backend.(backends.CappedLister)
+	if !ok {
+		return fmt.Errorf("capped lists not supported by backend")
+	}
+
+	data, err := c.encode(key, value)
+	if err != nil {
+		return err
+	}
+
+	return c.guard(func() error {
+		return lister.ListPushCapped(ctx, key, data, maxLen, ttl)
+	})
+}
+
+// ListRange returns the values of the list at key, newest first, or an
+// empty slice if there is none.
+func (c *CacheClient) ListRange(ctx context.Context, key string) ([]interface{}, error) {
+	// Start tracing span
+	ctx, span := c.startKeySpan(ctx, "cache.list_range", key)
+	defer span.End()
+
+	backend, err := c.backendFor(key)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := backend.(backends.CappedLister)
+	if !ok {
+		return nil, fmt.Errorf("capped lists not supported by backend")
+	}
+
+	var data [][]byte
+	err = c.guard(func() error {
+		var err error
+		data, err = lister.ListRange(ctx, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(data))
+	for i, d := range data {
+		if values[i], err = c.decode(key, d); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
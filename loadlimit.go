@@ -0,0 +1,103 @@
+package gocachex
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chmenegatti/gocachex/pkg/metrics"
+)
+
+// loaderLimiter bounds the number of loaders running at once per key group.
+type loaderLimiter struct {
+	mu    sync.Mutex
+	limit int
+	group metrics.KeyGroupFunc
+	slots map[string]*loaderSlots
+}
+
+// loaderSlots is the semaphore of a key group, dropped once no loader holds
+// or waits for it.
+type loaderSlots struct {
+	sem   chan struct{}
+	users int
+}
+
+func newLoaderLimiter(limit int) *loaderLimiter {
+	return &loaderLimiter{
+		limit: limit,
+		group: metrics.PrefixKeyGroup,
+		slots: make(map[string]*loaderSlots),
+	}
+}
+
+// setGroupFunc replaces the function mapping keys to their group.
+func (l *loaderLimiter) setGroupFunc(fn metrics.KeyGroupFunc) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.group = fn
+}
+
+// acquire waits for a loader slot in the group of key until ctx is done. The
+// returned function frees the slot.
+func (l *loaderLimiter) acquire(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	group := l.group(key)
+	slots, ok := l.slots[group]
+	if !ok {
+		slots = &loaderSlots{sem: make(chan struct{}, l.limit)}
+		l.slots[group] = slots
+	}
+	slots.users++
+	l.mu.Unlock()
+
+	select {
+	case slots.sem <- struct{}{}:
+		return func() {
+			<-slots.sem
+			l.leave(group, slots)
+		}, nil
+	case <-ctx.Done():
+		l.leave(group, slots)
+		return nil, ctx.Err()
+	}
+}
+
+// leave drops a holder or waiter of the group slots, forgetting the group
+// once it has none left.
+func (l *loaderLimiter) leave(group string, slots *loaderSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots.users--
+	if slots.users == 0 {
+		delete(l.slots, group)
+	}
+}
+
+// limitLoader wraps loader so it runs only while holding a slot of the group
+// of key. The slot is held until loader returns, even if the caller stopped
+// waiting for it.
+func (c *CacheClient) limitLoader(key string, loader LoaderFunc) LoaderFunc {
+	if c.loaders == nil {
+		return loader
+	}
+
+	return func(ctx context.Context) (interface{}, error) {
+		release, err := c.loaders.acquire(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		return loader(ctx)
+	}
+}
+
+// SetLoaderGroupFunc replaces the function grouping keys for the loader
+// limit, which groups keys by the prefix before the first colon by default.
+// It has no effect unless LoaderLimit.MaxConcurrent is set.
+func (c *CacheClient) SetLoaderGroupFunc(fn metrics.KeyGroupFunc) {
+	if c.loaders == nil {
+		return
+	}
+	c.loaders.setGroupFunc(fn)
+}
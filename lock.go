@@ -0,0 +1,152 @@
+package gocachex
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+)
+
+// lockKeyPrefix namespaces loader lock keys away from cached values.
+const lockKeyPrefix = "gocachex:lock:"
+
+// GetOrSetLocked is like GetOrSet, but coordinates loaders across every
+// client sharing the same backend. Only the client holding the lease for key
+// runs loader; the lease TTL is extended in the background for as long as the
+// loader runs, so slow loaders never lose it mid-load. Other clients wait for
+// the value to appear, and take over if the lease is released without one.
+func (c *CacheClient) GetOrSetLocked(ctx context.Context, key string, ttl time.Duration, loader LoaderFunc, opts ...GetOrSetOption) (interface{}, error) {
+	// Start tracing span
+	ctx, span := c.startKeySpan(ctx, "cache.get_or_set_locked", key)
+	defer span.End()
+
+	value, err := c.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if !errors.Is(err, backends.ErrNotFound) {
+		return nil, err
+	}
+
+	// A read-only client stores nothing, so there is no load to coordinate
+	if c.config.ReadOnly {
+		return c.GetOrSet(ctx, key, ttl, loader, opts...)
+	}
+
+	backend, err := c.backendFor(key)
+	if err != nil {
+		return nil, err
+	}
+	locker, ok := backend.(backends.LeaseLocker)
+	if !ok {
+		return nil, fmt.Errorf("locking not supported by %s backend", c.config.Backend)
+	}
+
+	token, err := newLeaseToken()
+	if err != nil {
+		return nil, err
+	}
+
+	lockKey := lockKeyPrefix + key
+	lease := c.config.Lock.Lease
+	for {
+		acquired, err := locker.AcquireLease(ctx, lockKey, token, lease)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire lock for key %s: %w", key, err)
+		}
+		if acquired {
+			return c.loadWithLease(ctx, locker, lockKey, token, key, ttl, loader, newGetOrSetOptions(opts))
+		}
+
+		// Another client is loading; wait for its result
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.config.Lock.RetryInterval):
+		}
+
+		value, err := c.Get(ctx, key)
+		if err == nil {
+			return value, nil
+		}
+		if !errors.Is(err, backends.ErrNotFound) {
+			return nil, err
+		}
+	}
+}
+
+// loadWithLease runs loader while holding the lease at lockKey, extending the
+// lease until the loader finishes and releasing it afterwards.
+func (c *CacheClient) loadWithLease(ctx context.Context, locker backends.LeaseLocker, lockKey, token, key string, ttl time.Duration, loader LoaderFunc, opts getOrSetOptions) (interface{}, error) {
+	stop := c.extendLease(ctx, locker, lockKey, token)
+	defer func() {
+		stop()
+		// Release with a fresh context so a cancelled caller still frees the lock
+		_, _ = locker.ReleaseLease(context.Background(), lockKey, token)
+	}()
+
+	// The previous holder may have stored the value just before releasing
+	value, err := c.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if !errors.Is(err, backends.ErrNotFound) {
+		return nil, err
+	}
+
+	value, err = loadWithContext(ctx, c.limitLoader(key, loader))
+	if err != nil {
+		return nil, err
+	}
+
+	c.storeLoaded(ctx, key, value, ttl, opts)
+	return value, nil
+}
+
+// extendLease starts a heartbeat extending the lease every third of its TTL.
+// The returned function stops the heartbeat and waits for it to exit.
+func (c *CacheClient) extendLease(ctx context.Context, locker backends.LeaseLocker, lockKey, token string) func() {
+	lease := c.config.Lock.Lease
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				held, err := locker.ExtendLease(ctx, lockKey, token, lease)
+				if err == nil && !held {
+					return // Lease lost; nothing left to extend
+				}
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// newLeaseToken returns a random token identifying a lease holder.
+func newLeaseToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,112 @@
+//go:build integration
+
+package gocachex
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedisClient connects to the Redis server in GOCACHEX_TEST_REDIS_ADDR
+// (default localhost:6379), skipping the test when it is unavailable.
+func newRedisClient(t *testing.T, lock config.LockConfig) *CacheClient {
+	addr := os.Getenv("GOCACHEX_TEST_REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	cache, err := New(config.Config{
+		Backend:    "redis",
+		Serializer: "json",
+		Redis:      config.RedisConfig{Addresses: []string{addr}},
+		Lock:       lock,
+	})
+	if err != nil {
+		t.Skipf("redis not available at %s: %v", addr, err)
+	}
+	t.Cleanup(func() { cache.Close() })
+
+	if err := cache.Health(context.Background()); err != nil {
+		t.Skipf("redis not available at %s: %v", addr, err)
+	}
+	return cache.(*CacheClient)
+}
+
+func TestRedisGetOrSetLocked(t *testing.T) {
+	lock := config.LockConfig{
+		Lease:         200 * time.Millisecond,
+		RetryInterval: 20 * time.Millisecond,
+	}
+	first := newRedisClient(t, lock)
+	second := newRedisClient(t, lock)
+
+	ctx := context.Background()
+	key := "gocachex:test:locked:" + time.Now().Format(time.RFC3339Nano)
+	lockKey := lockKeyPrefix + key
+	defer first.Delete(ctx, key)
+
+	started := make(chan struct{})
+	var loads int32
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		value, err := first.GetOrSetLocked(ctx, key, time.Minute, func(ctx context.Context) (interface{}, error) {
+			atomic.AddInt32(&loads, 1)
+			close(started)
+			time.Sleep(time.Second)
+			return "from-first", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "from-first", value)
+	}()
+
+	<-started
+
+	// Well past the original lease the lock is still held
+	time.Sleep(2 * lock.Lease)
+	exists, err := second.backend.Exists(ctx, lockKey)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	value, err := second.GetOrSetLocked(ctx, key, time.Minute, func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		return "from-second", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "from-first", value)
+
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	exists, err = second.backend.Exists(ctx, lockKey)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestRedisGetMultiWithMisses(t *testing.T) {
+	cache := newRedisClient(t, config.LockConfig{})
+	ctx := context.Background()
+	keys := []string{"gocachex:test:getmulti:a", "gocachex:test:getmulti:missing"}
+	require.NoError(t, cache.DeleteMulti(ctx, keys))
+	defer cache.DeleteMulti(ctx, keys)
+
+	require.NoError(t, cache.Set(ctx, keys[0], "1", time.Minute))
+
+	result, err := cache.GetMulti(ctx, keys)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{keys[0]: "1"}, result)
+
+	result, err = cache.GetMulti(ctx, keys, WithMisses())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{keys[0]: "1", keys[1]: Missing}, result)
+}
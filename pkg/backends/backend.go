@@ -3,17 +3,34 @@ package backends
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/chmenegatti/gocachex/pkg/config"
 )
 
+// ErrNotFound is returned when a key does not exist or has expired.
+var ErrNotFound = errors.New("key not found")
+
+// ErrCounterOverflow is returned when Increment or Decrement would take a
+// counter outside the int64 range.
+var ErrCounterOverflow = errors.New("counter overflow")
+
+// ErrQuotaExceeded is returned when a write would take a namespace over its
+// quota.
+var ErrQuotaExceeded = errors.New("namespace quota exceeded")
+
 // Backend represents a cache backend interface that all implementations must satisfy.
 type Backend interface {
 	// Basic operations
 	Get(ctx context.Context, key string) ([]byte, error)
 	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// GetSet stores value under key and returns the previous value. The key
+	// keeps its remaining TTL; a key that did not exist is stored as by Set
+	// with a zero TTL and ErrNotFound is returned.
+	GetSet(ctx context.Context, key string, value []byte) ([]byte, error)
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
 
@@ -30,6 +47,11 @@ type Backend interface {
 	Expire(ctx context.Context, key string, ttl time.Duration) error
 	TTL(ctx context.Context, key string) (time.Duration, error)
 
+	// Key enumeration operations
+	// Keys returns the live keys matching a Redis-style glob pattern such as
+	// "user:*", in no particular order.
+	Keys(ctx context.Context, pattern string) ([]string, error)
+
 	// Management operations
 	Clear(ctx context.Context) error
 	Stats(ctx context.Context) (*Stats, error)
@@ -37,6 +59,197 @@ type Backend interface {
 	Close() error
 }
 
+// EntryIterator is implemented by backends that can enumerate their entries.
+// fn receives the raw stored value and the remaining TTL, where a zero TTL
+// means the entry does not expire. Returning an error from fn stops iteration.
+// fn is never called concurrently, even when the backend reads in parallel.
+type EntryIterator interface {
+	Iterate(ctx context.Context, fn func(key string, value []byte, ttl time.Duration) error) error
+}
+
+// PatternIterator is implemented by backends that can restrict iteration to
+// keys matching a Redis-style glob pattern while scanning, so values of other
+// keys are never read.
+type PatternIterator interface {
+	IteratePattern(ctx context.Context, pattern string, fn func(key string, value []byte, ttl time.Duration) error) error
+}
+
+// Persister is implemented by backends that can apply a default TTL to
+// writes without one. Persist removes the expiration of a key.
+type Persister interface {
+	Persist(ctx context.Context, key string) error
+}
+
+// PatternChecker is implemented by backends that can report whether any key
+// matches a Redis-style glob pattern without enumerating every entry.
+type PatternChecker interface {
+	ExistsPattern(ctx context.Context, pattern string) (bool, error)
+}
+
+// CreateTTLIncrementer is implemented by backends that can increment a
+// counter and, only when the increment creates it, set its TTL in the same
+// atomic step. Later increments leave the TTL alone, so the counter expires
+// a fixed time after its first increment.
+type CreateTTLIncrementer interface {
+	IncrementWithTTLOnCreate(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// PatternDeleter is implemented by backends that can delete every key
+// matching a Redis-style glob pattern without first collecting the keys. It
+// returns the number of keys deleted.
+type PatternDeleter interface {
+	DeleteByPattern(ctx context.Context, pattern string) (int, error)
+}
+
+// MultiTTLReader is implemented by backends that can read the remaining TTL
+// of many keys in one round trip. Missing keys are omitted from the result
+// and keys without an expiration report -1.
+type MultiTTLReader interface {
+	GetMultiTTL(ctx context.Context, keys []string) (map[string]time.Duration, error)
+}
+
+// KeyProbe reports whether a key exists and its remaining TTL, which is -1
+// for keys without an expiration and zero for missing keys.
+type KeyProbe struct {
+	Exists bool
+	TTL    time.Duration
+}
+
+// MultiProber is implemented by backends that can check the existence and
+// TTL of many keys in one round trip. The result has an entry for every key.
+type MultiProber interface {
+	ProbeMulti(ctx context.Context, keys []string) (map[string]KeyProbe, error)
+}
+
+// KeyWatcher is implemented by backends that can signal when a key is
+// written. WatchKey returns a channel that receives after each write to key
+// and a function that stops watching.
+type KeyWatcher interface {
+	WatchKey(ctx context.Context, key string) (<-chan struct{}, func() error, error)
+}
+
+// Broadcaster is implemented by backends that can deliver messages to every
+// client connected to them. Publish sends message on channel. Subscribe
+// calls fn with each message later published on channel, returning once the
+// subscription is active, and a function that ends it. Delivery is best
+// effort: messages published while a subscriber is disconnected are lost.
+type Broadcaster interface {
+	Publish(ctx context.Context, channel, message string) error
+	Subscribe(ctx context.Context, channel string, fn func(message string)) (func() error, error)
+}
+
+// ExpireNotifier is implemented by backends that can call back when a key
+// expires. Callbacks are best effort: they may be delayed until the expired
+// key is noticed and are dropped when the key is deleted or overwritten.
+type ExpireNotifier interface {
+	SetWithExpireCallback(ctx context.Context, key string, value []byte, ttl time.Duration, cb func(key string)) error
+}
+
+// LeaseLocker is implemented by backends that provide atomic lease-based
+// locks. A lease is held under a caller-chosen token and can only be
+// extended or released by the holder of that token.
+type LeaseLocker interface {
+	AcquireLease(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	ExtendLease(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, key, token string) (bool, error)
+}
+
+// Aliaser is implemented by backends that store alias keys pointing at
+// another key. Repointing an alias is atomic, so a reader resolves either the
+// old or the new target, never anything in between. Aliases live in their
+// own namespace and do not expire.
+type Aliaser interface {
+	SetAlias(ctx context.Context, alias, target string) error
+	SwapAlias(ctx context.Context, alias, target string) (string, error)
+	ResolveAlias(ctx context.Context, alias string) (string, error)
+}
+
+// RateLimiter is implemented by backends that keep sliding-window rate
+// limits. RateLimitAllow records a request against key if fewer than limit
+// requests were recorded in the last window, and returns whether it was
+// allowed and how many requests the window still allows. Rejected requests
+// are not recorded. Limits live in their own namespace.
+type RateLimiter interface {
+	RateLimitAllow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, error)
+}
+
+// CappedLister is implemented by backends that keep capped lists.
+// ListPushCapped prepends value to the list at key, drops all but its newest
+// maxLen values and sets the list to expire after ttl, or never if ttl is 0.
+// ListRange returns the values of the list at key, newest first, and an
+// empty slice if there is none. Lists live in their own namespace.
+type CappedLister interface {
+	ListPushCapped(ctx context.Context, key string, value []byte, maxLen int, ttl time.Duration) error
+	ListRange(ctx context.Context, key string) ([][]byte, error)
+}
+
+// VersionedSetter is implemented by backends that can store a value only
+// when it is newer. SetIfNewer stores value under key and records version
+// with it if version is greater than the version recorded for key, or key
+// holds no live value, and reports whether it stored value. The check and the
+// write are atomic. Any other write or delete of key through the backend
+// resets its version, so the next SetIfNewer stores whatever its version.
+type VersionedSetter interface {
+	SetIfNewer(ctx context.Context, key string, value []byte, version int64, ttl time.Duration) (bool, error)
+}
+
+// AddressReporter is implemented by backends that connect to remote servers
+// and can report their addresses.
+type AddressReporter interface {
+	Addresses() []string
+}
+
+// ExtendedStatsReporter is implemented by backends that can report
+// statistics beyond the common Stats fields, keyed by snake_case names.
+type ExtendedStatsReporter interface {
+	ExtendedStats(ctx context.Context) (map[string]interface{}, error)
+}
+
+// Sizer is implemented by backends that can count their keys more cheaply
+// than collecting full Stats.
+type Sizer interface {
+	Size(ctx context.Context) (int64, error)
+}
+
+// StatsResetter is implemented by backends whose statistics can be reset.
+// ResetStats zeroes the hit, miss, set, delete and eviction counters; the
+// key count and memory usage keep reflecting the stored entries.
+type StatsResetter interface {
+	ResetStats(ctx context.Context) error
+}
+
+// ExpiredPurger is implemented by backends that keep expired entries until
+// a periodic cleanup or a read removes them. PurgeExpired removes them all
+// at once and returns how many were removed.
+type ExpiredPurger interface {
+	PurgeExpired(ctx context.Context) (int, error)
+}
+
+// Clock tells a backend the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock reads the system clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ClockReporter is implemented by backends that can read the clock their
+// expirations are measured against, such as the Redis server clock.
+// ServerTime returns the current time of that clock.
+type ClockReporter interface {
+	ServerTime(ctx context.Context) (time.Time, error)
+}
+
+// ConnectionReporter is implemented by backends that hold network
+// connections and can report how many are currently open.
+type ConnectionReporter interface {
+	ActiveConnections() int64
+}
+
 // Stats represents backend statistics.
 type Stats struct {
 	Hits        int64 `json:"hits"`
@@ -86,20 +299,49 @@ func NewSerializer(serializerType string) (Serializer, error) {
 		return &GobSerializer{}, nil
 	case "msgpack":
 		return &MsgPackSerializer{}, nil
+	case "typed":
+		return &TypedSerializer{}, nil
 	default:
 		return nil, fmt.Errorf("unsupported serializer type: %s", serializerType)
 	}
 }
 
-// NewCompressor creates a new compressor based on the algorithm.
+// placeholderCodecs maps serializers and compressors that are not yet
+// implemented to the codec they silently fall back to.
+var placeholderCodecs = map[string]string{}
+
+// PlaceholderFallback reports whether the named serializer or compressor is
+// a placeholder, and if so which codec is actually used in its place.
+func PlaceholderFallback(codec string) (string, bool) {
+	fallback, ok := placeholderCodecs[codec]
+	return fallback, ok
+}
+
+// NewCompressor creates a new compressor based on the algorithm, limiting
+// decompressed data to DefaultMaxDecompressedSize.
 func NewCompressor(algorithm string) (Compressor, error) {
+	return NewCompressorWithLimit(algorithm, DefaultMaxDecompressedSize)
+}
+
+// NewCompressorWithLimit creates a new compressor based on the algorithm that
+// rejects decompressed data larger than maxSize bytes. A maxSize of zero
+// means no limit.
+func NewCompressorWithLimit(algorithm string, maxSize int64) (Compressor, error) {
+	return NewCompressorWithLevel(algorithm, 0, maxSize)
+}
+
+// NewCompressorWithLevel is like NewCompressorWithLimit, compressing at the
+// given level. The level only applies to zstd; zero selects its default.
+func NewCompressorWithLevel(algorithm string, level int, maxSize int64) (Compressor, error) {
 	switch algorithm {
 	case "gzip":
-		return &GzipCompressor{}, nil
+		return &GzipCompressor{MaxDecompressedSize: maxSize}, nil
 	case "lz4":
-		return &LZ4Compressor{}, nil
+		return &LZ4Compressor{MaxDecompressedSize: maxSize}, nil
 	case "snappy":
-		return &SnappyCompressor{}, nil
+		return &SnappyCompressor{MaxDecompressedSize: maxSize}, nil
+	case "zstd":
+		return &ZstdCompressor{Level: level, MaxDecompressedSize: maxSize}, nil
 	default:
 		return nil, fmt.Errorf("unsupported compression algorithm: %s", algorithm)
 	}
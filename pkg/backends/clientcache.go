@@ -0,0 +1,247 @@
+package backends
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidationChannel is the channel Redis publishes tracking invalidations
+// on when they are redirected to another connection.
+const invalidationChannel = "__redis__:invalidate"
+
+// clientCache is an in-process cache of Redis values kept coherent by
+// server-assisted client-side caching. Every data connection enables
+// CLIENT TRACKING with its invalidations redirected to a dedicated
+// subscriber connection, so Redis reports each key read through this
+// backend once it changes. A nil clientCache is valid and caches nothing.
+type clientCache struct {
+	mu      sync.Mutex
+	entries map[string]clientCacheEntry
+	pending map[string]int  // in-flight reads per key
+	stale   map[string]bool // keys invalidated while a read was in flight
+	maxKeys int
+	ttl     time.Duration
+
+	// redirectID is the client ID of the subscriber connection. Once that
+	// connection is lost, tracking connections keep redirecting to the dead
+	// ID, so active is cleared and reads go straight to Redis.
+	redirectID int64
+	active     atomic.Bool
+
+	subscriber *redis.Client
+	pubsub     *redis.PubSub
+	hits       int64
+}
+
+type clientCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// newClientCache connects the invalidation subscriber for a single-instance
+// Redis at the address in cfg.
+func newClientCache(cfg config.RedisConfig, tlsConfig *tls.Config) (*clientCache, error) {
+	c := &clientCache{
+		entries: make(map[string]clientCacheEntry),
+		pending: make(map[string]int),
+		stale:   make(map[string]bool),
+		maxKeys: cfg.ClientCache.MaxKeys,
+		ttl:     cfg.ClientCache.TTL,
+	}
+
+	c.subscriber = redis.NewClient(&redis.Options{
+		Addr:        cfg.Addresses[0],
+		Password:    cfg.Password,
+		DB:          cfg.DB,
+		DialTimeout: cfg.DialTimeout,
+		PoolSize:    1,
+		OnConnect:   c.subscriberConnected,
+		TLSConfig:   tlsConfig,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Wait for the subscription so no invalidation is missed
+	c.pubsub = c.subscriber.Subscribe(ctx, invalidationChannel)
+	if _, err := c.pubsub.Receive(ctx); err != nil {
+		c.close()
+		return nil, fmt.Errorf("failed to subscribe to invalidations: %w", err)
+	}
+
+	go c.listen(c.pubsub.Channel())
+
+	return c, nil
+}
+
+// subscriberConnected records the subscriber's client ID. A reconnect means
+// invalidations may have been lost and tracking connections still redirect
+// to the old ID, so local caching is switched off for good.
+func (c *clientCache) subscriberConnected(ctx context.Context, cn *redis.Conn) error {
+	id, err := cn.ClientID(ctx).Result()
+	if err != nil {
+		return err
+	}
+
+	if !atomic.CompareAndSwapInt64(&c.redirectID, 0, id) {
+		c.active.Store(false)
+		c.flush()
+		return nil
+	}
+	c.active.Store(true)
+	return nil
+}
+
+// track enables invalidation tracking on a new data connection.
+func (c *clientCache) track(ctx context.Context, cn *redis.Conn) error {
+	cmd := redis.NewStatusCmd(ctx, "client", "tracking", "on", "redirect", atomic.LoadInt64(&c.redirectID))
+	_ = cn.Process(ctx, cmd)
+	return cmd.Err()
+}
+
+// listen applies invalidation messages until the subscription is closed.
+func (c *clientCache) listen(messages <-chan *redis.Message) {
+	for msg := range messages {
+		if msg.Payload != "" {
+			c.invalidate(msg.Payload)
+			continue
+		}
+		c.invalidate(msg.PayloadSlice...)
+	}
+}
+
+// get returns the locally cached value for key.
+func (c *clientCache) get(key string) ([]byte, bool) {
+	if c == nil || !c.active.Load() {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && c.ttl > 0 && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+	atomic.AddInt64(&c.hits, 1)
+	return entry.value, true
+}
+
+// begin marks a read of key from Redis as in flight. An invalidation arriving
+// before the matching finish keeps the read value out of the cache.
+func (c *clientCache) begin(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.pending[key]++
+	c.mu.Unlock()
+}
+
+// finish ends a read started with begin, caching value unless key was
+// invalidated in the meantime. A nil value is not cached.
+func (c *clientCache) finish(key string, value []byte) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if value != nil && !c.stale[key] && c.active.Load() {
+		if c.maxKeys > 0 && len(c.entries) >= c.maxKeys {
+			if _, exists := c.entries[key]; !exists {
+				// Evict an arbitrary entry to make room
+				for victim := range c.entries {
+					delete(c.entries, victim)
+					break
+				}
+			}
+		}
+		c.entries[key] = clientCacheEntry{value: value, expires: time.Now().Add(c.ttl)}
+	}
+
+	c.pending[key]--
+	if c.pending[key] <= 0 {
+		delete(c.pending, key)
+		delete(c.stale, key)
+	}
+}
+
+// invalidate drops keys from the cache. No keys means everything is dropped,
+// which is how Redis reports FLUSHDB and FLUSHALL.
+func (c *clientCache) invalidate(keys ...string) {
+	if c == nil {
+		return
+	}
+	if len(keys) == 0 {
+		c.flush()
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+		if c.pending[key] > 0 {
+			c.stale[key] = true
+		}
+	}
+}
+
+// flush drops every entry and any read in flight.
+func (c *clientCache) flush() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]clientCacheEntry)
+	for key := range c.pending {
+		c.stale[key] = true
+	}
+}
+
+// localHits returns the number of reads served from the local cache.
+func (c *clientCache) localHits() int64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&c.hits)
+}
+
+// resetHits zeroes the number of reads served from the local cache.
+func (c *clientCache) resetHits() {
+	if c == nil {
+		return
+	}
+	atomic.StoreInt64(&c.hits, 0)
+}
+
+// close stops the invalidation subscriber.
+func (c *clientCache) close() error {
+	if c == nil {
+		return nil
+	}
+
+	c.active.Store(false)
+	if c.pubsub != nil {
+		c.pubsub.Close()
+	}
+	return c.subscriber.Close()
+}
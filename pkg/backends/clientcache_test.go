@@ -0,0 +1,81 @@
+package backends
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClientCache(maxKeys int, ttl time.Duration) *clientCache {
+	c := &clientCache{
+		entries: make(map[string]clientCacheEntry),
+		pending: make(map[string]int),
+		stale:   make(map[string]bool),
+		maxKeys: maxKeys,
+		ttl:     ttl,
+	}
+	c.active.Store(true)
+	return c
+}
+
+func TestClientCacheInvalidation(t *testing.T) {
+	c := newTestClientCache(0, time.Minute)
+
+	c.begin("key")
+	c.finish("key", []byte("v1"))
+	value, ok := c.get("key")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("v1"), value)
+
+	c.invalidate("key")
+	_, ok = c.get("key")
+	assert.False(t, ok)
+
+	// An invalidation racing a read keeps the read value out of the cache
+	c.begin("key")
+	c.invalidate("key")
+	c.finish("key", []byte("stale"))
+	_, ok = c.get("key")
+	assert.False(t, ok)
+	assert.Empty(t, c.pending)
+	assert.Empty(t, c.stale)
+
+	// An invalidation without keys flushes everything
+	for _, key := range []string{"a", "b"} {
+		c.begin(key)
+		c.finish(key, []byte(key))
+	}
+	c.invalidate()
+	assert.Empty(t, c.entries)
+	assert.Equal(t, int64(1), c.localHits())
+}
+
+func TestClientCacheBounds(t *testing.T) {
+	c := newTestClientCache(2, 20*time.Millisecond)
+	for _, key := range []string{"a", "b", "c"} {
+		c.begin(key)
+		c.finish(key, []byte(key))
+	}
+	assert.Len(t, c.entries, 2)
+
+	time.Sleep(30 * time.Millisecond)
+	_, ok := c.get("c")
+	assert.False(t, ok, "entries expire after the TTL")
+
+	// An inactive cache serves nothing
+	c.begin("d")
+	c.finish("d", []byte("d"))
+	c.active.Store(false)
+	_, ok = c.get("d")
+	assert.False(t, ok)
+
+	// A nil cache is a no-op
+	var nilCache *clientCache
+	_, ok = nilCache.get("a")
+	assert.False(t, ok)
+	nilCache.begin("a")
+	nilCache.finish("a", []byte("a"))
+	nilCache.invalidate("a")
+	assert.NoError(t, nilCache.close())
+}
@@ -3,11 +3,29 @@ package backends
 import (
 	"bytes"
 	"compress/gzip"
+	"errors"
 	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 )
 
+// DefaultMaxDecompressedSize is the decompressed size limit applied by
+// NewCompressor.
+const DefaultMaxDecompressedSize = 64 << 20 // 64MB
+
+// ErrDecompressedTooLarge is returned when decompressed data exceeds the
+// compressor's size limit.
+var ErrDecompressedTooLarge = errors.New("decompressed data exceeds size limit")
+
 // GzipCompressor implements gzip compression.
-type GzipCompressor struct{}
+type GzipCompressor struct {
+	// MaxDecompressedSize limits the size of decompressed data in bytes.
+	// Zero means no limit.
+	MaxDecompressedSize int64
+}
 
 // Compress compresses data using gzip.
 func (g *GzipCompressor) Compress(data []byte) ([]byte, error) {
@@ -34,7 +52,25 @@ func (g *GzipCompressor) Decompress(data []byte) ([]byte, error) {
 	}
 	defer reader.Close()
 
-	return io.ReadAll(reader)
+	return readLimited(reader, g.MaxDecompressedSize)
+}
+
+// readLimited reads all of r, failing with ErrDecompressedTooLarge once more
+// than maxSize bytes are produced. A maxSize of zero means no limit.
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(r)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrDecompressedTooLarge
+	}
+
+	return data, nil
 }
 
 // Algorithm returns the compression algorithm name.
@@ -42,25 +78,34 @@ func (g *GzipCompressor) Algorithm() string {
 	return "gzip"
 }
 
-// LZ4Compressor implements LZ4 compression.
-// Note: This is a placeholder implementation. In a real implementation,
-// you would use a library like github.com/pierrec/lz4/v4
-type LZ4Compressor struct{}
+// LZ4Compressor implements LZ4 compression using the LZ4 frame format at its
+// fastest level, trading ratio for much lower CPU cost than gzip.
+type LZ4Compressor struct {
+	// MaxDecompressedSize limits the size of decompressed data in bytes.
+	// Zero means no limit.
+	MaxDecompressedSize int64
+}
 
-// Compress compresses data using LZ4 (placeholder).
+// Compress compresses data into an LZ4 frame.
 func (l *LZ4Compressor) Compress(data []byte) ([]byte, error) {
-	// For now, fall back to gzip
-	// In a real implementation, use LZ4 library
-	compressor := &GzipCompressor{}
-	return compressor.Compress(data)
+	var buf bytes.Buffer
+	writer := lz4.NewWriter(&buf)
+
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
 }
 
-// Decompress decompresses LZ4 data (placeholder).
+// Decompress decompresses an LZ4 frame.
 func (l *LZ4Compressor) Decompress(data []byte) ([]byte, error) {
-	// For now, fall back to gzip
-	// In a real implementation, use LZ4 library
-	compressor := &GzipCompressor{}
-	return compressor.Decompress(data)
+	return readLimited(lz4.NewReader(bytes.NewReader(data)), l.MaxDecompressedSize)
 }
 
 // Algorithm returns the compression algorithm name.
@@ -68,25 +113,31 @@ func (l *LZ4Compressor) Algorithm() string {
 	return "lz4"
 }
 
-// SnappyCompressor implements Snappy compression.
-// Note: This is a placeholder implementation. In a real implementation,
-// you would use a library like github.com/golang/snappy
-type SnappyCompressor struct{}
+// SnappyCompressor implements Snappy compression using the Snappy block
+// format, as produced by snappy.Encode in other services.
+type SnappyCompressor struct {
+	// MaxDecompressedSize limits the size of decompressed data in bytes.
+	// Zero means no limit.
+	MaxDecompressedSize int64
+}
 
-// Compress compresses data using Snappy (placeholder).
+// Compress compresses data into a Snappy block.
 func (s *SnappyCompressor) Compress(data []byte) ([]byte, error) {
-	// For now, fall back to gzip
-	// In a real implementation, use Snappy library
-	compressor := &GzipCompressor{}
-	return compressor.Compress(data)
+	return snappy.Encode(nil, data), nil
 }
 
-// Decompress decompresses Snappy data (placeholder).
+// Decompress decompresses a Snappy block. The block header records the
+// decoded length, so oversized data is rejected before it is decoded.
 func (s *SnappyCompressor) Decompress(data []byte) ([]byte, error) {
-	// For now, fall back to gzip
-	// In a real implementation, use Snappy library
-	compressor := &GzipCompressor{}
-	return compressor.Decompress(data)
+	size, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	if s.MaxDecompressedSize > 0 && int64(size) > s.MaxDecompressedSize {
+		return nil, ErrDecompressedTooLarge
+	}
+
+	return snappy.Decode(nil, data)
 }
 
 // Algorithm returns the compression algorithm name.
@@ -94,6 +145,81 @@ func (s *SnappyCompressor) Algorithm() string {
 	return "snappy"
 }
 
+// DefaultZstdLevel is the zstd level used when none is configured.
+const DefaultZstdLevel = 3
+
+// ZstdCompressor implements Zstandard compression. Encoders and decoders are
+// created on first use and shared, so a ZstdCompressor is safe for concurrent
+// use but must not be copied after first use.
+type ZstdCompressor struct {
+	// Level is the zstd compression level, from 1 (fastest) to 22 (best
+	// ratio). Zero means DefaultZstdLevel.
+	Level int
+
+	// MaxDecompressedSize limits the size of decompressed data in bytes.
+	// Zero means no limit.
+	MaxDecompressedSize int64
+
+	once    sync.Once
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	err     error
+}
+
+// init creates the shared encoder and decoder.
+func (z *ZstdCompressor) init() {
+	level := z.Level
+	if level == 0 {
+		level = DefaultZstdLevel
+	}
+
+	z.encoder, z.err = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	if z.err != nil {
+		return
+	}
+
+	options := []zstd.DOption{zstd.WithDecoderConcurrency(0)}
+	if z.MaxDecompressedSize > 0 {
+		options = append(options, zstd.WithDecoderMaxMemory(uint64(z.MaxDecompressedSize)))
+	}
+	z.decoder, z.err = zstd.NewReader(nil, options...)
+}
+
+// Compress compresses data into a zstd frame.
+func (z *ZstdCompressor) Compress(data []byte) ([]byte, error) {
+	z.once.Do(z.init)
+	if z.err != nil {
+		return nil, z.err
+	}
+	return z.encoder.EncodeAll(data, nil), nil
+}
+
+// Decompress decompresses a zstd frame.
+func (z *ZstdCompressor) Decompress(data []byte) ([]byte, error) {
+	z.once.Do(z.init)
+	if z.err != nil {
+		return nil, z.err
+	}
+
+	decoded, err := z.decoder.DecodeAll(data, nil)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		return nil, ErrDecompressedTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if z.MaxDecompressedSize > 0 && int64(len(decoded)) > z.MaxDecompressedSize {
+		return nil, ErrDecompressedTooLarge
+	}
+
+	return decoded, nil
+}
+
+// Algorithm returns the compression algorithm name.
+func (z *ZstdCompressor) Algorithm() string {
+	return "zstd"
+}
+
 // CompressData is a helper function to compress data if a compressor is provided.
 func CompressData(compressor Compressor, data []byte) ([]byte, error) {
 	if compressor == nil {
@@ -123,6 +249,12 @@ func ShouldCompress(data []byte, minSize int) bool {
 		if data[0] == 0x1f && data[1] == 0x8b { // gzip
 			return false
 		}
+		if data[0] == 0x04 && data[1] == 0x22 && data[2] == 0x4d && data[3] == 0x18 { // lz4
+			return false
+		}
+		if data[0] == 0x28 && data[1] == 0xb5 && data[2] == 0x2f && data[3] == 0xfd { // zstd
+			return false
+		}
 		if data[0] == 0x50 && data[1] == 0x4b { // zip
 			return false
 		}
@@ -0,0 +1,217 @@
+package backends
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompressSizeLimit(t *testing.T) {
+	for _, algorithm := range []string{"gzip", "lz4", "snappy", "zstd"} {
+		t.Run(algorithm, func(t *testing.T) {
+			unlimited, err := NewCompressorWithLimit(algorithm, 0)
+			require.NoError(t, err)
+
+			// A megabyte of zeros compresses to a small fraction of its size
+			bomb, err := unlimited.Compress(make([]byte, 1<<20))
+			require.NoError(t, err)
+			require.Less(t, len(bomb), 64<<10)
+
+			limited, err := NewCompressorWithLimit(algorithm, 64<<10)
+			require.NoError(t, err)
+
+			_, err = limited.Decompress(bomb)
+			assert.ErrorIs(t, err, ErrDecompressedTooLarge)
+
+			// Data exactly at the limit is accepted
+			exact, err := limited.Compress(bytes.Repeat([]byte("a"), 64<<10))
+			require.NoError(t, err)
+			data, err := limited.Decompress(exact)
+			require.NoError(t, err)
+			assert.Len(t, data, 64<<10)
+
+			data, err = unlimited.Decompress(bomb)
+			require.NoError(t, err)
+			assert.Len(t, data, 1<<20)
+		})
+	}
+}
+
+func TestLZ4FrameFormat(t *testing.T) {
+	compressor := &LZ4Compressor{}
+	data := bytes.Repeat([]byte("gocachex hot key payload "), (1<<20)/25)
+
+	compressed, err := compressor.Compress(data)
+	require.NoError(t, err)
+
+	// LZ4 frames start with the magic number 0x184D2204, little endian
+	assert.Equal(t, []byte{0x04, 0x22, 0x4d, 0x18}, compressed[:4])
+	assert.Less(t, len(compressed), len(data)/10)
+	assert.False(t, ShouldCompress(compressed, 0))
+}
+
+func TestLZ4RoundTrip(t *testing.T) {
+	compressor, err := NewCompressor("lz4")
+	require.NoError(t, err)
+	assert.Equal(t, "lz4", compressor.Algorithm())
+
+	for _, data := range [][]byte{
+		{},
+		[]byte("short"),
+		bytes.Repeat([]byte("abc"), 100000),
+	} {
+		compressed, err := compressor.Compress(data)
+		require.NoError(t, err)
+
+		decompressed, err := compressor.Decompress(compressed)
+		require.NoError(t, err)
+		assert.Equal(t, len(data), len(decompressed))
+		assert.True(t, bytes.Equal(data, decompressed))
+	}
+
+	// Gzip data is rejected rather than misread
+	gzipped, err := (&GzipCompressor{}).Compress([]byte("value"))
+	require.NoError(t, err)
+	_, err = compressor.Decompress(gzipped)
+	assert.Error(t, err)
+}
+
+func TestSnappyRoundTrip(t *testing.T) {
+	compressor, err := NewCompressor("snappy")
+	require.NoError(t, err)
+	assert.Equal(t, "snappy", compressor.Algorithm())
+
+	for _, data := range [][]byte{
+		{},
+		[]byte("short"),
+		bytes.Repeat([]byte("gocachex "), 100000),
+	} {
+		compressed, err := compressor.Compress(data)
+		require.NoError(t, err)
+
+		decompressed, err := compressor.Decompress(compressed)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(data, decompressed))
+	}
+}
+
+func TestSnappyIsNotGzip(t *testing.T) {
+	compressor, err := NewCompressor("snappy")
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte("snappy block "), 1000)
+	compressed, err := compressor.Compress(data)
+	require.NoError(t, err)
+	assert.NotEqual(t, []byte{0x1f, 0x8b}, compressed[:2])
+
+	// Blocks interoperate with plain snappy.Decode users
+	decoded, err := snappy.Decode(nil, compressed)
+	require.NoError(t, err)
+	assert.Equal(t, data, decoded)
+
+	_, fallback := PlaceholderFallback("snappy")
+	assert.False(t, fallback)
+}
+
+func TestZstdRoundTripLevels(t *testing.T) {
+	data := bytes.Repeat([]byte("gocachex zstd payload "), 50000)
+
+	sizes := make(map[int]int)
+	for _, level := range []int{1, 3, 19} {
+		t.Run(fmt.Sprintf("level%d", level), func(t *testing.T) {
+			compressor, err := NewCompressorWithLevel("zstd", level, 0)
+			require.NoError(t, err)
+			assert.Equal(t, "zstd", compressor.Algorithm())
+
+			for _, value := range [][]byte{{}, []byte("short"), data} {
+				compressed, err := compressor.Compress(value)
+				require.NoError(t, err)
+
+				decompressed, err := compressor.Decompress(compressed)
+				require.NoError(t, err)
+				assert.True(t, bytes.Equal(value, decompressed))
+			}
+
+			compressed, err := compressor.Compress(data)
+			require.NoError(t, err)
+			// Zstd frames start with the magic number 0xFD2FB528, little endian
+			assert.Equal(t, []byte{0x28, 0xb5, 0x2f, 0xfd}, compressed[:4])
+			assert.False(t, ShouldCompress(compressed, 0))
+			sizes[level] = len(compressed)
+		})
+	}
+
+	assert.LessOrEqual(t, sizes[19], sizes[1])
+}
+
+func TestZstdRejectsGzip(t *testing.T) {
+	compressor, err := NewCompressor("zstd")
+	require.NoError(t, err)
+
+	gzipped, err := (&GzipCompressor{}).Compress([]byte("value"))
+	require.NoError(t, err)
+	_, err = compressor.Decompress(gzipped)
+	assert.Error(t, err)
+}
+
+// compressionPayload returns a JSON document resembling a cached API
+// response.
+func compressionPayload(b *testing.B) []byte {
+	type item struct {
+		ID     int      `json:"id"`
+		Name   string   `json:"name"`
+		Status string   `json:"status"`
+		Tags   []string `json:"tags"`
+		Score  float64  `json:"score"`
+	}
+
+	items := make([]item, 500)
+	for i := range items {
+		items[i] = item{
+			ID:     i,
+			Name:   fmt.Sprintf("product-%d", i),
+			Status: []string{"active", "pending", "archived"}[i%3],
+			Tags:   []string{"catalog", fmt.Sprintf("group-%d", i%17)},
+			Score:  float64(i%100) / 7,
+		}
+	}
+
+	data, err := json.Marshal(items)
+	require.NoError(b, err)
+	return data
+}
+
+func BenchmarkCompressionRatio(b *testing.B) {
+	data := compressionPayload(b)
+
+	for _, tc := range []struct {
+		algorithm string
+		level     int
+	}{
+		{"gzip", 0},
+		{"zstd", 1},
+		{"zstd", 3},
+		{"zstd", 19},
+	} {
+		b.Run(fmt.Sprintf("%s-%d", tc.algorithm, tc.level), func(b *testing.B) {
+			compressor, err := NewCompressorWithLevel(tc.algorithm, tc.level, 0)
+			require.NoError(b, err)
+
+			var compressed []byte
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				compressed, err = compressor.Compress(data)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(data))/float64(len(compressed)), "ratio")
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package backends
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+)
+
+// Eviction policies of the memory backend. Unknown policies behave as LRU.
+const (
+	policyLRU    = "lru"
+	policyLFU    = "lfu"
+	policyRandom = "random"
+)
+
+// evictionPolicy normalizes the configured eviction policy.
+func evictionPolicy(policy string) string {
+	switch policy {
+	case policyLFU, policyRandom:
+		return policy
+	default:
+		return policyLRU
+	}
+}
+
+// lruList is an intrusive doubly linked list of items ordered from most to
+// least recently used, so the LRU victim is found in O(1). Items not in the
+// list have nil links.
+type lruList struct {
+	root memoryItem // sentinel: root.next is the front, root.prev the back
+}
+
+// init empties the list.
+func (l *lruList) init() {
+	l.root.next = &l.root
+	l.root.prev = &l.root
+}
+
+// pushFront inserts item as the most recently used.
+func (l *lruList) pushFront(item *memoryItem) {
+	item.prev = &l.root
+	item.next = l.root.next
+	l.root.next.prev = item
+	l.root.next = item
+}
+
+// remove unlinks item, if it is in the list.
+func (l *lruList) remove(item *memoryItem) {
+	if item.next == nil {
+		return
+	}
+	item.prev.next = item.next
+	item.next.prev = item.prev
+	item.prev, item.next = nil, nil
+}
+
+// moveToFront marks item as the most recently used, if it is in the list.
+func (l *lruList) moveToFront(item *memoryItem) {
+	if item.next == nil || l.root.next == item {
+		return
+	}
+	item.prev.next = item.next
+	item.next.prev = item.prev
+	l.pushFront(item)
+}
+
+// back returns the least recently used item, or nil if the list is empty.
+func (l *lruList) back() *memoryItem {
+	if l.root.prev == &l.root {
+		return nil
+	}
+	return l.root.prev
+}
+
+// lfuHeap is a min-heap of items by access count, so the LFU victim is found
+// in O(1) and counts are updated in O(log n). Halving every count preserves
+// the order, so frequency decay needs no re-heapify.
+type lfuHeap []*memoryItem
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	return atomic.LoadInt64(&h[i].accessCount) < atomic.LoadInt64(&h[j].accessCount)
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	item := x.(*memoryItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	item.index = -1
+	return item
+}
+
+// contains reports whether item is in the heap.
+func (h lfuHeap) contains(item *memoryItem) bool {
+	return item.index >= 0 && item.index < len(h) && h[item.index] == item
+}
+
+// remove removes item, if it is in the heap.
+func (h *lfuHeap) remove(item *memoryItem) {
+	if h.contains(item) {
+		heap.Remove(h, item.index)
+	}
+}
+
+// fix restores the heap order after the access count of item changed.
+func (h *lfuHeap) fix(item *memoryItem) {
+	if h.contains(item) {
+		heap.Fix(h, item.index)
+	}
+}
+
+// min returns the least frequently used item, or nil if the heap is empty.
+func (h lfuHeap) min() *memoryItem {
+	if len(h) == 0 {
+		return nil
+	}
+	return h[0]
+}
+
+// track adds item, just stored under its key, to the eviction order. The
+// caller must hold the stripe's write lock.
+func (s *memoryStripe) track(item *memoryItem) {
+	if s.policy == policyRandom {
+		return
+	}
+
+	s.orderMu.Lock()
+	defer s.orderMu.Unlock()
+
+	if s.policy == policyLFU {
+		heap.Push(&s.lfu, item)
+	} else {
+		s.lru.pushFront(item)
+	}
+}
+
+// untrack removes item from the eviction order. The caller must hold the
+// stripe's write lock.
+func (s *memoryStripe) untrack(item *memoryItem) {
+	if s.policy == policyRandom {
+		return
+	}
+
+	s.orderMu.Lock()
+	defer s.orderMu.Unlock()
+
+	if s.policy == policyLFU {
+		s.lfu.remove(item)
+	} else {
+		s.lru.remove(item)
+	}
+}
+
+// resetOrder empties the eviction order. The caller must hold the stripe's
+// write lock.
+func (s *memoryStripe) resetOrder() {
+	s.orderMu.Lock()
+	defer s.orderMu.Unlock()
+
+	// Readers may still hold items of the old order; unlink them so touch
+	// leaves the new one alone
+	for _, item := range s.data {
+		item.prev, item.next = nil, nil
+		item.index = -1
+	}
+	s.lru.init()
+	s.lfu = nil
+}
+
+// touch records an access to item: a read, which also counts towards its
+// frequency, or an in-place write. It only needs the item to have been
+// found under the read lock; items removed since are left alone.
+func (s *memoryStripe) touch(item *memoryItem, read bool) {
+	atomic.StoreInt64(&item.accessTime, time.Now().UnixNano())
+	if s.policy == policyRandom {
+		if read {
+			atomic.AddInt64(&item.accessCount, 1)
+		}
+		return
+	}
+
+	s.orderMu.Lock()
+	defer s.orderMu.Unlock()
+
+	// Counts change under orderMu so the heap never sees a stale order
+	if read {
+		atomic.AddInt64(&item.accessCount, 1)
+	}
+	if s.policy == policyLFU {
+		s.lfu.fix(item)
+	} else {
+		s.lru.moveToFront(item)
+	}
+}
+
+// victim returns the next item of the stripe to evict under the LRU or LFU
+// policy, or nil if the stripe is empty.
+func (s *memoryStripe) victim() *memoryItem {
+	s.orderMu.Lock()
+	defer s.orderMu.Unlock()
+
+	if s.policy == policyLFU {
+		return s.lfu.min()
+	}
+	return s.lru.back()
+}
@@ -0,0 +1,484 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FailoverBackend serves every operation from a primary backend while
+// mirroring writes to a warm standby. The primary's health is checked
+// periodically, and once a check fails the standby is promoted and serves
+// all operations from then on. Promotion is one-way: a recovered primary has
+// missed writes, so it is not switched back to automatically.
+type FailoverBackend struct {
+	primary  Backend
+	standby  Backend
+	promoted atomic.Bool
+
+	stopCheck chan struct{}
+	closeOnce sync.Once
+}
+
+// NewFailoverBackend creates a backend that fails over from primary to
+// standby. The primary's health is checked every checkInterval; a zero
+// interval disables background checks, leaving promotion to CheckPrimary.
+func NewFailoverBackend(primary, standby Backend, checkInterval time.Duration) *FailoverBackend {
+	f := &FailoverBackend{
+		primary:   primary,
+		standby:   standby,
+		stopCheck: make(chan struct{}),
+	}
+
+	if checkInterval > 0 {
+		go f.check(checkInterval)
+	}
+
+	return f
+}
+
+// Promoted reports whether the standby has been promoted.
+func (f *FailoverBackend) Promoted() bool {
+	return f.promoted.Load()
+}
+
+// CheckPrimary checks the primary's health and promotes the standby if it
+// fails. It returns the health check error.
+func (f *FailoverBackend) CheckPrimary(ctx context.Context) error {
+	if f.promoted.Load() {
+		return nil
+	}
+
+	if err := f.primary.Health(ctx); err != nil {
+		f.promoted.Store(true)
+		return fmt.Errorf("primary unhealthy, standby promoted: %w", err)
+	}
+	return nil
+}
+
+// check runs CheckPrimary every interval until the standby is promoted or
+// the backend is closed.
+func (f *FailoverBackend) check(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			_ = f.CheckPrimary(ctx)
+			cancel()
+			if f.promoted.Load() {
+				return
+			}
+		case <-f.stopCheck:
+			return
+		}
+	}
+}
+
+// active returns the backend currently serving operations.
+func (f *FailoverBackend) active() Backend {
+	if f.promoted.Load() {
+		return f.standby
+	}
+	return f.primary
+}
+
+// mirror applies a write to the active backend and, while the primary is
+// active, to the standby as well. Standby writes are best effort: their
+// errors are ignored so a lagging standby never fails the primary.
+func (f *FailoverBackend) mirror(write func(b Backend) error) error {
+	if f.promoted.Load() {
+		return write(f.standby)
+	}
+
+	if err := write(f.primary); err != nil {
+		return err
+	}
+	_ = write(f.standby)
+	return nil
+}
+
+// Get retrieves a value from the active backend.
+func (f *FailoverBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	return f.active().Get(ctx, key)
+}
+
+// Set stores a value in the active backend and mirrors it to the standby.
+func (f *FailoverBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return f.mirror(func(b Backend) error {
+		return b.Set(ctx, key, value, ttl)
+	})
+}
+
+// SetNX stores a value in the active backend if key does not exist there,
+// and mirrors a stored value to the standby.
+func (f *FailoverBackend) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if f.promoted.Load() {
+		return f.standby.SetNX(ctx, key, value, ttl)
+	}
+
+	stored, err := f.primary.SetNX(ctx, key, value, ttl)
+	if stored {
+		_ = f.standby.Set(ctx, key, value, ttl)
+	}
+	return stored, err
+}
+
+// GetSet replaces the value of key in the active backend and mirrors the
+// swap to the standby.
+func (f *FailoverBackend) GetSet(ctx context.Context, key string, value []byte) ([]byte, error) {
+	if f.promoted.Load() {
+		return f.standby.GetSet(ctx, key, value)
+	}
+
+	old, err := f.primary.GetSet(ctx, key, value)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		_, _ = f.standby.GetSet(ctx, key, value)
+	}
+	return old, err
+}
+
+// SetWithExpireCallback stores a value and registers cb with the active
+// backend; the standby receives a plain copy of the value.
+func (f *FailoverBackend) SetWithExpireCallback(ctx context.Context, key string, value []byte, ttl time.Duration, cb func(key string)) error {
+	notifier, ok := f.active().(ExpireNotifier)
+	if !ok {
+		return fmt.Errorf("expire callbacks not supported by active backend")
+	}
+
+	if err := notifier.SetWithExpireCallback(ctx, key, value, ttl, cb); err != nil {
+		return err
+	}
+	if !f.promoted.Load() {
+		_ = f.standby.Set(ctx, key, value, ttl)
+	}
+	return nil
+}
+
+// Delete removes a value from the active backend and the standby.
+func (f *FailoverBackend) Delete(ctx context.Context, key string) error {
+	return f.mirror(func(b Backend) error {
+		return b.Delete(ctx, key)
+	})
+}
+
+// Exists checks if a key exists in the active backend.
+func (f *FailoverBackend) Exists(ctx context.Context, key string) (bool, error) {
+	return f.active().Exists(ctx, key)
+}
+
+// GetMulti retrieves multiple values from the active backend.
+func (f *FailoverBackend) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	return f.active().GetMulti(ctx, keys)
+}
+
+// SetMulti stores multiple values in the active backend and the standby.
+func (f *FailoverBackend) SetMulti(ctx context.Context, items map[string][]byte, ttl time.Duration) error {
+	return f.mirror(func(b Backend) error {
+		return b.SetMulti(ctx, items, ttl)
+	})
+}
+
+// DeleteMulti removes multiple values from the active backend and the standby.
+func (f *FailoverBackend) DeleteMulti(ctx context.Context, keys []string) error {
+	return f.mirror(func(b Backend) error {
+		return b.DeleteMulti(ctx, keys)
+	})
+}
+
+// Increment increments a counter in the active backend and the standby.
+func (f *FailoverBackend) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	var value int64
+	err := f.mirror(func(b Backend) error {
+		result, err := b.Increment(ctx, key, delta)
+		if b == f.active() {
+			value = result
+		}
+		return err
+	})
+	return value, err
+}
+
+// Decrement decrements a counter in the active backend and the standby.
+func (f *FailoverBackend) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	var value int64
+	err := f.mirror(func(b Backend) error {
+		result, err := b.Decrement(ctx, key, delta)
+		if b == f.active() {
+			value = result
+		}
+		return err
+	})
+	return value, err
+}
+
+// Expire sets a timeout on a key in the active backend and the standby.
+func (f *FailoverBackend) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return f.mirror(func(b Backend) error {
+		return b.Expire(ctx, key, ttl)
+	})
+}
+
+// Persist removes the expiration of a key in the active backend and the
+// standby. Backends without a default TTL never expire keys written without
+// one, so they are skipped.
+func (f *FailoverBackend) Persist(ctx context.Context, key string) error {
+	return f.mirror(func(b Backend) error {
+		persister, ok := b.(Persister)
+		if !ok {
+			return nil
+		}
+		return persister.Persist(ctx, key)
+	})
+}
+
+// TTL returns the remaining time to live of a key in the active backend.
+func (f *FailoverBackend) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return f.active().TTL(ctx, key)
+}
+
+// Keys returns the keys of the active backend matching the glob pattern.
+func (f *FailoverBackend) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return f.active().Keys(ctx, pattern)
+}
+
+// DeleteByPattern deletes the keys matching the glob pattern in the active
+// backend and the standby, returning the active backend's count.
+func (f *FailoverBackend) DeleteByPattern(ctx context.Context, pattern string) (int, error) {
+	var deleted int
+	err := f.mirror(func(b Backend) error {
+		deleter, ok := b.(PatternDeleter)
+		if !ok {
+			return fmt.Errorf("delete by pattern not supported by backend")
+		}
+		n, err := deleter.DeleteByPattern(ctx, pattern)
+		if b == f.active() {
+			deleted = n
+		}
+		return err
+	})
+	return deleted, err
+}
+
+// Iterate calls fn for every entry of the active backend.
+func (f *FailoverBackend) Iterate(ctx context.Context, fn func(key string, value []byte, ttl time.Duration) error) error {
+	iterator, ok := f.active().(EntryIterator)
+	if !ok {
+		return fmt.Errorf("iteration not supported by active backend")
+	}
+	return iterator.Iterate(ctx, fn)
+}
+
+// IteratePattern calls fn for every entry of the active backend whose key
+// matches pattern.
+func (f *FailoverBackend) IteratePattern(ctx context.Context, pattern string, fn func(key string, value []byte, ttl time.Duration) error) error {
+	iterator, ok := f.active().(PatternIterator)
+	if !ok {
+		return fmt.Errorf("pattern iteration not supported by active backend")
+	}
+	return iterator.IteratePattern(ctx, pattern, fn)
+}
+
+// RateLimitAllow checks the rate limit of key in the active backend only;
+// a promoted standby starts with empty windows.
+func (f *FailoverBackend) RateLimitAllow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, error) {
+	limiter, ok := f.active().(RateLimiter)
+	if !ok {
+		return false, 0, fmt.Errorf("rate limiting not supported by active backend")
+	}
+	return limiter.RateLimitAllow(ctx, key, limit, window)
+}
+
+// Publish sends message on channel through the active backend.
+func (f *FailoverBackend) Publish(ctx context.Context, channel, message string) error {
+	broadcaster, ok := f.active().(Broadcaster)
+	if !ok {
+		return fmt.Errorf("pub/sub not supported by active backend")
+	}
+	return broadcaster.Publish(ctx, channel, message)
+}
+
+// Subscribe subscribes to channel on the backend active when it is called;
+// the subscription does not move to a promoted standby.
+func (f *FailoverBackend) Subscribe(ctx context.Context, channel string, fn func(message string)) (func() error, error) {
+	broadcaster, ok := f.active().(Broadcaster)
+	if !ok {
+		return nil, fmt.Errorf("pub/sub not supported by active backend")
+	}
+	return broadcaster.Subscribe(ctx, channel, fn)
+}
+
+// SetIfNewer stores value in the active backend if version is newer, and
+// mirrors a stored value to the standby with the same version, so a
+// promoted standby keeps ordering versioned writes.
+func (f *FailoverBackend) SetIfNewer(ctx context.Context, key string, value []byte, version int64, ttl time.Duration) (bool, error) {
+	setter, ok := f.active().(VersionedSetter)
+	if !ok {
+		return false, fmt.Errorf("versioned sets not supported by active backend")
+	}
+
+	stored, err := setter.SetIfNewer(ctx, key, value, version, ttl)
+	if !stored || f.promoted.Load() {
+		return stored, err
+	}
+
+	if standby, ok := f.standby.(VersionedSetter); ok {
+		_, _ = standby.SetIfNewer(ctx, key, value, version, ttl)
+	} else {
+		_ = f.standby.Set(ctx, key, value, ttl)
+	}
+	return stored, err
+}
+
+// ListPushCapped pushes to the list at key in the active backend only; a
+// promoted standby starts with empty lists.
+func (f *FailoverBackend) ListPushCapped(ctx context.Context, key string, value []byte, maxLen int, ttl time.Duration) error {
+	lister, ok := f.active().(CappedLister)
+	if !ok {
+		return fmt.Errorf("capped lists not supported by active backend")
+	}
+	return lister.ListPushCapped(ctx, key, value, maxLen, ttl)
+}
+
+// ListRange reads the list at key from the active backend.
+func (f *FailoverBackend) ListRange(ctx context.Context, key string) ([][]byte, error) {
+	lister, ok := f.active().(CappedLister)
+	if !ok {
+		return nil, fmt.Errorf("capped lists not supported by active backend")
+	}
+	return lister.ListRange(ctx, key)
+}
+
+// IncrementWithTTLOnCreate increments a counter in the active backend and
+// mirrors the increment to the standby.
+func (f *FailoverBackend) IncrementWithTTLOnCreate(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	var value int64
+	err := f.mirror(func(b Backend) error {
+		incrementer, ok := b.(CreateTTLIncrementer)
+		if !ok {
+			return fmt.Errorf("increment with TTL not supported by backend")
+		}
+		result, err := incrementer.IncrementWithTTLOnCreate(ctx, key, delta, ttl)
+		if b == f.active() {
+			value = result
+		}
+		return err
+	})
+	return value, err
+}
+
+// SetAlias points alias at target in the active backend and the standby.
+func (f *FailoverBackend) SetAlias(ctx context.Context, alias, target string) error {
+	return f.mirror(func(b Backend) error {
+		aliaser, ok := b.(Aliaser)
+		if !ok {
+			return fmt.Errorf("aliases not supported by backend")
+		}
+		return aliaser.SetAlias(ctx, alias, target)
+	})
+}
+
+// SwapAlias repoints alias in the active backend and the standby, returning
+// the active backend's previous target.
+func (f *FailoverBackend) SwapAlias(ctx context.Context, alias, target string) (string, error) {
+	var previous string
+	err := f.mirror(func(b Backend) error {
+		aliaser, ok := b.(Aliaser)
+		if !ok {
+			return fmt.Errorf("aliases not supported by backend")
+		}
+		result, err := aliaser.SwapAlias(ctx, alias, target)
+		if b == f.active() {
+			previous = result
+		}
+		return err
+	})
+	return previous, err
+}
+
+// ResolveAlias returns the target of alias in the active backend.
+func (f *FailoverBackend) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	aliaser, ok := f.active().(Aliaser)
+	if !ok {
+		return "", fmt.Errorf("aliases not supported by active backend")
+	}
+	return aliaser.ResolveAlias(ctx, alias)
+}
+
+// Clear removes all keys from the active backend and the standby.
+func (f *FailoverBackend) Clear(ctx context.Context) error {
+	return f.mirror(func(b Backend) error {
+		return b.Clear(ctx)
+	})
+}
+
+// Stats returns statistics of the active backend.
+func (f *FailoverBackend) Stats(ctx context.Context) (*Stats, error) {
+	return f.active().Stats(ctx)
+}
+
+// ServerTime reads the clock of the active backend.
+func (f *FailoverBackend) ServerTime(ctx context.Context) (time.Time, error) {
+	reporter, ok := f.active().(ClockReporter)
+	if !ok {
+		return time.Time{}, fmt.Errorf("reading the clock not supported by active backend")
+	}
+	return reporter.ServerTime(ctx)
+}
+
+// ResetStats resets the statistics of the active backend.
+func (f *FailoverBackend) ResetStats(ctx context.Context) error {
+	resetter, ok := f.active().(StatsResetter)
+	if !ok {
+		return fmt.Errorf("resetting stats not supported by active backend")
+	}
+	return resetter.ResetStats(ctx)
+}
+
+// PurgeExpired removes the expired entries of the active backend.
+func (f *FailoverBackend) PurgeExpired(ctx context.Context) (int, error) {
+	purger, ok := f.active().(ExpiredPurger)
+	if !ok {
+		return 0, fmt.Errorf("purging expired entries not supported by active backend")
+	}
+	return purger.PurgeExpired(ctx)
+}
+
+// Size returns the number of keys in the active backend.
+func (f *FailoverBackend) Size(ctx context.Context) (int64, error) {
+	if sizer, ok := f.active().(Sizer); ok {
+		return sizer.Size(ctx)
+	}
+	stats, err := f.active().Stats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return stats.KeyCount, nil
+}
+
+// Health checks the health of the active backend.
+func (f *FailoverBackend) Health(ctx context.Context) error {
+	return f.active().Health(ctx)
+}
+
+// Close stops health checks and closes both backends. It is safe to call
+// more than once.
+func (f *FailoverBackend) Close() error {
+	var err error
+	f.closeOnce.Do(func() {
+		close(f.stopCheck)
+		primaryErr := f.primary.Close()
+		standbyErr := f.standby.Close()
+		if primaryErr != nil {
+			err = primaryErr
+		} else {
+			err = standbyErr
+		}
+	})
+	return err
+}
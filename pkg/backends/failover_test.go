@@ -0,0 +1,142 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingBackend wraps a backend and fails every operation once down is set.
+type failingBackend struct {
+	Backend
+	down atomic.Bool
+}
+
+var errBackendDown = errors.New("backend down")
+
+func (b *failingBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	if b.down.Load() {
+		return nil, errBackendDown
+	}
+	return b.Backend.Get(ctx, key)
+}
+
+func (b *failingBackend) Health(ctx context.Context) error {
+	if b.down.Load() {
+		return errBackendDown
+	}
+	return b.Backend.Health(ctx)
+}
+
+func (b *failingBackend) SetIfNewer(ctx context.Context, key string, value []byte, version int64, ttl time.Duration) (bool, error) {
+	if b.down.Load() {
+		return false, errBackendDown
+	}
+	return b.Backend.(VersionedSetter).SetIfNewer(ctx, key, value, version, ttl)
+}
+
+func newFailoverPair(t *testing.T, checkInterval time.Duration) (*failingBackend, *MemoryBackend, *FailoverBackend) {
+	primaryBackend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	standby, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+
+	primary := &failingBackend{Backend: primaryBackend}
+	failover := NewFailoverBackend(primary, standby, checkInterval)
+	t.Cleanup(func() { failover.Close() })
+	return primary, standby, failover
+}
+
+func TestFailoverMirrorsWrites(t *testing.T) {
+	ctx := context.Background()
+	_, standby, failover := newFailoverPair(t, 0)
+
+	require.NoError(t, failover.Set(ctx, "a", []byte("1"), 0))
+	require.NoError(t, failover.SetMulti(ctx, map[string][]byte{"b": []byte("2"), "c": []byte("3")}, 0))
+	require.NoError(t, failover.Delete(ctx, "c"))
+
+	value, err := standby.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1"), value)
+
+	value, err = standby.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), value)
+
+	_, err = standby.Get(ctx, "c")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	count, err := failover.Increment(ctx, "counter", 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+	count, err = standby.Increment(ctx, "counter", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+}
+
+func TestFailoverMirrorsVersionedWrites(t *testing.T) {
+	ctx := context.Background()
+	primary, standby, failover := newFailoverPair(t, 0)
+
+	stored, err := failover.SetIfNewer(ctx, "key", []byte("v5"), 5, time.Minute)
+	require.NoError(t, err)
+	require.True(t, stored)
+
+	value, err := standby.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v5"), value)
+
+	// The promoted standby still rejects older versions
+	primary.down.Store(true)
+	require.Error(t, failover.CheckPrimary(ctx))
+	require.True(t, failover.Promoted())
+
+	stored, err = failover.SetIfNewer(ctx, "key", []byte("v3"), 3, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, stored)
+}
+
+func TestFailoverPromotesStandby(t *testing.T) {
+	ctx := context.Background()
+	primary, _, failover := newFailoverPair(t, 0)
+
+	require.NoError(t, failover.Set(ctx, "key", []byte("value"), 0))
+	require.NoError(t, failover.CheckPrimary(ctx))
+	assert.False(t, failover.Promoted())
+
+	primary.down.Store(true)
+	assert.ErrorIs(t, failover.CheckPrimary(ctx), errBackendDown)
+	assert.True(t, failover.Promoted())
+
+	value, err := failover.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+	assert.NoError(t, failover.Health(ctx))
+
+	// Promotion sticks even if the primary recovers
+	primary.down.Store(false)
+	require.NoError(t, failover.Set(ctx, "after", []byte("x"), 0))
+	_, err = primary.Get(ctx, "after")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.True(t, failover.Promoted())
+}
+
+func TestFailoverBackgroundCheck(t *testing.T) {
+	ctx := context.Background()
+	primary, _, failover := newFailoverPair(t, 10*time.Millisecond)
+
+	require.NoError(t, failover.Set(ctx, "key", []byte("value"), 0))
+	primary.down.Store(true)
+
+	require.Eventually(t, failover.Promoted, time.Second, 5*time.Millisecond)
+
+	value, err := failover.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+}
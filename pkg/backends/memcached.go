@@ -1,9 +1,14 @@
 package backends
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"math"
+	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
@@ -33,30 +38,72 @@ func NewMemcachedBackend(cfg config.MemcachedConfig) (*MemcachedBackend, error)
 	}, nil
 }
 
+// memcachedMaxRelativeExpiration is the longest expiration Memcached reads as
+// a number of seconds from now; larger values are absolute Unix timestamps.
+const memcachedMaxRelativeExpiration = 30 * 24 * 60 * 60
+
+// memcachedExpiration converts ttl into a Memcached expiration, zero for no
+// expiration. Memcached counts whole seconds, so ttl is rounded up to the
+// next second and entries never expire early. TTLs over 30 days are sent as
+// the absolute Unix time now+ttl, as Memcached requires.
+func memcachedExpiration(ttl time.Duration, now time.Time) int32 {
+	if ttl <= 0 {
+		return 0
+	}
+
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	if seconds > memcachedMaxRelativeExpiration {
+		seconds += now.Unix()
+	}
+	if seconds > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(seconds)
+}
+
 // Get retrieves a value from Memcached.
 func (m *MemcachedBackend) Get(ctx context.Context, key string) ([]byte, error) {
 	item, err := m.client.Get(key)
 	if err != nil {
 		if err == memcache.ErrCacheMiss {
-			return nil, fmt.Errorf("key not found")
+			return nil, ErrNotFound
 		}
 		return nil, err
 	}
 	return item.Value, nil
 }
 
-// Set stores a value in Memcached.
+// Set stores a value in Memcached. Memcached expires entries with second
+// precision, so ttl is rounded up to a whole number of seconds.
 func (m *MemcachedBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
 	item := &memcache.Item{
-		Key:   key,
-		Value: value,
+		Key:        key,
+		Value:      value,
+		Expiration: memcachedExpiration(ttl, time.Now()),
 	}
 
-	if ttl > 0 {
-		item.Expiration = int32(ttl.Seconds())
+	return m.client.Set(item)
+}
+
+// SetNX stores a value with add, which fails if the key already exists.
+func (m *MemcachedBackend) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	item := &memcache.Item{
+		Key:        key,
+		Value:      value,
+		Expiration: memcachedExpiration(ttl, time.Now()),
 	}
 
-	return m.client.Set(item)
+	err := m.client.Add(item)
+	if err == memcache.ErrNotStored {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetSet is not supported by Memcached, which cannot keep the remaining TTL
+// of a key it overwrites.
+func (m *MemcachedBackend) GetSet(ctx context.Context, key string, value []byte) ([]byte, error) {
+	return nil, fmt.Errorf("GetSet operation not supported by Memcached")
 }
 
 // Delete removes a value from Memcached.
@@ -135,6 +182,30 @@ func (m *MemcachedBackend) Increment(ctx context.Context, key string, delta int6
 	return int64(newValue), nil
 }
 
+// IncrementWithTTLOnCreate creates the counter with add, which sets its
+// expiration, and increments it with incr if it already exists.
+func (m *MemcachedBackend) IncrementWithTTLOnCreate(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	item := &memcache.Item{
+		Key:        key,
+		Value:      []byte(strconv.FormatInt(delta, 10)),
+		Expiration: memcachedExpiration(ttl, time.Now()),
+	}
+
+	err := m.client.Add(item)
+	if err == nil {
+		return delta, nil
+	}
+	if err != memcache.ErrNotStored {
+		return 0, err
+	}
+
+	newValue, err := m.client.Increment(key, uint64(delta))
+	if err != nil {
+		return 0, err
+	}
+	return int64(newValue), nil
+}
+
 // Decrement atomically decrements a numeric value in Memcached.
 func (m *MemcachedBackend) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
 	newValue, err := m.client.Decrement(key, uint64(delta))
@@ -168,25 +239,107 @@ func (m *MemcachedBackend) TTL(ctx context.Context, key string) (time.Duration,
 	return 0, fmt.Errorf("TTL operation not supported by Memcached")
 }
 
+// Keys is not supported by Memcached, which cannot enumerate its keys.
+func (m *MemcachedBackend) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return nil, fmt.Errorf("Keys operation not supported by Memcached")
+}
+
 // Clear removes all keys from Memcached.
 func (m *MemcachedBackend) Clear(ctx context.Context) error {
 	return m.client.FlushAll()
 }
 
-// Stats returns Memcached statistics.
+// Stats returns Memcached statistics aggregated across all servers. Servers
+// that cannot be reached are skipped; an error is returned only when none of
+// them answers.
 func (m *MemcachedBackend) Stats(ctx context.Context) (*Stats, error) {
-	// Note: The gomemcache library doesn't expose a simple Stats() method
-	// This is a placeholder implementation
-	return &Stats{
-		Hits:        0,
-		Misses:      0,
-		Sets:        0,
-		Deletes:     0,
-		Evictions:   0,
-		KeyCount:    0,
-		MemoryUsage: 0,
-		Uptime:      0,
-	}, nil
+	stats := &Stats{}
+	var lastErr error
+	answered := 0
+
+	for _, addr := range m.config.Servers {
+		server, err := m.serverStats(ctx, addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		answered++
+
+		stats.Hits += memcachedStat(server, "get_hits")
+		stats.Misses += memcachedStat(server, "get_misses")
+		stats.Sets += memcachedStat(server, "cmd_set")
+		stats.Deletes += memcachedStat(server, "delete_hits")
+		stats.Evictions += memcachedStat(server, "evictions")
+		stats.KeyCount += memcachedStat(server, "curr_items")
+		stats.MemoryUsage += memcachedStat(server, "bytes")
+		if uptime := memcachedStat(server, "uptime"); uptime > stats.Uptime {
+			stats.Uptime = uptime
+		}
+	}
+
+	if answered == 0 && lastErr != nil {
+		return nil, fmt.Errorf("failed to get Memcached stats: %w", lastErr)
+	}
+
+	return stats, nil
+}
+
+// serverStats issues the stats command to the Memcached server at addr on a
+// dedicated connection, as gomemcache does not expose it.
+func (m *MemcachedBackend) serverStats(ctx context.Context, addr string) (map[string]string, error) {
+	network := "tcp"
+	if strings.Contains(addr, "/") {
+		network = "unix"
+	}
+
+	dialer := net.Dialer{Timeout: m.config.Timeout}
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else if m.config.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(m.config.Timeout))
+	}
+
+	if _, err := io.WriteString(conn, "stats\r\n"); err != nil {
+		return nil, err
+	}
+
+	return parseMemcachedStats(conn)
+}
+
+// parseMemcachedStats reads "STAT <name> <value>" lines up to the closing
+// END of a stats response.
+func parseMemcachedStats(r io.Reader) (map[string]string, error) {
+	stats := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "END" {
+			return stats, nil
+		}
+
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 || fields[0] != "STAT" {
+			return nil, fmt.Errorf("unexpected stats response: %q", line)
+		}
+		stats[fields[1]] = fields[2]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// memcachedStat returns the named counter of a server, zero when it is
+// missing or not a number.
+func memcachedStat(stats map[string]string, name string) int64 {
+	value, _ := strconv.ParseInt(stats[name], 10, 64)
+	return value
 }
 
 // Health checks the health of the Memcached connection.
@@ -194,6 +347,11 @@ func (m *MemcachedBackend) Health(ctx context.Context) error {
 	return m.client.Ping()
 }
 
+// Addresses returns the configured Memcached server addresses.
+func (m *MemcachedBackend) Addresses() []string {
+	return append([]string(nil), m.config.Servers...)
+}
+
 // Close closes the Memcached connection.
 func (m *MemcachedBackend) Close() error {
 	// Memcached client doesn't have a close method
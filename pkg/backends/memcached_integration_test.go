@@ -0,0 +1,53 @@
+//go:build integration
+
+package backends
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMemcached connects to the Memcached server in
+// GOCACHEX_TEST_MEMCACHED_ADDR (default localhost:11211), skipping the test
+// when it is unavailable.
+func newTestMemcached(t *testing.T) *MemcachedBackend {
+	addr := os.Getenv("GOCACHEX_TEST_MEMCACHED_ADDR")
+	if addr == "" {
+		addr = "localhost:11211"
+	}
+
+	backend, err := NewMemcachedBackend(config.MemcachedConfig{
+		Servers: []string{addr},
+		Timeout: time.Second,
+	})
+	if err != nil {
+		t.Skipf("memcached not available at %s: %v", addr, err)
+	}
+	t.Cleanup(func() { backend.Close() })
+	return backend
+}
+
+func TestMemcachedStatsIntegration(t *testing.T) {
+	backend := newTestMemcached(t)
+	ctx := context.Background()
+
+	require.NoError(t, backend.Set(ctx, "stats:key", []byte("value"), time.Minute))
+	_, err := backend.Get(ctx, "stats:key")
+	require.NoError(t, err)
+	_, _ = backend.Get(ctx, "stats:missing")
+
+	stats, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, stats.Hits, int64(0))
+	assert.Greater(t, stats.Misses, int64(0))
+	assert.Greater(t, stats.Sets, int64(0))
+	assert.Greater(t, stats.KeyCount, int64(0))
+	assert.Greater(t, stats.MemoryUsage, int64(0))
+	assert.Greater(t, stats.Uptime, int64(0))
+}
@@ -0,0 +1,99 @@
+package backends
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemcachedExpiration(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	thirtyDays := 30 * 24 * time.Hour
+
+	tests := []struct {
+		ttl  time.Duration
+		want int32
+	}{
+		{0, 0},
+		{-time.Second, 0},
+		{time.Millisecond, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+		{thirtyDays - time.Second, int32((thirtyDays - time.Second) / time.Second)},
+		{thirtyDays, int32(thirtyDays / time.Second)},
+		{thirtyDays + time.Second, int32(now.Add(thirtyDays + time.Second).Unix())},
+		{365 * 24 * time.Hour, int32(now.Add(365 * 24 * time.Hour).Unix())},
+		{200 * 365 * 24 * time.Hour, math.MaxInt32},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, memcachedExpiration(tt.ttl, now), "ttl %s", tt.ttl)
+	}
+}
+
+// fakeMemcachedStats serves a single stats response with the given counters,
+// returning the server address.
+func fakeMemcachedStats(t *testing.T, stats map[string]int64) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+			return
+		}
+		for name, value := range stats {
+			fmt.Fprintf(conn, "STAT %s %d\r\n", name, value)
+		}
+		fmt.Fprint(conn, "STAT version 1.6.21\r\nEND\r\n")
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestMemcachedStats(t *testing.T) {
+	first := fakeMemcachedStats(t, map[string]int64{
+		"get_hits": 3, "get_misses": 1, "cmd_set": 4, "delete_hits": 1,
+		"evictions": 2, "curr_items": 5, "bytes": 512, "uptime": 100,
+	})
+	second := fakeMemcachedStats(t, map[string]int64{
+		"get_hits": 2, "get_misses": 2, "cmd_set": 1, "delete_hits": 0,
+		"evictions": 0, "curr_items": 1, "bytes": 64, "uptime": 250,
+	})
+
+	// A closed listener stands in for a server that is down.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	down := ln.Addr().String()
+	ln.Close()
+
+	backend := &MemcachedBackend{config: config.MemcachedConfig{
+		Servers: []string{first, down, second},
+		Timeout: time.Second,
+	}}
+
+	stats, err := backend.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &Stats{
+		Hits: 5, Misses: 3, Sets: 5, Deletes: 1, Evictions: 2,
+		KeyCount: 6, MemoryUsage: 576, Uptime: 250,
+	}, stats)
+
+	backend.config.Servers = []string{down}
+	_, err = backend.Stats(context.Background())
+	assert.Error(t, err)
+}
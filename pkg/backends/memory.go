@@ -2,31 +2,157 @@ package backends
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/chmenegatti/gocachex/pkg/hashing"
 )
 
-// MemoryBackend implements an in-memory cache backend.
+// mapEntryOverhead approximates the bytes a Go map[string]*memoryItem spends
+// per entry on bucket storage: the string header, the pointer and the tophash
+// byte, spread over buckets filled to the average load factor of 6.5/8.
+const mapEntryOverhead = (16 + 8 + 1) * 8 / 6.5
+
+// entryOverhead is the fixed number of bytes accounted for each entry on top
+// of its key and value: the memoryItem struct plus mapEntryOverhead, rounded
+// up.
+const entryOverhead = int(unsafe.Sizeof(memoryItem{})) + 31
+
+// entrySize returns the bytes accounted against MaxSize for storing value
+// under key.
+func entrySize(key string, value []byte) int {
+	return len(key) + len(value) + entryOverhead
+}
+
+// MemoryBackend implements an in-memory cache backend. Entries are spread
+// over stripes by key hash, each with its own lock, so writes to different
+// stripes proceed in parallel. Limits are enforced on totals shared by all
+// stripes: a write evicts from its own stripe first and from the others once
+// its stripe is empty.
 type MemoryBackend struct {
-	mu          sync.RWMutex
-	data        map[string]*memoryItem
+	stripes     []*memoryStripe // a power of two, indexed by key hash
+	hash        hashing.Func    // picks the stripe of a key
+	stripeShift uint            // drops the hash bits below the stripe index
 	stats       *memoryStats
 	config      config.MemoryConfig
-	stopCleanup chan bool
+	stopCleanup chan bool // nil when no background goroutine runs
+	closeOnce   sync.Once
 	maxSize     int64
-	currentSize int64
+	currentSize atomic.Int64 // bytes accounted against maxSize
+	keyCount    atomic.Int64 // keys across all stripes
+	quotas      map[string]namespaceQuota
+	usage       map[string]*namespaceUsage // per namespace with a quota, fixed at creation
+	clock       Clock                      // expirations are measured against it
+
+	mu         sync.RWMutex // guards aliases, rateLimits and lists
+	aliases    map[string]string
+	rateLimits map[string]*rateWindow
+	lists      map[string]*cappedList
+}
+
+// memoryStripe holds the entries whose keys hash to it.
+type memoryStripe struct {
+	mu     sync.RWMutex
+	data   map[string]*memoryItem
+	policy string
+
+	// Eviction order of the lru and lfu policies. It has its own lock, taken
+	// after mu, so reads can update it while holding only the read lock.
+	orderMu sync.Mutex
+	lru     lruList
+	lfu     lfuHeap
+}
+
+// rateWindow holds the times of the requests recorded against a rate limit
+// key, oldest first.
+type rateWindow struct {
+	hits   []time.Time
+	window time.Duration
+}
+
+// prune drops the hits older than the window ending at now.
+func (w *rateWindow) prune(now time.Time) {
+	start := now.Add(-w.window)
+	i := 0
+	for i < len(w.hits) && !w.hits[i].After(start) {
+		i++
+	}
+	w.hits = w.hits[i:]
+}
+
+// cappedList is a ring buffer holding the newest values pushed to a list.
+type cappedList struct {
+	values     [][]byte // ring of capacity maxLen
+	next       int      // position of the next push
+	count      int
+	expireTime time.Time
+}
+
+// push stores value as the newest one, overwriting the oldest once the ring
+// is full. The ring is resized, keeping the newest values, when maxLen
+// changes.
+func (l *cappedList) push(value []byte, maxLen int) {
+	if len(l.values) != maxLen {
+		newest := l.newest()
+		if len(newest) > maxLen {
+			newest = newest[:maxLen]
+		}
+		l.values = make([][]byte, maxLen)
+		l.count = len(newest)
+		for i, v := range newest {
+			l.values[l.count-1-i] = v
+		}
+		l.next = l.count % maxLen
+	}
+
+	l.values[l.next] = value
+	l.next = (l.next + 1) % maxLen
+	if l.count < maxLen {
+		l.count++
+	}
+}
+
+// newest returns the values of the list, newest first.
+func (l *cappedList) newest() [][]byte {
+	values := make([][]byte, l.count)
+	for i := range values {
+		values[i] = l.values[(l.next-1-i+len(l.values))%len(l.values)]
+	}
+	return values
+}
+
+// expired reports whether the list has passed its expiration time at now.
+func (l *cappedList) expired(now time.Time) bool {
+	return !l.expireTime.IsZero() && now.After(l.expireTime)
 }
 
 type memoryItem struct {
+	key         string
 	value       []byte
 	expireTime  time.Time
-	accessTime  time.Time
-	accessCount int64
+	accessTime  int64            // unix nanoseconds of the last access, updated atomically
+	accessCount int64            // number of reads, updated atomically
+	onExpire    func(key string) // called once the item is removed as expired
+	version     int64            // recorded by SetIfNewer
+	scheduled   time.Time        // queued expiration, guarded by namespaceUsage.expiryMu
+
+	// Position in the eviction order, guarded by the stripe's orderMu
+	prev, next *memoryItem // lru
+	index      int         // lfu
+}
+
+// lastAccess returns when the item was last read or written.
+func (item *memoryItem) lastAccess() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&item.accessTime))
 }
 
 type memoryStats struct {
@@ -35,136 +161,471 @@ type memoryStats struct {
 	sets      int64
 	deletes   int64
 	evictions int64
-	startTime time.Time
+	startTime int64 // unix nanoseconds, updated atomically
 }
 
 // NewMemoryBackend creates a new in-memory backend.
 func NewMemoryBackend(cfg config.MemoryConfig) (*MemoryBackend, error) {
+	return NewMemoryBackendWithClock(cfg, systemClock{})
+}
+
+// NewMemoryBackendWithClock creates a new in-memory backend whose expirations
+// are measured against clock instead of the system clock. Cleanup still runs
+// on real timers, removing entries that have expired by clock.
+func NewMemoryBackendWithClock(cfg config.MemoryConfig, clock Clock) (*MemoryBackend, error) {
 	maxSize, err := parseSize(cfg.MaxSize)
 	if err != nil {
 		return nil, fmt.Errorf("invalid max size: %w", err)
 	}
 
+	quotas, err := parseQuotas(cfg.NamespaceQuotas)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := hashing.New(cfg.HashFunction)
+	if err != nil {
+		return nil, err
+	}
+
 	backend := &MemoryBackend{
-		data:        make(map[string]*memoryItem),
-		config:      cfg,
-		maxSize:     maxSize,
-		stopCleanup: make(chan bool),
+		aliases:    make(map[string]string),
+		rateLimits: make(map[string]*rateWindow),
+		lists:      make(map[string]*cappedList),
+		quotas:     quotas,
+		usage:      make(map[string]*namespaceUsage, len(quotas)),
+		config:     cfg,
+		maxSize:    maxSize,
+		hash:       hash,
+		clock:      clock,
 		stats: &memoryStats{
-			startTime: time.Now(),
+			startTime: clock.Now().UnixNano(),
 		},
 	}
+	for namespace := range quotas {
+		backend.usage[namespace] = &namespaceUsage{}
+	}
+
+	// Round the stripe count up to a power of two so the top bits of the
+	// hash pick the stripe
+	stripes := 1
+	backend.stripeShift = 32
+	for stripes < cfg.Stripes {
+		stripes <<= 1
+		backend.stripeShift--
+	}
+	policy := evictionPolicy(cfg.EvictionPolicy)
+	backend.stripes = make([]*memoryStripe, stripes)
+	for i := range backend.stripes {
+		s := &memoryStripe{
+			data:   make(map[string]*memoryItem),
+			policy: policy,
+		}
+		s.lru.init()
+		backend.stripes[i] = s
+	}
+
+	if !cfg.DisableCleanup || cfg.LFUHalfLife > 0 {
+		backend.stopCleanup = make(chan bool)
+	}
 
 	// Start cleanup goroutine
-	go backend.cleanup()
+	if !cfg.DisableCleanup {
+		go backend.cleanup()
+	}
+
+	// Start frequency decay goroutine
+	if cfg.LFUHalfLife > 0 {
+		go backend.decay()
+	}
 
 	return backend, nil
 }
 
-// Get retrieves a value from the cache.
+// stripe returns the stripe holding key, picked by the top bits of the
+// hash. In distributed mode the same hash picks the shard modulo the shard
+// count, so masking its low bits would leave stripes of every shard empty
+// whenever the shard count is even.
+func (m *MemoryBackend) stripe(key string) *memoryStripe {
+	if len(m.stripes) == 1 {
+		return m.stripes[0]
+	}
+	return m.stripes[m.hash(key)>>m.stripeShift]
+}
+
+// lock takes the write lock of the stripe of key, for a write that may grow
+// the cache, and returns the stripe. When key's namespace has a quota, the
+// namespace lock is taken first, so a quota check and the write it allows
+// are atomic while writes to other namespaces proceed. Writes that only
+// remove entries lock the stripe alone. Every section locked by lock must
+// end with unlock.
+func (m *MemoryBackend) lock(key string) *memoryStripe {
+	if usage := m.namespaceUsage(key); usage != nil {
+		usage.mu.Lock()
+	}
+	s := m.stripe(key)
+	s.mu.Lock()
+	return s
+}
+
+// unlock releases the locks taken by lock for key.
+func (m *MemoryBackend) unlock(key string, s *memoryStripe) {
+	s.mu.Unlock()
+	if usage := m.namespaceUsage(key); usage != nil {
+		usage.mu.Unlock()
+	}
+}
+
+// namespaceUsage returns the usage of key's namespace, or nil when it has no
+// quota.
+func (m *MemoryBackend) namespaceUsage(key string) *namespaceUsage {
+	if len(m.quotas) == 0 {
+		return nil
+	}
+	return m.usage[namespaceOf(key)]
+}
+
+// lockAll takes the locks of the quota namespaces in namespaces, in name
+// order, then the write lock of every stripe in stripes, for operations
+// spanning several stripes. stripes must be in the order of m.stripes. It
+// returns a function releasing the locks.
+func (m *MemoryBackend) lockAll(namespaces []string, stripes []*memoryStripe) func() {
+	sort.Strings(namespaces)
+	var usages []*namespaceUsage
+	for _, namespace := range namespaces {
+		if usage := m.usage[namespace]; usage != nil {
+			usage.mu.Lock()
+			usages = append(usages, usage)
+		}
+	}
+	for _, s := range stripes {
+		s.mu.Lock()
+	}
+	return func() {
+		for _, s := range stripes {
+			s.mu.Unlock()
+		}
+		for _, usage := range usages {
+			usage.mu.Unlock()
+		}
+	}
+}
+
+// Get retrieves a value from the cache. The value and expiration are read
+// under the read lock, since writers such as Increment and Expire update
+// items in place; the access statistics are updated atomically.
 func (m *MemoryBackend) Get(ctx context.Context, key string) ([]byte, error) {
-	m.mu.RLock()
-	item, exists := m.data[key]
-	m.mu.RUnlock()
+	s := m.stripe(key)
+	s.mu.RLock()
+	item, exists := s.data[key]
+	var value []byte
+	var expireTime time.Time
+	if exists {
+		value = item.value
+		expireTime = item.expireTime
+	}
+	s.mu.RUnlock()
 
 	if !exists {
 		atomic.AddInt64(&m.stats.misses, 1)
-		return nil, fmt.Errorf("key not found")
+		return nil, ErrNotFound
 	}
 
 	// Check expiration
-	if !item.expireTime.IsZero() && time.Now().After(item.expireTime) {
-		m.mu.Lock()
-		delete(m.data, key)
-		m.mu.Unlock()
+	if !expireTime.IsZero() && m.now().After(expireTime) {
+		m.removeExpired(key, item)
 		atomic.AddInt64(&m.stats.misses, 1)
-		return nil, fmt.Errorf("key expired")
+		return nil, ErrNotFound
 	}
 
 	// Update access statistics
-	item.accessTime = time.Now()
-	atomic.AddInt64(&item.accessCount, 1)
+	s.touch(item, true)
 	atomic.AddInt64(&m.stats.hits, 1)
 
-	return item.value, nil
+	return value, nil
 }
 
 // Set stores a value in the cache.
 func (m *MemoryBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return m.set(key, value, ttl, nil)
+}
+
+// SetWithExpireCallback stores a value and calls cb in a new goroutine once
+// the key expires. The callback runs when the expired key is removed, either
+// by the cleanup goroutine or by a read that finds it expired, so it can be
+// late by up to CleanupInterval; with DisableCleanup it only runs if the key
+// is read again. Deleting, overwriting or evicting the key drops cb.
+func (m *MemoryBackend) SetWithExpireCallback(ctx context.Context, key string, value []byte, ttl time.Duration, cb func(key string)) error {
+	return m.set(key, value, ttl, cb)
+}
+
+// SetNX stores a value only if key has no live entry. The check and the
+// insert happen under one lock, so exactly one of several concurrent callers
+// succeeds.
+func (m *MemoryBackend) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	item := m.newItem(value, ttl, nil)
+
+	s := m.lock(key)
+	defer m.unlock(key, s)
+
+	if existing, exists := s.data[key]; exists && !m.expired(existing) {
+		return false, nil
+	}
+	if err := m.store(s, key, item); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SetIfNewer stores value under one lock if version is greater than the
+// version of the live item at key.
+func (m *MemoryBackend) SetIfNewer(ctx context.Context, key string, value []byte, version int64, ttl time.Duration) (bool, error) {
+	item := m.newItem(value, ttl, nil)
+	item.version = version
+
+	s := m.lock(key)
+	defer m.unlock(key, s)
+
+	if existing, exists := s.data[key]; exists && !m.expired(existing) && existing.version >= version {
+		return false, nil
+	}
+	if err := m.store(s, key, item); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetSet replaces the value of key under one lock, keeping its expiration
+// time, and returns the previous value. An expire callback registered for
+// the old value is dropped, as with Set.
+func (m *MemoryBackend) GetSet(ctx context.Context, key string, value []byte) ([]byte, error) {
+	item := m.newItem(value, 0, nil)
+
+	s := m.lock(key)
+	defer m.unlock(key, s)
+
+	existing, exists := s.data[key]
+	if !exists || m.expired(existing) {
+		if err := m.store(s, key, item); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+
+	item.expireTime = existing.expireTime
+	if err := m.store(s, key, item); err != nil {
+		return nil, err
+	}
+
+	return existing.value, nil
+}
+
+// set stores a value with an optional expiration callback.
+func (m *MemoryBackend) set(key string, value []byte, ttl time.Duration, onExpire func(key string)) error {
+	item := m.newItem(value, ttl, onExpire)
+
+	s := m.lock(key)
+	defer m.unlock(key, s)
+
+	return m.store(s, key, item)
+}
+
+// newItem creates an item expiring after ttl, or after the default TTL when
+// ttl is zero.
+func (m *MemoryBackend) newItem(value []byte, ttl time.Duration, onExpire func(key string)) *memoryItem {
 	var expireTime time.Time
 	if ttl > 0 {
-		expireTime = time.Now().Add(ttl)
+		expireTime = m.now().Add(ttl)
 	} else if m.config.DefaultTTL > 0 {
-		expireTime = time.Now().Add(m.config.DefaultTTL)
+		expireTime = m.now().Add(m.config.DefaultTTL)
 	}
 
-	item := &memoryItem{
+	return &memoryItem{
 		value:      value,
 		expireTime: expireTime,
-		accessTime: time.Now(),
+		accessTime: m.now().UnixNano(),
+		onExpire:   onExpire,
 	}
+}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// store inserts item under key in s, evicting entries to stay within the
+// size and key limits: those of s first and, once s is empty, those of other
+// stripes. It returns ErrQuotaExceeded, storing nothing, if the item does not
+// fit in the quota of its namespace. The caller must hold the write lock of
+// s.
+func (m *MemoryBackend) store(s *memoryStripe, key string, item *memoryItem) error {
+	size := entrySize(key, item.value)
+	if err := m.checkQuota(key, size); err != nil {
+		return err
+	}
 
-	// Check if we need to evict items
-	newSize := m.currentSize + int64(len(value))
-	if m.maxSize > 0 && newSize > m.maxSize {
-		m.evictItems(newSize - m.maxSize)
+	for m.overLimits(int64(size), 1) {
+		if !m.evictItems(s, 0) && !m.evictElsewhere() {
+			break // Nothing left to evict
+		}
 	}
 
-	// Check max keys limit
-	if m.config.MaxKeys > 0 && int64(len(m.data)) >= m.config.MaxKeys {
-		m.evictLRU()
+	m.put(s, key, item)
+	atomic.AddInt64(&m.stats.sets, 1)
+
+	return nil
+}
+
+// put sets key to item in s and updates the size counters. The caller must
+// hold the write lock of s.
+func (m *MemoryBackend) put(s *memoryStripe, key string, item *memoryItem) {
+	old, exists := s.data[key]
+	if exists {
+		m.account(key, int64(len(item.value)-len(old.value)), 0)
+		s.untrack(old)
+	} else {
+		m.account(key, int64(entrySize(key, item.value)), 1)
 	}
+	item.key = key
+	s.data[key] = item
+	s.track(item)
 
-	// Update current size
-	if oldItem, exists := m.data[key]; exists {
-		m.currentSize -= int64(len(oldItem.value))
+	if usage := m.namespaceUsage(key); usage != nil {
+		if exists {
+			usage.unschedule(old)
+		}
+		usage.schedule(key, item)
 	}
-	m.currentSize += int64(len(value))
+}
 
-	m.data[key] = item
-	atomic.AddInt64(&m.stats.sets, 1)
+// remove deletes key, currently holding item, from s and updates the size
+// counters. The caller must hold the write lock of s.
+func (m *MemoryBackend) remove(s *memoryStripe, key string, item *memoryItem) {
+	m.account(key, -int64(entrySize(key, item.value)), -1)
+	delete(s.data, key)
+	s.untrack(item)
 
-	return nil
+	if usage := m.namespaceUsage(key); usage != nil {
+		usage.unschedule(item)
+	}
+}
+
+// expire removes the expired item at key from s and runs its expire
+// callback. The caller must hold the write lock of s.
+func (m *MemoryBackend) expire(s *memoryStripe, key string, item *memoryItem) {
+	m.remove(s, key, item)
+	if item.onExpire != nil {
+		go item.onExpire(key)
+	}
 }
 
 // Delete removes a value from the cache.
 func (m *MemoryBackend) Delete(ctx context.Context, key string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	s := m.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if item, exists := m.data[key]; exists {
-		m.currentSize -= int64(len(item.value))
-		delete(m.data, key)
+	if item, exists := s.data[key]; exists {
+		m.remove(s, key, item)
 		atomic.AddInt64(&m.stats.deletes, 1)
 	}
-
 	return nil
 }
 
 // Exists checks if a key exists in the cache.
 func (m *MemoryBackend) Exists(ctx context.Context, key string) (bool, error) {
-	m.mu.RLock()
-	item, exists := m.data[key]
-	m.mu.RUnlock()
+	s := m.stripe(key)
+	s.mu.RLock()
+	item, exists := s.data[key]
+	var expireTime time.Time
+	if exists {
+		expireTime = item.expireTime
+	}
+	s.mu.RUnlock()
 
 	if !exists {
 		return false, nil
 	}
 
 	// Check expiration
-	if !item.expireTime.IsZero() && time.Now().After(item.expireTime) {
-		m.mu.Lock()
-		delete(m.data, key)
-		m.mu.Unlock()
+	if !expireTime.IsZero() && m.now().After(expireTime) {
+		m.removeExpired(key, item)
 		return false, nil
 	}
 
 	return true, nil
 }
 
+// Keys returns every live key matching the glob pattern.
+func (m *MemoryBackend) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	now := m.now()
+	for _, s := range m.stripes {
+		s.mu.RLock()
+		for key, item := range s.data {
+			if !item.expireTime.IsZero() && now.After(item.expireTime) {
+				continue
+			}
+			if MatchPattern(pattern, key) {
+				keys = append(keys, key)
+			}
+		}
+		s.mu.RUnlock()
+	}
+
+	return keys, nil
+}
+
+// DeleteByPattern deletes every key matching the glob pattern, holding the
+// lock of one stripe at a time. Expired keys are removed too but not counted.
+func (m *MemoryBackend) DeleteByPattern(ctx context.Context, pattern string) (int, error) {
+	deleted := 0
+	for _, s := range m.stripes {
+		s.mu.Lock()
+		for key, item := range s.data {
+			if !MatchPattern(pattern, key) {
+				continue
+			}
+			if !m.expired(item) {
+				deleted++
+			}
+			m.remove(s, key, item)
+		}
+		s.mu.Unlock()
+	}
+	atomic.AddInt64(&m.stats.deletes, int64(deleted))
+
+	return deleted, nil
+}
+
+// ExistsPattern reports whether any live key matches the glob pattern,
+// stopping at the first match.
+func (m *MemoryBackend) ExistsPattern(ctx context.Context, pattern string) (bool, error) {
+	now := m.now()
+	for _, s := range m.stripes {
+		if s.existsPattern(pattern, now) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// existsPattern reports whether any key of s live at now matches the glob
+// pattern.
+func (s *memoryStripe) existsPattern(pattern string, now time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for key, item := range s.data {
+		if !item.expireTime.IsZero() && now.After(item.expireTime) {
+			continue
+		}
+		if MatchPattern(pattern, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GetMulti retrieves multiple values from the cache.
 func (m *MemoryBackend) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
 	result := make(map[string][]byte)
@@ -178,16 +639,124 @@ func (m *MemoryBackend) GetMulti(ctx context.Context, keys []string) (map[string
 	return result, nil
 }
 
-// SetMulti stores multiple values in the cache.
+// SetMulti stores multiple values in the cache. The stripes of the keys are
+// locked once for the whole batch, and eviction runs after every item is
+// applied.
 func (m *MemoryBackend) SetMulti(ctx context.Context, items map[string][]byte, ttl time.Duration) error {
-	for key, value := range items {
-		if err := m.Set(ctx, key, value, ttl); err != nil {
+	if len(items) == 0 {
+		return nil
+	}
+
+	now := m.now()
+	var expireTime time.Time
+	if ttl > 0 {
+		expireTime = now.Add(ttl)
+	} else if m.config.DefaultTTL > 0 {
+		expireTime = now.Add(m.config.DefaultTTL)
+	}
+
+	stripes := m.stripes
+	if len(stripes) > 1 {
+		touched := make(map[*memoryStripe]bool)
+		for key := range items {
+			touched[m.stripe(key)] = true
+		}
+		stripes = make([]*memoryStripe, 0, len(touched))
+		for _, s := range m.stripes {
+			if touched[s] {
+				stripes = append(stripes, s)
+			}
+		}
+	}
+	var namespaces []string
+	if len(m.quotas) > 0 {
+		touched := make(map[string]bool)
+		for key := range items {
+			touched[namespaceOf(key)] = true
+		}
+		for namespace := range touched {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	defer m.lockAll(namespaces, stripes)()
+
+	if len(m.quotas) > 0 {
+		sizes := make(map[string]int, len(items))
+		for key, value := range items {
+			sizes[key] = entrySize(key, value)
+		}
+		if err := m.checkQuotas(sizes); err != nil {
 			return err
 		}
 	}
+
+	for key, value := range items {
+		m.put(m.stripe(key), key, &memoryItem{
+			value:      value,
+			expireTime: expireTime,
+			accessTime: now.UnixNano(),
+		})
+	}
+	atomic.AddInt64(&m.stats.sets, int64(len(items)))
+
+	m.evictOverLimits(stripes)
+
 	return nil
 }
 
+// overLimits reports whether adding size bytes and keys keys would take the
+// cache over MaxSize or MaxKeys. An entry larger than MaxSize never fits, so
+// it does not count as over the size limit rather than emptying the cache.
+func (m *MemoryBackend) overLimits(size, keys int64) bool {
+	if m.maxSize > 0 && size <= m.maxSize && m.currentSize.Load()+size > m.maxSize {
+		return true
+	}
+	return m.config.MaxKeys > 0 && m.keyCount.Load()+keys > m.config.MaxKeys
+}
+
+// evictOverLimits evicts entries until the cache is within MaxSize and
+// MaxKeys, first from the stripes in held, whose write locks the caller
+// holds, then from the others.
+func (m *MemoryBackend) evictOverLimits(held []*memoryStripe) {
+	for _, s := range held {
+		for m.overLimits(0, 0) {
+			if !m.evictItems(s, 0) {
+				break
+			}
+		}
+	}
+	for m.overLimits(0, 0) {
+		if !m.evictElsewhere() {
+			return
+		}
+	}
+}
+
+// evictElsewhere evicts an entry of a stripe whose lock the caller does not
+// hold, for when the stripes it holds have nothing left to evict. Waiting for
+// another stripe while holding one could deadlock, so stripes are tried with
+// TryLock from a random start, skipping the busy ones. It reports whether an
+// entry was evicted.
+func (m *MemoryBackend) evictElsewhere() bool {
+	if len(m.stripes) == 1 {
+		return false
+	}
+
+	start := rand.Intn(len(m.stripes))
+	for i := range m.stripes {
+		s := m.stripes[(start+i)%len(m.stripes)]
+		if !s.mu.TryLock() {
+			continue
+		}
+		evicted := m.evictItems(s, 0)
+		s.mu.Unlock()
+		if evicted {
+			return true
+		}
+	}
+	return false
+}
+
 // DeleteMulti removes multiple values from the cache.
 func (m *MemoryBackend) DeleteMulti(ctx context.Context, keys []string) error {
 	for _, key := range keys {
@@ -200,120 +769,611 @@ func (m *MemoryBackend) DeleteMulti(ctx context.Context, keys []string) error {
 
 // Increment atomically increments a numeric value.
 func (m *MemoryBackend) Increment(ctx context.Context, key string, delta int64) (int64, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	return m.adjust(key, delta, 0, addInt64)
+}
 
-	item, exists := m.data[key]
-	if !exists {
-		// Create new item with delta value
-		value := fmt.Sprintf("%d", delta)
-		m.data[key] = &memoryItem{
-			value:      []byte(value),
-			accessTime: time.Now(),
-		}
-		return delta, nil
+// Decrement atomically decrements a numeric value.
+func (m *MemoryBackend) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	return m.adjust(key, delta, 0, subInt64)
+}
+
+// IncrementWithTTLOnCreate increments a numeric value, giving it ttl if the
+// increment creates it.
+func (m *MemoryBackend) IncrementWithTTLOnCreate(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return m.adjust(key, delta, ttl, addInt64)
+}
+
+// adjust applies op to the counter stored at key, treating a missing or
+// expired key as zero. A counter created by adjust expires after ttl, or
+// DefaultTTL when ttl is zero, and is stored like Set would, evicting to stay
+// within the limits. It returns ErrCounterOverflow instead of wrapping around.
+func (m *MemoryBackend) adjust(key string, delta int64, ttl time.Duration, op func(a, b int64) (int64, bool)) (int64, error) {
+	s := m.lock(key)
+	defer m.unlock(key, s)
+
+	item, exists := s.data[key]
+	if exists && m.expired(item) {
+		m.expire(s, key, item)
+		exists = false
 	}
 
 	// Parse current value
 	var current int64
-	if err := json.Unmarshal(item.value, &current); err != nil {
-		return 0, fmt.Errorf("value is not a number")
+	if exists {
+		var err error
+		if current, err = strconv.ParseInt(string(item.value), 10, 64); err != nil {
+			return 0, fmt.Errorf("value is not a number")
+		}
 	}
 
-	// Increment and store
-	newValue := current + delta
-	value := fmt.Sprintf("%d", newValue)
-	item.value = []byte(value)
-	item.accessTime = time.Now()
+	newValue, ok := op(current, delta)
+	if !ok {
+		return 0, ErrCounterOverflow
+	}
+
+	value := strconv.AppendInt(nil, newValue, 10)
+	if !exists {
+		// Create new item with the adjusted value
+		if err := m.store(s, key, m.newItem(value, ttl, nil)); err != nil {
+			return 0, err
+		}
+		return newValue, nil
+	}
+
+	if err := m.checkQuota(key, entrySize(key, value)); err != nil {
+		return 0, err
+	}
+	m.account(key, int64(len(value)-len(item.value)), 0)
+	item.value = value
+	s.touch(item, false)
 
 	return newValue, nil
 }
 
-// Decrement atomically decrements a numeric value.
-func (m *MemoryBackend) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
-	return m.Increment(ctx, key, -delta)
+// addInt64 returns a+b and whether the sum fits in an int64.
+func addInt64(a, b int64) (int64, bool) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, false
+	}
+	return sum, true
 }
 
-// Expire sets a timeout on a key.
-func (m *MemoryBackend) Expire(ctx context.Context, key string, ttl time.Duration) error {
+// subInt64 returns a-b and whether the difference fits in an int64.
+func subInt64(a, b int64) (int64, bool) {
+	diff := a - b
+	if (b > 0 && diff > a) || (b < 0 && diff < a) {
+		return 0, false
+	}
+	return diff, true
+}
+
+// AcquireLease stores token at key with the given TTL unless a live entry
+// already exists. The lease is stored like Set would, evicting to stay within
+// the limits.
+func (m *MemoryBackend) AcquireLease(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	s := m.lock(key)
+	defer m.unlock(key, s)
+
+	if item, exists := s.data[key]; exists && !m.expired(item) {
+		return false, nil
+	}
+
+	if err := m.store(s, key, m.newItem([]byte(token), ttl, nil)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ExtendLease resets the TTL of the lease at key if it is still held by token.
+func (m *MemoryBackend) ExtendLease(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	s := m.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, exists := s.data[key]
+	if !exists || m.expired(item) || string(item.value) != token {
+		return false, nil
+	}
+
+	item.expireTime = m.now().Add(ttl)
+	if usage := m.namespaceUsage(key); usage != nil {
+		usage.schedule(key, item)
+	}
+	return true, nil
+}
+
+// ReleaseLease deletes the lease at key if it is still held by token.
+func (m *MemoryBackend) ReleaseLease(ctx context.Context, key, token string) (bool, error) {
+	s := m.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, exists := s.data[key]
+	if !exists || m.expired(item) || string(item.value) != token {
+		return false, nil
+	}
+
+	m.remove(s, key, item)
+	return true, nil
+}
+
+// SetAlias points alias at target, replacing any previous target.
+func (m *MemoryBackend) SetAlias(ctx context.Context, alias, target string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.aliases[alias] = target
+	return nil
+}
+
+// SwapAlias points an existing alias at target and returns its previous
+// target, or ErrNotFound if the alias is not set.
+func (m *MemoryBackend) SwapAlias(ctx context.Context, alias, target string) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	item, exists := m.data[key]
+	previous, exists := m.aliases[alias]
 	if !exists {
-		return fmt.Errorf("key not found")
+		return "", ErrNotFound
 	}
+	m.aliases[alias] = target
+	return previous, nil
+}
+
+// ResolveAlias returns the key alias points at.
+func (m *MemoryBackend) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	target, exists := m.aliases[alias]
+	if !exists {
+		return "", ErrNotFound
+	}
+	return target, nil
+}
+
+// RateLimitAllow records a request against key under the write lock if the
+// last window holds fewer than limit requests.
+func (m *MemoryBackend) RateLimitAllow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	w, exists := m.rateLimits[key]
+	if !exists {
+		w = &rateWindow{}
+		m.rateLimits[key] = w
+	}
+	w.window = window
+	w.prune(now)
+
+	if len(w.hits) >= limit {
+		return false, 0, nil
+	}
+	w.hits = append(w.hits, now)
+	return true, limit - len(w.hits), nil
+}
+
+// ListPushCapped prepends value to the ring buffer of the list at key,
+// replacing a list that expired.
+func (m *MemoryBackend) ListPushCapped(ctx context.Context, key string, value []byte, maxLen int, ttl time.Duration) error {
+	if maxLen <= 0 {
+		return fmt.Errorf("list length must be positive, got %d", maxLen)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	l, exists := m.lists[key]
+	if !exists || l.expired(now) {
+		l = &cappedList{}
+		m.lists[key] = l
+	}
+	l.push(value, maxLen)
 
 	if ttl > 0 {
-		item.expireTime = time.Now().Add(ttl)
+		l.expireTime = now.Add(ttl)
+	} else {
+		l.expireTime = time.Time{}
+	}
+	return nil
+}
+
+// ListRange returns the values of the list at key, newest first.
+func (m *MemoryBackend) ListRange(ctx context.Context, key string) ([][]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	l, exists := m.lists[key]
+	if !exists || l.expired(m.now()) {
+		return [][]byte{}, nil
+	}
+	return l.newest(), nil
+}
+
+// expired reports whether item has passed its expiration time.
+func (m *MemoryBackend) expired(item *memoryItem) bool {
+	return !item.expireTime.IsZero() && m.now().After(item.expireTime)
+}
+
+// Expire sets a timeout on a key.
+func (m *MemoryBackend) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	s := m.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, exists := s.data[key]
+	if !exists {
+		return ErrNotFound
+	}
+	if m.expired(item) {
+		m.expire(s, key, item)
+		return ErrNotFound
+	}
+
+	if ttl > 0 {
+		item.expireTime = m.now().Add(ttl)
 	} else {
 		item.expireTime = time.Time{}
 	}
+	if usage := m.namespaceUsage(key); usage != nil {
+		usage.schedule(key, item)
+	}
 
 	return nil
 }
 
+// Persist removes the expiration of a key, including one applied from
+// DefaultTTL, so it is kept until deleted or evicted.
+func (m *MemoryBackend) Persist(ctx context.Context, key string) error {
+	s := m.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, exists := s.data[key]
+	if !exists {
+		return ErrNotFound
+	}
+	if m.expired(item) {
+		m.expire(s, key, item)
+		return ErrNotFound
+	}
+
+	item.expireTime = time.Time{}
+	if usage := m.namespaceUsage(key); usage != nil {
+		usage.schedule(key, item)
+	}
+	return nil
+}
+
 // TTL returns the remaining time to live of a key.
 func (m *MemoryBackend) TTL(ctx context.Context, key string) (time.Duration, error) {
-	m.mu.RLock()
-	item, exists := m.data[key]
-	m.mu.RUnlock()
+	s := m.stripe(key)
+	s.mu.RLock()
+	item, exists := s.data[key]
+	var expireTime time.Time
+	if exists {
+		expireTime = item.expireTime
+	}
+	s.mu.RUnlock()
 
 	if !exists {
-		return 0, fmt.Errorf("key not found")
+		return 0, ErrNotFound
 	}
 
-	if item.expireTime.IsZero() {
+	if expireTime.IsZero() {
 		return -1, nil // No expiration
 	}
 
-	remaining := time.Until(item.expireTime)
+	remaining := expireTime.Sub(m.now())
 	if remaining < 0 {
-		return 0, nil // Expired
+		m.removeExpired(key, item)
+		return 0, ErrNotFound
 	}
 
 	return remaining, nil
 }
 
+// GetMultiTTL returns the remaining TTL of every live key in keys. Keys
+// without an expiration report -1.
+func (m *MemoryBackend) GetMultiTTL(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	result := make(map[string]time.Duration, len(keys))
+	now := m.now()
+
+	for _, key := range keys {
+		expireTime, exists := m.stripe(key).expiration(key)
+		if !exists {
+			continue
+		}
+		if expireTime.IsZero() {
+			result[key] = -1
+			continue
+		}
+		if remaining := expireTime.Sub(now); remaining > 0 {
+			result[key] = remaining
+		}
+	}
+
+	return result, nil
+}
+
+// ProbeMulti reports the existence and remaining TTL of every key in keys.
+// Expired keys are reported as missing.
+func (m *MemoryBackend) ProbeMulti(ctx context.Context, keys []string) (map[string]KeyProbe, error) {
+	result := make(map[string]KeyProbe, len(keys))
+	now := m.now()
+
+	for _, key := range keys {
+		expireTime, exists := m.stripe(key).expiration(key)
+		switch {
+		case !exists:
+			result[key] = KeyProbe{}
+		case expireTime.IsZero():
+			result[key] = KeyProbe{Exists: true, TTL: -1}
+		default:
+			if remaining := expireTime.Sub(now); remaining > 0 {
+				result[key] = KeyProbe{Exists: true, TTL: remaining}
+			} else {
+				result[key] = KeyProbe{}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// expiration returns the expiration time of key, read under the read lock,
+// and whether s holds key.
+func (s *memoryStripe) expiration(key string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, exists := s.data[key]
+	if !exists {
+		return time.Time{}, false
+	}
+	return item.expireTime, true
+}
+
+// Iterate calls fn for every live entry in the cache. Keys are snapshotted up
+// front, so fn may safely call back into the backend.
+func (m *MemoryBackend) Iterate(ctx context.Context, fn func(key string, value []byte, ttl time.Duration) error) error {
+	return m.IteratePattern(ctx, "*", fn)
+}
+
+// IteratePattern calls fn for every live entry whose key matches pattern,
+// like Iterate.
+func (m *MemoryBackend) IteratePattern(ctx context.Context, pattern string, fn func(key string, value []byte, ttl time.Duration) error) error {
+	keys := make([]string, 0, m.keyCount.Load())
+	for _, s := range m.stripes {
+		s.mu.RLock()
+		for key := range s.data {
+			if MatchPattern(pattern, key) {
+				keys = append(keys, key)
+			}
+		}
+		s.mu.RUnlock()
+	}
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		s := m.stripe(key)
+		s.mu.RLock()
+		item, exists := s.data[key]
+		var value []byte
+		var expireTime time.Time
+		if exists {
+			value = item.value
+			expireTime = item.expireTime
+		}
+		s.mu.RUnlock()
+
+		if !exists {
+			continue
+		}
+
+		var ttl time.Duration
+		if !expireTime.IsZero() {
+			ttl = expireTime.Sub(m.now())
+			if ttl <= 0 {
+				continue // Expired
+			}
+		}
+
+		if err := fn(key, value, ttl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Clear removes all keys from the cache.
 func (m *MemoryBackend) Clear(ctx context.Context) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	namespaces := make([]string, 0, len(m.usage))
+	for namespace := range m.usage {
+		namespaces = append(namespaces, namespace)
+	}
+	defer m.lockAll(namespaces, m.stripes)()
+
+	for _, s := range m.stripes {
+		s.resetOrder()
+		s.data = make(map[string]*memoryItem)
+	}
+	for _, usage := range m.usage {
+		usage.reset()
+	}
+	m.currentSize.Store(0)
+	m.keyCount.Store(0)
 
-	m.data = make(map[string]*memoryItem)
-	m.currentSize = 0
+	m.mu.Lock()
+	m.aliases = make(map[string]string)
+	m.rateLimits = make(map[string]*rateWindow)
+	m.lists = make(map[string]*cappedList)
+	m.mu.Unlock()
 
 	return nil
 }
 
+// Size returns the number of stored keys without taking the lock. Expired
+// keys count until they are cleaned up.
+func (m *MemoryBackend) Size(ctx context.Context) (int64, error) {
+	return m.keyCount.Load(), nil
+}
+
 // Stats returns cache statistics.
 func (m *MemoryBackend) Stats(ctx context.Context) (*Stats, error) {
-	m.mu.RLock()
-	keyCount := int64(len(m.data))
-	memoryUsage := m.currentSize
-	m.mu.RUnlock()
-
 	return &Stats{
 		Hits:        atomic.LoadInt64(&m.stats.hits),
 		Misses:      atomic.LoadInt64(&m.stats.misses),
 		Sets:        atomic.LoadInt64(&m.stats.sets),
 		Deletes:     atomic.LoadInt64(&m.stats.deletes),
 		Evictions:   atomic.LoadInt64(&m.stats.evictions),
-		KeyCount:    keyCount,
-		MemoryUsage: memoryUsage,
-		Uptime:      int64(time.Since(m.stats.startTime).Seconds()),
+		KeyCount:    m.keyCount.Load(),
+		MemoryUsage: m.currentSize.Load(),
+		Uptime:      int64(m.now().Sub(time.Unix(0, atomic.LoadInt64(&m.stats.startTime))).Seconds()),
 	}, nil
 }
 
+// now returns the current time of the backend clock.
+func (m *MemoryBackend) now() time.Time {
+	return m.clock.Now()
+}
+
+// ServerTime returns the current time of the backend clock.
+func (m *MemoryBackend) ServerTime(ctx context.Context) (time.Time, error) {
+	return m.now(), nil
+}
+
+// ResetStats zeroes the operation counters and restarts the uptime.
+func (m *MemoryBackend) ResetStats(ctx context.Context) error {
+	atomic.StoreInt64(&m.stats.hits, 0)
+	atomic.StoreInt64(&m.stats.misses, 0)
+	atomic.StoreInt64(&m.stats.sets, 0)
+	atomic.StoreInt64(&m.stats.deletes, 0)
+	atomic.StoreInt64(&m.stats.evictions, 0)
+	atomic.StoreInt64(&m.stats.startTime, m.now().UnixNano())
+	return nil
+}
+
+// ExtendedStats reports how accesses are distributed across keys: access
+// count percentiles, keys never read, keys with a TTL and the age of the
+// least recently used key.
+func (m *MemoryBackend) ExtendedStats(ctx context.Context) (map[string]interface{}, error) {
+	counts := make([]int64, 0, m.keyCount.Load())
+	var neverRead, withTTL int64
+	var oldestAccess time.Time
+	for _, s := range m.stripes {
+		s.mu.RLock()
+		for _, item := range s.data {
+			count := atomic.LoadInt64(&item.accessCount)
+			counts = append(counts, count)
+			if count == 0 {
+				neverRead++
+			}
+			if !item.expireTime.IsZero() {
+				withTTL++
+			}
+			if accessed := item.lastAccess(); oldestAccess.IsZero() || accessed.Before(oldestAccess) {
+				oldestAccess = accessed
+			}
+		}
+		s.mu.RUnlock()
+	}
+
+	sort.Slice(counts, func(i, j int) bool { return counts[i] < counts[j] })
+	percentile := func(p float64) int64 {
+		if len(counts) == 0 {
+			return 0
+		}
+		return counts[int(p*float64(len(counts)-1))]
+	}
+
+	overhead := m.MemoryOverhead()
+
+	extra := map[string]interface{}{
+		"estimated_heap_bytes":      overhead.EstimatedBytes,
+		"overhead_ratio":            overhead.Ratio,
+		"eviction_policy":           m.config.EvictionPolicy,
+		"max_keys":                  m.config.MaxKeys,
+		"max_size_bytes":            m.maxSize,
+		"access_count_p50":          percentile(0.5),
+		"access_count_p90":          percentile(0.9),
+		"access_count_p99":          percentile(0.99),
+		"access_count_max":          percentile(1),
+		"keys_never_read":           neverRead,
+		"keys_with_ttl":             withTTL,
+		"oldest_access_age_seconds": int64(0),
+	}
+	if !oldestAccess.IsZero() {
+		extra["oldest_access_age_seconds"] = int64(m.now().Sub(oldestAccess).Seconds())
+	}
+
+	return extra, nil
+}
+
+// MemoryOverhead compares the bytes the backend accounts against MaxSize
+// with an estimate of the heap it actually holds.
+type MemoryOverhead struct {
+	// AccountedBytes is the total size of keys, values and the fixed
+	// per-entry overhead, as limited by MaxSize
+	AccountedBytes int64
+
+	// EstimatedBytes is the estimated heap held by keys, values, item
+	// metadata and map storage
+	EstimatedBytes int64
+
+	// Ratio is EstimatedBytes divided by AccountedBytes, or zero when empty
+	Ratio float64
+}
+
+// MemoryOverhead estimates the heap held by the cache from the sizes of its
+// Go objects. MaxSize counts keys, values and a fixed per-entry overhead, so
+// Ratio stays close to 1; it grows when values keep spare capacity beyond
+// their length.
+func (m *MemoryBackend) MemoryOverhead() MemoryOverhead {
+	itemSize := int64(unsafe.Sizeof(memoryItem{}))
+
+	accounted := m.currentSize.Load()
+	estimated := float64(0)
+	for _, s := range m.stripes {
+		s.mu.RLock()
+		for key, item := range s.data {
+			estimated += mapEntryOverhead + float64(len(key)+cap(item.value)) + float64(itemSize)
+		}
+		s.mu.RUnlock()
+	}
+
+	overhead := MemoryOverhead{
+		AccountedBytes: accounted,
+		EstimatedBytes: int64(estimated),
+	}
+	if accounted > 0 {
+		overhead.Ratio = estimated / float64(accounted)
+	}
+	return overhead
+}
+
 // Health checks the health of the backend.
 func (m *MemoryBackend) Health(ctx context.Context) error {
 	// Memory backend is always healthy if it's running
 	return nil
 }
 
-// Close closes the backend and releases resources.
+// Close closes the backend and releases resources. It is safe to call more
+// than once.
 func (m *MemoryBackend) Close() error {
-	close(m.stopCleanup)
+	m.closeOnce.Do(func() {
+		if m.stopCleanup != nil {
+			close(m.stopCleanup)
+		}
+	})
 	return nil
 }
 
@@ -332,114 +1392,210 @@ func (m *MemoryBackend) cleanup() {
 	}
 }
 
-// cleanupExpired removes expired items from the cache.
-func (m *MemoryBackend) cleanupExpired() {
+// decay halves every access frequency once per LFUHalfLife.
+func (m *MemoryBackend) decay() {
+	ticker := time.NewTicker(m.config.LFUHalfLife)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.decayFrequencies()
+		case <-m.stopCleanup:
+			return
+		}
+	}
+}
+
+// decayFrequencies halves the access count of every item.
+func (m *MemoryBackend) decayFrequencies() {
+	for _, s := range m.stripes {
+		s.decayFrequencies()
+	}
+}
+
+// decayFrequencies halves the access count of every item of s.
+func (s *memoryStripe) decayFrequencies() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Halving keeps the heap order, so it only has to be kept from moving
+	s.orderMu.Lock()
+	defer s.orderMu.Unlock()
+
+	for _, item := range s.data {
+		// Get increments counts outside the lock, so halve with CAS
+		for {
+			count := atomic.LoadInt64(&item.accessCount)
+			if atomic.CompareAndSwapInt64(&item.accessCount, count, count/2) {
+				break
+			}
+		}
+	}
+}
+
+// cleanupExpired removes expired items from the cache, one stripe at a time,
+// and returns how many it removed.
+func (m *MemoryBackend) cleanupExpired() int {
+	removed := 0
+	now := m.now()
+	for _, s := range m.stripes {
+		s.mu.Lock()
+		for key, item := range s.data {
+			if !item.expireTime.IsZero() && now.After(item.expireTime) {
+				m.expire(s, key, item)
+				removed++
+			}
+		}
+		s.mu.Unlock()
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	now := time.Now()
-	for key, item := range m.data {
-		if !item.expireTime.IsZero() && now.After(item.expireTime) {
-			m.currentSize -= int64(len(item.value))
-			delete(m.data, key)
+	for key, w := range m.rateLimits {
+		if w.prune(now); len(w.hits) == 0 {
+			delete(m.rateLimits, key)
+		}
+	}
+	for key, l := range m.lists {
+		if l.expired(now) {
+			delete(m.lists, key)
 		}
 	}
+
+	return removed
+}
+
+// PurgeExpired removes every expired item now, rather than waiting for the
+// next cleanup or a read of the key, and returns how many it removed.
+func (m *MemoryBackend) PurgeExpired(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return m.cleanupExpired(), nil
 }
 
-// evictItems evicts items to free up the specified amount of memory.
-func (m *MemoryBackend) evictItems(sizeToFree int64) {
-	switch m.config.EvictionPolicy {
-	case "lru":
-		m.evictLRU()
-	case "lfu":
-		m.evictLFU()
-	case "random":
-		m.evictRandom()
+// removeExpired removes an expired item found by a read, unless key was
+// rewritten or removed since the item was read.
+func (m *MemoryBackend) removeExpired(key string, item *memoryItem) {
+	s := m.stripe(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data[key] != item {
+		return
+	}
+	m.expire(s, key, item)
+}
+
+// evictItems evicts an item of s to free up the specified amount of memory.
+// It reports whether s had an item to evict.
+func (m *MemoryBackend) evictItems(s *memoryStripe, sizeToFree int64) bool {
+	switch s.policy {
+	case policyRandom:
+		return m.evictRandom(s)
 	default:
-		m.evictLRU()
+		return m.evictOrdered(s)
 	}
 }
 
-// evictLRU evicts the least recently used item.
-func (m *MemoryBackend) evictLRU() {
-	var oldestKey string
-	var oldestTime time.Time
+// evictOrdered evicts the least recently or least frequently used item of s,
+// as kept in order by the lru list or the lfu heap.
+func (m *MemoryBackend) evictOrdered(s *memoryStripe) bool {
+	item := s.victim()
+	if item == nil {
+		return false
+	}
 
-	for key, item := range m.data {
-		if oldestKey == "" || item.accessTime.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = item.accessTime
+	m.remove(s, item.key, item)
+	atomic.AddInt64(&m.stats.evictions, 1)
+	return true
+}
+
+// randomEvictionSamples is the number of keys sampled by random eviction.
+const randomEvictionSamples = 5
+
+// evictRandom samples a few keys of s uniformly at random and evicts the one
+// with the highest weight, where the weight grows with both the size of the
+// value and the time since it was last accessed.
+func (m *MemoryBackend) evictRandom(s *memoryStripe) bool {
+	// Reservoir sampling keeps every key equally likely to be a candidate,
+	// regardless of its position in the map iteration order
+	var candidates [randomEvictionSamples]string
+	count := 0
+	seen := 0
+	for key := range s.data {
+		seen++
+		if count < len(candidates) {
+			candidates[count] = key
+			count++
+			continue
+		}
+		if i := rand.Intn(seen); i < len(candidates) {
+			candidates[i] = key
 		}
 	}
 
-	if oldestKey != "" {
-		item := m.data[oldestKey]
-		m.currentSize -= int64(len(item.value))
-		delete(m.data, oldestKey)
-		atomic.AddInt64(&m.stats.evictions, 1)
+	if count == 0 {
+		return false
 	}
-}
 
-// evictLFU evicts the least frequently used item.
-func (m *MemoryBackend) evictLFU() {
+	now := m.now()
 	var targetKey string
-	var minAccess int64 = -1
-
-	for key, item := range m.data {
-		if minAccess == -1 || item.accessCount < minAccess {
+	maxWeight := -1.0
+	for _, key := range candidates[:count] {
+		item := s.data[key]
+		weight := float64(len(item.value)+1) * float64(now.Sub(item.lastAccess())+1)
+		if weight > maxWeight {
 			targetKey = key
-			minAccess = item.accessCount
+			maxWeight = weight
 		}
 	}
 
-	if targetKey != "" {
-		item := m.data[targetKey]
-		m.currentSize -= int64(len(item.value))
-		delete(m.data, targetKey)
-		atomic.AddInt64(&m.stats.evictions, 1)
-	}
+	m.remove(s, targetKey, s.data[targetKey])
+	atomic.AddInt64(&m.stats.evictions, 1)
+	return true
 }
 
-// evictRandom evicts a random item.
-func (m *MemoryBackend) evictRandom() {
-	for key, item := range m.data {
-		m.currentSize -= int64(len(item.value))
-		delete(m.data, key)
-		atomic.AddInt64(&m.stats.evictions, 1)
-		break // Remove only one item
-	}
+// sizeUnits maps the unit suffixes accepted by parseSize to their size in
+// bytes.
+var sizeUnits = map[string]float64{
+	"":   1,
+	"B":  1,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+	"TB": 1 << 40,
 }
 
-// parseSize parses a size string like "100MB" into bytes.
+// parseSize parses a size string like "100MB", "1.5 GB" or "512" into bytes.
+// The number may have a fractional part and is followed by an optional unit,
+// B, KB, MB, GB or TB in any case, in powers of 1024. An empty string means
+// no limit.
 func parseSize(sizeStr string) (int64, error) {
-	if sizeStr == "" {
+	s := strings.TrimSpace(sizeStr)
+	if s == "" {
 		return 0, nil
 	}
 
-	// Simple implementation - can be enhanced
-	switch {
-	case len(sizeStr) > 2 && sizeStr[len(sizeStr)-2:] == "KB":
-		size := parseInt(sizeStr[:len(sizeStr)-2])
-		return int64(size) * 1024, nil
-	case len(sizeStr) > 2 && sizeStr[len(sizeStr)-2:] == "MB":
-		size := parseInt(sizeStr[:len(sizeStr)-2])
-		return int64(size) * 1024 * 1024, nil
-	case len(sizeStr) > 2 && sizeStr[len(sizeStr)-2:] == "GB":
-		size := parseInt(sizeStr[:len(sizeStr)-2])
-		return int64(size) * 1024 * 1024 * 1024, nil
-	default:
-		return int64(parseInt(sizeStr)), nil
+	number, unit := s, ""
+	if i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }); i >= 0 {
+		number, unit = s[:i], strings.ToUpper(strings.TrimSpace(s[i:]))
 	}
-}
 
-func parseInt(s string) int {
-	var result int
-	for _, char := range s {
-		if char >= '0' && char <= '9' {
-			result = result*10 + int(char-'0')
-		} else {
-			break
-		}
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", sizeStr, unit)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %q is not a number", sizeStr, number)
+	}
+
+	size := value * multiplier
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: too large", sizeStr)
 	}
-	return result
+	return int64(size), nil
 }
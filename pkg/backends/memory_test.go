@@ -0,0 +1,1789 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/chmenegatti/gocachex/pkg/hashing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRandomEvictionBackend(t *testing.T) *MemoryBackend {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		EvictionPolicy:  "random",
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { backend.Close() })
+	return backend
+}
+
+func TestEvictRandomSpreadsAcrossKeyspace(t *testing.T) {
+	const keys = 100
+	victims := make(map[string]int)
+
+	for trial := 0; trial < 200; trial++ {
+		backend := newRandomEvictionBackend(t)
+		for i := 0; i < keys; i++ {
+			require.NoError(t, backend.Set(context.Background(), fmt.Sprintf("key-%03d", i), []byte("v"), 0))
+		}
+
+		s := backend.stripes[0]
+		s.mu.Lock()
+		backend.evictRandom(s)
+		s.mu.Unlock()
+
+		require.Len(t, s.data, keys-1)
+		for i := 0; i < keys; i++ {
+			key := fmt.Sprintf("key-%03d", i)
+			if _, ok := s.data[key]; !ok {
+				victims[key]++
+			}
+		}
+	}
+
+	// A fixed-position eviction would keep hitting the same few keys
+	assert.Greater(t, len(victims), keys/4)
+
+	low, high := 0, 0
+	for key := range victims {
+		if key < "key-050" {
+			low++
+		} else {
+			high++
+		}
+	}
+	assert.Positive(t, low)
+	assert.Positive(t, high)
+}
+
+func TestEvictRandomPrefersLargeStaleItems(t *testing.T) {
+	backend := newRandomEvictionBackend(t)
+	ctx := context.Background()
+
+	// With fewer keys than samples every key is a candidate
+	require.NoError(t, backend.Set(ctx, "large", []byte(strings.Repeat("x", 1024)), 0))
+	require.NoError(t, backend.Set(ctx, "small", []byte("x"), 0))
+	require.NoError(t, backend.Set(ctx, "fresh", []byte(strings.Repeat("x", 1024)), 0))
+
+	s := backend.stripes[0]
+	s.mu.Lock()
+	s.data["large"].accessTime = time.Now().Add(-time.Hour).UnixNano()
+	s.data["small"].accessTime = time.Now().Add(-time.Hour).UnixNano()
+	backend.evictRandom(s)
+	s.mu.Unlock()
+
+	exists, err := backend.Exists(ctx, "large")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	for _, key := range []string{"small", "fresh"} {
+		exists, err := backend.Exists(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, exists, key)
+	}
+}
+
+func TestMaxKeysUsesEvictionPolicy(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		MaxKeys:         10,
+		EvictionPolicy:  "random",
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, backend.Set(context.Background(), fmt.Sprintf("key-%02d", i), []byte("v"), 0))
+	}
+
+	stats, err := backend.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), stats.KeyCount)
+	assert.Equal(t, int64(40), stats.Evictions)
+}
+
+func TestLFUDecayEvictsStaleHotKeys(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		MaxKeys:         3,
+		EvictionPolicy:  "lfu",
+		CleanupInterval: time.Minute,
+		LFUHalfLife:     time.Hour,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	get := func(key string, times int) {
+		for i := 0; i < times; i++ {
+			_, err := backend.Get(ctx, key)
+			require.NoError(t, err)
+		}
+	}
+
+	for _, key := range []string{"yesterday", "today", "filler"} {
+		require.NoError(t, backend.Set(ctx, key, []byte("v"), 0))
+	}
+
+	// Hot yesterday, then several half-lives pass
+	get("yesterday", 100)
+	for i := 0; i < 5; i++ {
+		backend.decayFrequencies()
+	}
+
+	// Hot today
+	get("today", 10)
+	get("filler", 5)
+
+	require.NoError(t, backend.Set(ctx, "new", []byte("v"), 0))
+
+	exists, err := backend.Exists(ctx, "yesterday")
+	require.NoError(t, err)
+	assert.False(t, exists, "formerly hot key should have decayed and been evicted")
+
+	for _, key := range []string{"today", "filler", "new"} {
+		exists, err := backend.Exists(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, exists, key)
+	}
+}
+
+func TestLFUDecayRunsPeriodically(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		EvictionPolicy:  "lfu",
+		CleanupInterval: time.Minute,
+		LFUHalfLife:     10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	require.NoError(t, backend.Set(ctx, "key", []byte("v"), 0))
+	for i := 0; i < 64; i++ {
+		_, err := backend.Get(ctx, "key")
+		require.NoError(t, err)
+	}
+
+	assert.Eventually(t, func() bool {
+		s := backend.stripes[0]
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return atomic.LoadInt64(&s.data["key"].accessCount) < 64
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestDisableCleanupExpiresLazily(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		CleanupInterval: time.Millisecond,
+		DisableCleanup:  true,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, backend.stopCleanup)
+
+	ctx := context.Background()
+	require.NoError(t, backend.Set(ctx, "get", []byte("v"), 10*time.Millisecond))
+	require.NoError(t, backend.Set(ctx, "exists", []byte("v"), 10*time.Millisecond))
+	require.NoError(t, backend.Set(ctx, "expire", []byte("v"), 10*time.Millisecond))
+	require.NoError(t, backend.Set(ctx, "ttl", []byte("v"), 10*time.Millisecond))
+	require.NoError(t, backend.Set(ctx, "persist", []byte("v"), 10*time.Millisecond))
+	time.Sleep(30 * time.Millisecond)
+
+	// Nothing swept the expired keys in the background
+	s := backend.stripes[0]
+	s.mu.RLock()
+	assert.Len(t, s.data, 5)
+	s.mu.RUnlock()
+
+	// Expired keys cannot be brought back
+	assert.ErrorIs(t, backend.Expire(ctx, "expire", time.Hour), ErrNotFound)
+	assert.ErrorIs(t, backend.Persist(ctx, "persist"), ErrNotFound)
+	_, err = backend.Get(ctx, "expire")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = backend.TTL(ctx, "ttl")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = backend.Get(ctx, "get")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	exists, err := backend.Exists(ctx, "exists")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	s.mu.RLock()
+	assert.Empty(t, s.data)
+	s.mu.RUnlock()
+
+	assert.NotPanics(t, func() { backend.Close() })
+}
+
+func TestDisableCleanupKeepsDecay(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		DisableCleanup: true,
+		LFUHalfLife:    time.Minute,
+	})
+	require.NoError(t, err)
+
+	// The decay goroutine still needs a stop channel
+	require.NotNil(t, backend.stopCleanup)
+	assert.NotPanics(t, func() { backend.Close() })
+}
+
+func TestMemoryCloseTwice(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		CleanupInterval: time.Minute,
+		LFUHalfLife:     time.Minute,
+	})
+	require.NoError(t, err)
+
+	assert.NotPanics(t, func() {
+		assert.NoError(t, backend.Close())
+		assert.NoError(t, backend.Close())
+	})
+}
+
+func TestMemoryResetStats(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		MaxKeys:         2,
+		EvictionPolicy:  "lru",
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, backend.Set(ctx, key, []byte(key), 0))
+	}
+	_, err = backend.Get(ctx, "c")
+	require.NoError(t, err)
+	_, err = backend.Get(ctx, "a")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, backend.Delete(ctx, "b"))
+
+	// Let the uptime pass a second so its restart shows
+	atomic.AddInt64(&backend.stats.startTime, -int64(2*time.Second))
+	stats, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	usage := stats.MemoryUsage
+	assert.Equal(t, &Stats{Hits: 1, Misses: 1, Sets: 3, Deletes: 1, Evictions: 1, KeyCount: 1, MemoryUsage: usage, Uptime: 2}, stats)
+
+	require.NoError(t, backend.ResetStats(ctx))
+	stats, err = backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Stats{KeyCount: 1, MemoryUsage: usage}, stats)
+}
+
+// manualClock is a Clock that only moves when advanced.
+type manualClock struct {
+	now atomic.Int64
+}
+
+func newManualClock() *manualClock {
+	c := &manualClock{}
+	c.now.Store(time.Unix(1700000000, 0).UnixNano())
+	return c
+}
+
+func (c *manualClock) Now() time.Time {
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.now.Add(int64(d))
+}
+
+func TestMemoryPurgeExpired(t *testing.T) {
+	clock := newManualClock()
+	backend, err := NewMemoryBackendWithClock(config.MemoryConfig{DisableCleanup: true}, clock)
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	require.NoError(t, backend.Set(ctx, "short:1", []byte("value"), time.Second))
+	require.NoError(t, backend.Set(ctx, "short:2", []byte("value"), time.Second))
+	require.NoError(t, backend.Set(ctx, "long", []byte("value"), time.Hour))
+	require.NoError(t, backend.Set(ctx, "forever", []byte("value"), 0))
+
+	removed, err := backend.PurgeExpired(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+
+	before, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), before.KeyCount)
+
+	// Expired items linger until purged
+	clock.Advance(2 * time.Second)
+	size, err := backend.Size(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), size)
+
+	removed, err = backend.PurgeExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	after, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), after.KeyCount)
+	assert.Less(t, after.MemoryUsage, before.MemoryUsage)
+
+	for _, key := range []string{"long", "forever"} {
+		_, err = backend.Get(ctx, key)
+		assert.NoError(t, err, key)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = backend.PurgeExpired(canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMemorySetIfNewer(t *testing.T) {
+	clock := newManualClock()
+	backend, err := NewMemoryBackendWithClock(config.MemoryConfig{DisableCleanup: true}, clock)
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	stored, err := backend.SetIfNewer(ctx, "key", []byte("v5"), 5, time.Second)
+	require.NoError(t, err)
+	assert.True(t, stored)
+
+	for _, version := range []int64{4, 5} {
+		stored, err = backend.SetIfNewer(ctx, "key", []byte("stale"), version, time.Second)
+		require.NoError(t, err)
+		assert.False(t, stored, version)
+	}
+
+	stored, err = backend.SetIfNewer(ctx, "key", []byte("v6"), 6, time.Second)
+	require.NoError(t, err)
+	assert.True(t, stored)
+
+	value, err := backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v6"), value)
+
+	// An expired value no longer holds its version
+	clock.Advance(2 * time.Second)
+	stored, err = backend.SetIfNewer(ctx, "key", []byte("v1"), 1, time.Second)
+	require.NoError(t, err)
+	assert.True(t, stored)
+
+	testSetIfNewerReset(t, backend)
+}
+
+// versionedBackend is a backend supporting SetIfNewer.
+type versionedBackend interface {
+	Backend
+	VersionedSetter
+}
+
+// testSetIfNewerReset checks that every other write or delete of a key resets
+// the version recorded by SetIfNewer, while Expire keeps it. Memory and
+// Redis share it so both follow the same rule.
+func testSetIfNewerReset(t *testing.T, backend versionedBackend) {
+	ctx := context.Background()
+
+	resets := map[string]func(key string) error{
+		"Set": func(key string) error { return backend.Set(ctx, key, []byte("plain"), time.Minute) },
+		"SetMulti": func(key string) error {
+			return backend.SetMulti(ctx, map[string][]byte{key: []byte("plain")}, time.Minute)
+		},
+		"GetSet": func(key string) error {
+			_, err := backend.GetSet(ctx, key, []byte("plain"))
+			return err
+		},
+		"Delete":      func(key string) error { return backend.Delete(ctx, key) },
+		"DeleteMulti": func(key string) error { return backend.DeleteMulti(ctx, []string{key}) },
+	}
+	for op, reset := range resets {
+		key := "reset:" + op
+		stored, err := backend.SetIfNewer(ctx, key, []byte("v5"), 5, time.Minute)
+		require.NoError(t, err, op)
+		require.True(t, stored, op)
+
+		require.NoError(t, reset(key), op)
+
+		stored, err = backend.SetIfNewer(ctx, key, []byte("v3"), 3, time.Minute)
+		require.NoError(t, err, op)
+		assert.True(t, stored, op)
+	}
+
+	key := "reset:Expire"
+	stored, err := backend.SetIfNewer(ctx, key, []byte("v5"), 5, time.Minute)
+	require.NoError(t, err)
+	require.True(t, stored)
+	require.NoError(t, backend.Expire(ctx, key, time.Hour))
+
+	stored, err = backend.SetIfNewer(ctx, key, []byte("v3"), 3, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, stored)
+}
+
+func TestMemoryExtendedStats(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		EvictionPolicy:  "lru",
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		require.NoError(t, backend.Set(ctx, key, []byte("v"), 0))
+		for j := 0; j < i; j++ {
+			_, err := backend.Get(ctx, key)
+			require.NoError(t, err)
+		}
+	}
+
+	extra, err := backend.ExtendedStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "lru", extra["eviction_policy"])
+	assert.Equal(t, int64(4), extra["access_count_p50"])
+	assert.Equal(t, int64(8), extra["access_count_p90"])
+	assert.Equal(t, int64(9), extra["access_count_max"])
+	assert.Equal(t, int64(1), extra["keys_never_read"])
+	assert.Equal(t, int64(0), extra["keys_with_ttl"])
+}
+
+func TestMemoryOverhead(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	assert.Zero(t, backend.MemoryOverhead().Ratio)
+
+	const keys = 20000
+	ctx := context.Background()
+
+	// Collect twice so sync.Pool buffers left by other tests are freed
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for i := 0; i < keys; i++ {
+		require.NoError(t, backend.Set(ctx, fmt.Sprintf("key-%06d", i), make([]byte, 64), 0))
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	overhead := backend.MemoryOverhead()
+	assert.Equal(t, int64(keys*entrySize("key-000000", make([]byte, 64))), overhead.AccountedBytes)
+
+	// Accounting keys and per-entry overhead keeps the ratio close to 1
+	assert.InDelta(t, 1, overhead.Ratio, 0.25)
+
+	// The estimate tracks the measured heap growth within allocator slack
+	measured := float64(after.HeapAlloc - before.HeapAlloc)
+	assert.InDelta(t, 1, float64(overhead.EstimatedBytes)/measured, 0.5)
+
+	extra, err := backend.ExtendedStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, overhead.Ratio, extra["overhead_ratio"])
+}
+
+func TestMemoryExpireCallback(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	var calls int32
+	fired := make(chan string, 1)
+	require.NoError(t, backend.SetWithExpireCallback(ctx, "session", []byte("v"), 30*time.Millisecond, func(key string) {
+		atomic.AddInt32(&calls, 1)
+		fired <- key
+	}))
+
+	select {
+	case key := <-fired:
+		assert.Equal(t, "session", key)
+	case <-time.After(time.Second):
+		t.Fatal("expire callback not called")
+	}
+
+	// Later cleanups and reads must not fire it again
+	time.Sleep(50 * time.Millisecond)
+	_, err = backend.Get(ctx, "session")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestMemoryExpireCallbackLazy(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{DisableCleanup: true})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	fired := make(chan string, 2)
+	require.NoError(t, backend.SetWithExpireCallback(ctx, "session", []byte("v"), 10*time.Millisecond, func(key string) {
+		fired <- key
+	}))
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, fired, "callback fired before the expired key was noticed")
+
+	_, err = backend.Get(ctx, "session")
+	assert.ErrorIs(t, err, ErrNotFound)
+	select {
+	case key := <-fired:
+		assert.Equal(t, "session", key)
+	case <-time.After(time.Second):
+		t.Fatal("expire callback not called")
+	}
+
+	stats, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.MemoryUsage)
+}
+
+func TestMemoryExpireCallbackDroppedOnOverwrite(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: 5 * time.Millisecond})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	var calls int32
+	require.NoError(t, backend.SetWithExpireCallback(ctx, "a", []byte("v"), 10*time.Millisecond, func(string) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	require.NoError(t, backend.SetWithExpireCallback(ctx, "b", []byte("v"), 10*time.Millisecond, func(string) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	require.NoError(t, backend.Set(ctx, "a", []byte("new"), 10*time.Millisecond))
+	require.NoError(t, backend.Delete(ctx, "b"))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, atomic.LoadInt32(&calls))
+}
+
+func TestMemoryAlias(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	_, err = backend.ResolveAlias(ctx, "data")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = backend.SwapAlias(ctx, "data", "data:v1")
+	assert.ErrorIs(t, err, ErrNotFound, "only existing aliases can be swapped")
+
+	require.NoError(t, backend.SetAlias(ctx, "data", "data:v1"))
+	target, err := backend.ResolveAlias(ctx, "data")
+	require.NoError(t, err)
+	assert.Equal(t, "data:v1", target)
+
+	previous, err := backend.SwapAlias(ctx, "data", "data:v2")
+	require.NoError(t, err)
+	assert.Equal(t, "data:v1", previous)
+	target, err = backend.ResolveAlias(ctx, "data")
+	require.NoError(t, err)
+	assert.Equal(t, "data:v2", target)
+
+	// Aliases are not keys
+	exists, err := backend.Exists(ctx, "data")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	size, err := backend.Size(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, size)
+
+	require.NoError(t, backend.Clear(ctx))
+	_, err = backend.ResolveAlias(ctx, "data")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemorySetNX(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	stored, err := backend.SetNX(ctx, "lock", []byte("a"), 20*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, stored)
+
+	stored, err = backend.SetNX(ctx, "lock", []byte("b"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, stored)
+	value, err := backend.Get(ctx, "lock")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), value)
+
+	// An expired entry does not block SetNX
+	time.Sleep(30 * time.Millisecond)
+	stored, err = backend.SetNX(ctx, "lock", []byte("c"), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, stored)
+	value, err = backend.Get(ctx, "lock")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("c"), value)
+}
+
+func TestMemoryAcquireLeaseLimits(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		MaxKeys:         2,
+		CleanupInterval: time.Minute,
+		NamespaceQuotas: map[string]config.NamespaceQuota{
+			"locks": {MaxKeys: 1},
+		},
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	require.NoError(t, backend.Set(ctx, "a", []byte("1"), 0))
+	require.NoError(t, backend.Set(ctx, "b", []byte("2"), 0))
+
+	// A lease is stored like Set, evicting to stay within MaxKeys
+	acquired, err := backend.AcquireLease(ctx, "locks:report", "token", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	stats, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.KeyCount)
+	assert.Equal(t, int64(1), stats.Evictions)
+	assert.Equal(t, int64(3), stats.Sets)
+
+	// and counts against its namespace quota
+	_, err = backend.AcquireLease(ctx, "locks:export", "token", time.Minute)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+}
+
+func TestMemoryGetSet(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		CleanupInterval: time.Minute,
+		DefaultTTL:      time.Hour,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	old, err := backend.GetSet(ctx, "key", []byte("a"))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, old)
+	ttl, err := backend.TTL(ctx, "key")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), ttl.Seconds(), 1, "new keys get the default TTL like Set")
+
+	require.NoError(t, backend.Set(ctx, "key", []byte("b"), time.Minute))
+	old, err = backend.GetSet(ctx, "key", []byte("c"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), old)
+
+	value, err := backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("c"), value)
+	ttl, err = backend.TTL(ctx, "key")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 1, "the swap keeps the remaining TTL")
+}
+
+func TestMemoryListPushCapped(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	values, err := backend.ListRange(ctx, "events")
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	// The ring wraps around several times, keeping the newest three
+	for i := 0; i < 8; i++ {
+		require.NoError(t, backend.ListPushCapped(ctx, "events", []byte(strconv.Itoa(i)), 3, 0))
+	}
+	values, err = backend.ListRange(ctx, "events")
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("7"), []byte("6"), []byte("5")}, values)
+
+	// Growing the limit keeps the values, shrinking it drops the oldest
+	require.NoError(t, backend.ListPushCapped(ctx, "events", []byte("8"), 5, 0))
+	values, err = backend.ListRange(ctx, "events")
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("8"), []byte("7"), []byte("6"), []byte("5")}, values)
+
+	require.NoError(t, backend.ListPushCapped(ctx, "events", []byte("9"), 2, 0))
+	values, err = backend.ListRange(ctx, "events")
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("9"), []byte("8")}, values)
+
+	assert.Error(t, backend.ListPushCapped(ctx, "events", []byte("10"), 0, 0))
+
+	// Lists are not keys
+	exists, err := backend.Exists(ctx, "events")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Expired lists read as empty, start over on push and are dropped by
+	// cleanup
+	require.NoError(t, backend.ListPushCapped(ctx, "recent", []byte("a"), 3, 20*time.Millisecond))
+	time.Sleep(30 * time.Millisecond)
+	values, err = backend.ListRange(ctx, "recent")
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	require.NoError(t, backend.ListPushCapped(ctx, "recent", []byte("b"), 3, 20*time.Millisecond))
+	values, err = backend.ListRange(ctx, "recent")
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("b")}, values)
+
+	time.Sleep(30 * time.Millisecond)
+	backend.cleanupExpired()
+	backend.mu.RLock()
+	assert.NotContains(t, backend.lists, "recent")
+	assert.Contains(t, backend.lists, "events")
+	backend.mu.RUnlock()
+}
+
+func TestMemoryRateLimitSlidingWindow(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	window := 100 * time.Millisecond
+
+	allowed, _, err := backend.RateLimitAllow(ctx, "key", 2, window)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	time.Sleep(60 * time.Millisecond)
+	allowed, remaining, err := backend.RateLimitAllow(ctx, "key", 2, window)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Zero(t, remaining)
+
+	allowed, _, err = backend.RateLimitAllow(ctx, "key", 2, window)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Only the first request has left the window
+	time.Sleep(60 * time.Millisecond)
+	allowed, _, err = backend.RateLimitAllow(ctx, "key", 2, window)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, _, err = backend.RateLimitAllow(ctx, "key", 2, window)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Windows that emptied are dropped by cleanup
+	time.Sleep(window)
+	backend.cleanupExpired()
+	backend.mu.RLock()
+	assert.Empty(t, backend.rateLimits)
+	backend.mu.RUnlock()
+
+	// Rate limits are not keys
+	exists, err := backend.Exists(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestMemoryNamespaceQuota(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		CleanupInterval: time.Minute,
+		NamespaceQuotas: map[string]config.NamespaceQuota{
+			"tenant1": {MaxKeys: 3},
+			"tenant2": {MaxKeys: 3},
+		},
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, backend.Set(ctx, fmt.Sprintf("tenant1:%d", i), []byte("v"), time.Minute))
+	}
+	err = backend.Set(ctx, "tenant1:3", []byte("v"), time.Minute)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = backend.Get(ctx, "tenant1:3")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Overwriting an existing key does not add a key
+	require.NoError(t, backend.Set(ctx, "tenant1:0", []byte("updated"), time.Minute))
+
+	// The other namespace and keys without a quota are unaffected
+	for i := 0; i < 3; i++ {
+		require.NoError(t, backend.Set(ctx, fmt.Sprintf("tenant2:%d", i), []byte("v"), time.Minute))
+	}
+	for i := 0; i < 10; i++ {
+		require.NoError(t, backend.Set(ctx, fmt.Sprintf("shared:%d", i), []byte("v"), time.Minute))
+	}
+
+	// Deleting frees quota
+	require.NoError(t, backend.Delete(ctx, "tenant1:1"))
+	require.NoError(t, backend.Set(ctx, "tenant1:3", []byte("v"), time.Minute))
+
+	// Batches are rejected as a whole
+	err = backend.SetMulti(ctx, map[string][]byte{"tenant2:3": []byte("v"), "shared:x": []byte("v")}, time.Minute)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	exists, err := backend.Exists(ctx, "shared:x")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = backend.Increment(ctx, "tenant2:counter", 1)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+}
+
+func TestMemoryNamespaceQuotaSize(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		CleanupInterval: time.Minute,
+		NamespaceQuotas: map[string]config.NamespaceQuota{
+			"tenant1": {MaxSize: "1KB"},
+		},
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	value := make([]byte, 300)
+	require.NoError(t, backend.Set(ctx, "tenant1:a", value, time.Minute))
+	require.NoError(t, backend.Set(ctx, "tenant1:b", value, 20*time.Millisecond))
+	err = backend.Set(ctx, "tenant1:c", value, time.Minute)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// Expired entries are reclaimed when a namespace runs out of quota
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, backend.Set(ctx, "tenant1:c", value, time.Minute))
+
+	usage := backend.usage["tenant1"]
+	assert.Equal(t, int64(2), usage.keys.Load())
+	assert.Equal(t, int64(2*entrySize("tenant1:a", value)), usage.size.Load())
+
+	require.NoError(t, backend.Clear(ctx))
+	require.NoError(t, backend.Set(ctx, "tenant1:a", value, time.Minute))
+	require.NoError(t, backend.Set(ctx, "tenant1:b", value, time.Minute))
+}
+
+func TestMemoryNamespaceQuotaExpiredElsewhere(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		Stripes:        8,
+		DisableCleanup: true,
+		NamespaceQuotas: map[string]config.NamespaceQuota{
+			"tenant1": {MaxKeys: 4},
+		},
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	// Fill the quota with short-lived keys outside the stripe of the new key
+	ctx := context.Background()
+	var keys []string
+	for i := 0; len(keys) < 4; i++ {
+		key := fmt.Sprintf("tenant1:%d", i)
+		if backend.stripe(key) != backend.stripe("tenant1:new") {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		require.NoError(t, backend.Set(ctx, key, []byte("v"), 20*time.Millisecond))
+	}
+	require.NoError(t, backend.Expire(ctx, keys[0], time.Minute))
+	require.NoError(t, backend.Persist(ctx, keys[1]))
+	err = backend.Set(ctx, "tenant1:new", []byte("v"), time.Minute)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// Expired entries of other stripes are reclaimed, unlike the ones whose
+	// expiration was extended or removed
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, backend.Set(ctx, "tenant1:new", []byte("v"), time.Minute))
+	require.NoError(t, backend.Set(ctx, "tenant1:other", []byte("v"), time.Minute))
+	err = backend.Set(ctx, "tenant1:last", []byte("v"), time.Minute)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	for _, key := range keys[:2] {
+		exists, err := backend.Exists(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, exists, key)
+	}
+}
+
+func TestMemoryNamespaceQuotaLocks(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		Stripes:         4,
+		CleanupInterval: time.Minute,
+		NamespaceQuotas: map[string]config.NamespaceQuota{
+			"tenant1": {MaxKeys: 10},
+			"tenant2": {MaxKeys: 10},
+		},
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	// A write holding the tenant1 lock does not block other namespaces
+	backend.usage["tenant1"].mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx := context.Background()
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, backend.Set(ctx, fmt.Sprintf("tenant2:%d", i), []byte("v"), 0))
+			assert.NoError(t, backend.Set(ctx, fmt.Sprintf("other:%d", i), []byte("v"), 0))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("writes to other namespaces waited for the tenant1 lock")
+	}
+	backend.usage["tenant1"].mu.Unlock()
+}
+
+func TestMemoryKeys(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"user:1", "user:2", "user:1:profile", "team:1:profile", "session:1"} {
+		require.NoError(t, backend.Set(ctx, key, []byte("v"), time.Minute))
+	}
+	require.NoError(t, backend.Set(ctx, "user:expired", []byte("v"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	for _, tc := range []struct {
+		pattern  string
+		expected []string
+	}{
+		{"user:*", []string{"user:1", "user:1:profile", "user:2"}},
+		{"*:profile", []string{"team:1:profile", "user:1:profile"}},
+		{"user:?", []string{"user:1", "user:2"}},
+		{"session:1", []string{"session:1"}},
+		{"missing:*", nil},
+	} {
+		keys, err := backend.Keys(ctx, tc.pattern)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, tc.expected, keys, tc.pattern)
+	}
+}
+
+func TestMemoryIncrementExpired(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		DefaultTTL:      time.Hour,
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	require.NoError(t, backend.Set(ctx, "counter", []byte("41"), 10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+
+	// The expired value is not read back
+	value, err := backend.Increment(ctx, "counter", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+
+	// New counters expire after the default TTL, like Set
+	ttl, err := backend.TTL(ctx, "counter")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), ttl.Seconds(), 1)
+
+	require.NoError(t, backend.Set(ctx, "text", []byte(`"41"`), 0))
+	_, err = backend.Increment(ctx, "text", 1)
+	assert.Error(t, err)
+}
+
+func TestMemoryIncrementSizeAccounting(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 1000; i++ {
+		_, err := backend.Increment(ctx, "counter", 1)
+		require.NoError(t, err)
+	}
+	_, err = backend.Decrement(ctx, "counter", 995)
+	require.NoError(t, err)
+
+	stats, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(entrySize("counter", []byte("5"))), stats.MemoryUsage)
+
+	require.NoError(t, backend.Delete(ctx, "counter"))
+	stats, err = backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.MemoryUsage)
+}
+
+func TestMemoryIncrementWithTTLOnCreate(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	value, err := backend.IncrementWithTTLOnCreate(ctx, "counter", 2, 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), value)
+	first, err := backend.TTL(ctx, "counter")
+	require.NoError(t, err)
+	assert.InDelta(t, (100 * time.Millisecond).Seconds(), first.Seconds(), 0.02)
+
+	time.Sleep(30 * time.Millisecond)
+	value, err = backend.IncrementWithTTLOnCreate(ctx, "counter", 3, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+	second, err := backend.TTL(ctx, "counter")
+	require.NoError(t, err)
+	assert.Less(t, second, first, "later increments do not reset the TTL")
+
+	// Once the counter expires, the next increment starts a new window
+	time.Sleep(second + 10*time.Millisecond)
+	value, err = backend.IncrementWithTTLOnCreate(ctx, "counter", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+	ttl, err := backend.TTL(ctx, "counter")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 1)
+
+	// A zero TTL creates a counter that does not expire
+	_, err = backend.IncrementWithTTLOnCreate(ctx, "forever", 1, 0)
+	require.NoError(t, err)
+	ttl, err = backend.TTL(ctx, "forever")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), ttl)
+}
+
+func TestMemoryDeleteByPattern(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"products:1", "products:2", "products:all", "product:1", "users:1"} {
+		require.NoError(t, backend.Set(ctx, key, []byte("v"), time.Minute))
+	}
+	require.NoError(t, backend.Set(ctx, "products:expired", []byte("v"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	deleted, err := backend.DeleteByPattern(ctx, "products:*")
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted, "expired keys are removed but not counted")
+
+	keys, err := backend.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"product:1", "users:1"}, keys)
+	size, err := backend.Size(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), size)
+
+	deleted, err = backend.DeleteByPattern(ctx, "products:*")
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestMemorySize(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	assertSize := func(expected int64) {
+		t.Helper()
+		size, err := backend.Size(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, expected, size)
+
+		stats, err := backend.Stats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, stats.KeyCount, size)
+	}
+
+	assertSize(0)
+	require.NoError(t, backend.Set(ctx, "a", []byte("1"), 0))
+	require.NoError(t, backend.Set(ctx, "a", []byte("2"), 0))
+	require.NoError(t, backend.SetMulti(ctx, map[string][]byte{"b": []byte("1"), "c": []byte("1")}, 0))
+	_, err = backend.Increment(ctx, "counter", 1)
+	require.NoError(t, err)
+	assertSize(4)
+
+	require.NoError(t, backend.Delete(ctx, "a"))
+	require.NoError(t, backend.Set(ctx, "short", []byte("1"), time.Millisecond))
+	assertSize(4)
+
+	time.Sleep(5 * time.Millisecond)
+	backend.cleanupExpired()
+	assertSize(3)
+
+	require.NoError(t, backend.Clear(ctx))
+	assertSize(0)
+}
+
+func TestMemoryGetConcurrent(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		EvictionPolicy:  "lfu",
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	require.NoError(t, backend.Set(ctx, "hot", []byte("1"), time.Minute))
+
+	const readers, writers, reads = 16, 4, 1000
+	var wg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < reads; i++ {
+				_, err := backend.Get(ctx, "hot")
+				assert.NoError(t, err)
+				_, _ = backend.TTL(ctx, "hot")
+			}
+		}()
+	}
+
+	// Writers update the same item in place while it is being read
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < reads; i++ {
+				_, _ = backend.Increment(ctx, "hot", 1)
+				_ = backend.Expire(ctx, "hot", time.Minute)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := backend.stripes[0]
+	s.mu.RLock()
+	item := s.data["hot"]
+	s.mu.RUnlock()
+	assert.Equal(t, int64(readers*reads), atomic.LoadInt64(&item.accessCount))
+	assert.WithinDuration(t, time.Now(), item.lastAccess(), time.Second)
+
+	value, err := backend.Get(ctx, "hot")
+	require.NoError(t, err)
+	assert.Equal(t, []byte(fmt.Sprint(1+writers*reads)), value)
+}
+
+func TestMemoryKeyCountConcurrent(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		MaxKeys:         200,
+		EvictionPolicy:  "lru",
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := fmt.Sprintf("key-%d", (w*7919+i)%500)
+				switch i % 5 {
+				case 0, 1:
+					_ = backend.Set(ctx, key, []byte("value"), 0)
+				case 2:
+					_ = backend.Set(ctx, key, []byte("short"), time.Microsecond)
+				case 3:
+					_ = backend.Delete(ctx, key)
+				case 4:
+					_, _ = backend.Exists(ctx, key)
+				}
+				if i%500 == 0 {
+					backend.cleanupExpired()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assertCount := func() {
+		t.Helper()
+		s := backend.stripes[0]
+		s.mu.RLock()
+		expected := int64(len(s.data))
+		s.mu.RUnlock()
+
+		size, err := backend.Size(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, expected, size)
+
+		stats, err := backend.Stats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, expected, stats.KeyCount)
+	}
+
+	assertCount()
+	assert.LessOrEqual(t, backend.keyCount.Load(), int64(200))
+
+	time.Sleep(time.Millisecond)
+	backend.cleanupExpired()
+	assertCount()
+}
+
+func TestMemorySetMultiBatched(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		MaxKeys:         100,
+		EvictionPolicy:  "lru",
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	require.NoError(t, backend.Set(ctx, "old", []byte("old-value"), 0))
+	require.NoError(t, backend.Set(ctx, "item-0", []byte("replaced"), 0))
+	time.Sleep(time.Millisecond)
+
+	items := make(map[string][]byte)
+	for i := 0; i < 100; i++ {
+		items[fmt.Sprintf("item-%d", i)] = []byte(fmt.Sprintf("value-%d", i))
+	}
+	require.NoError(t, backend.SetMulti(ctx, items, time.Minute))
+
+	// The least recently used key made room for the batch
+	_, err = backend.Get(ctx, "old")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for key, value := range items {
+		got, err := backend.Get(ctx, key)
+		require.NoError(t, err, key)
+		assert.Equal(t, value, got, key)
+
+		ttl, err := backend.TTL(ctx, key)
+		require.NoError(t, err)
+		assert.Greater(t, ttl, 59*time.Second)
+	}
+
+	stats, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), stats.KeyCount)
+	assert.Equal(t, int64(102), stats.Sets)
+	assert.Equal(t, int64(1), stats.Evictions)
+
+	var size int64
+	for key, value := range items {
+		size += int64(entrySize(key, value))
+	}
+	assert.Equal(t, size, stats.MemoryUsage)
+}
+
+func TestMemorySetMultiMaxSize(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		MaxSize:         "1KB",
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	items := make(map[string][]byte)
+	for i := 0; i < 20; i++ {
+		items[fmt.Sprintf("item-%d", i)] = make([]byte, 100)
+	}
+	require.NoError(t, backend.SetMulti(context.Background(), items, 0))
+
+	stats, err := backend.Stats(context.Background())
+	require.NoError(t, err)
+	assert.LessOrEqual(t, stats.MemoryUsage, int64(1024))
+	assert.Equal(t, int64(1024/entrySize("item-10", items["item-10"])), stats.KeyCount)
+}
+
+func TestMemorySizeCountsKeys(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		MaxSize:         "64KB",
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	prefix := strings.Repeat("k", 500)
+	for i := 0; i < 50; i++ {
+		require.NoError(t, backend.Set(ctx, fmt.Sprintf("%s-%02d", prefix, i), []byte("v"), 0))
+	}
+
+	stats, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(50*(len(prefix)+3+1+entryOverhead)), stats.MemoryUsage)
+
+	// Tiny values under long keys still fill MaxSize
+	for i := 50; i < 200; i++ {
+		require.NoError(t, backend.Set(ctx, fmt.Sprintf("%s-%03d", prefix, i), []byte("v"), 0))
+	}
+	stats, err = backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, stats.MemoryUsage, int64(64*1024))
+	assert.Positive(t, stats.Evictions)
+
+	key := fmt.Sprintf("%s-%03d", prefix, 199)
+	require.NoError(t, backend.Delete(ctx, key))
+	after, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, stats.MemoryUsage-int64(entrySize(key, []byte("v"))), after.MemoryUsage)
+
+	require.NoError(t, backend.Clear(ctx))
+	stats, err = backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.MemoryUsage)
+}
+
+func BenchmarkMemorySetMulti(b *testing.B) {
+	items := make(map[string][]byte)
+	for i := 0; i < 10000; i++ {
+		items[fmt.Sprintf("key-%05d", i)] = []byte("value")
+	}
+
+	b.Run("batched", func(b *testing.B) {
+		backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+		require.NoError(b, err)
+		defer backend.Close()
+
+		ctx := context.Background()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = backend.SetMulti(ctx, items, time.Minute)
+		}
+	})
+
+	b.Run("per-key", func(b *testing.B) {
+		backend, err := NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+		require.NoError(b, err)
+		defer backend.Close()
+
+		ctx := context.Background()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for key, value := range items {
+				_ = backend.Set(ctx, key, value, time.Minute)
+			}
+		}
+	})
+}
+
+func TestMemoryEvictionOrder(t *testing.T) {
+	tests := []struct {
+		policy  string
+		access  func(backend *MemoryBackend, key string)
+		evicted string
+	}{
+		{
+			// Overwriting counts as a use, so b is now the least recent
+			policy: "lru",
+			access: func(backend *MemoryBackend, key string) {
+				require.NoError(t, backend.Set(context.Background(), key, []byte("v2"), 0))
+			},
+			evicted: "b",
+		},
+		{
+			// Reads raise a and c above b, which is never read
+			policy: "lfu",
+			access: func(backend *MemoryBackend, key string) {
+				_, err := backend.Get(context.Background(), key)
+				require.NoError(t, err)
+			},
+			evicted: "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			backend, err := NewMemoryBackend(config.MemoryConfig{
+				MaxKeys:         3,
+				EvictionPolicy:  tt.policy,
+				CleanupInterval: time.Minute,
+			})
+			require.NoError(t, err)
+			defer backend.Close()
+
+			ctx := context.Background()
+			fill := func() {
+				for _, key := range []string{"a", "b", "c"} {
+					require.NoError(t, backend.Set(ctx, key, []byte("v"), 0))
+				}
+				tt.access(backend, "a")
+				tt.access(backend, "c")
+				require.NoError(t, backend.Set(ctx, "d", []byte("v"), 0))
+			}
+
+			fill()
+			exists, err := backend.Exists(ctx, tt.evicted)
+			require.NoError(t, err)
+			assert.False(t, exists)
+
+			// The order starts over after Clear
+			require.NoError(t, backend.Clear(ctx))
+			fill()
+			for _, key := range []string{"a", "b", "c", "d"} {
+				exists, err := backend.Exists(ctx, key)
+				require.NoError(t, err)
+				assert.Equal(t, key != tt.evicted, exists, key)
+			}
+
+			stats, err := backend.Stats(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), stats.KeyCount)
+		})
+	}
+}
+
+func TestMemoryEvictionOrderConcurrent(t *testing.T) {
+	for _, policy := range []string{"lru", "lfu"} {
+		t.Run(policy, func(t *testing.T) {
+			backend, err := NewMemoryBackend(config.MemoryConfig{
+				MaxKeys:         50,
+				EvictionPolicy:  policy,
+				CleanupInterval: time.Minute,
+			})
+			require.NoError(t, err)
+			defer backend.Close()
+
+			ctx := context.Background()
+			var wg sync.WaitGroup
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < 1000; i++ {
+						key := fmt.Sprintf("key-%d", (w*31+i)%100)
+						switch i % 4 {
+						case 0:
+							_ = backend.Set(ctx, key, []byte("v"), 0)
+						case 1:
+							_, _ = backend.Get(ctx, key)
+						case 2:
+							_, _ = backend.Increment(ctx, key, 1)
+						case 3:
+							_ = backend.Delete(ctx, key)
+						}
+						if i == 500 && w == 0 {
+							_ = backend.Clear(ctx)
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			// Every stored item is in the eviction order exactly once
+			s := backend.stripes[0]
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+			tracked := 0
+			if policy == "lfu" {
+				tracked = s.lfu.Len()
+				for _, item := range s.data {
+					assert.True(t, s.lfu.contains(item), item.key)
+				}
+			} else {
+				for item := s.lru.root.next; item != &s.lru.root; item = item.next {
+					assert.Same(t, s.data[item.key], item, item.key)
+					tracked++
+				}
+			}
+			assert.Equal(t, len(s.data), tracked)
+			assert.LessOrEqual(t, len(s.data), 50)
+		})
+	}
+}
+
+// BenchmarkMemoryEviction sets new keys into a full cache, so every Set
+// evicts. The time per Set should stay roughly flat as the key count grows.
+func BenchmarkMemoryEviction(b *testing.B) {
+	for _, policy := range []string{"lru", "lfu"} {
+		for _, keys := range []int{10000, 100000, 1000000} {
+			b.Run(fmt.Sprintf("%s/%d", policy, keys), func(b *testing.B) {
+				backend, err := NewMemoryBackend(config.MemoryConfig{
+					MaxKeys:         int64(keys),
+					EvictionPolicy:  policy,
+					CleanupInterval: time.Minute,
+				})
+				require.NoError(b, err)
+				defer backend.Close()
+
+				ctx := context.Background()
+				value := []byte("value")
+				for i := 0; i < keys; i++ {
+					_ = backend.Set(ctx, fmt.Sprintf("key-%d", i), value, 0)
+				}
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					_ = backend.Set(ctx, fmt.Sprintf("new-%d", i), value, 0)
+				}
+			})
+		}
+	}
+}
+
+func TestMemoryStripes(t *testing.T) {
+	backend, err := NewMemoryBackend(config.MemoryConfig{
+		Stripes:         5,
+		CleanupInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	defer backend.Close()
+
+	// The stripe count is rounded up to a power of two
+	require.Len(t, backend.stripes, 8)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := fmt.Sprintf("key-%d", (w*7919+i)%300)
+				switch i % 5 {
+				case 0, 1:
+					_ = backend.Set(ctx, key, []byte(strings.Repeat("v", i%7)), 0)
+				case 2:
+					_, _ = backend.Increment(ctx, "counter-"+key, 1)
+				case 3:
+					_ = backend.Delete(ctx, key)
+				case 4:
+					_, _ = backend.Get(ctx, key)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// The shared totals match the stripes, and keys live in their stripe
+	var keys, size int64
+	for _, s := range backend.stripes {
+		s.mu.RLock()
+		for key, item := range s.data {
+			assert.Same(t, s, backend.stripe(key), key)
+			keys++
+			size += int64(entrySize(key, item.value))
+		}
+		s.mu.RUnlock()
+	}
+	stats, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, keys, stats.KeyCount)
+	assert.Equal(t, size, stats.MemoryUsage)
+
+	all, err := backend.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Len(t, all, int(keys))
+
+	require.NoError(t, backend.Clear(ctx))
+	stats, err = backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.KeyCount)
+	assert.Zero(t, stats.MemoryUsage)
+}
+
+func TestMemoryStripesLimits(t *testing.T) {
+	newBackend := func(cfg config.MemoryConfig) *MemoryBackend {
+		cfg.Stripes = 8
+		cfg.CleanupInterval = time.Minute
+		backend, err := NewMemoryBackend(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { backend.Close() })
+		return backend
+	}
+	writeAll := func(write func(w, i int)) {
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					write(w, i)
+				}
+			}(w)
+		}
+		wg.Wait()
+	}
+	ctx := context.Background()
+
+	// Quota checks are atomic across stripes
+	quoted := newBackend(config.MemoryConfig{
+		NamespaceQuotas: map[string]config.NamespaceQuota{
+			"tenant1": {MaxKeys: 10},
+		},
+	})
+	var stored atomic.Int64
+	writeAll(func(w, i int) {
+		if quoted.Set(ctx, fmt.Sprintf("tenant1:%d-%d", w, i), []byte("v"), 0) == nil {
+			stored.Add(1)
+		}
+	})
+	assert.Equal(t, int64(10), stored.Load())
+
+	// Eviction keeps the total near MaxKeys, off by at most the writes that
+	// found their stripe empty and every other stripe busy
+	limited := newBackend(config.MemoryConfig{MaxKeys: 100})
+	writeAll(func(w, i int) {
+		_ = limited.Set(ctx, fmt.Sprintf("key-%d-%d", w, i), []byte("v"), 0)
+	})
+	size, err := limited.Size(ctx)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, size, int64(100+len(limited.stripes)))
+
+	stats, err := limited.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(800)-size, stats.Evictions)
+}
+
+func TestMemoryStripesEvictFromOtherStripes(t *testing.T) {
+	for name, cfg := range map[string]config.MemoryConfig{
+		"max keys": {MaxKeys: 12},
+		"max size": {MaxSize: strconv.Itoa(12 * entrySize("key-00", []byte("v")))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg.Stripes = 4
+			cfg.CleanupInterval = time.Minute
+			backend, err := NewMemoryBackend(cfg)
+			require.NoError(t, err)
+			defer backend.Close()
+
+			// Fill every stripe but the first up to the limit
+			ctx := context.Background()
+			empty := backend.stripes[0]
+			stored := 0
+			for i := 0; stored < 12; i++ {
+				key := fmt.Sprintf("key-%02d", i)
+				if backend.stripe(key) == empty {
+					continue
+				}
+				require.NoError(t, backend.Set(ctx, key, []byte("v"), 0))
+				stored++
+			}
+
+			// A write to the empty stripe evicts from another one
+			key := "key-00"
+			for i := 0; backend.stripe(key) != empty; i++ {
+				key = fmt.Sprintf("key-%c%d", 'a'+i%26, i%10)
+			}
+			require.NoError(t, backend.Set(ctx, key, []byte("v"), 0))
+
+			stats, err := backend.Stats(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, int64(12), stats.KeyCount)
+			assert.Equal(t, int64(1), stats.Evictions)
+			_, err = backend.Get(ctx, key)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMemoryStripeHash(t *testing.T) {
+	for _, name := range []string{"crc32", "fnv", "md5"} {
+		t.Run(name, func(t *testing.T) {
+			backend, err := NewMemoryBackend(config.MemoryConfig{
+				Stripes:         8,
+				HashFunction:    name,
+				CleanupInterval: time.Minute,
+			})
+			require.NoError(t, err)
+			defer backend.Close()
+
+			hash, err := hashing.New(name)
+			require.NoError(t, err)
+
+			// The keys one of two shards receives still use every stripe
+			used := make(map[*memoryStripe]int)
+			for i := 0; i < 1000; i++ {
+				key := fmt.Sprintf("key-%d", i)
+				if hashing.Index(hash, key, 2) != 0 {
+					continue
+				}
+				assert.Same(t, backend.stripes[hash(key)>>29], backend.stripe(key), key)
+				used[backend.stripe(key)]++
+			}
+			assert.Len(t, used, 8)
+		})
+	}
+
+	_, err := NewMemoryBackend(config.MemoryConfig{HashFunction: "sha1"})
+	assert.Error(t, err)
+}
+
+// BenchmarkMemorySetParallel writes distinct keys from every goroutine. With
+// several stripes the writes no longer queue behind a single lock.
+func BenchmarkMemorySetParallel(b *testing.B) {
+	for _, stripes := range []int{1, 16} {
+		b.Run(fmt.Sprintf("stripes-%d", stripes), func(b *testing.B) {
+			backend, err := NewMemoryBackend(config.MemoryConfig{
+				Stripes:         stripes,
+				CleanupInterval: time.Minute,
+			})
+			require.NoError(b, err)
+			defer backend.Close()
+
+			ctx := context.Background()
+			value := []byte("value")
+			var worker atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				prefix := fmt.Sprintf("worker-%d-", worker.Add(1))
+				i := 0
+				for pb.Next() {
+					_ = backend.Set(ctx, prefix+strconv.Itoa(i%10000), value, 0)
+					i++
+				}
+			})
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"512", 512, false},
+		{"512B", 512, false},
+		{"100MB", 100 << 20, false},
+		{"64kb", 64 << 10, false},
+		{"1.5 gb", 3 << 29, false},
+		{" 2TB ", 2 << 40, false},
+		{"0.5KB", 512, false},
+		{"bogus", 0, true},
+		{"MB", 0, true},
+		{"10 XB", 0, true},
+		{"1.2.3MB", 0, true},
+		{"-1MB", 0, true},
+		{"100MBx", 0, true},
+		{"99999999999TB", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSize(tt.size)
+		if tt.wantErr {
+			assert.Error(t, err, "size %q", tt.size)
+			continue
+		}
+		if assert.NoError(t, err, "size %q", tt.size) {
+			assert.Equal(t, tt.want, got, "size %q", tt.size)
+		}
+	}
+}
+
+func TestMemoryInvalidMaxSize(t *testing.T) {
+	_, err := NewMemoryBackend(config.MemoryConfig{MaxSize: "bogus"})
+	assert.ErrorContains(t, err, "invalid max size")
+
+	_, err = NewMemoryBackend(config.MemoryConfig{
+		NamespaceQuotas: map[string]config.NamespaceQuota{"tenant1": {MaxSize: "10 parsecs"}},
+	})
+	assert.ErrorContains(t, err, "invalid max size for namespace tenant1")
+}
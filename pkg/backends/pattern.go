@@ -0,0 +1,119 @@
+package backends
+
+// MatchPattern reports whether key matches a Redis-style glob pattern:
+// '*' matches any sequence of characters, '?' matches a single character,
+// '[abc]', '[a-z]' and '[^abc]' match character classes, and '\' escapes the
+// next character. Unlike path.Match, '*' also matches separators such as '/'.
+// Matching runs in O(len(pattern) * len(key)) time: only the most recent '*'
+// is retried on a mismatch, since an earlier one can never do better.
+func MatchPattern(pattern, key string) bool {
+	p, k := 0, 0
+	starP, starK := -1, 0
+	for k < len(key) {
+		if p < len(pattern) {
+			if pattern[p] == '*' {
+				for p < len(pattern) && pattern[p] == '*' {
+					p++
+				}
+				if p == len(pattern) {
+					return true
+				}
+				starP, starK = p, k
+				continue
+			}
+			if width, ok := matchToken(pattern[p:], key[k]); ok {
+				p += width
+				k++
+				continue
+			}
+		}
+
+		// Mismatch: let the last '*' absorb one more character
+		if starP < 0 {
+			return false
+		}
+		starK++
+		p, k = starP, starK
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchToken reports whether c matches the single-character token at the
+// start of pattern, which must not be '*', along with the token's width.
+func matchToken(pattern string, c byte) (int, bool) {
+	switch pattern[0] {
+	case '?':
+		return 1, true
+	case '[':
+		end := classEnd(pattern)
+		if end < 0 {
+			// Unterminated class, match '[' literally
+			return 1, c == '['
+		}
+		return end + 1, matchClass(pattern[1:end], c)
+	case '\\':
+		if len(pattern) > 1 {
+			return 2, c == pattern[1]
+		}
+		return 1, c == '\\'
+	default:
+		return 1, c == pattern[0]
+	}
+}
+
+// classEnd returns the index of the ']' closing the class that starts at
+// pattern[0], or -1 if the class is unterminated.
+func classEnd(pattern string) int {
+	i := 1
+	if i < len(pattern) && pattern[i] == '^' {
+		i++
+	}
+	for ; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// matchClass reports whether c matches the body of a character class.
+func matchClass(class string, c byte) bool {
+	negate := false
+	if len(class) > 0 && class[0] == '^' {
+		negate = true
+		class = class[1:]
+	}
+
+	matched := false
+	for i := 0; i < len(class); i++ {
+		lo := class[i]
+		if lo == '\\' && i+1 < len(class) {
+			i++
+			lo = class[i]
+		}
+		hi := lo
+		if i+2 < len(class) && class[i+1] == '-' {
+			hi = class[i+2]
+			i += 2
+			if hi == '\\' && i+1 < len(class) {
+				i++
+				hi = class[i]
+			}
+		}
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if c >= lo && c <= hi {
+			matched = true
+		}
+	}
+
+	return matched != negate
+}
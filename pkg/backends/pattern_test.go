@@ -0,0 +1,46 @@
+package backends
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		key     string
+		want    bool
+	}{
+		{"*", "anything", true},
+		{"*", "", true},
+		{"user:*", "user:1", true},
+		{"user:*", "user:", true},
+		{"user:*", "session:1", false},
+		{"*:profile", "user:42:profile", true},
+		{"*:profile", "user:42:settings", false},
+		{"user:?", "user:1", true},
+		{"user:?", "user:12", false},
+		{"user:[0-4]", "user:3", true},
+		{"user:[0-4]", "user:7", false},
+		{"user:[^0-4]", "user:7", true},
+		{"user:[abc]", "user:b", true},
+		{"a*b*c", "a/x/b/y/c", true},
+		{"a*b*c", "a/x/b/y/d", false},
+		{`key\*`, "key*", true},
+		{`key\*`, "keys", false},
+		{"key[", "key[", true},
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+		{"?", "", false},
+		{"a*", "b", false},
+		{"*a*a*b", "xaxxayb", true},
+		{"*a*a*a*a*a*a*b", strings.Repeat("a", 5000), false},
+		{"*a*a*a*a*a*a*b", strings.Repeat("a", 5000) + "b", true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MatchPattern(tt.pattern, tt.key), "pattern %q key %q", tt.pattern, tt.key)
+	}
+}
@@ -0,0 +1,132 @@
+package backends
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// poolHeadroom is the multiplier applied to the observed peak concurrency
+// when recommending a pool size, leaving room for bursts above the peak.
+const poolHeadroom = 1.25
+
+// PoolTracker observes how many operations run concurrently against a
+// connection pool of a fixed size. Operations started while every connection
+// is busy are counted as waits, since they have to queue for a connection.
+// Queued operations are handed connections in arrival order as running ones
+// complete, and the time each spent queued is added to the wait time.
+type PoolTracker struct {
+	size      int64
+	inFlight  int64
+	peak      int64
+	ops       int64
+	waits     int64
+	waitNanos int64
+
+	mu     sync.Mutex
+	queued []time.Time
+}
+
+// PoolRecommendation summarizes the observed pool usage and the suggested size.
+type PoolRecommendation struct {
+	CurrentSize  int           `json:"current_size"`
+	PeakInFlight int64         `json:"peak_in_flight"`
+	Operations   int64         `json:"operations"`
+	Waits        int64         `json:"waits"`
+	WaitTime     time.Duration `json:"wait_time"`
+	Timeouts     int64         `json:"timeouts"`
+	Recommended  int           `json:"recommended"`
+}
+
+// NewPoolTracker creates a new pool tracker for a pool of the given size.
+func NewPoolTracker(size int) *PoolTracker {
+	return &PoolTracker{size: int64(size)}
+}
+
+// Begin marks the start of an operation and returns a function that must be
+// called when the operation completes.
+func (p *PoolTracker) Begin() func() {
+	current := atomic.AddInt64(&p.inFlight, 1)
+	atomic.AddInt64(&p.ops, 1)
+	if p.size > 0 && current > p.size {
+		atomic.AddInt64(&p.waits, 1)
+		p.mu.Lock()
+		p.queued = append(p.queued, time.Now())
+		p.mu.Unlock()
+	}
+
+	for {
+		peak := atomic.LoadInt64(&p.peak)
+		if current <= peak || atomic.CompareAndSwapInt64(&p.peak, peak, current) {
+			break
+		}
+	}
+
+	return func() {
+		atomic.AddInt64(&p.inFlight, -1)
+		p.release()
+	}
+}
+
+// release hands the connection freed by a completed operation to the oldest
+// queued operation, recording how long it waited.
+func (p *PoolTracker) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.queued) == 0 {
+		return
+	}
+	atomic.AddInt64(&p.waitNanos, int64(time.Since(p.queued[0])))
+	p.queued = p.queued[1:]
+}
+
+// InFlight returns the number of operations currently running.
+func (p *PoolTracker) InFlight() int64 {
+	return atomic.LoadInt64(&p.inFlight)
+}
+
+// Peak returns the highest number of concurrent operations observed.
+func (p *PoolTracker) Peak() int64 {
+	return atomic.LoadInt64(&p.peak)
+}
+
+// Recommend returns a pool size recommendation based on the observed peak
+// concurrency, clamped to [minSize, maxSize]. A maxSize of zero means no
+// upper bound. Until any operation is observed the current size is kept.
+func (p *PoolTracker) Recommend(minSize, maxSize int) PoolRecommendation {
+	rec := PoolRecommendation{
+		CurrentSize:  int(p.size),
+		PeakInFlight: p.Peak(),
+		Operations:   atomic.LoadInt64(&p.ops),
+		Waits:        atomic.LoadInt64(&p.waits),
+		WaitTime:     time.Duration(atomic.LoadInt64(&p.waitNanos)),
+	}
+
+	recommended := int(p.size)
+	if rec.PeakInFlight > 0 {
+		recommended = int(math.Ceil(float64(rec.PeakInFlight) * poolHeadroom))
+	}
+
+	if minSize <= 0 {
+		minSize = 1
+	}
+	if recommended < minSize {
+		recommended = minSize
+	}
+	if maxSize > 0 && recommended > maxSize {
+		recommended = maxSize
+	}
+	rec.Recommended = recommended
+
+	return rec
+}
+
+// Reset clears the observed peak and counters.
+func (p *PoolTracker) Reset() {
+	atomic.StoreInt64(&p.peak, atomic.LoadInt64(&p.inFlight))
+	atomic.StoreInt64(&p.ops, 0)
+	atomic.StoreInt64(&p.waits, 0)
+	atomic.StoreInt64(&p.waitNanos, 0)
+}
@@ -0,0 +1,83 @@
+package backends
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runConcurrent holds n operations open on the tracker at the same time.
+func runConcurrent(tracker *PoolTracker, n int) {
+	var started, finished sync.WaitGroup
+	release := make(chan struct{})
+
+	started.Add(n)
+	finished.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer finished.Done()
+			done := tracker.Begin()
+			started.Done()
+			<-release
+			done()
+		}()
+	}
+
+	started.Wait()
+	close(release)
+	finished.Wait()
+}
+
+func TestPoolTrackerRecommendationTracksPeak(t *testing.T) {
+	tracker := NewPoolTracker(10)
+
+	// Nothing observed yet keeps the configured size
+	assert.Equal(t, 10, tracker.Recommend(0, 0).Recommended)
+
+	runConcurrent(tracker, 8)
+	rec := tracker.Recommend(0, 0)
+	assert.Equal(t, int64(8), rec.PeakInFlight)
+	assert.Equal(t, int64(8), rec.Operations)
+	assert.Equal(t, int64(0), rec.Waits)
+	assert.Equal(t, 10, rec.Recommended)
+	assert.Equal(t, int64(0), tracker.InFlight())
+
+	runConcurrent(tracker, 40)
+	rec = tracker.Recommend(0, 0)
+	assert.Equal(t, int64(40), rec.PeakInFlight)
+	assert.Equal(t, int64(30), rec.Waits)
+	assert.Equal(t, 50, rec.Recommended)
+}
+
+func TestPoolTrackerRecommendationBounds(t *testing.T) {
+	tracker := NewPoolTracker(10)
+	runConcurrent(tracker, 40)
+
+	assert.Equal(t, 20, tracker.Recommend(0, 20).Recommended)
+
+	tracker.Reset()
+	runConcurrent(tracker, 2)
+	assert.Equal(t, int64(2), tracker.Peak())
+	assert.Equal(t, 5, tracker.Recommend(5, 20).Recommended)
+}
+
+func TestPoolTrackerWaitTime(t *testing.T) {
+	tracker := NewPoolTracker(1)
+
+	first := tracker.Begin()
+	second := tracker.Begin()
+
+	// The second operation queues until the first releases its connection
+	time.Sleep(20 * time.Millisecond)
+	first()
+	second()
+
+	rec := tracker.Recommend(0, 0)
+	assert.Equal(t, int64(1), rec.Waits)
+	assert.GreaterOrEqual(t, rec.WaitTime, 20*time.Millisecond)
+
+	tracker.Reset()
+	assert.Zero(t, tracker.Recommend(0, 0).WaitTime)
+}
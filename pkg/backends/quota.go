@@ -0,0 +1,271 @@
+package backends
+
+import (
+	"container/heap"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+)
+
+// namespaceQuota holds the parsed limits of a namespace. Zero means no limit.
+type namespaceQuota struct {
+	maxKeys int64
+	maxSize int64
+}
+
+// namespaceUsage counts the keys and bytes stored in a namespace. The counts
+// are updated atomically, since removals from different stripes account
+// concurrently. Expired entries count until they are removed, so the
+// namespace also queues its expiring entries, soonest first, to find the
+// expired ones without scanning the stripes.
+type namespaceUsage struct {
+	mu   sync.Mutex // taken by writes to the namespace before any stripe lock
+	keys atomic.Int64
+	size atomic.Int64
+
+	expiryMu sync.Mutex // taken last, after any stripe lock
+	expiries expiryQueue
+}
+
+// expiryEntry queues the expiration of item, stored under key, at at. The
+// entry is stale once the item is removed or its expiration changes, when
+// at no longer matches item.scheduled.
+type expiryEntry struct {
+	at   time.Time
+	key  string
+	item *memoryItem
+}
+
+// expiryQueue is a min-heap of expiry entries ordered by expiration time.
+type expiryQueue []expiryEntry
+
+func (q expiryQueue) Len() int            { return len(q) }
+func (q expiryQueue) Less(i, j int) bool  { return q[i].at.Before(q[j].at) }
+func (q expiryQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *expiryQueue) Push(x interface{}) { *q = append(*q, x.(expiryEntry)) }
+
+func (q *expiryQueue) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	old[len(old)-1] = expiryEntry{}
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// schedule queues the expiration of item, stored under key, replacing any
+// earlier one. The caller must hold the write lock of key's stripe.
+func (u *namespaceUsage) schedule(key string, item *memoryItem) {
+	u.expiryMu.Lock()
+	defer u.expiryMu.Unlock()
+
+	item.scheduled = item.expireTime
+	if item.expireTime.IsZero() {
+		return
+	}
+	heap.Push(&u.expiries, expiryEntry{at: item.expireTime, key: key, item: item})
+
+	// Drop stale entries once they could outnumber the live ones
+	if n := len(u.expiries); n > 64 && int64(n) > 2*u.keys.Load() {
+		live := u.expiries[:0]
+		for _, entry := range u.expiries {
+			if entry.at.Equal(entry.item.scheduled) {
+				live = append(live, entry)
+			}
+		}
+		for i := len(live); i < n; i++ {
+			u.expiries[i] = expiryEntry{}
+		}
+		u.expiries = live
+		heap.Init(&u.expiries)
+	}
+}
+
+// unschedule cancels the queued expiration of item, which is being removed.
+// The caller must hold the write lock of the item's stripe.
+func (u *namespaceUsage) unschedule(item *memoryItem) {
+	u.expiryMu.Lock()
+	item.scheduled = time.Time{}
+	u.expiryMu.Unlock()
+}
+
+// nextExpired dequeues the first entry that expired before now and is not
+// stale, if any.
+func (u *namespaceUsage) nextExpired(now time.Time) (expiryEntry, bool) {
+	u.expiryMu.Lock()
+	defer u.expiryMu.Unlock()
+
+	for len(u.expiries) > 0 && u.expiries[0].at.Before(now) {
+		entry := heap.Pop(&u.expiries).(expiryEntry)
+		if entry.at.Equal(entry.item.scheduled) {
+			return entry, true
+		}
+	}
+	return expiryEntry{}, false
+}
+
+// requeue puts back an entry dequeued by nextExpired.
+func (u *namespaceUsage) requeue(entry expiryEntry) {
+	u.expiryMu.Lock()
+	heap.Push(&u.expiries, entry)
+	u.expiryMu.Unlock()
+}
+
+// reset empties the usage, for when every entry is removed at once.
+func (u *namespaceUsage) reset() {
+	u.keys.Store(0)
+	u.size.Store(0)
+
+	u.expiryMu.Lock()
+	u.expiries = nil
+	u.expiryMu.Unlock()
+}
+
+// namespaceOf returns the namespace of key, the prefix before the first
+// colon, or "" if key has none.
+func namespaceOf(key string) string {
+	namespace, _, found := strings.Cut(key, ":")
+	if !found {
+		return ""
+	}
+	return namespace
+}
+
+// parseQuotas parses the configured namespace quotas.
+func parseQuotas(cfg map[string]config.NamespaceQuota) (map[string]namespaceQuota, error) {
+	quotas := make(map[string]namespaceQuota, len(cfg))
+	for namespace, quota := range cfg {
+		if namespace == "" {
+			return nil, fmt.Errorf("namespace quota needs a namespace")
+		}
+		if quota.MaxKeys < 0 {
+			return nil, fmt.Errorf("invalid max keys for namespace %s: %d", namespace, quota.MaxKeys)
+		}
+		maxSize, err := parseSize(quota.MaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max size for namespace %s: %w", namespace, err)
+		}
+		quotas[namespace] = namespaceQuota{maxKeys: quota.MaxKeys, maxSize: maxSize}
+	}
+	return quotas, nil
+}
+
+// account adjusts the totals by size bytes and keys keys and, when key's
+// namespace has a quota, its usage too. The caller must hold the write lock
+// of key's stripe.
+func (m *MemoryBackend) account(key string, size, keys int64) {
+	m.currentSize.Add(size)
+	m.keyCount.Add(keys)
+	if len(m.quotas) == 0 {
+		return
+	}
+
+	if usage := m.usage[namespaceOf(key)]; usage != nil {
+		usage.keys.Add(keys)
+		usage.size.Add(size)
+	}
+}
+
+// checkQuota is checkQuotas for a single entry.
+func (m *MemoryBackend) checkQuota(key string, size int) error {
+	if len(m.quotas) == 0 {
+		return nil
+	}
+	return m.checkQuotas(map[string]int{key: size})
+}
+
+// checkQuotas returns ErrQuotaExceeded if storing entries of the given sizes,
+// as returned by entrySize, under their keys would take a namespace over its
+// quota. The expired entries of a namespace over its quota are removed
+// before giving up. The caller must hold the locks of the keys' namespaces
+// and the write locks of their stripes.
+func (m *MemoryBackend) checkQuotas(sizes map[string]int) error {
+	if len(m.quotas) == 0 {
+		return nil
+	}
+
+	over := m.overQuota(sizes)
+	if len(over) == 0 {
+		return nil
+	}
+
+	held := make(map[*memoryStripe]bool)
+	for key := range sizes {
+		held[m.stripe(key)] = true
+	}
+	for _, namespace := range over {
+		m.expireNamespace(m.usage[namespace], held)
+	}
+
+	if over = m.overQuota(sizes); len(over) > 0 {
+		return fmt.Errorf("%w: %s", ErrQuotaExceeded, over[0])
+	}
+	return nil
+}
+
+// expireNamespace removes the expired entries of the namespace with usage,
+// soonest first, from the stripes in held, whose write locks the caller
+// holds, and from the others. Waiting for another stripe while holding one
+// could deadlock, so it stops at the first busy stripe, leaving the rest for
+// a later write.
+func (m *MemoryBackend) expireNamespace(usage *namespaceUsage, held map[*memoryStripe]bool) {
+	now := m.now()
+	for {
+		entry, ok := usage.nextExpired(now)
+		if !ok {
+			return
+		}
+
+		s := m.stripe(entry.key)
+		if !held[s] && !s.mu.TryLock() {
+			usage.requeue(entry)
+			return
+		}
+		if item, exists := s.data[entry.key]; exists && item == entry.item && m.expired(item) {
+			m.expire(s, entry.key, item)
+		}
+		if !held[s] {
+			s.mu.Unlock()
+		}
+	}
+}
+
+// overQuota returns the namespaces that storing entries of the given sizes
+// would take over their quota.
+func (m *MemoryBackend) overQuota(sizes map[string]int) []string {
+	type usageDelta struct {
+		keys int64
+		size int64
+	}
+
+	deltas := make(map[string]usageDelta)
+	for key, size := range sizes {
+		namespace := namespaceOf(key)
+		if _, ok := m.quotas[namespace]; !ok {
+			continue
+		}
+
+		delta := deltas[namespace]
+		if old, exists := m.stripe(key).data[key]; exists {
+			delta.size += int64(size - entrySize(key, old.value))
+		} else {
+			delta.keys++
+			delta.size += int64(size)
+		}
+		deltas[namespace] = delta
+	}
+
+	var over []string
+	for namespace, delta := range deltas {
+		quota := m.quotas[namespace]
+		usage := m.usage[namespace]
+		if (quota.maxKeys > 0 && delta.keys > 0 && usage.keys.Load()+delta.keys > quota.maxKeys) ||
+			(quota.maxSize > 0 && delta.size > 0 && usage.size.Load()+delta.size > quota.maxSize) {
+			over = append(over, namespace)
+		}
+	}
+	return over
+}
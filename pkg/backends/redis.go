@@ -2,8 +2,16 @@ package backends
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"math/rand"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/chmenegatti/gocachex/pkg/config"
@@ -12,15 +20,70 @@ import (
 
 // RedisBackend implements a Redis cache backend.
 type RedisBackend struct {
-	client redis.UniversalClient
-	config config.RedisConfig
+	client    redis.UniversalClient
+	config    config.RedisConfig
+	pool      *PoolTracker
+	local     *clientCache  // nil unless client-side caching is enabled
+	closed    chan struct{} // closed by Close
+	closeOnce sync.Once
+
+	// Expiration callbacks share one subscription to the expired key event
+	// channel, started by the first SetWithExpireCallback
+	expireMu        sync.Mutex
+	expireSub       *redis.PubSub
+	expireCallbacks map[string]func(key string)
 }
 
 // NewRedisBackend creates a new Redis backend.
 func NewRedisBackend(cfg config.RedisConfig) (*RedisBackend, error) {
+	client, local, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Track concurrency for pool size recommendations
+	pool := NewPoolTracker(cfg.PoolSize)
+	client.AddHook(poolHook{tracker: pool})
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		local.close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &RedisBackend{
+		client: client,
+		config: cfg,
+		pool:   pool,
+		local:  local,
+		closed: make(chan struct{}),
+	}, nil
+}
+
+// newRedisClient creates the client selected by the configured mode. It
+// does not connect.
+func newRedisClient(cfg config.RedisConfig) (redis.UniversalClient, *clientCache, error) {
 	var client redis.UniversalClient
+	var local *clientCache
 
-	if cfg.Cluster.Enabled {
+	mode := cfg.EffectiveMode()
+	if cfg.ClientCache.Enabled && mode != config.RedisModeSingle {
+		return nil, nil, fmt.Errorf("redis client cache is only supported for a single instance")
+	}
+	if cfg.ClientCache.Enabled && cfg.ReadFromReplica {
+		return nil, nil, fmt.Errorf("redis client cache is not supported with read_from_replica")
+	}
+
+	tlsConfig, err := redisTLSConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case mode == config.RedisModeCluster:
 		// Cluster mode
 		client = redis.NewClusterClient(&redis.ClusterOptions{
 			Addrs:          cfg.Addresses,
@@ -37,11 +100,15 @@ func NewRedisBackend(cfg config.RedisConfig) (*RedisBackend, error) {
 			MaxRetries:      cfg.MaxRetries,
 			MinRetryBackoff: cfg.MinRetryBackoff,
 			MaxRetryBackoff: cfg.MaxRetryBackoff,
+			TLSConfig:       tlsConfig,
 		})
-	} else if len(cfg.Addresses) > 1 {
+	case cfg.ReadFromReplica:
+		// Reads from replicas, writes to the master
+		client = newReplicaClient(cfg, tlsConfig)
+	case mode == config.RedisModeSentinel:
 		// Sentinel mode
 		client = redis.NewFailoverClient(&redis.FailoverOptions{
-			MasterName:    "master", // This should be configurable
+			MasterName:    masterName(cfg),
 			SentinelAddrs: cfg.Addresses,
 			Password:      cfg.Password,
 			DB:            cfg.DB,
@@ -53,9 +120,18 @@ func NewRedisBackend(cfg config.RedisConfig) (*RedisBackend, error) {
 			MaxRetries:      cfg.MaxRetries,
 			MinRetryBackoff: cfg.MinRetryBackoff,
 			MaxRetryBackoff: cfg.MaxRetryBackoff,
+			TLSConfig:       tlsConfig,
 		})
-	} else {
+	default:
 		// Single instance mode
+		var onConnect func(ctx context.Context, cn *redis.Conn) error
+		if cfg.ClientCache.Enabled {
+			if local, err = newClientCache(cfg, tlsConfig); err != nil {
+				return nil, nil, fmt.Errorf("failed to connect to Redis: %w", err)
+			}
+			onConnect = local.track
+		}
+
 		client = redis.NewClient(&redis.Options{
 			Addr:         cfg.Addresses[0],
 			Password:     cfg.Password,
@@ -68,43 +144,111 @@ func NewRedisBackend(cfg config.RedisConfig) (*RedisBackend, error) {
 			MaxRetries:      cfg.MaxRetries,
 			MinRetryBackoff: cfg.MinRetryBackoff,
 			MaxRetryBackoff: cfg.MaxRetryBackoff,
+			OnConnect:       onConnect,
+			TLSConfig:       tlsConfig,
 		})
 	}
 
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	return client, local, nil
+}
 
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+// redisTLSConfig builds the TLS configuration of the connections, or nil
+// when TLS is disabled.
+func redisTLSConfig(cfg config.RedisConfig) (*tls.Config, error) {
+	if !cfg.TLS {
+		return nil, nil
 	}
 
-	return &RedisBackend{
-		client: client,
-		config: cfg,
-	}, nil
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.TLSServerName,
+		InsecureSkipVerify: cfg.TLSSkipVerify,
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in redis CA file %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
 }
 
-// Get retrieves a value from Redis.
+// Get retrieves a value from Redis. With client-side caching enabled, values
+// read before are served locally until Redis invalidates them.
 func (r *RedisBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	if value, ok := r.local.get(key); ok {
+		return value, nil
+	}
+
+	r.local.begin(key)
 	val, err := r.client.Get(ctx, key).Result()
 	if err != nil {
+		r.local.finish(key, nil)
 		if err == redis.Nil {
-			return nil, fmt.Errorf("key not found")
+			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+	r.local.finish(key, []byte(val))
 	return []byte(val), nil
 }
 
-// Set stores a value in Redis.
+// Set stores a value in Redis, resetting the version recorded by SetIfNewer.
 func (r *RedisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	// Drop the local copy right away rather than waiting for Redis to
+	// report the change
+	r.local.invalidate(key)
+	r.dropExpireCallbacks(key)
+
 	return r.client.Set(ctx, key, value, ttl).Err()
 }
 
+// SetNX stores a value with SET NX, only if key does not exist.
+func (r *RedisBackend) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	stored, err := r.client.SetNX(ctx, key, value, ttl).Result()
+	if stored {
+		r.local.invalidate(key)
+		r.dropExpireCallbacks(key)
+	}
+	return stored, err
+}
+
+// GetSet replaces the value of key with SET KEEPTTL GET, which requires
+// Redis 6.2 or later, and returns the previous value.
+func (r *RedisBackend) GetSet(ctx context.Context, key string, value []byte) ([]byte, error) {
+	r.local.invalidate(key)
+	r.dropExpireCallbacks(key)
+
+	old, err := r.client.SetArgs(ctx, key, value, redis.SetArgs{KeepTTL: true, Get: true}).Result()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(old), nil
+}
+
 // Delete removes a value from Redis.
 func (r *RedisBackend) Delete(ctx context.Context, key string) error {
-	return r.client.Del(ctx, key).Err()
+	r.local.invalidate(key)
+	r.dropExpireCallbacks(key)
+	return r.client.Del(ctx, key, versionKey(key)).Err()
 }
 
 // Exists checks if a key exists in Redis.
@@ -137,10 +281,17 @@ func (r *RedisBackend) GetMulti(ctx context.Context, keys []string) (map[string]
 }
 
 // SetMulti stores multiple values in Redis.
+// With AtomicSetMulti the batch runs in a MULTI/EXEC transaction, so it is
+// applied all-or-nothing; in cluster mode this holds per hash slot.
 func (r *RedisBackend) SetMulti(ctx context.Context, items map[string][]byte, ttl time.Duration) error {
 	pipe := r.client.Pipeline()
+	if r.config.AtomicSetMulti {
+		pipe = r.client.TxPipeline()
+	}
 
 	for key, value := range items {
+		r.local.invalidate(key)
+		r.dropExpireCallbacks(key)
 		pipe.Set(ctx, key, value, ttl)
 	}
 
@@ -153,22 +304,282 @@ func (r *RedisBackend) DeleteMulti(ctx context.Context, keys []string) error {
 	if len(keys) == 0 {
 		return nil
 	}
-	return r.client.Del(ctx, keys...).Err()
+	r.local.invalidate(keys...)
+	r.dropExpireCallbacks(keys...)
+
+	// Each version key shares the slot of its key
+	all := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		all = append(all, key, versionKey(key))
+	}
+	return r.client.Del(ctx, all...).Err()
 }
 
 // Increment atomically increments a numeric value in Redis.
 func (r *RedisBackend) Increment(ctx context.Context, key string, delta int64) (int64, error) {
-	return r.client.IncrBy(ctx, key, delta).Result()
+	r.local.invalidate(key)
+	value, err := r.client.IncrBy(ctx, key, delta).Result()
+	return value, counterError(err)
 }
 
 // Decrement atomically decrements a numeric value in Redis.
 func (r *RedisBackend) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
-	return r.client.DecrBy(ctx, key, delta).Result()
+	r.local.invalidate(key)
+	value, err := r.client.DecrBy(ctx, key, delta).Result()
+	return value, counterError(err)
+}
+
+// counterError maps Redis overflow replies from INCRBY/DECRBY to
+// ErrCounterOverflow.
+func counterError(err error) error {
+	if err != nil && strings.Contains(err.Error(), "would overflow") {
+		return ErrCounterOverflow
+	}
+	return err
+}
+
+// incrementWithTTLOnCreateScript increments a counter and sets its TTL only
+// if the increment created it.
+var incrementWithTTLOnCreateScript = redis.NewScript(`
+local created = redis.call("EXISTS", KEYS[1]) == 0
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+if created and tonumber(ARGV[2]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return value
+`)
+
+// IncrementWithTTLOnCreate increments a counter with a script that sets the
+// TTL only when the counter is created.
+func (r *RedisBackend) IncrementWithTTLOnCreate(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	r.local.invalidate(key)
+	value, err := incrementWithTTLOnCreateScript.Run(ctx, r.client, []string{key}, delta, ttl.Milliseconds()).Int64()
+	return value, counterError(err)
+}
+
+// extendLeaseScript extends a lease only if it is still held by the token.
+var extendLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLeaseScript deletes a lease only if it is still held by the token.
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireLease stores token at key with SET NX PX.
+func (r *RedisBackend) AcquireLease(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, token, ttl).Result()
+}
+
+// ExtendLease resets the TTL of the lease at key if it is still held by token.
+func (r *RedisBackend) ExtendLease(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	extended, err := extendLeaseScript.Run(ctx, r.client, []string{key}, token, ttl.Milliseconds()).Int64()
+	return extended == 1, err
+}
+
+// ReleaseLease deletes the lease at key if it is still held by token.
+func (r *RedisBackend) ReleaseLease(ctx context.Context, key, token string) (bool, error) {
+	released, err := releaseLeaseScript.Run(ctx, r.client, []string{key}, token).Int64()
+	return released == 1, err
+}
+
+// aliasKeyPrefix namespaces the pointer keys that hold alias targets.
+const aliasKeyPrefix = "gocachex:alias:"
+
+// swapAliasScript repoints an alias only if it already exists, returning the
+// previous target.
+var swapAliasScript = redis.NewScript(`
+local previous = redis.call("GET", KEYS[1])
+if not previous then
+	return false
+end
+redis.call("SET", KEYS[1], ARGV[1])
+return previous
+`)
+
+// SetAlias stores target in the pointer key of alias.
+func (r *RedisBackend) SetAlias(ctx context.Context, alias, target string) error {
+	return r.client.Set(ctx, aliasKeyPrefix+alias, target, 0).Err()
+}
+
+// SwapAlias atomically repoints an existing alias at target and returns its
+// previous target, or ErrNotFound if the alias is not set.
+func (r *RedisBackend) SwapAlias(ctx context.Context, alias, target string) (string, error) {
+	previous, err := swapAliasScript.Run(ctx, r.client, []string{aliasKeyPrefix + alias}, target).Text()
+	if err == redis.Nil {
+		return "", ErrNotFound
+	}
+	return previous, err
+}
+
+// ResolveAlias reads the target from the pointer key of alias.
+func (r *RedisBackend) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	target, err := r.client.Get(ctx, aliasKeyPrefix+alias).Result()
+	if err == redis.Nil {
+		return "", ErrNotFound
+	}
+	return target, err
+}
+
+// versionKeyPrefix namespaces the keys that hold the versions recorded by
+// SetIfNewer.
+const versionKeyPrefix = "gocachex:version:"
+
+// setIfNewerScript stores the value and its version unless the live key has
+// a version at least as new. The version key holds the version, as the
+// fixed-width string of versionString, since Lua numbers cannot hold every
+// int64, followed by ":" and the SHA-1 of the value it was stored with. A
+// version whose digest no longer matches the value was reset by another
+// write.
+var setIfNewerScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	local current = redis.call("GET", KEYS[2])
+	if current and string.sub(current, 22) == redis.sha1hex(redis.call("GET", KEYS[1]))
+		and string.sub(current, 1, 20) >= ARGV[2] then
+		return 0
+	end
+end
+local recorded = ARGV[2] .. ":" .. redis.sha1hex(ARGV[1])
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[3])
+	redis.call("SET", KEYS[2], recorded, "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+	redis.call("SET", KEYS[2], recorded)
+end
+return 1
+`)
+
+// SetIfNewer stores value with a script if version is newer than the version
+// recorded for the live key. The version is kept in a separate key with the
+// same TTL, in the same cluster slot as key, along with a digest of the value
+// it was stored with, so any write of another value, from this backend or
+// another Redis client, resets it without touching the version key. Deletes
+// through this backend remove the version key along with key.
+func (r *RedisBackend) SetIfNewer(ctx context.Context, key string, value []byte, version int64, ttl time.Duration) (bool, error) {
+	stored, err := setIfNewerScript.Run(ctx, r.client, []string{key, versionKey(key)},
+		value, versionString(version), ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	if stored == 1 {
+		r.dropExpireCallbacks(key)
+	}
+	return stored == 1, nil
+}
+
+// versionKey returns the key holding the version of key. A key without a
+// hash tag is wrapped in one so both keys hash to the same cluster slot; in
+// cluster mode this fails for keys that contain "}" but no hash tag.
+func versionKey(key string) string {
+	if open := strings.IndexByte(key, '{'); open >= 0 {
+		if end := strings.IndexByte(key[open+1:], '}'); end > 0 {
+			return versionKeyPrefix + key
+		}
+	}
+	return versionKeyPrefix + "{" + key + "}"
+}
+
+// versionString encodes version so that versions compare as strings in the
+// same order as numbers.
+func versionString(version int64) string {
+	return fmt.Sprintf("%020d", uint64(version)^(1<<63))
+}
+
+// rateLimitKeyPrefix namespaces the sorted sets that hold rate limit windows.
+const rateLimitKeyPrefix = "gocachex:ratelimit:"
+
+// rateLimitScript keeps a sliding window in a sorted set scored by request
+// time in microseconds. It drops the requests that left the window and
+// records a new one if the window has room, returning whether it was allowed
+// and the remaining allowance.
+var rateLimitScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+if count >= limit then
+	return {0, 0}
+end
+redis.call("ZADD", KEYS[1], now, ARGV[4])
+redis.call("PEXPIRE", KEYS[1], math.ceil(window / 1000))
+return {1, limit - count - 1}
+`)
+
+// RateLimitAllow runs the sliding-window check for key in a single script.
+func (r *RedisBackend) RateLimitAllow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, error) {
+	now := time.Now().UnixMicro()
+	member := fmt.Sprintf("%d-%d", now, rand.Int63())
+
+	result, err := rateLimitScript.Run(ctx, r.client, []string{rateLimitKeyPrefix + key},
+		now, window.Microseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return result[0] == 1, int(result[1]), nil
+}
+
+// listKeyPrefix namespaces the Redis lists that hold capped lists.
+const listKeyPrefix = "gocachex:list:"
+
+// listPushCappedScript prepends a value to a list, trims it to its newest
+// values and sets its expiration, or removes it when the TTL is 0.
+var listPushCappedScript = redis.NewScript(`
+redis.call("LPUSH", KEYS[1], ARGV[1])
+redis.call("LTRIM", KEYS[1], 0, tonumber(ARGV[2]) - 1)
+local ttl = tonumber(ARGV[3])
+if ttl > 0 then
+	redis.call("PEXPIRE", KEYS[1], ttl)
+else
+	redis.call("PERSIST", KEYS[1])
+end
+return 1
+`)
+
+// ListPushCapped pushes to and trims the list at key in a single script.
+func (r *RedisBackend) ListPushCapped(ctx context.Context, key string, value []byte, maxLen int, ttl time.Duration) error {
+	if maxLen <= 0 {
+		return fmt.Errorf("list length must be positive, got %d", maxLen)
+	}
+	// Round TTLs below a millisecond up, since 0 means no expiration
+	ms := ttl.Milliseconds()
+	if ttl > 0 && ms == 0 {
+		ms = 1
+	}
+	return listPushCappedScript.Run(ctx, r.client, []string{listKeyPrefix + key},
+		value, maxLen, ms).Err()
+}
+
+// ListRange reads the whole list at key, newest first.
+func (r *RedisBackend) ListRange(ctx context.Context, key string) ([][]byte, error) {
+	values, err := r.client.LRange(ctx, listKeyPrefix+key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][]byte, len(values))
+	for i, value := range values {
+		result[i] = []byte(value)
+	}
+	return result, nil
 }
 
 // Expire sets a timeout on a key in Redis.
 func (r *RedisBackend) Expire(ctx context.Context, key string, ttl time.Duration) error {
-	return r.client.Expire(ctx, key, ttl).Err()
+	// Keep the version recorded by SetIfNewer for as long as the key
+	pipe := r.client.Pipeline()
+	expire := pipe.Expire(ctx, key, ttl)
+	pipe.Expire(ctx, versionKey(key), ttl)
+	_, _ = pipe.Exec(ctx)
+	return expire.Err()
 }
 
 // TTL returns the remaining time to live of a key in Redis.
@@ -176,14 +587,334 @@ func (r *RedisBackend) TTL(ctx context.Context, key string) (time.Duration, erro
 	return r.client.TTL(ctx, key).Result()
 }
 
+// GetMultiTTL returns the remaining TTL of every existing key in keys using a
+// single pipeline of TTL commands. Keys without an expiration report -1.
+func (r *RedisBackend) GetMultiTTL(ctx context.Context, keys []string) (map[string]time.Duration, error) {
+	result := make(map[string]time.Duration, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.TTL(ctx, key)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	for i, cmd := range cmds {
+		ttl := cmd.Val()
+		if ttl == -2 {
+			continue // Key does not exist
+		}
+		result[keys[i]] = ttl
+	}
+
+	return result, nil
+}
+
+// ProbeMulti reports the existence and remaining TTL of every key in keys
+// using a single pipeline of EXISTS and PTTL commands.
+func (r *RedisBackend) ProbeMulti(ctx context.Context, keys []string) (map[string]KeyProbe, error) {
+	result := make(map[string]KeyProbe, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	pipe := r.client.Pipeline()
+	exists := make([]*redis.IntCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		exists[i] = pipe.Exists(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		// A key expiring between the two commands reports -2 as its TTL
+		ttl := ttls[i].Val()
+		if exists[i].Val() == 0 || ttl == -2 {
+			result[key] = KeyProbe{}
+			continue
+		}
+		result[key] = KeyProbe{Exists: true, TTL: ttl}
+	}
+
+	return result, nil
+}
+
+// WatchKey subscribes to keyspace notifications for key. It requires
+// KeyspaceNotifications to be enabled and is not supported in cluster mode,
+// where notifications are only published on the node owning the key.
+func (r *RedisBackend) WatchKey(ctx context.Context, key string) (<-chan struct{}, func() error, error) {
+	pubsub, err := r.subscribeKeyspace(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := make(chan struct{}, 1)
+	go func() {
+		for range pubsub.Channel() {
+			select {
+			case events <- struct{}{}:
+			default: // A wakeup is already pending
+			}
+		}
+	}()
+
+	return events, pubsub.Close, nil
+}
+
+// SetWithExpireCallback stores a value and calls cb once Redis reports that
+// key expired. Every callback is dispatched from a single subscription to
+// the expired key event channel, which requires the "Ex" notify-keyspace-events
+// flags. Redis sends events at most once and only to connected subscribers,
+// so a callback is lost if the connection drops. The callback is dropped
+// when key is written or deleted through this backend, or when the backend
+// is closed; writes from other clients do not drop it.
+func (r *RedisBackend) SetWithExpireCallback(ctx context.Context, key string, value []byte, ttl time.Duration, cb func(key string)) error {
+	if err := r.watchExpirations(ctx); err != nil {
+		return err
+	}
+
+	// Register before writing, so an expiration right after the write is
+	// not missed
+	r.local.invalidate(key)
+	r.expireMu.Lock()
+	r.expireCallbacks[key] = cb
+	r.expireMu.Unlock()
+
+	if err := r.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		r.dropExpireCallbacks(key)
+		return err
+	}
+	return nil
+}
+
+// watchExpirations subscribes to the expired key event channel unless a
+// subscription is already running, waiting for it to be confirmed.
+func (r *RedisBackend) watchExpirations(ctx context.Context) error {
+	if !r.config.KeyspaceNotifications {
+		return fmt.Errorf("keyspace notifications not enabled")
+	}
+	if _, ok := r.client.(*redis.ClusterClient); ok {
+		return fmt.Errorf("keyspace notifications not supported in cluster mode")
+	}
+
+	r.expireMu.Lock()
+	defer r.expireMu.Unlock()
+
+	if r.expireSub != nil {
+		return nil
+	}
+	select {
+	case <-r.closed:
+		return fmt.Errorf("backend is closed")
+	default:
+	}
+
+	pubsub := r.client.Subscribe(ctx, fmt.Sprintf("__keyevent@%d__:expired", r.config.DB))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return err
+	}
+
+	r.expireSub = pubsub
+	r.expireCallbacks = make(map[string]func(key string))
+	go r.dispatchExpirations(pubsub.Channel())
+
+	return nil
+}
+
+// dispatchExpirations calls the callback registered for each expired key
+// until the subscription is closed.
+func (r *RedisBackend) dispatchExpirations(messages <-chan *redis.Message) {
+	for msg := range messages {
+		key := msg.Payload
+
+		r.expireMu.Lock()
+		cb, ok := r.expireCallbacks[key]
+		delete(r.expireCallbacks, key)
+		r.expireMu.Unlock()
+
+		if ok {
+			cb(key)
+		}
+	}
+}
+
+// dropExpireCallbacks forgets the expiration callbacks of keys that are
+// written or deleted.
+func (r *RedisBackend) dropExpireCallbacks(keys ...string) {
+	r.expireMu.Lock()
+	defer r.expireMu.Unlock()
+
+	for _, key := range keys {
+		delete(r.expireCallbacks, key)
+	}
+}
+
+// Publish sends message on a Redis pub/sub channel.
+func (r *RedisBackend) Publish(ctx context.Context, channel, message string) error {
+	return r.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe subscribes to a Redis pub/sub channel and calls fn with every
+// message received until the returned function is called. The client
+// resubscribes after reconnecting, dropping messages published meanwhile.
+func (r *RedisBackend) Subscribe(ctx context.Context, channel string, fn func(message string)) (func() error, error) {
+	pubsub := r.client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			fn(msg.Payload)
+		}
+	}()
+
+	return pubsub.Close, nil
+}
+
+// subscribeKeyspace subscribes to the keyspace channel of key and waits for
+// the subscription to be confirmed, so no event after it returns is missed.
+func (r *RedisBackend) subscribeKeyspace(ctx context.Context, key string) (*redis.PubSub, error) {
+	if !r.config.KeyspaceNotifications {
+		return nil, fmt.Errorf("keyspace notifications not enabled")
+	}
+	if _, ok := r.client.(*redis.ClusterClient); ok {
+		return nil, fmt.Errorf("keyspace notifications not supported in cluster mode")
+	}
+
+	pubsub := r.client.Subscribe(ctx, fmt.Sprintf("__keyspace@%d__:%s", r.config.DB, key))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	return pubsub, nil
+}
+
+// Iterate calls fn for every string key in Redis. Keys are fetched with SCAN
+// in batches of scanBatchSize, so large keyspaces are never loaded at once.
+// In cluster mode every master is scanned concurrently, but fn is never
+// called from more than one goroutine at a time.
+func (r *RedisBackend) Iterate(ctx context.Context, fn func(key string, value []byte, ttl time.Duration) error) error {
+	return r.IteratePattern(ctx, "*", fn)
+}
+
+// IteratePattern calls fn for every string key matching pattern, like
+// Iterate, filtering keys with SCAN MATCH on the server.
+func (r *RedisBackend) IteratePattern(ctx context.Context, pattern string, fn func(key string, value []byte, ttl time.Duration) error) error {
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		var mu sync.Mutex
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return iterateRedis(ctx, node, pattern, func(key string, value []byte, ttl time.Duration) error {
+				mu.Lock()
+				defer mu.Unlock()
+				return fn(key, value, ttl)
+			})
+		})
+	}
+	return iterateRedis(ctx, r.client, pattern, fn)
+}
+
+// ExistsPattern reports whether any key matches the glob pattern using
+// SCAN MATCH, stopping at the first batch that returns a key. In cluster
+// mode every master is scanned until one reports a match.
+func (r *RedisBackend) ExistsPattern(ctx context.Context, pattern string) (bool, error) {
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			found, err := existsPatternRedis(ctx, node, pattern)
+			if err != nil {
+				return err
+			}
+			if found {
+				return errPatternFound
+			}
+			return nil
+		})
+		if err == errPatternFound {
+			return true, nil
+		}
+		return false, err
+	}
+	return existsPatternRedis(ctx, r.client, pattern)
+}
+
+// Keys returns the keys matching the glob pattern using SCAN MATCH, paging
+// with the cursor rather than blocking the server like KEYS. In cluster mode
+// every master is scanned.
+func (r *RedisBackend) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		var mu sync.Mutex
+		var keys []string
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			nodeKeys, err := keysRedis(ctx, node, pattern)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			keys = append(keys, nodeKeys...)
+			mu.Unlock()
+			return nil
+		})
+		return keys, err
+	}
+	return keysRedis(ctx, r.client, pattern)
+}
+
+// DeleteByPattern deletes the keys matching the glob pattern one SCAN page at
+// a time, each page with a pipeline of DEL commands, so the full key list is
+// never held in memory. In cluster mode every master is scanned.
+func (r *RedisBackend) DeleteByPattern(ctx context.Context, pattern string) (int, error) {
+	var deleted atomic.Int64
+	deleteNode := func(ctx context.Context, client redis.Cmdable) error {
+		n, err := deleteByPatternRedis(ctx, client, pattern, func(keys ...string) {
+			r.local.invalidate(keys...)
+			r.dropExpireCallbacks(keys...)
+		})
+		deleted.Add(int64(n))
+		return err
+	}
+
+	if cluster, ok := r.client.(*redis.ClusterClient); ok {
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return deleteNode(ctx, node)
+		})
+		return int(deleted.Load()), err
+	}
+	err := deleteNode(ctx, r.client)
+	return int(deleted.Load()), err
+}
+
 // Clear removes all keys from the Redis database.
 func (r *RedisBackend) Clear(ctx context.Context) error {
+	r.local.flush()
+	r.expireMu.Lock()
+	clear(r.expireCallbacks)
+	r.expireMu.Unlock()
 	return r.client.FlushDB(ctx).Err()
 }
 
-// Stats returns Redis statistics.
+// Size returns the number of keys in the database using DBSIZE, summed over
+// every master in cluster mode. Unlike scans, the count includes the pointer
+// keys of aliases.
+func (r *RedisBackend) Size(ctx context.Context) (int64, error) {
+	return r.client.DBSize(ctx).Result()
+}
+
+// Stats returns Redis statistics. Like hits and misses, the set, delete and
+// eviction counts are kept by the server and cover all of its clients.
 func (r *RedisBackend) Stats(ctx context.Context) (*Stats, error) {
-	info, err := r.client.Info(ctx, "stats", "memory", "keyspace").Result()
+	info, err := r.client.Info(ctx, "stats", "memory", "keyspace", "commandstats").Result()
 	if err != nil {
 		return nil, err
 	}
@@ -197,6 +928,8 @@ func (r *RedisBackend) Stats(ctx context.Context) (*Stats, error) {
 	if val, ok := lines["keyspace_hits"]; ok {
 		stats.Hits, _ = strconv.ParseInt(val, 10, 64)
 	}
+	// Reads served by the client cache never reach the server
+	stats.Hits += r.local.localHits()
 	if val, ok := lines["keyspace_misses"]; ok {
 		stats.Misses, _ = strconv.ParseInt(val, 10, 64)
 	}
@@ -206,6 +939,17 @@ func (r *RedisBackend) Stats(ctx context.Context) (*Stats, error) {
 	if val, ok := lines["uptime_in_seconds"]; ok {
 		stats.Uptime, _ = strconv.ParseInt(val, 10, 64)
 	}
+	if val, ok := lines["evicted_keys"]; ok {
+		stats.Evictions, _ = strconv.ParseInt(val, 10, 64)
+	}
+
+	// Count sets and deletes from the calls of the commands that write them
+	for _, cmd := range redisSetCommands {
+		stats.Sets += parseCommandCalls(lines["cmdstat_"+cmd])
+	}
+	for _, cmd := range redisDeleteCommands {
+		stats.Deletes += parseCommandCalls(lines["cmdstat_"+cmd])
+	}
 
 	// Get key count from keyspace info
 	dbInfo, err := r.client.Info(ctx, "keyspace").Result()
@@ -222,14 +966,314 @@ func (r *RedisBackend) Stats(ctx context.Context) (*Stats, error) {
 	return stats, nil
 }
 
+// ServerTime reads the Redis server clock with TIME. Redis measures TTLs
+// against it.
+func (r *RedisBackend) ServerTime(ctx context.Context) (time.Time, error) {
+	return r.client.Time(ctx).Result()
+}
+
+// ResetStats resets the server statistics with CONFIG RESETSTAT, along with
+// the count of reads served by the client cache. The server statistics are
+// shared by every client of the server.
+func (r *RedisBackend) ResetStats(ctx context.Context) error {
+	if err := r.client.ConfigResetStat(ctx).Err(); err != nil {
+		return err
+	}
+	r.local.resetHits()
+	return nil
+}
+
+// redisExtendedFields are the INFO fields reported by ExtendedStats.
+var redisExtendedFields = []string{
+	"redis_version",
+	"connected_clients",
+	"blocked_clients",
+	"evicted_keys",
+	"expired_keys",
+	"rejected_connections",
+	"total_commands_processed",
+	"instantaneous_ops_per_sec",
+	"used_memory_peak",
+	"maxmemory",
+	"maxmemory_policy",
+	"mem_fragmentation_ratio",
+}
+
+// ExtendedStats reports server fields from INFO such as evicted_keys,
+// expired_keys and connected_clients. Numeric fields are returned as int64
+// or float64, everything else as strings.
+func (r *RedisBackend) ExtendedStats(ctx context.Context) (map[string]interface{}, error) {
+	info, err := r.client.Info(ctx, "server", "clients", "stats", "memory").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := parseRedisInfo(info)
+	extra := make(map[string]interface{}, len(redisExtendedFields))
+	for _, field := range redisExtendedFields {
+		val, ok := lines[field]
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			extra[field] = n
+		} else if f, err := strconv.ParseFloat(val, 64); err == nil {
+			extra[field] = f
+		} else {
+			extra[field] = val
+		}
+	}
+
+	if r.local != nil {
+		extra["client_cache_hits"] = r.local.localHits()
+	}
+
+	return extra, nil
+}
+
 // Health checks the health of the Redis connection.
 func (r *RedisBackend) Health(ctx context.Context) error {
 	return r.client.Ping(ctx).Err()
 }
 
-// Close closes the Redis connection.
+// RecommendPoolSize returns a pool size recommendation based on the peak
+// number of concurrent commands observed, bounded by MinPoolSize and
+// MaxPoolSize. go-redis pools are sized at construction, so the
+// recommendation applies to the next client created with this configuration.
+func (r *RedisBackend) RecommendPoolSize() PoolRecommendation {
+	rec := r.pool.Recommend(r.config.MinPoolSize, r.config.MaxPoolSize)
+	if stats := r.client.PoolStats(); stats != nil {
+		rec.Timeouts = int64(stats.Timeouts)
+	}
+	return rec
+}
+
+// Addresses returns the configured Redis server addresses.
+func (r *RedisBackend) Addresses() []string {
+	return append([]string(nil), r.config.Addresses...)
+}
+
+// ActiveConnections returns the number of connections currently open in the pool.
+func (r *RedisBackend) ActiveConnections() int64 {
+	stats := r.client.PoolStats()
+	if stats == nil {
+		return 0
+	}
+	return int64(stats.TotalConns)
+}
+
+// Close closes the Redis connection. Calls after the first are no-ops.
 func (r *RedisBackend) Close() error {
-	return r.client.Close()
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.closed)
+		r.local.close()
+
+		r.expireMu.Lock()
+		if r.expireSub != nil {
+			r.expireSub.Close()
+		}
+		r.expireCallbacks = nil
+		r.expireMu.Unlock()
+
+		err = r.client.Close()
+	})
+	return err
+}
+
+// scanBatchSize is the COUNT hint passed to SCAN.
+const scanBatchSize = 100
+
+// internalKeyPrefixes namespace the keys the backend keeps besides entries:
+// alias pointers, SetIfNewer versions, rate limit windows and capped lists.
+var internalKeyPrefixes = []string{aliasKeyPrefix, versionKeyPrefix, rateLimitKeyPrefix, listKeyPrefix}
+
+// dropInternalKeys removes the internal keys from a page of scanned keys, in
+// place, so scans see the same keyspace as in the memory backend, which
+// keeps them apart from entries.
+func dropInternalKeys(keys []string) []string {
+	kept := keys[:0]
+	for _, key := range keys {
+		if !isInternalKey(key) {
+			kept = append(kept, key)
+		}
+	}
+	return kept
+}
+
+// isInternalKey reports whether key has one of the internalKeyPrefixes.
+func isInternalKey(key string) bool {
+	for _, prefix := range internalKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// iterateRedis scans the keys of a single Redis node matching pattern,
+// fetching values and TTLs for each batch of keys in one pipeline.
+func iterateRedis(ctx context.Context, client redis.Cmdable, pattern string, fn func(key string, value []byte, ttl time.Duration) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+		keys = dropInternalKeys(keys)
+
+		if len(keys) > 0 {
+			pipe := client.Pipeline()
+			values := make([]*redis.StringCmd, len(keys))
+			ttls := make([]*redis.DurationCmd, len(keys))
+			for i, key := range keys {
+				values[i] = pipe.Get(ctx, key)
+				ttls[i] = pipe.PTTL(ctx, key)
+			}
+			// Individual command errors are checked below
+			_, _ = pipe.Exec(ctx)
+
+			for i, key := range keys {
+				value, err := values[i].Bytes()
+				if err == redis.Nil {
+					continue // Deleted or expired since SCAN
+				}
+				if err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE") {
+					continue // Not a plain cache entry
+				}
+				if err != nil {
+					return fmt.Errorf("failed to read key %s: %w", key, err)
+				}
+
+				ttl := ttls[i].Val()
+				if ttl < 0 {
+					ttl = 0 // No expiration
+				}
+
+				if err := fn(key, value, ttl); err != nil {
+					return err
+				}
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// keysRedis scans a single node for the keys matching pattern. SCAN may
+// return a key more than once, so the result is deduplicated.
+func keysRedis(ctx context.Context, client redis.Cmdable, pattern string) ([]string, error) {
+	var keys []string
+	seen := make(map[string]struct{})
+	var cursor uint64
+	for {
+		batch, next, err := client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return nil, err
+		}
+		batch = dropInternalKeys(batch)
+		for _, key := range batch {
+			if _, dup := seen[key]; !dup {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// deleteByPatternRedis deletes the keys of a single node matching pattern,
+// invalidating every page of keys, and returns how many keys were deleted.
+// Keys are deleted with one DEL each, along with their version keys, since
+// the keys of a page may belong to different cluster slots.
+func deleteByPatternRedis(ctx context.Context, client redis.Cmdable, pattern string, invalidate func(keys ...string)) (int, error) {
+	deleted := 0
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return deleted, err
+		}
+		keys = dropInternalKeys(keys)
+
+		if len(keys) > 0 {
+			invalidate(keys...)
+			pipe := client.Pipeline()
+			dels := make([]*redis.IntCmd, len(keys))
+			for i, key := range keys {
+				dels[i] = pipe.Del(ctx, key, versionKey(key))
+			}
+			if _, err := pipe.Exec(ctx); err != nil {
+				return deleted, err
+			}
+			// The reply also counts the version key, so count keys once
+			for _, del := range dels {
+				if del.Val() > 0 {
+					deleted++
+				}
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// errPatternFound stops a cluster-wide scan once a matching key is found.
+var errPatternFound = errors.New("pattern found")
+
+// existsPatternRedis scans a single node for the first key matching pattern.
+func existsPatternRedis(ctx context.Context, client redis.Cmdable, pattern string) (bool, error) {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return false, err
+		}
+		keys = dropInternalKeys(keys)
+		if len(keys) > 0 {
+			return true, nil
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return false, nil
+		}
+	}
+}
+
+// poolHook reports every command and pipeline to a PoolTracker.
+type poolHook struct {
+	tracker *PoolTracker
+}
+
+func (h poolHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h poolHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		done := h.tracker.Begin()
+		defer done()
+		return next(ctx, cmd)
+	}
+}
+
+func (h poolHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		done := h.tracker.Begin()
+		defer done()
+		return next(ctx, cmds)
+	}
 }
 
 // parseRedisInfo parses Redis INFO command output into a map.
@@ -250,6 +1294,27 @@ func parseRedisInfo(info string) map[string]string {
 	return result
 }
 
+// redisSetCommands and redisDeleteCommands are the commands counted as sets
+// and deletes in Stats.
+var (
+	redisSetCommands    = []string{"set", "setnx", "setex", "psetex", "mset"}
+	redisDeleteCommands = []string{"del", "unlink"}
+)
+
+// parseCommandCalls parses the number of calls from a commandstats entry.
+func parseCommandCalls(cmdInfo string) int64 {
+	// Parse "calls=N,usec=M,usec_per_call=X" format
+	parts := splitString(cmdInfo, ',')
+	for _, part := range parts {
+		if len(part) > 6 && part[:6] == "calls=" {
+			if calls, err := strconv.ParseInt(part[6:], 10, 64); err == nil {
+				return calls
+			}
+		}
+	}
+	return 0
+}
+
 // parseKeyCount parses the key count from db info string.
 func parseKeyCount(dbInfo string) int64 {
 	// Parse "keys=N,expires=M,avg_ttl=X" format
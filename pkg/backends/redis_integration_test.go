@@ -0,0 +1,815 @@
+//go:build integration
+
+package backends
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRedis connects to the Redis server in GOCACHEX_TEST_REDIS_ADDR
+// (default localhost:6379), skipping the test when it is unavailable.
+func newTestRedis(t *testing.T, cfg config.RedisConfig) *RedisBackend {
+	addr := os.Getenv("GOCACHEX_TEST_REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	cfg.Addresses = []string{addr}
+
+	backend, err := NewRedisBackend(cfg)
+	if err != nil {
+		t.Skipf("redis not available at %s: %v", addr, err)
+	}
+	t.Cleanup(func() { backend.Close() })
+	return backend
+}
+
+// invalidCommandHook injects a malformed command into the middle of every
+// pipeline, forcing a failure partway through the batch.
+type invalidCommandHook struct{}
+
+func (invalidCommandHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (invalidCommandHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (invalidCommandHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		middle := len(cmds) / 2
+		injected := append([]redis.Cmder{}, cmds[:middle]...)
+		injected = append(injected, redis.NewStatusCmd(ctx, "set", "gocachex:test:invalid"))
+		injected = append(injected, cmds[middle:]...)
+		return next(ctx, injected)
+	}
+}
+
+func TestRedisAtomicSetMulti(t *testing.T) {
+	for _, atomic := range []bool{false, true} {
+		t.Run(fmt.Sprintf("atomic=%v", atomic), func(t *testing.T) {
+			backend := newTestRedis(t, config.RedisConfig{AtomicSetMulti: atomic})
+			ctx := context.Background()
+
+			prefix := fmt.Sprintf("gocachex:test:setmulti:%d:", time.Now().UnixNano())
+			items := make(map[string][]byte)
+			keys := make([]string, 0, 10)
+			for i := 0; i < 10; i++ {
+				key := fmt.Sprintf("%s%d", prefix, i)
+				items[key] = []byte("value")
+				keys = append(keys, key)
+			}
+			defer backend.DeleteMulti(ctx, keys)
+
+			backend.client.AddHook(invalidCommandHook{})
+			assert.Error(t, backend.SetMulti(ctx, items, time.Minute))
+
+			written, err := backend.client.Exists(ctx, keys...).Result()
+			require.NoError(t, err)
+			if atomic {
+				assert.Zero(t, written, "atomic batch must not be partially applied")
+			} else {
+				assert.Positive(t, written, "pipelined batch applies the valid commands")
+			}
+		})
+	}
+}
+
+func TestRedisProbeMulti(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	require.NoError(t, backend.Set(ctx, "expiring", []byte("value"), time.Minute))
+	require.NoError(t, backend.Set(ctx, "persistent", []byte("value"), 0))
+
+	probes, err := backend.ProbeMulti(ctx, []string{"expiring", "persistent", "missing"})
+	require.NoError(t, err)
+	require.Len(t, probes, 3)
+
+	assert.True(t, probes["expiring"].Exists)
+	assert.InDelta(t, time.Minute.Seconds(), probes["expiring"].TTL.Seconds(), 1)
+	assert.Equal(t, KeyProbe{Exists: true, TTL: -1}, probes["persistent"])
+	assert.Equal(t, KeyProbe{}, probes["missing"])
+}
+
+func TestRedisGetMultiTTL(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{})
+	ctx := context.Background()
+
+	prefix := fmt.Sprintf("gocachex:test:multittl:%d:", time.Now().UnixNano())
+	expirations := map[string]time.Duration{
+		prefix + "short": time.Minute,
+		prefix + "long":  time.Hour,
+	}
+	keys := []string{prefix + "persistent", prefix + "missing"}
+	for key, ttl := range expirations {
+		require.NoError(t, backend.Set(ctx, key, []byte("value"), ttl))
+		keys = append(keys, key)
+	}
+	require.NoError(t, backend.Set(ctx, prefix+"persistent", []byte("value"), 0))
+	defer backend.DeleteMulti(ctx, keys)
+
+	ttls, err := backend.GetMultiTTL(ctx, keys)
+	require.NoError(t, err)
+	require.Len(t, ttls, 3)
+
+	for key, ttl := range expirations {
+		assert.LessOrEqual(t, ttls[key], ttl, key)
+		assert.Greater(t, ttls[key], ttl-2*time.Second, key)
+	}
+	assert.Equal(t, time.Duration(-1), ttls[prefix+"persistent"])
+	assert.NotContains(t, ttls, prefix+"missing")
+}
+
+func TestRedisCloseTwice(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{})
+
+	assert.NoError(t, backend.Close())
+	assert.NoError(t, backend.Close())
+}
+
+func TestRedisWatchKey(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{KeyspaceNotifications: true})
+	ctx := context.Background()
+
+	if err := backend.client.ConfigSet(ctx, "notify-keyspace-events", "K$").Err(); err != nil {
+		t.Skipf("cannot enable keyspace notifications: %v", err)
+	}
+
+	key := fmt.Sprintf("gocachex:test:watch:%d", time.Now().UnixNano())
+	defer backend.Delete(ctx, key)
+
+	events, stop, err := backend.WatchKey(ctx, key)
+	require.NoError(t, err)
+	defer stop()
+
+	require.NoError(t, backend.Set(ctx, key, []byte("value"), time.Minute))
+
+	select {
+	case <-events:
+	case <-time.After(time.Second):
+		t.Fatal("no notification received for write")
+	}
+}
+
+func TestRedisExpireCallback(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{KeyspaceNotifications: true})
+	ctx := context.Background()
+
+	if err := backend.client.ConfigSet(ctx, "notify-keyspace-events", "Ex").Err(); err != nil {
+		t.Skipf("cannot enable keyspace notifications: %v", err)
+	}
+
+	key := fmt.Sprintf("gocachex:test:expire:%d", time.Now().UnixNano())
+	fired := make(chan string, 1)
+	require.NoError(t, backend.SetWithExpireCallback(ctx, key, []byte("value"), 100*time.Millisecond, func(key string) {
+		fired <- key
+	}))
+
+	select {
+	case got := <-fired:
+		assert.Equal(t, key, got)
+	case <-time.After(3 * time.Second):
+		t.Fatal("expire callback not called")
+	}
+}
+
+func TestRedisExpireCallbackSubscription(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{KeyspaceNotifications: true})
+	ctx := context.Background()
+
+	if err := backend.client.ConfigSet(ctx, "notify-keyspace-events", "Ex").Err(); err != nil {
+		t.Skipf("cannot enable keyspace notifications: %v", err)
+	}
+
+	observer := newTestRedis(t, config.RedisConfig{})
+	channel := fmt.Sprintf("__keyevent@%d__:expired", backend.config.DB)
+	subscribers := func() int64 {
+		counts, err := observer.client.PubSubNumSub(ctx, channel).Result()
+		require.NoError(t, err)
+		return counts[channel]
+	}
+	before := subscribers()
+
+	// Every callback shares one subscription
+	prefix := fmt.Sprintf("gocachex:test:expire:%d", time.Now().UnixNano())
+	var mu sync.Mutex
+	fired := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("%s:%d", prefix, i)
+		require.NoError(t, backend.SetWithExpireCallback(ctx, key, []byte("value"), 200*time.Millisecond, func(key string) {
+			mu.Lock()
+			fired[key]++
+			mu.Unlock()
+		}))
+	}
+	assert.Equal(t, before+1, subscribers())
+
+	// Overwriting or deleting a key drops its callback
+	overwritten, deleted := prefix+":0", prefix+":1"
+	require.NoError(t, backend.Set(ctx, overwritten, []byte("other"), 200*time.Millisecond))
+	require.NoError(t, backend.Delete(ctx, deleted))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(fired) == 18
+	}, 3*time.Second, 20*time.Millisecond)
+
+	// Let the overwritten key expire too
+	time.Sleep(500 * time.Millisecond)
+	mu.Lock()
+	assert.Zero(t, fired[overwritten])
+	assert.Zero(t, fired[deleted])
+	for key, n := range fired {
+		assert.Equal(t, 1, n, key)
+	}
+	mu.Unlock()
+
+	require.NoError(t, backend.Close())
+	assert.Eventually(t, func() bool { return subscribers() == before }, time.Second, 20*time.Millisecond)
+}
+
+// commandCounter counts GET commands sent to the server.
+type commandCounter struct {
+	gets atomic.Int64
+}
+
+func (c *commandCounter) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (c *commandCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "get" {
+			c.gets.Add(1)
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (c *commandCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestRedisClientCache(t *testing.T) {
+	cached := newTestRedis(t, config.RedisConfig{
+		ClientCache: config.RedisClientCacheConfig{Enabled: true, MaxKeys: 100, TTL: time.Minute},
+	})
+	other := newTestRedis(t, config.RedisConfig{})
+	ctx := context.Background()
+
+	counter := &commandCounter{}
+	cached.client.AddHook(counter)
+
+	key := fmt.Sprintf("gocachex:test:clientcache:%d", time.Now().UnixNano())
+	defer other.Delete(ctx, key)
+	require.NoError(t, other.Set(ctx, key, []byte("v1"), time.Minute))
+
+	// The first read reaches the server, later ones are served locally
+	for i := 0; i < 3; i++ {
+		value, err := cached.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v1"), value)
+	}
+	assert.Equal(t, int64(1), counter.gets.Load())
+
+	// A write from another client invalidates the local copy
+	require.NoError(t, other.Set(ctx, key, []byte("v2"), time.Minute))
+	assert.Eventually(t, func() bool {
+		value, err := cached.Get(ctx, key)
+		return err == nil && string(value) == "v2"
+	}, time.Second, 10*time.Millisecond)
+	assert.Greater(t, counter.gets.Load(), int64(1))
+}
+
+func TestRedisExtendedStats(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{})
+
+	extra, err := backend.ExtendedStats(context.Background())
+	require.NoError(t, err)
+
+	for _, field := range []string{"evicted_keys", "expired_keys", "connected_clients"} {
+		assert.IsType(t, int64(0), extra[field], field)
+	}
+	assert.Positive(t, extra["connected_clients"])
+	assert.IsType(t, "", extra["redis_version"])
+}
+
+func TestRedisResetStats(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	require.NoError(t, backend.Set(ctx, "key", []byte("value"), time.Minute))
+	_, err := backend.Get(ctx, "key")
+	require.NoError(t, err)
+	_, err = backend.Get(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	stats, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	require.Positive(t, stats.Hits)
+	require.Positive(t, stats.Misses)
+
+	require.NoError(t, backend.ResetStats(ctx))
+	stats, err = backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Hits)
+	assert.Zero(t, stats.Misses)
+	assert.Equal(t, int64(1), stats.KeyCount)
+}
+
+func TestRedisStatsCounts(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	before, err := backend.Stats(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, backend.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, backend.Set(ctx, "b", []byte("2"), 0))
+	require.NoError(t, backend.Delete(ctx, "a"))
+
+	after, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, after.Sets-before.Sets, int64(2))
+	assert.GreaterOrEqual(t, after.Deletes-before.Deletes, int64(1))
+}
+
+func TestRedisSetIfNewer(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	// Nanosecond timestamps exceed the integers Lua numbers hold exactly
+	base := time.Now().UnixNano()
+	stored, err := backend.SetIfNewer(ctx, "key", []byte("new"), base+1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, stored)
+
+	stored, err = backend.SetIfNewer(ctx, "key", []byte("old"), base, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, stored)
+
+	value, err := backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new"), value)
+
+	ttl, err := backend.client.PTTL(ctx, versionKey("key")).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, 50*time.Second)
+
+	// Plain writes reset the version without deleting the version key
+	before, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	require.NoError(t, backend.Set(ctx, "key", []byte("plain"), time.Minute))
+	after, err := backend.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, before.Deletes, after.Deletes)
+
+	// A deleted key no longer holds its version
+	require.NoError(t, backend.Delete(ctx, "key"))
+	stored, err = backend.SetIfNewer(ctx, "key", []byte("old"), base, 0)
+	require.NoError(t, err)
+	assert.True(t, stored)
+
+	testSetIfNewerReset(t, backend)
+
+	// Out-of-order concurrent writers leave the highest version
+	var wg sync.WaitGroup
+	for _, i := range rand.Perm(50) {
+		wg.Add(1)
+		go func(version int64) {
+			defer wg.Done()
+			_, err := backend.SetIfNewer(ctx, "race", []byte(strconv.FormatInt(version, 10)), version, time.Minute)
+			assert.NoError(t, err)
+		}(int64(i))
+	}
+	wg.Wait()
+
+	value, err = backend.Get(ctx, "race")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("49"), value)
+}
+
+func TestRedisServerTime(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{})
+
+	now, err := backend.ServerTime(context.Background())
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), now, 5*time.Second)
+}
+
+// commandNames records the name of every command sent.
+type commandNames struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (c *commandNames) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (c *commandNames) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		c.mu.Lock()
+		c.names = append(c.names, cmd.Name())
+		c.mu.Unlock()
+		return next(ctx, cmd)
+	}
+}
+
+func (c *commandNames) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestRedisSize(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, backend.Set(ctx, fmt.Sprintf("gocachex:test:size:%d", i), []byte("v"), time.Minute))
+	}
+
+	names := &commandNames{}
+	backend.client.AddHook(names)
+
+	size, err := backend.Size(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), size)
+	assert.Equal(t, []string{"dbsize"}, names.names)
+}
+
+func TestRedisAlias(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	_, err := backend.SwapAlias(ctx, "data", "data:v1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, backend.SetAlias(ctx, "data", "data:v1"))
+	target, err := backend.ResolveAlias(ctx, "data")
+	require.NoError(t, err)
+	assert.Equal(t, "data:v1", target)
+
+	previous, err := backend.SwapAlias(ctx, "data", "data:v2")
+	require.NoError(t, err)
+	assert.Equal(t, "data:v1", previous)
+
+	// The alias is stored in a namespaced pointer key
+	pointer, err := backend.client.Get(ctx, aliasKeyPrefix+"data").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "data:v2", pointer)
+
+	_, err = backend.Get(ctx, "data")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Scans skip the pointer key, so pattern operations leave it alone
+	keys, err := backend.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	found, err := backend.ExistsPattern(ctx, "*")
+	require.NoError(t, err)
+	assert.False(t, found)
+	require.NoError(t, backend.Iterate(ctx, func(key string, value []byte, ttl time.Duration) error {
+		t.Errorf("unexpected entry %s", key)
+		return nil
+	}))
+	deleted, err := backend.DeleteByPattern(ctx, "*")
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	target, err = backend.ResolveAlias(ctx, "data")
+	require.NoError(t, err)
+	assert.Equal(t, "data:v2", target)
+}
+
+func TestRedisSetNX(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	var wins atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			stored, err := backend.SetNX(ctx, "lock", []byte(fmt.Sprint(i)), time.Minute)
+			assert.NoError(t, err)
+			if stored {
+				wins.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), wins.Load())
+	ttl, err := backend.client.TTL(ctx, "lock").Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+}
+
+func TestRedisGetSet(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	_, err := backend.GetSet(ctx, "key", []byte("a"))
+	assert.ErrorIs(t, err, ErrNotFound)
+	ttl, err := backend.client.TTL(ctx, "key").Result()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), ttl, "new keys do not expire")
+
+	require.NoError(t, backend.Set(ctx, "key", []byte("b"), time.Minute))
+	old, err := backend.GetSet(ctx, "key", []byte("c"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), old)
+
+	value, err := backend.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("c"), value)
+	ttl, err = backend.client.TTL(ctx, "key").Result()
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 1, "the swap keeps the remaining TTL")
+}
+
+func TestRedisGetSetConcurrent(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	require.NoError(t, backend.Set(ctx, "key", []byte("initial"), time.Minute))
+
+	// Every swap returns the value of exactly one earlier swap, so each
+	// value is seen once, either returned or left in the key
+	const writers = 50
+	olds := make([]string, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			old, err := backend.GetSet(ctx, "key", []byte(fmt.Sprint(i)))
+			assert.NoError(t, err)
+			olds[i] = string(old)
+		}(i)
+	}
+	wg.Wait()
+
+	final, err := backend.Get(ctx, "key")
+	require.NoError(t, err)
+
+	seen := map[string]int{string(final): 1}
+	for _, old := range olds {
+		seen[old]++
+	}
+	assert.Len(t, seen, writers+1)
+	for value, count := range seen {
+		assert.Equal(t, 1, count, value)
+	}
+	assert.Equal(t, 1, seen["initial"])
+}
+
+func TestRedisIncrementWithTTLOnCreate(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	value, err := backend.IncrementWithTTLOnCreate(ctx, "counter", 2, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), value)
+	ttl, err := backend.client.TTL(ctx, "counter").Result()
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 1)
+
+	value, err = backend.IncrementWithTTLOnCreate(ctx, "counter", 3, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+	ttl, err = backend.client.TTL(ctx, "counter").Result()
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 1, "later increments do not reset the TTL")
+
+	require.NoError(t, backend.Set(ctx, "text", []byte("abc"), 0))
+	_, err = backend.IncrementWithTTLOnCreate(ctx, "text", 1, time.Minute)
+	assert.Error(t, err)
+}
+
+func TestRedisRateLimit(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	window := 200 * time.Millisecond
+	for i := 0; i < 5; i++ {
+		allowed, remaining, err := backend.RateLimitAllow(ctx, "api", 5, window)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, 4-i, remaining)
+	}
+	allowed, _, err := backend.RateLimitAllow(ctx, "api", 5, window)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// The window is a sorted set that expires with the window
+	count, err := backend.client.ZCard(ctx, rateLimitKeyPrefix+"api").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+	ttl, err := backend.client.PTTL(ctx, rateLimitKeyPrefix+"api").Result()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, ttl, window)
+
+	time.Sleep(window + 50*time.Millisecond)
+	allowed, remaining, err := backend.RateLimitAllow(ctx, "api", 5, window)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, 4, remaining)
+}
+
+func TestRedisListPushCapped(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	for i := 0; i < 8; i++ {
+		require.NoError(t, backend.ListPushCapped(ctx, "events", []byte(strconv.Itoa(i)), 3, time.Minute))
+	}
+	values, err := backend.ListRange(ctx, "events")
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("7"), []byte("6"), []byte("5")}, values)
+
+	// The list is a Redis list in its own namespace, expiring with the TTL
+	length, err := backend.client.LLen(ctx, listKeyPrefix+"events").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), length)
+	ttl, err := backend.client.PTTL(ctx, listKeyPrefix+"events").Result()
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 1)
+
+	// A push without a TTL makes the list persistent
+	require.NoError(t, backend.ListPushCapped(ctx, "events", []byte("8"), 3, 0))
+	ttl, err = backend.client.PTTL(ctx, listKeyPrefix+"events").Result()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), ttl)
+
+	values, err = backend.ListRange(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestRedisKeys(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	// Enough keys to take several SCAN pages
+	items := make(map[string][]byte)
+	var expected []string
+	for i := 0; i < 3*scanBatchSize; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		items[key] = []byte("v")
+		expected = append(expected, key)
+		items[fmt.Sprintf("session:%d", i)] = []byte("v")
+	}
+	require.NoError(t, backend.SetMulti(ctx, items, time.Minute))
+	require.NoError(t, backend.Set(ctx, "user:1:profile", []byte("v"), time.Minute))
+
+	keys, err := backend.Keys(ctx, "user:*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, append(expected, "user:1:profile"), keys)
+
+	keys, err = backend.Keys(ctx, "*:profile")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:1:profile"}, keys)
+}
+
+func TestRedisDeleteByPattern(t *testing.T) {
+	backend := newTestRedis(t, config.RedisConfig{DB: 15})
+	ctx := context.Background()
+	require.NoError(t, backend.Clear(ctx))
+	defer backend.Clear(ctx)
+
+	// Enough keys to take several SCAN pages
+	items := make(map[string][]byte)
+	for i := 0; i < 3*scanBatchSize; i++ {
+		items[fmt.Sprintf("products:%d", i)] = []byte("v")
+	}
+	items["product:1"] = []byte("v")
+	items["users:1"] = []byte("v")
+	require.NoError(t, backend.SetMulti(ctx, items, time.Minute))
+	stored, err := backend.SetIfNewer(ctx, "products:versioned", []byte("v"), 1, time.Minute)
+	require.NoError(t, err)
+	require.True(t, stored)
+
+	deleted, err := backend.DeleteByPattern(ctx, "products:*")
+	require.NoError(t, err)
+	assert.Equal(t, 3*scanBatchSize+1, deleted)
+	exists, err := backend.client.Exists(ctx, versionKey("products:versioned")).Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists)
+
+	// Internal keys are not entries
+	stored, err = backend.SetIfNewer(ctx, "users:2", []byte("v"), 1, time.Minute)
+	require.NoError(t, err)
+	require.True(t, stored)
+	require.NoError(t, backend.SetAlias(ctx, "alias", "users:1"))
+	_, _, err = backend.RateLimitAllow(ctx, "limit", 10, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, backend.ListPushCapped(ctx, "list", []byte("v"), 5, time.Minute))
+
+	keys, err := backend.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"product:1", "users:1", "users:2"}, keys)
+}
+
+// commandCalls returns how many times the server at client ran the command,
+// from INFO commandstats.
+func commandCalls(t *testing.T, client *redis.Client, name string) int64 {
+	info, err := client.Info(context.Background(), "commandstats").Result()
+	require.NoError(t, err)
+
+	var calls int64
+	for _, line := range strings.Split(info, "\r\n") {
+		if strings.HasPrefix(line, "cmdstat_"+name+":") {
+			fmt.Sscanf(strings.TrimPrefix(line, "cmdstat_"+name+":"), "calls=%d", &calls)
+		}
+	}
+	return calls
+}
+
+func TestRedisReadFromReplica(t *testing.T) {
+	replica := os.Getenv("GOCACHEX_TEST_REDIS_REPLICA_ADDR")
+	if replica == "" {
+		t.Skip("GOCACHEX_TEST_REDIS_REPLICA_ADDR not set")
+	}
+	master := os.Getenv("GOCACHEX_TEST_REDIS_ADDR")
+	if master == "" {
+		master = "localhost:6379"
+	}
+
+	backend, err := NewRedisBackend(config.RedisConfig{
+		Addresses:        []string{master},
+		ReplicaAddresses: []string{replica},
+		ReadFromReplica:  true,
+	})
+	if err != nil {
+		t.Skipf("redis not available at %s: %v", master, err)
+	}
+	defer backend.Close()
+
+	masterClient := redis.NewClient(&redis.Options{Addr: master})
+	defer masterClient.Close()
+	replicaClient := redis.NewClient(&redis.Options{Addr: replica})
+	defer replicaClient.Close()
+
+	ctx := context.Background()
+	key := fmt.Sprintf("gocachex:test:replica:%d", time.Now().UnixNano())
+	defer backend.Delete(ctx, key)
+
+	masterSets := commandCalls(t, masterClient, "set")
+	replicaGets := commandCalls(t, replicaClient, "get")
+	masterGets := commandCalls(t, masterClient, "get")
+
+	require.NoError(t, backend.Set(ctx, key, []byte("value"), time.Minute))
+	assert.Equal(t, masterSets+1, commandCalls(t, masterClient, "set"))
+
+	// Wait for the write to replicate
+	require.Eventually(t, func() bool {
+		value, err := backend.Get(ctx, key)
+		return err == nil && string(value) == "value"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Greater(t, commandCalls(t, replicaClient, "get"), replicaGets)
+	assert.Equal(t, masterGets, commandCalls(t, masterClient, "get"))
+}
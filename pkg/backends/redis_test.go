@@ -0,0 +1,182 @@
+package backends
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisClientMode(t *testing.T) {
+	addresses := []string{"localhost:6379", "localhost:6380"}
+
+	tests := []struct {
+		name string
+		cfg  config.RedisConfig
+		want string // single, cluster or sentinel
+	}{
+		{"single", config.RedisConfig{Mode: config.RedisModeSingle, Addresses: addresses}, "single"},
+		{"cluster", config.RedisConfig{Mode: config.RedisModeCluster, Addresses: addresses}, "cluster"},
+		{"sentinel", config.RedisConfig{Mode: config.RedisModeSentinel, Addresses: addresses[:1], MasterName: "cache"}, "sentinel"},
+		{"inferred single", config.RedisConfig{Addresses: addresses[:1]}, "single"},
+		{"inferred cluster", config.RedisConfig{Addresses: addresses, Cluster: config.RedisClusterConfig{Enabled: true}}, "cluster"},
+		{"inferred sentinel", config.RedisConfig{Addresses: addresses}, "sentinel"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _, err := newRedisClient(tt.cfg)
+			require.NoError(t, err)
+			defer client.Close()
+
+			switch c := client.(type) {
+			case *redis.ClusterClient:
+				assert.Equal(t, "cluster", tt.want)
+			case *redis.Client:
+				if c.Options().Addr == "FailoverClient" {
+					assert.Equal(t, "sentinel", tt.want)
+				} else {
+					assert.Equal(t, "single", tt.want)
+					assert.Equal(t, addresses[0], c.Options().Addr)
+				}
+			default:
+				t.Fatalf("unexpected client %T", client)
+			}
+		})
+	}
+}
+
+func TestRedisMasterName(t *testing.T) {
+	assert.Equal(t, "master", masterName(config.RedisConfig{}))
+	assert.Equal(t, "cache", masterName(config.RedisConfig{MasterName: "cache"}))
+}
+
+// clientOptionsTLS returns the TLS configuration of a client created by
+// newRedisClient.
+func clientOptionsTLS(t *testing.T, client redis.UniversalClient) *tls.Config {
+	switch c := client.(type) {
+	case *redis.Client:
+		return c.Options().TLSConfig
+	case *redis.ClusterClient:
+		return c.Options().TLSConfig
+	default:
+		t.Fatalf("unexpected client %T", client)
+		return nil
+	}
+}
+
+func TestRedisTLS(t *testing.T) {
+	for _, mode := range []string{config.RedisModeSingle, config.RedisModeCluster, config.RedisModeSentinel} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := config.RedisConfig{Mode: mode, Addresses: []string{"localhost:6379"}, MasterName: "cache"}
+
+			client, _, err := newRedisClient(cfg)
+			require.NoError(t, err)
+			assert.Nil(t, clientOptionsTLS(t, client))
+			client.Close()
+
+			cfg.TLS = true
+			cfg.TLSSkipVerify = true
+			client, _, err = newRedisClient(cfg)
+			require.NoError(t, err)
+			defer client.Close()
+
+			tlsConfig := clientOptionsTLS(t, client)
+			require.NotNil(t, tlsConfig)
+			assert.True(t, tlsConfig.InsecureSkipVerify)
+		})
+	}
+}
+
+func TestRedisTLSFiles(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+
+	tlsConfig, err := redisTLSConfig(config.RedisConfig{
+		TLS:           true,
+		TLSCertFile:   certFile,
+		TLSKeyFile:    keyFile,
+		TLSCAFile:     certFile,
+		TLSServerName: "redis.internal",
+	})
+	require.NoError(t, err)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Equal(t, "redis.internal", tlsConfig.ServerName)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	_, err = redisTLSConfig(config.RedisConfig{TLS: true, TLSCAFile: keyFile})
+	assert.ErrorContains(t, err, "no certificates found")
+
+	_, err = redisTLSConfig(config.RedisConfig{TLS: true, TLSCertFile: certFile, TLSKeyFile: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.ErrorContains(t, err, "failed to load redis client certificate")
+}
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM
+// files, returning their paths.
+func writeTestCertificate(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis.internal"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestRedisVersionKey(t *testing.T) {
+	assert.Equal(t, "gocachex:version:{user:1}", versionKey("user:1"))
+	assert.Equal(t, "gocachex:version:{user}:1", versionKey("{user}:1"))
+}
+
+func TestRedisDropInternalKeys(t *testing.T) {
+	keys := []string{
+		"user:1",
+		"gocachex:alias:user",
+		"gocachex:version:{user:1}",
+		"gocachex:ratelimit:api",
+		"gocachex:list:events",
+		"gocachex:other",
+	}
+	assert.Equal(t, []string{"user:1", "gocachex:other"}, dropInternalKeys(keys))
+}
+
+func TestRedisVersionString(t *testing.T) {
+	versions := []int64{math.MinInt64, -1 << 53, -2, -1, 0, 1, 2, 1<<53 + 1, math.MaxInt64}
+
+	encoded := make([]string, len(versions))
+	for i, version := range versions {
+		encoded[i] = versionString(version)
+		assert.Len(t, encoded[i], 20)
+	}
+	assert.True(t, sort.StringsAreSorted(encoded))
+}
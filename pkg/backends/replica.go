@@ -0,0 +1,120 @@
+package backends
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultSentinelMasterName is the master name used when none is configured,
+// as sentinel mode may be inferred from the addresses.
+const defaultSentinelMasterName = "master"
+
+// masterName returns the master monitored by the sentinels.
+func masterName(cfg config.RedisConfig) string {
+	if cfg.MasterName == "" {
+		return defaultSentinelMasterName
+	}
+	return cfg.MasterName
+}
+
+// newReplicaClient creates a client that routes read-only commands to
+// replicas and all other commands to the master. The topology is described
+// to a ClusterClient as a single slot range served by the master and its
+// replicas, which makes it pick a replica for commands Redis flags as
+// read-only. In sentinel mode the master and replicas are looked up through
+// the sentinels; otherwise they are the configured addresses.
+func newReplicaClient(cfg config.RedisConfig, tlsConfig *tls.Config) *redis.ClusterClient {
+	slots := staticReplicaSlots(cfg.Addresses[0], cfg.ReplicaAddresses)
+	if cfg.EffectiveMode() == config.RedisModeSentinel {
+		slots = sentinelReplicaSlots(cfg, tlsConfig)
+	}
+
+	// Cluster clients have no DB option, so select it on every connection
+	var onConnect func(ctx context.Context, cn *redis.Conn) error
+	if cfg.DB != 0 {
+		onConnect = func(ctx context.Context, cn *redis.Conn) error {
+			return cn.Select(ctx, cfg.DB).Err()
+		}
+	}
+
+	return redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots:    slots,
+		ReadOnly:        true,
+		Password:        cfg.Password,
+		DialTimeout:     cfg.DialTimeout,
+		ReadTimeout:     cfg.ReadTimeout,
+		WriteTimeout:    cfg.WriteTimeout,
+		PoolSize:        cfg.PoolSize,
+		PoolTimeout:     cfg.PoolTimeout,
+		ConnMaxIdleTime: cfg.IdleTimeout,
+		MaxRetries:      cfg.MaxRetries,
+		MinRetryBackoff: cfg.MinRetryBackoff,
+		MaxRetryBackoff: cfg.MaxRetryBackoff,
+		OnConnect:       onConnect,
+		TLSConfig:       tlsConfig,
+	})
+}
+
+// staticReplicaSlots describes a fixed master and its replicas.
+func staticReplicaSlots(master string, replicas []string) func(ctx context.Context) ([]redis.ClusterSlot, error) {
+	nodes := []redis.ClusterNode{{Addr: master}}
+	for _, addr := range replicas {
+		nodes = append(nodes, redis.ClusterNode{Addr: addr})
+	}
+
+	return func(ctx context.Context) ([]redis.ClusterSlot, error) {
+		return []redis.ClusterSlot{{Start: 0, End: 16383, Nodes: nodes}}, nil
+	}
+}
+
+// sentinelReplicaSlots asks the sentinels in cfg.Addresses for the current
+// master and its healthy replicas, using the first sentinel that answers.
+func sentinelReplicaSlots(cfg config.RedisConfig, tlsConfig *tls.Config) func(ctx context.Context) ([]redis.ClusterSlot, error) {
+	return func(ctx context.Context) ([]redis.ClusterSlot, error) {
+		var lastErr error
+		for _, addr := range cfg.Addresses {
+			sentinel := redis.NewSentinelClient(&redis.Options{
+				Addr:        addr,
+				DialTimeout: cfg.DialTimeout,
+				ReadTimeout: cfg.ReadTimeout,
+				TLSConfig:   tlsConfig,
+			})
+			nodes, err := sentinelNodes(ctx, sentinel, masterName(cfg))
+			sentinel.Close()
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			return []redis.ClusterSlot{{Start: 0, End: 16383, Nodes: nodes}}, nil
+		}
+		return nil, fmt.Errorf("no sentinel available: %w", lastErr)
+	}
+}
+
+// sentinelNodes returns the master followed by the replicas that are up.
+func sentinelNodes(ctx context.Context, sentinel *redis.SentinelClient, name string) ([]redis.ClusterNode, error) {
+	master, err := sentinel.GetMasterAddrByName(ctx, name).Result()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []redis.ClusterNode{{Addr: net.JoinHostPort(master[0], master[1])}}
+
+	replicas, err := sentinel.Replicas(ctx, name).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, replica := range replicas {
+		if flags := replica["flags"]; strings.Contains(flags, "down") || strings.Contains(flags, "disconnected") {
+			continue
+		}
+		nodes = append(nodes, redis.ClusterNode{Addr: net.JoinHostPort(replica["ip"], replica["port"])})
+	}
+
+	return nodes, nil
+}
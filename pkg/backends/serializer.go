@@ -4,19 +4,101 @@ import (
 	"bytes"
 	"encoding/gob"
 	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 // JSONSerializer implements JSON serialization.
-type JSONSerializer struct{}
+type JSONSerializer struct {
+	// PreserveIntegers decodes integral numbers into interface{} values as
+	// int64 instead of float64. Numbers with a fraction or an exponent, or
+	// out of the int64 range, still decode as float64.
+	PreserveIntegers bool
+
+	// Canonical serializes equal values to identical bytes, so they can be
+	// hashed for checksums or ETags: the keys of every object are sorted,
+	// struct fields included, and HTML characters are not escaped.
+	Canonical bool
+}
 
 // Serialize serializes data to JSON.
 func (j *JSONSerializer) Serialize(data interface{}) ([]byte, error) {
+	if j.Canonical {
+		return canonicalJSON(data)
+	}
 	return json.Marshal(data)
 }
 
+// canonicalJSON encodes data with sorted object keys. encoding/json sorts
+// map keys but writes struct fields, and the output of MarshalJSON methods,
+// as they come, so data is first decoded into maps, keeping the exact text
+// of numbers, and then encoded again.
+func canonicalJSON(data interface{}) ([]byte, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
 // Deserialize deserializes JSON data.
 func (j *JSONSerializer) Deserialize(data []byte, target interface{}) error {
-	return json.Unmarshal(data, target)
+	out, ok := target.(*interface{})
+	if !j.PreserveIntegers || !ok {
+		return json.Unmarshal(data, target)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level JSON value")
+	}
+
+	*out = convertNumbers(value)
+	return nil
+}
+
+// convertNumbers replaces the json.Number values in a decoded JSON value
+// with int64 when they are integral and float64 otherwise.
+func convertNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	}
+	return value
 }
 
 // ContentType returns the content type for JSON.
@@ -49,23 +131,29 @@ func (g *GobSerializer) ContentType() string {
 	return "application/gob"
 }
 
-// MsgPackSerializer implements MessagePack serialization.
-// Note: This is a placeholder implementation. In a real implementation,
-// you would use a library like github.com/vmihailenco/msgpack/v5
+// MsgPackSerializer implements MessagePack serialization. Struct fields
+// use their msgpack tags, falling back to json tags. Values decoded into an
+// interface{} keep integers as int64 or uint64 rather than float64, decode
+// maps as map[string]interface{} and keep time.Time values intact.
 type MsgPackSerializer struct{}
 
-// Serialize serializes data using MessagePack (placeholder).
+// Serialize serializes data using MessagePack.
 func (m *MsgPackSerializer) Serialize(data interface{}) ([]byte, error) {
-	// For now, fall back to JSON
-	// In a real implementation, use msgpack library
-	return json.Marshal(data)
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
-// Deserialize deserializes MessagePack data (placeholder).
+// Deserialize deserializes MessagePack data.
 func (m *MsgPackSerializer) Deserialize(data []byte, target interface{}) error {
-	// For now, fall back to JSON
-	// In a real implementation, use msgpack library
-	return json.Unmarshal(data, target)
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	decoder.UseLooseInterfaceDecoding(true)
+	return decoder.Decode(target)
 }
 
 // ContentType returns the content type for MessagePack.
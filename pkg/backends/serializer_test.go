@@ -0,0 +1,174 @@
+package backends
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type msgpackOrder struct {
+	ID       int64             `msgpack:"id"`
+	Quantity uint16            `msgpack:"qty"`
+	Customer string            `json:"customer"`
+	Placed   time.Time         `msgpack:"placed"`
+	Tags     map[string]string `msgpack:"tags"`
+}
+
+func TestMsgPackSerializerNotJSON(t *testing.T) {
+	s := &MsgPackSerializer{}
+	value := map[string]interface{}{"name": "gocachex", "count": 3}
+
+	data, err := s.Serialize(value)
+	require.NoError(t, err)
+
+	jsonData, err := json.Marshal(value)
+	require.NoError(t, err)
+	assert.NotEqual(t, jsonData, data)
+	assert.False(t, json.Valid(data))
+	assert.Less(t, len(data), len(jsonData))
+}
+
+func TestMsgPackSerializerStructRoundTrip(t *testing.T) {
+	s := &MsgPackSerializer{}
+	order := msgpackOrder{
+		ID:       1 << 40,
+		Quantity: 7,
+		Customer: "ana",
+		Placed:   time.Date(2024, 5, 1, 12, 30, 0, 123, time.UTC),
+		Tags:     map[string]string{"priority": "high"},
+	}
+
+	data, err := s.Serialize(order)
+	require.NoError(t, err)
+
+	var decoded msgpackOrder
+	require.NoError(t, s.Deserialize(data, &decoded))
+	assert.Equal(t, order.ID, decoded.ID)
+	assert.Equal(t, order.Quantity, decoded.Quantity)
+	assert.Equal(t, order.Customer, decoded.Customer)
+	assert.True(t, order.Placed.Equal(decoded.Placed))
+	assert.Equal(t, order.Tags, decoded.Tags)
+
+	// Struct tags name the encoded fields, with json tags as a fallback
+	var fields map[string]interface{}
+	require.NoError(t, s.Deserialize(data, &fields))
+	assert.Contains(t, fields, "qty")
+	assert.Contains(t, fields, "customer")
+}
+
+func TestMsgPackSerializerInterfaceKeepsIntegers(t *testing.T) {
+	s := &MsgPackSerializer{}
+	value := map[string]interface{}{
+		"count": 42,
+		"big":   int64(1) << 53,
+		"ratio": 0.5,
+		"nested": map[string]interface{}{
+			"items": []interface{}{1, "two"},
+		},
+	}
+
+	data, err := s.Serialize(value)
+	require.NoError(t, err)
+
+	var decoded interface{}
+	require.NoError(t, s.Deserialize(data, &decoded))
+
+	m, ok := decoded.(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, int64(42), m["count"])
+	assert.Equal(t, int64(1)<<53, m["big"])
+	assert.Equal(t, 0.5, m["ratio"])
+
+	nested, ok := m["nested"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, []interface{}{int64(1), "two"}, nested["items"])
+}
+
+func TestJSONSerializerPreserveIntegers(t *testing.T) {
+	data := []byte(`{"count":42,"big":9007199254740993,"ratio":0.5,"exp":1e3,"items":[1,"two",{"n":-7}]}`)
+
+	var plain interface{}
+	require.NoError(t, (&JSONSerializer{}).Deserialize(data, &plain))
+	assert.Equal(t, float64(42), plain.(map[string]interface{})["count"])
+
+	s := &JSONSerializer{PreserveIntegers: true}
+	var decoded interface{}
+	require.NoError(t, s.Deserialize(data, &decoded))
+
+	m := decoded.(map[string]interface{})
+	assert.Equal(t, int64(42), m["count"])
+	assert.Equal(t, int64(9007199254740993), m["big"], "integers beyond float64 precision survive")
+	assert.Equal(t, 0.5, m["ratio"])
+	assert.Equal(t, float64(1000), m["exp"])
+	assert.Equal(t, []interface{}{int64(1), "two", map[string]interface{}{"n": int64(-7)}}, m["items"])
+
+	var scalar interface{}
+	require.NoError(t, s.Deserialize([]byte(`123`), &scalar))
+	assert.Equal(t, int64(123), scalar)
+
+	// Typed targets decode as usual
+	var typed struct {
+		Count int     `json:"count"`
+		Ratio float64 `json:"ratio"`
+	}
+	require.NoError(t, s.Deserialize(data, &typed))
+	assert.Equal(t, 42, typed.Count)
+
+	assert.Error(t, s.Deserialize([]byte(`1 2`), &scalar))
+	assert.Error(t, s.Deserialize([]byte(`{"a":`), &scalar))
+}
+
+// reversedFields declares its fields out of alphabetical order.
+type reversedFields struct {
+	Zeta  string                 `json:"zeta"`
+	Alpha int                    `json:"alpha"`
+	Inner map[string]interface{} `json:"inner"`
+}
+
+func TestJSONSerializerCanonical(t *testing.T) {
+	s := &JSONSerializer{Canonical: true}
+
+	// Maps filled in different orders serialize identically
+	first := make(map[string]interface{})
+	second := make(map[string]interface{})
+	keys := []string{"b", "a", "d", "c", "f", "e"}
+	for i, key := range keys {
+		first[key] = map[string]interface{}{"z": i, "y": []interface{}{key, i}}
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		second[keys[i]] = map[string]interface{}{"y": []interface{}{keys[i], i}, "z": i}
+	}
+	a, err := s.Serialize(first)
+	require.NoError(t, err)
+	b, err := s.Serialize(second)
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	// Struct fields are sorted too, so a struct and the equivalent map match
+	value := reversedFields{Zeta: "<z&>", Alpha: 1, Inner: map[string]interface{}{"n": 9007199254740993}}
+	fromStruct, err := s.Serialize(value)
+	require.NoError(t, err)
+	fromMap, err := s.Serialize(map[string]interface{}{
+		"inner": map[string]interface{}{"n": int64(9007199254740993)},
+		"alpha": 1,
+		"zeta":  "<z&>",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"alpha":1,"inner":{"n":9007199254740993},"zeta":"<z&>"}`, string(fromStruct))
+	assert.Equal(t, fromStruct, fromMap)
+
+	var decoded reversedFields
+	require.NoError(t, s.Deserialize(fromStruct, &decoded))
+	assert.Equal(t, "<z&>", decoded.Zeta)
+
+	// Without the option struct fields keep their declaration order
+	plain, err := (&JSONSerializer{}).Serialize(value)
+	require.NoError(t, err)
+	assert.NotEqual(t, fromStruct, plain)
+
+	_, err = s.Serialize(make(chan int))
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package backends
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// typeRegistry maps type names recorded by TypedSerializer to their types.
+var typeRegistry = struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+}{types: make(map[string]reflect.Type)}
+
+func init() {
+	// Register the basic types so numbers keep their concrete type
+	for _, value := range []interface{}{
+		false, "",
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+	} {
+		RegisterType(value)
+	}
+}
+
+// RegisterType records the concrete type of value so TypedSerializer can
+// reconstruct it on read. Register pointer and value types separately, e.g.
+// RegisterType(&Product{}) for values stored as *Product.
+func RegisterType(value interface{}) {
+	t := reflect.TypeOf(value)
+	if t == nil {
+		return
+	}
+
+	typeRegistry.Lock()
+	typeRegistry.types[typeName(t)] = t
+	typeRegistry.Unlock()
+}
+
+// lookupType returns the registered type with the given name.
+func lookupType(name string) (reflect.Type, bool) {
+	typeRegistry.RLock()
+	defer typeRegistry.RUnlock()
+	t, ok := typeRegistry.types[name]
+	return t, ok
+}
+
+// typeName returns a name identifying t, qualified by its package path so
+// equally named types from different packages do not collide.
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		return "*" + typeName(t.Elem())
+	}
+	if t.Name() != "" && t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
+}
+
+// typedEnvelope is the stored form of a TypedSerializer value.
+type typedEnvelope struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// TypedSerializer implements JSON serialization that records the concrete
+// type of each value, so values of registered types round-trip through an
+// interface{} as their original type instead of generic maps and float64s.
+// Values of unregistered types are decoded as plain JSON.
+type TypedSerializer struct{}
+
+// Serialize serializes data to JSON wrapped in a type envelope.
+func (s *TypedSerializer) Serialize(data interface{}) ([]byte, error) {
+	value, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := typedEnvelope{Value: value}
+	if t := reflect.TypeOf(data); t != nil {
+		envelope.Type = typeName(t)
+	}
+	return json.Marshal(envelope)
+}
+
+// Deserialize deserializes a type envelope. When target is an *interface{}
+// and the recorded type is registered, it receives a value of that type.
+func (s *TypedSerializer) Deserialize(data []byte, target interface{}) error {
+	var envelope typedEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return err
+	}
+
+	out, ok := target.(*interface{})
+	if !ok {
+		return json.Unmarshal(envelope.Value, target)
+	}
+
+	t, registered := lookupType(envelope.Type)
+	if !registered {
+		return json.Unmarshal(envelope.Value, target)
+	}
+
+	value := reflect.New(t)
+	if err := json.Unmarshal(envelope.Value, value.Interface()); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", envelope.Type, err)
+	}
+	*out = value.Elem().Interface()
+	return nil
+}
+
+// ContentType returns the content type for typed JSON.
+func (s *TypedSerializer) ContentType() string {
+	return "application/vnd.gocachex.typed+json"
+}
@@ -0,0 +1,55 @@
+package backends
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type typedProduct struct {
+	ID    int       `json:"id"`
+	Name  string    `json:"name"`
+	Price float64   `json:"price"`
+	Added time.Time `json:"added"`
+}
+
+type unregisteredProduct struct {
+	ID int `json:"id"`
+}
+
+func TestTypedSerializerRoundTrip(t *testing.T) {
+	RegisterType(typedProduct{})
+	RegisterType(&typedProduct{})
+
+	s := &TypedSerializer{}
+	product := typedProduct{ID: 1, Name: "Laptop", Price: 999.99, Added: time.Now().UTC().Truncate(time.Second)}
+
+	for _, value := range []interface{}{product, &product, int64(42), 3, true} {
+		data, err := s.Serialize(value)
+		require.NoError(t, err)
+
+		var result interface{}
+		require.NoError(t, s.Deserialize(data, &result))
+		assert.IsType(t, value, result)
+		assert.Equal(t, value, result)
+	}
+}
+
+func TestTypedSerializerUnregisteredType(t *testing.T) {
+	s := &TypedSerializer{}
+
+	data, err := s.Serialize(unregisteredProduct{ID: 7})
+	require.NoError(t, err)
+
+	// Unregistered types decode as plain JSON
+	var result interface{}
+	require.NoError(t, s.Deserialize(data, &result))
+	assert.Equal(t, map[string]interface{}{"id": float64(7)}, result)
+
+	// A concrete target is filled directly
+	var product unregisteredProduct
+	require.NoError(t, s.Deserialize(data, &product))
+	assert.Equal(t, unregisteredProduct{ID: 7}, product)
+}
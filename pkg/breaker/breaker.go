@@ -0,0 +1,129 @@
+// Package breaker provides a circuit breaker that stops calls to a failing
+// backend until a cooldown has passed.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow while the breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// Closed lets every call through.
+	Closed State = iota
+	// Open rejects every call until the cooldown has passed.
+	Open
+	// HalfOpen lets a single trial call through after the cooldown.
+	HalfOpen
+)
+
+// String returns the state name.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker opens after a number of consecutive failures and rejects calls
+// until the cooldown has passed. It then lets one trial call through: a
+// success closes the breaker again, a failure re-opens it.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     State
+	failures  int
+	openedAt  time.Time
+	trial     bool
+}
+
+// New creates a breaker that opens after threshold consecutive failures and
+// stays open for cooldown.
+func New(threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &Breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow returns ErrOpen if the call should be rejected. Every allowed call
+// must be followed by Record with its outcome.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrOpen
+		}
+		b.state = HalfOpen
+		b.trial = true
+		return nil
+	case HalfOpen:
+		// Only one trial call at a time
+		if b.trial {
+			return ErrOpen
+		}
+		b.trial = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of an allowed call.
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.state = Closed
+		b.failures = 0
+		b.trial = false
+		return
+	}
+
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.threshold {
+		b.state = Open
+		b.openedAt = time.Now()
+		b.trial = false
+	}
+}
+
+// State returns the current state. An open breaker whose cooldown has
+// passed reports HalfOpen.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && time.Since(b.openedAt) >= b.cooldown {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// Reset closes the breaker and clears the failure count immediately,
+// without waiting for the cooldown.
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = Closed
+	b.failures = 0
+	b.trial = false
+}
@@ -0,0 +1,69 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := New(3, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Record(true)
+	}
+	assert.Equal(t, Closed, b.State())
+
+	// A success clears the failure count
+	assert.NoError(t, b.Allow())
+	b.Record(false)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Record(true)
+	}
+	assert.Equal(t, Closed, b.State())
+
+	assert.NoError(t, b.Allow())
+	b.Record(true)
+	assert.Equal(t, Open, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+}
+
+func TestBreakerHalfOpenTrial(t *testing.T) {
+	b := New(1, 20*time.Millisecond)
+
+	assert.NoError(t, b.Allow())
+	b.Record(true)
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, HalfOpen, b.State())
+
+	// Only one trial call is let through
+	assert.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	// A failed trial re-opens the breaker
+	b.Record(true)
+	assert.Equal(t, Open, b.State())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, b.Allow())
+	b.Record(false)
+	assert.Equal(t, Closed, b.State())
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreakerReset(t *testing.T) {
+	b := New(1, time.Hour)
+
+	assert.NoError(t, b.Allow())
+	b.Record(true)
+	assert.Equal(t, Open, b.State())
+
+	b.Reset()
+	assert.Equal(t, Closed, b.State())
+	assert.NoError(t, b.Allow())
+}
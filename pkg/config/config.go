@@ -17,12 +17,63 @@ type Config struct {
 	// Compression enables data compression
 	Compression bool `json:"compression"`
 
-	// CompressionAlgorithm specifies the compression algorithm: "gzip", "lz4", "snappy"
+	// CompressionAlgorithm specifies the compression algorithm: "gzip", "lz4", "snappy", "zstd"
 	CompressionAlgorithm string `json:"compression_algorithm"`
 
-	// Serializer specifies the serialization format: "json", "gob", "msgpack"
+	// CompressionLevel sets the zstd level, from 1 (fastest) to 22 (best
+	// ratio). Zero uses the default of 3. Other algorithms ignore it.
+	CompressionLevel int `json:"compression_level"`
+
+	// CompressionMinSize is the smallest encoded value, in bytes, that is
+	// compressed; smaller values are stored as is because compression would
+	// grow them (default: 1024). Set it to 1 to compress every value.
+	CompressionMinSize int `json:"compression_min_size"`
+
+	// MaxDecompressedSize limits the size in bytes a compressed value may
+	// expand to when read back (default: 64MB)
+	MaxDecompressedSize int64 `json:"max_decompressed_size"`
+
+	// Serializer specifies the serialization format: "json", "gob", "msgpack",
+	// or "typed" for JSON that preserves registered Go types
 	Serializer string `json:"serializer"`
 
+	// PreserveIntegers makes the "json" serializer return integral numbers
+	// as int64 instead of float64 when values are read back as interface{}
+	PreserveIntegers bool `json:"preserve_integers"`
+
+	// CanonicalJSON makes the "json" serializer write equal values as
+	// identical bytes, with the keys of every object sorted, so stored
+	// values can be hashed for checksums
+	CanonicalJSON bool `json:"canonical_json"`
+
+	// StrictCodecs rejects serializers and compression algorithms that are
+	// placeholders falling back to another codec (currently none; every
+	// built-in codec is implemented)
+	StrictCodecs bool `json:"strict_codecs"`
+
+	// DereferencePointers serializes values stored through a pointer as the
+	// value they point to, so *T and T are stored identically with every
+	// serializer, and the "typed" serializer records T. Nil pointers are
+	// rejected. Read values back as pointers with GetTyped[*T]
+	DereferencePointers bool `json:"dereference_pointers"`
+
+	// EvictUndecodable deletes stored values that cannot be decompressed or
+	// deserialized, such as values written with an incompatible schema, when
+	// they are read, and reports them as misses so loaders store fresh data
+	EvictUndecodable bool `json:"evict_undecodable"`
+
+	// ReadOnly makes the client reject every write with ErrReadOnly without
+	// reaching the backend, for canary or debug deployments that must never
+	// mutate a shared cache. Reads work normally; GetOrSet returns loaded
+	// values without storing them, and only a hierarchical client's own L1
+	// is filled on reads
+	ReadOnly bool `json:"read_only"`
+
+	// LargeValueThreshold reports every value whose encoded size, in bytes,
+	// exceeds it to the large value hook, which logs it by default. Zero
+	// disables the check
+	LargeValueThreshold int `json:"large_value_threshold"`
+
 	// Distributed enables distributed cache mode
 	Distributed bool `json:"distributed"`
 
@@ -47,6 +98,13 @@ type Config struct {
 	// L2 cache configuration (hierarchical mode)
 	L2 CacheConfig `json:"l2,omitempty"`
 
+	// InvalidationChannel keeps the L1 tiers of a fleet of hierarchical
+	// clients coherent: every write or delete that changes L2 publishes the
+	// key, or the pattern of a DeleteByPattern, on this channel of the L2
+	// backend, and every other client subscribed to it drops the matching
+	// keys from its L1. It requires an L2 backend with pub/sub, such as Redis
+	InvalidationChannel string `json:"invalidation_channel"`
+
 	// Prometheus metrics configuration
 	Prometheus PrometheusConfig `json:"prometheus,omitempty"`
 
@@ -55,11 +113,32 @@ type Config struct {
 
 	// Sharding configuration
 	Sharding ShardingConfig `json:"sharding,omitempty"`
+
+	// SingleFlight makes concurrent GetOrSet misses for the same key within
+	// this process share a single loader call and its result
+	SingleFlight bool `json:"single_flight"`
+
+	// Loader limit configuration
+	LoaderLimit LoaderLimitConfig `json:"loader_limit,omitempty"`
+
+	// Lock configuration
+	Lock LockConfig `json:"lock,omitempty"`
+
+	// Circuit breaker configuration
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker,omitempty"`
+
+	// Failover configuration
+	Failover FailoverConfig `json:"failover,omitempty"`
+
+	// Warmup configuration
+	Warmup WarmupConfig `json:"warmup,omitempty"`
 }
 
 // MemoryConfig represents configuration for in-memory cache backend.
 type MemoryConfig struct {
-	// MaxSize is the maximum memory size (e.g., "100MB", "1GB")
+	// MaxSize is the maximum memory size (e.g., "100MB", "1.5 GB"), counting
+	// keys, values and a fixed per-entry overhead. Units are B, KB, MB, GB
+	// and TB in any case; a bare number is bytes
 	MaxSize string `json:"max_size"`
 
 	// MaxKeys is the maximum number of keys
@@ -73,6 +152,43 @@ type MemoryConfig struct {
 
 	// CleanupInterval is the interval for cleanup operations
 	CleanupInterval time.Duration `json:"cleanup_interval"`
+
+	// DisableCleanup turns off the background cleanup goroutine. Expired keys
+	// are then only removed lazily when they are read.
+	DisableCleanup bool `json:"disable_cleanup"`
+
+	// LFUHalfLife halves every key's access frequency once per interval, so
+	// keys that are no longer hot become evictable under the "lfu" policy.
+	// Zero disables decay.
+	LFUHalfLife time.Duration `json:"lfu_half_life"`
+
+	// NamespaceQuotas limits the keys and bytes of each namespace, the key
+	// prefix before the first colon ("tenant1" for "tenant1:user:42"). Writes
+	// that would exceed a quota fail with backends.ErrQuotaExceeded.
+	NamespaceQuotas map[string]NamespaceQuota `json:"namespace_quotas"`
+
+	// Stripes splits the store into independently locked stripes, rounded up
+	// to a power of two, so writes to different keys proceed in parallel.
+	// With more than one stripe, eviction picks the least recently or least
+	// frequently used key of the stripe being written rather than of the
+	// whole cache, moving on to other stripes once that one is empty. Zero
+	// or one keeps a single lock.
+	Stripes int `json:"stripes"`
+
+	// HashFunction picks the stripe of each key: "crc32", "fnv" or "md5".
+	// Empty uses Sharding.HashFunction, so keys are hashed the same way to
+	// pick their shard and their stripe.
+	HashFunction string `json:"hash_function"`
+}
+
+// NamespaceQuota represents the limits of one namespace of the memory backend.
+type NamespaceQuota struct {
+	// MaxKeys is the maximum number of keys, zero for no limit
+	MaxKeys int64 `json:"max_keys"`
+
+	// MaxSize is the maximum size of the entries, counting keys, values and
+	// per-entry overhead (e.g., "10MB"), empty for no limit
+	MaxSize string `json:"max_size"`
 }
 
 // RedisConfig represents configuration for Redis backend.
@@ -89,6 +205,12 @@ type RedisConfig struct {
 	// PoolSize is the connection pool size
 	PoolSize int `json:"pool_size"`
 
+	// MinPoolSize is the lower bound for pool size recommendations
+	MinPoolSize int `json:"min_pool_size"`
+
+	// MaxPoolSize is the upper bound for pool size recommendations (0 means unbounded)
+	MaxPoolSize int `json:"max_pool_size"`
+
 	// DialTimeout is the connection timeout
 	DialTimeout time.Duration `json:"dial_timeout"`
 
@@ -122,13 +244,101 @@ type RedisConfig struct {
 	// TLSSkipVerify skips TLS certificate verification
 	TLSSkipVerify bool `json:"tls_skip_verify"`
 
+	// TLSCertFile and TLSKeyFile are the paths to the client certificate
+	// and key presented to the server, for mutual TLS
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+
+	// TLSCAFile is the path to the CA certificates verifying the server,
+	// instead of the system roots
+	TLSCAFile string `json:"tls_ca_file"`
+
+	// TLSServerName overrides the server name verified in the certificate
+	TLSServerName string `json:"tls_server_name"`
+
+	// AtomicSetMulti wraps SetMulti in MULTI/EXEC so a batch is applied
+	// all-or-nothing, instead of the faster non-transactional pipeline
+	AtomicSetMulti bool `json:"atomic_set_multi"`
+
+	// KeyspaceNotifications lets GetWait subscribe to keyspace events instead
+	// of only polling, and enables SetWithExpireCallback. The server must
+	// publish them, e.g. with notify-keyspace-events set to "K$" for GetWait
+	// or "Ex" for expiration callbacks. Not supported in cluster mode.
+	KeyspaceNotifications bool `json:"keyspace_notifications"`
+
+	// ReadFromReplica sends read-only commands (Get, GetMulti, Exists, TTL)
+	// to replicas and writes to the master. In sentinel mode replicas are
+	// discovered through the sentinels; for a single instance they are listed
+	// in ReplicaAddresses. Reads may observe replication lag.
+	ReadFromReplica bool `json:"read_from_replica"`
+
+	// ReplicaAddresses lists the replicas of a single-instance master for
+	// ReadFromReplica
+	ReplicaAddresses []string `json:"replica_addresses"`
+
+	// Mode selects the client: RedisModeSingle connects to the first
+	// address, RedisModeCluster to a cluster through the addresses and
+	// RedisModeSentinel to the master named MasterName through the sentinels
+	// in Addresses. When empty the mode is inferred for compatibility, see
+	// EffectiveMode
+	Mode string `json:"mode"`
+
+	// MasterName is the master monitored by the sentinels in sentinel mode
+	MasterName string `json:"master_name"`
+
 	// Cluster mode configuration
 	Cluster RedisClusterConfig `json:"cluster,omitempty"`
+
+	// ClientCache configures server-assisted client-side caching
+	ClientCache RedisClientCacheConfig `json:"client_cache,omitempty"`
+}
+
+// Redis client modes.
+const (
+	RedisModeSingle   = "single"
+	RedisModeCluster  = "cluster"
+	RedisModeSentinel = "sentinel"
+)
+
+// EffectiveMode returns Mode or, when it is empty, the mode inferred from
+// the rest of the configuration: cluster when Cluster.Enabled is set,
+// sentinel with more than one address and single otherwise.
+//
+// Deprecated: inferring the mode is kept for existing configurations. Set
+// Mode explicitly, since listing several addresses of a standalone server
+// selects sentinel mode.
+func (c RedisConfig) EffectiveMode() string {
+	switch {
+	case c.Mode != "":
+		return c.Mode
+	case c.Cluster.Enabled:
+		return RedisModeCluster
+	case len(c.Addresses) > 1:
+		return RedisModeSentinel
+	default:
+		return RedisModeSingle
+	}
+}
+
+// RedisClientCacheConfig represents configuration for Redis client-side
+// caching, where reads are served from an in-process cache that Redis keeps
+// coherent through CLIENT TRACKING invalidations. It requires Redis 6+ and is
+// only supported for a single Redis instance.
+type RedisClientCacheConfig struct {
+	// Enabled indicates if client-side caching is enabled
+	Enabled bool `json:"enabled"`
+
+	// MaxKeys is the maximum number of keys cached locally
+	MaxKeys int `json:"max_keys"`
+
+	// TTL bounds how long a local entry is served, as a safety net should
+	// an invalidation be lost
+	TTL time.Duration `json:"ttl"`
 }
 
 // RedisClusterConfig represents Redis cluster configuration.
 type RedisClusterConfig struct {
-	// Enabled indicates if cluster mode is enabled
+	// Enabled selects cluster mode when RedisConfig.Mode is empty
 	Enabled bool `json:"enabled"`
 
 	// MaxRedirects is the maximum number of redirects
@@ -191,6 +401,15 @@ type CacheConfig struct {
 	// TTL is the default TTL
 	TTL time.Duration `json:"ttl"`
 
+	// Compression overrides Config.Compression for this tier in
+	// hierarchical mode, e.g. to store raw values in a memory L1 and
+	// compressed values in a Redis L2. Nil inherits the global setting.
+	Compression *bool `json:"compression,omitempty"`
+
+	// CompressionAlgorithm overrides Config.CompressionAlgorithm for this
+	// tier in hierarchical mode. Empty inherits the global algorithm.
+	CompressionAlgorithm string `json:"compression_algorithm,omitempty"`
+
 	// Backend-specific configurations
 	Memory    MemoryConfig    `json:"memory,omitempty"`
 	Redis     RedisConfig     `json:"redis,omitempty"`
@@ -208,7 +427,8 @@ type PrometheusConfig struct {
 	// Subsystem is the metrics subsystem
 	Subsystem string `json:"subsystem"`
 
-	// Port is the metrics server port
+	// Port is the metrics server port. When set, the client serves metrics
+	// on it until Close
 	Port int `json:"port"`
 
 	// Path is the metrics endpoint path
@@ -216,26 +436,54 @@ type PrometheusConfig struct {
 
 	// Labels are additional labels for metrics
 	Labels map[string]string `json:"labels"`
+
+	// StatsInterval is how often backend stats are copied into the size,
+	// key count and connection gauges
+	StatsInterval time.Duration `json:"stats_interval"`
+
+	// KeyGroups enables per-operation counters labelled by key group, such
+	// as "user" for "user:42". Raw keys are never used as labels.
+	KeyGroups bool `json:"key_groups"`
+
+	// MaxKeyGroups caps the number of distinct key group labels; further
+	// groups are reported as "other" (default: 100)
+	MaxKeyGroups int `json:"max_key_groups"`
+
+	// Buckets overrides the operation duration histogram buckets, in
+	// seconds, per backend type (e.g. "memory", "redis"). Backends without
+	// an entry use defaults sized for their typical latency.
+	Buckets map[string][]float64 `json:"buckets"`
+
+	// Summary adds p50/p95/p99 operation latency summaries
+	Summary bool `json:"summary"`
 }
 
-// TracingConfig represents tracing configuration.
+// TracingConfig represents tracing configuration. With a Provider set, the
+// client builds its own OpenTelemetry tracer provider exporting to Endpoint;
+// without one, spans go through the global provider installed by the
+// application with otel.SetTracerProvider.
 type TracingConfig struct {
 	// Enabled indicates if tracing is enabled
 	Enabled bool `json:"enabled"`
 
-	// Provider specifies the tracing provider: "jaeger", "zipkin", "otlp"
+	// Provider specifies the tracing provider: "jaeger", "zipkin", "otlp".
+	// Jaeger receives spans through its OTLP endpoint
 	Provider string `json:"provider"`
 
-	// Endpoint is the tracing endpoint
+	// Endpoint is the URL spans are exported to, such as
+	// "http://collector:4318/v1/traces"; a bare host:port is reached over
+	// plain HTTP. It defaults to the provider's local endpoint
 	Endpoint string `json:"endpoint"`
 
 	// ServiceName is the service name for tracing
 	ServiceName string `json:"service_name"`
 
-	// SampleRate is the sampling rate (0.0 to 1.0)
+	// SampleRate is the fraction of traces sampled (0.0 to 1.0). Zero, the
+	// default, samples every trace; disable tracing with Enabled instead.
+	// Spans with a sampled parent are always sampled
 	SampleRate float64 `json:"sample_rate"`
 
-	// Headers are additional headers for tracing
+	// Headers are additional HTTP headers sent with exported spans
 	Headers map[string]string `json:"headers"`
 }
 
@@ -252,6 +500,79 @@ type ShardingConfig struct {
 
 	// Shards is the number of shards
 	Shards int `json:"shards"`
+
+	// HashFunction specifies the key hash function: "crc32" (default), "fnv", "md5"
+	HashFunction string `json:"hash_function"`
+
+	// LoadFactor bounds consistent hashing loads: no shard owns more than
+	// LoadFactor times the mean share of the hash ring (e.g. 1.25). Setting
+	// it places distributed keys on the consistent hash ring instead of by
+	// hash modulo the shard count. Zero disables the bound.
+	LoadFactor float64 `json:"load_factor"`
+}
+
+// LockConfig represents configuration for lease-based loader locks.
+type LockConfig struct {
+	// Lease is the lock TTL, extended in the background while the loader
+	// runs (default: 10s)
+	Lease time.Duration `json:"lease"`
+
+	// RetryInterval is how often a node waiting on another node's loader
+	// checks for the result (default: 50ms)
+	RetryInterval time.Duration `json:"retry_interval"`
+}
+
+// LoaderLimitConfig represents configuration for limiting concurrent loaders
+// per key group.
+type LoaderLimitConfig struct {
+	// MaxConcurrent is the maximum number of loaders running at once for
+	// keys of the same group; 0 disables the limit. Groups are derived from
+	// keys by the prefix before the first colon unless the client is given
+	// another group function
+	MaxConcurrent int `json:"max_concurrent"`
+}
+
+// CircuitBreakerConfig represents configuration for the backend circuit breaker.
+type CircuitBreakerConfig struct {
+	// Enabled indicates if the circuit breaker is enabled
+	Enabled bool `json:"enabled"`
+
+	// FailureThreshold is the number of consecutive failures that opens
+	// the breaker (default: 5)
+	FailureThreshold int `json:"failure_threshold"`
+
+	// Cooldown is how long the breaker stays open before a trial call
+	// is let through (default: 30s)
+	Cooldown time.Duration `json:"cooldown"`
+}
+
+// FailoverConfig represents configuration for a warm standby backend that
+// mirrors writes and is promoted when the primary backend fails its health check.
+type FailoverConfig struct {
+	// Enabled indicates if failover is enabled
+	Enabled bool `json:"enabled"`
+
+	// Standby is the standby backend configuration
+	Standby CacheConfig `json:"standby"`
+
+	// CheckInterval is the interval between primary health checks (default: 5s)
+	CheckInterval time.Duration `json:"check_interval"`
+}
+
+// WarmupConfig represents configuration for critical keys that are loaded
+// and cached before the client is returned, so they never miss once traffic
+// is served.
+type WarmupConfig struct {
+	// CriticalKeys lists the keys to load. Each needs a loader registered
+	// with gocachex.RegisterLoader; client creation fails if any key has no
+	// loader or its loader fails.
+	CriticalKeys []string `json:"critical_keys"`
+
+	// Timeout bounds loading all critical keys (default: 30s)
+	Timeout time.Duration `json:"timeout"`
+
+	// TTL is the TTL of the loaded keys; zero means no expiration
+	TTL time.Duration `json:"ttl"`
 }
 
 // Validate validates the configuration.
@@ -266,7 +587,7 @@ func (c *Config) Validate() error {
 	if c.Serializer == "" {
 		c.Serializer = "json"
 	}
-	validSerializers := []string{"json", "gob", "msgpack"}
+	validSerializers := []string{"json", "gob", "msgpack", "typed"}
 	if !contains(validSerializers, c.Serializer) {
 		return fmt.Errorf("invalid serializer: %s, must be one of %v", c.Serializer, validSerializers)
 	}
@@ -276,10 +597,29 @@ func (c *Config) Validate() error {
 		c.CompressionAlgorithm = "gzip"
 	}
 	if c.Compression {
-		validAlgorithms := []string{"gzip", "lz4", "snappy"}
+		validAlgorithms := []string{"gzip", "lz4", "snappy", "zstd"}
 		if !contains(validAlgorithms, c.CompressionAlgorithm) {
 			return fmt.Errorf("invalid compression algorithm: %s, must be one of %v", c.CompressionAlgorithm, validAlgorithms)
 		}
+		if c.CompressionLevel < 0 || c.CompressionLevel > 22 {
+			return fmt.Errorf("invalid compression level: %d, must be between 1 and 22", c.CompressionLevel)
+		}
+		if c.CompressionMinSize < 0 {
+			return fmt.Errorf("compression min size cannot be negative")
+		}
+		if c.CompressionMinSize == 0 {
+			c.CompressionMinSize = 1024
+		}
+		if c.MaxDecompressedSize < 0 {
+			return fmt.Errorf("max decompressed size cannot be negative")
+		}
+		if c.MaxDecompressedSize == 0 {
+			c.MaxDecompressedSize = 64 << 20 // 64MB
+		}
+	}
+
+	if c.LargeValueThreshold < 0 {
+		return fmt.Errorf("large value threshold cannot be negative")
 	}
 
 	// Validate hierarchical configuration
@@ -288,6 +628,9 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("hierarchical mode requires both L1 and L2 backend configurations")
 		}
 	}
+	if c.InvalidationChannel != "" && !c.Hierarchical {
+		return fmt.Errorf("invalidation channel requires hierarchical mode")
+	}
 
 	// Validate distributed configuration
 	if c.Distributed {
@@ -297,6 +640,99 @@ func (c *Config) Validate() error {
 		if len(c.GRPC.Peers) == 0 {
 			return fmt.Errorf("distributed mode requires at least one peer")
 		}
+		if c.Sharding.Shards < 0 {
+			return fmt.Errorf("distributed mode requires at least one shard")
+		}
+	}
+
+	// Validate metrics configuration
+	if c.Prometheus.Enabled && c.Prometheus.StatsInterval == 0 {
+		c.Prometheus.StatsInterval = 15 * time.Second
+	}
+	if c.Prometheus.KeyGroups && c.Prometheus.MaxKeyGroups == 0 {
+		c.Prometheus.MaxKeyGroups = 100
+	}
+
+	// Validate tracing configuration
+	if c.Tracing.Enabled {
+		validProviders := []string{"", "otlp", "jaeger", "zipkin"}
+		if !contains(validProviders, c.Tracing.Provider) {
+			return fmt.Errorf("invalid tracing provider: %s, must be one of %v", c.Tracing.Provider, validProviders[1:])
+		}
+		if c.Tracing.SampleRate < 0 || c.Tracing.SampleRate > 1 {
+			return fmt.Errorf("tracing sample rate must be between 0 and 1")
+		}
+	}
+
+	// Validate sharding hash function
+	if c.Sharding.HashFunction != "" {
+		validHashFunctions := []string{"crc32", "fnv", "md5"}
+		if !contains(validHashFunctions, c.Sharding.HashFunction) {
+			return fmt.Errorf("invalid hash function: %s, must be one of %v", c.Sharding.HashFunction, validHashFunctions)
+		}
+	}
+
+	// Validate lock configuration
+	if c.Lock.Lease < 0 || c.Lock.RetryInterval < 0 {
+		return fmt.Errorf("lock lease and retry interval cannot be negative")
+	}
+	if c.Lock.Lease == 0 {
+		c.Lock.Lease = 10 * time.Second
+	}
+	if c.Lock.RetryInterval == 0 {
+		c.Lock.RetryInterval = 50 * time.Millisecond
+	}
+
+	// Validate loader limit configuration
+	if c.LoaderLimit.MaxConcurrent < 0 {
+		return fmt.Errorf("loader limit max concurrent cannot be negative")
+	}
+
+	// Validate circuit breaker configuration
+	if c.CircuitBreaker.Enabled {
+		if c.CircuitBreaker.FailureThreshold == 0 {
+			c.CircuitBreaker.FailureThreshold = 5
+		}
+		if c.CircuitBreaker.Cooldown == 0 {
+			c.CircuitBreaker.Cooldown = 30 * time.Second
+		}
+	}
+
+	// Validate failover configuration
+	if c.Failover.Enabled {
+		if c.Distributed || c.Hierarchical {
+			return fmt.Errorf("failover is only supported for a single backend")
+		}
+		if c.Failover.Standby.Backend == "" {
+			return fmt.Errorf("failover requires a standby backend configuration")
+		}
+		if c.Failover.CheckInterval < 0 {
+			return fmt.Errorf("failover check interval cannot be negative")
+		}
+		if c.Failover.CheckInterval == 0 {
+			c.Failover.CheckInterval = 5 * time.Second
+		}
+	}
+
+	// Validate warmup configuration
+	if len(c.Warmup.CriticalKeys) > 0 {
+		if c.Warmup.Timeout < 0 {
+			return fmt.Errorf("warmup timeout cannot be negative")
+		}
+		if c.Warmup.Timeout == 0 {
+			c.Warmup.Timeout = 30 * time.Second
+		}
+		if c.Warmup.TTL < 0 {
+			return fmt.Errorf("warmup ttl cannot be negative")
+		}
+	}
+
+	// Validate sharding load factor
+	if c.Sharding.LoadFactor != 0 && c.Sharding.LoadFactor <= 1 {
+		return fmt.Errorf("sharding load factor must be greater than 1")
+	}
+	if c.Sharding.LoadFactor != 0 && c.Sharding.Algorithm != "" && c.Sharding.Algorithm != "consistent" {
+		return fmt.Errorf("sharding load factor requires the consistent algorithm")
 	}
 
 	// Validate backend-specific configurations
@@ -319,14 +755,44 @@ func (c *Config) Validate() error {
 }
 
 func (c *Config) validateRedisConfig() error {
+	switch c.Redis.Mode {
+	case "":
+	case RedisModeSingle, RedisModeSentinel:
+		if c.Redis.Cluster.Enabled {
+			return fmt.Errorf("redis cluster.enabled conflicts with mode %s", c.Redis.Mode)
+		}
+	case RedisModeCluster:
+	default:
+		return fmt.Errorf("invalid redis mode: %s, must be one of %v", c.Redis.Mode, []string{RedisModeSingle, RedisModeCluster, RedisModeSentinel})
+	}
+	if c.Redis.Mode == RedisModeCluster && len(c.Redis.Addresses) == 0 {
+		return fmt.Errorf("redis cluster mode requires at least one address")
+	}
+	if c.Redis.Mode == RedisModeSentinel {
+		if len(c.Redis.Addresses) == 0 {
+			return fmt.Errorf("redis sentinel mode requires at least one sentinel address")
+		}
+		if c.Redis.MasterName == "" {
+			return fmt.Errorf("redis sentinel mode requires a master name")
+		}
+	}
+
 	if len(c.Redis.Addresses) == 0 {
 		c.Redis.Addresses = []string{"localhost:6379"}
 	}
+	mode := c.Redis.EffectiveMode()
+
+	if (c.Redis.TLSCertFile == "") != (c.Redis.TLSKeyFile == "") {
+		return fmt.Errorf("redis tls_cert_file and tls_key_file must be set together")
+	}
 
 	// Set defaults
 	if c.Redis.PoolSize == 0 {
 		c.Redis.PoolSize = 10
 	}
+	if c.Redis.MaxPoolSize > 0 && c.Redis.MinPoolSize > c.Redis.MaxPoolSize {
+		return fmt.Errorf("redis min_pool_size (%d) exceeds max_pool_size (%d)", c.Redis.MinPoolSize, c.Redis.MaxPoolSize)
+	}
 	if c.Redis.DialTimeout == 0 {
 		c.Redis.DialTimeout = 5 * time.Second
 	}
@@ -337,6 +803,30 @@ func (c *Config) validateRedisConfig() error {
 		c.Redis.WriteTimeout = 3 * time.Second
 	}
 
+	if c.Redis.ReadFromReplica {
+		if mode == RedisModeCluster {
+			return fmt.Errorf("redis read_from_replica is not supported in cluster mode, use cluster read_only")
+		}
+		if mode == RedisModeSingle && len(c.Redis.ReplicaAddresses) == 0 {
+			return fmt.Errorf("redis read_from_replica requires sentinels or replica addresses")
+		}
+		if c.Redis.ClientCache.Enabled {
+			return fmt.Errorf("redis client cache is not supported with read_from_replica")
+		}
+	}
+
+	if c.Redis.ClientCache.Enabled {
+		if mode != RedisModeSingle {
+			return fmt.Errorf("redis client cache is only supported for a single instance")
+		}
+		if c.Redis.ClientCache.MaxKeys == 0 {
+			c.Redis.ClientCache.MaxKeys = 10000
+		}
+		if c.Redis.ClientCache.TTL == 0 {
+			c.Redis.ClientCache.TTL = time.Minute
+		}
+	}
+
 	return nil
 }
 
@@ -367,12 +857,22 @@ func (c *Config) validateMemoryConfig() error {
 	if c.Memory.CleanupInterval == 0 {
 		c.Memory.CleanupInterval = 10 * time.Minute
 	}
+	if c.Memory.HashFunction == "" {
+		c.Memory.HashFunction = c.Sharding.HashFunction
+	}
 
 	validPolicies := []string{"lru", "lfu", "random"}
 	if !contains(validPolicies, c.Memory.EvictionPolicy) {
 		return fmt.Errorf("invalid eviction policy: %s, must be one of %v", c.Memory.EvictionPolicy, validPolicies)
 	}
 
+	if c.Memory.HashFunction != "" {
+		validHashFunctions := []string{"crc32", "fnv", "md5"}
+		if !contains(validHashFunctions, c.Memory.HashFunction) {
+			return fmt.Errorf("invalid memory hash function: %s, must be one of %v", c.Memory.HashFunction, validHashFunctions)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,536 @@
+package gocachextest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+)
+
+// Op names a backend operation, matching the Backend method name.
+type Op string
+
+// Backend operations that can be failed and are recorded.
+const (
+	OpGet         Op = "Get"
+	OpSet         Op = "Set"
+	OpSetNX       Op = "SetNX"
+	OpGetSet      Op = "GetSet"
+	OpDelete      Op = "Delete"
+	OpExists      Op = "Exists"
+	OpGetMulti    Op = "GetMulti"
+	OpSetMulti    Op = "SetMulti"
+	OpDeleteMulti Op = "DeleteMulti"
+	OpIncrement   Op = "Increment"
+	OpDecrement   Op = "Decrement"
+	OpExpire      Op = "Expire"
+	OpTTL         Op = "TTL"
+	OpKeys        Op = "Keys"
+	OpIterate     Op = "Iterate"
+	OpClear       Op = "Clear"
+	OpStats       Op = "Stats"
+	OpResetStats  Op = "ResetStats"
+	OpHealth      Op = "Health"
+	OpClose       Op = "Close"
+)
+
+// Call records a single operation received by the fake backend.
+type Call struct {
+	Op      Op
+	Keys    []string      // keys in the order given, sorted for SetMulti
+	Value   []byte        // value for Set
+	TTL     time.Duration // ttl for Set, SetMulti and Expire
+	Delta   int64         // delta for Increment and Decrement
+	Pattern string        // pattern for Keys
+	Err     error         // error returned to the caller
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time // zero means no expiry
+}
+
+// Backend is an in-memory fake implementing backends.Backend. Entries expire
+// against its Clock, so tests control time without sleeping. It is safe for
+// concurrent use.
+type Backend struct {
+	mu      sync.Mutex
+	clock   Clock
+	data    map[string]entry
+	fail    map[Op]error
+	failOne map[Op][]error
+	calls   []Call
+	stats   backends.Stats
+}
+
+// NewBackend creates an empty fake backend reading time from clock. A nil
+// clock uses the system clock.
+func NewBackend(clock Clock) *Backend {
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &Backend{
+		clock:   clock,
+		data:    make(map[string]entry),
+		fail:    make(map[Op]error),
+		failOne: make(map[Op][]error),
+	}
+}
+
+// FailOn makes every call to op return err until ClearFailures is called.
+// A nil err stops failing op.
+func (b *Backend) FailOn(op Op, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		delete(b.fail, op)
+		return
+	}
+	b.fail[op] = err
+}
+
+// FailNext makes the next call to op return err. Repeated calls queue
+// errors for consecutive calls; they take precedence over FailOn.
+func (b *Backend) FailNext(op Op, err error) {
+	b.mu.Lock()
+	b.failOne[op] = append(b.failOne[op], err)
+	b.mu.Unlock()
+}
+
+// ClearFailures removes every failure set with FailOn or FailNext.
+func (b *Backend) ClearFailures() {
+	b.mu.Lock()
+	b.fail = make(map[Op]error)
+	b.failOne = make(map[Op][]error)
+	b.mu.Unlock()
+}
+
+// Calls returns every call received so far, oldest first.
+func (b *Backend) Calls() []Call {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Call(nil), b.calls...)
+}
+
+// CallsTo returns the calls received for op, oldest first.
+func (b *Backend) CallsTo(op Op) []Call {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var calls []Call
+	for _, call := range b.calls {
+		if call.Op == op {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// ResetCalls forgets every recorded call.
+func (b *Backend) ResetCalls() {
+	b.mu.Lock()
+	b.calls = nil
+	b.mu.Unlock()
+}
+
+// begin records call and returns the injected error for it, if any. The
+// caller must hold the lock and must not run the operation on error.
+func (b *Backend) begin(call Call) error {
+	if queued := b.failOne[call.Op]; len(queued) > 0 {
+		call.Err = queued[0]
+		b.failOne[call.Op] = queued[1:]
+	} else {
+		call.Err = b.fail[call.Op]
+	}
+	b.calls = append(b.calls, call)
+	return call.Err
+}
+
+// record sets the error returned by the most recent call. The caller must
+// hold the lock.
+func (b *Backend) record(err error) error {
+	b.calls[len(b.calls)-1].Err = err
+	return err
+}
+
+// lookup returns the live entry at key, dropping it if it has expired. The
+// caller must hold the lock.
+func (b *Backend) lookup(key string) (entry, bool) {
+	e, ok := b.data[key]
+	if !ok {
+		return entry{}, false
+	}
+	if !e.expires.IsZero() && !b.clock.Now().Before(e.expires) {
+		delete(b.data, key)
+		b.stats.Evictions++
+		return entry{}, false
+	}
+	return e, true
+}
+
+// expiry returns the expiration time for ttl, where zero means none.
+func (b *Backend) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return b.clock.Now().Add(ttl)
+}
+
+// Get retrieves a value.
+func (b *Backend) Get(ctx context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpGet, Keys: []string{key}}); err != nil {
+		return nil, err
+	}
+
+	e, ok := b.lookup(key)
+	if !ok {
+		b.stats.Misses++
+		return nil, b.record(backends.ErrNotFound)
+	}
+	b.stats.Hits++
+	return e.value, nil
+}
+
+// Set stores a value. A ttl of zero or less means no expiry.
+func (b *Backend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpSet, Keys: []string{key}, Value: value, TTL: ttl}); err != nil {
+		return err
+	}
+
+	b.data[key] = entry{value: value, expires: b.expiry(ttl)}
+	b.stats.Sets++
+	return nil
+}
+
+// SetNX stores a value only if key has no live entry.
+func (b *Backend) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpSetNX, Keys: []string{key}, Value: value, TTL: ttl}); err != nil {
+		return false, err
+	}
+
+	if _, ok := b.lookup(key); ok {
+		return false, nil
+	}
+	b.data[key] = entry{value: value, expires: b.expiry(ttl)}
+	b.stats.Sets++
+	return true, nil
+}
+
+// GetSet replaces the value of key, keeping its expiry, and returns the
+// previous value.
+func (b *Backend) GetSet(ctx context.Context, key string, value []byte) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpGetSet, Keys: []string{key}, Value: value}); err != nil {
+		return nil, err
+	}
+
+	b.stats.Sets++
+	old, ok := b.lookup(key)
+	if !ok {
+		b.data[key] = entry{value: value}
+		return nil, backends.ErrNotFound
+	}
+	b.data[key] = entry{value: value, expires: old.expires}
+	return old.value, nil
+}
+
+// Delete removes a value.
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpDelete, Keys: []string{key}}); err != nil {
+		return err
+	}
+
+	if _, ok := b.data[key]; ok {
+		delete(b.data, key)
+		b.stats.Deletes++
+	}
+	return nil
+}
+
+// Exists checks if a key exists.
+func (b *Backend) Exists(ctx context.Context, key string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpExists, Keys: []string{key}}); err != nil {
+		return false, err
+	}
+
+	_, ok := b.lookup(key)
+	return ok, nil
+}
+
+// GetMulti retrieves the live values among keys.
+func (b *Backend) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpGetMulti, Keys: append([]string(nil), keys...)}); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]byte)
+	for _, key := range keys {
+		if e, ok := b.lookup(key); ok {
+			result[key] = e.value
+			b.stats.Hits++
+		} else {
+			b.stats.Misses++
+		}
+	}
+	return result, nil
+}
+
+// SetMulti stores multiple values.
+func (b *Backend) SetMulti(ctx context.Context, items map[string][]byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if err := b.begin(Call{Op: OpSetMulti, Keys: keys, TTL: ttl}); err != nil {
+		return err
+	}
+
+	expires := b.expiry(ttl)
+	for key, value := range items {
+		b.data[key] = entry{value: value, expires: expires}
+	}
+	b.stats.Sets += int64(len(items))
+	return nil
+}
+
+// DeleteMulti removes multiple values.
+func (b *Backend) DeleteMulti(ctx context.Context, keys []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpDeleteMulti, Keys: append([]string(nil), keys...)}); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if _, ok := b.data[key]; ok {
+			delete(b.data, key)
+			b.stats.Deletes++
+		}
+	}
+	return nil
+}
+
+// Increment adds delta to the counter at key, treating a missing key as zero.
+func (b *Backend) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpIncrement, Keys: []string{key}, Delta: delta}); err != nil {
+		return 0, err
+	}
+	return b.adjust(key, delta)
+}
+
+// Decrement subtracts delta from the counter at key, treating a missing key
+// as zero.
+func (b *Backend) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpDecrement, Keys: []string{key}, Delta: delta}); err != nil {
+		return 0, err
+	}
+	return b.adjust(key, -delta)
+}
+
+// adjust adds delta to the decimal counter at key, keeping its expiry. The
+// caller must hold the lock.
+func (b *Backend) adjust(key string, delta int64) (int64, error) {
+	e, _ := b.lookup(key)
+
+	var current int64
+	if e.value != nil {
+		var err error
+		if current, err = strconv.ParseInt(string(e.value), 10, 64); err != nil {
+			return 0, b.record(fmt.Errorf("value is not a number"))
+		}
+	}
+
+	current += delta
+	e.value = []byte(strconv.FormatInt(current, 10))
+	b.data[key] = e
+	return current, nil
+}
+
+// Expire sets a timeout on a key.
+func (b *Backend) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpExpire, Keys: []string{key}, TTL: ttl}); err != nil {
+		return err
+	}
+
+	e, ok := b.lookup(key)
+	if !ok {
+		return b.record(backends.ErrNotFound)
+	}
+	e.expires = b.expiry(ttl)
+	b.data[key] = e
+	return nil
+}
+
+// TTL returns the remaining time to live of a key, or -1 when it does not
+// expire.
+func (b *Backend) TTL(ctx context.Context, key string) (time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpTTL, Keys: []string{key}}); err != nil {
+		return 0, err
+	}
+
+	e, ok := b.lookup(key)
+	if !ok {
+		return 0, b.record(backends.ErrNotFound)
+	}
+	if e.expires.IsZero() {
+		return -1, nil
+	}
+	return e.expires.Sub(b.clock.Now()), nil
+}
+
+// Keys returns the live keys matching the glob pattern in key order.
+func (b *Backend) Keys(ctx context.Context, pattern string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpKeys, Pattern: pattern}); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range b.data {
+		if _, ok := b.lookup(key); ok && backends.MatchPattern(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Iterate calls fn for every live entry in key order. The lock is not held
+// while fn runs.
+func (b *Backend) Iterate(ctx context.Context, fn func(key string, value []byte, ttl time.Duration) error) error {
+	b.mu.Lock()
+	if err := b.begin(Call{Op: OpIterate}); err != nil {
+		b.mu.Unlock()
+		return err
+	}
+
+	keys := make([]string, 0, len(b.data))
+	for key := range b.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	type snapshot struct {
+		key   string
+		value []byte
+		ttl   time.Duration
+	}
+	entries := make([]snapshot, 0, len(keys))
+	for _, key := range keys {
+		if e, ok := b.lookup(key); ok {
+			var ttl time.Duration
+			if !e.expires.IsZero() {
+				ttl = e.expires.Sub(b.clock.Now())
+			}
+			entries = append(entries, snapshot{key, e.value, ttl})
+		}
+	}
+	b.mu.Unlock()
+
+	for _, e := range entries {
+		if err := fn(e.key, e.value, e.ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear removes all keys.
+func (b *Backend) Clear(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpClear}); err != nil {
+		return err
+	}
+	b.data = make(map[string]entry)
+	return nil
+}
+
+// Stats returns operation counts and the number of stored keys.
+func (b *Backend) Stats(ctx context.Context) (*backends.Stats, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpStats}); err != nil {
+		return nil, err
+	}
+
+	stats := b.stats
+	stats.KeyCount = int64(len(b.data))
+	for _, e := range b.data {
+		stats.MemoryUsage += int64(len(e.value))
+	}
+	return &stats, nil
+}
+
+// ResetStats zeroes the operation counts.
+func (b *Backend) ResetStats(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.begin(Call{Op: OpResetStats}); err != nil {
+		return err
+	}
+	b.stats = backends.Stats{}
+	return nil
+}
+
+// Health reports an error only when one is injected.
+func (b *Backend) Health(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.begin(Call{Op: OpHealth})
+}
+
+// Close records the call; the fake keeps working after it.
+func (b *Backend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.begin(Call{Op: OpClose})
+}
@@ -0,0 +1,183 @@
+package gocachextest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex"
+	"github.com/chmenegatti/gocachex/pkg/backends"
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/chmenegatti/gocachex/pkg/gocachextest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ backends.Backend = (*gocachextest.Backend)(nil)
+
+func TestBackendClockControl(t *testing.T) {
+	ctx := context.Background()
+	clock := gocachextest.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	fake := gocachextest.NewBackend(clock)
+
+	require.NoError(t, fake.Set(ctx, "short", []byte("a"), time.Minute))
+	require.NoError(t, fake.Set(ctx, "forever", []byte("b"), 0))
+
+	clock.Advance(59 * time.Second)
+	ttl, err := fake.TTL(ctx, "short")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, ttl)
+
+	clock.Advance(time.Second)
+	_, err = fake.Get(ctx, "short")
+	assert.ErrorIs(t, err, backends.ErrNotFound)
+
+	ttl, err = fake.TTL(ctx, "forever")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), ttl)
+
+	require.NoError(t, fake.Expire(ctx, "forever", time.Hour))
+	clock.Set(clock.Now().Add(2 * time.Hour))
+	exists, err := fake.Exists(ctx, "forever")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestBackendErrorInjection(t *testing.T) {
+	ctx := context.Background()
+	fake := gocachextest.NewBackend(nil)
+	errDown := errors.New("down")
+	errTimeout := errors.New("timeout")
+
+	fake.FailNext(gocachextest.OpGet, errTimeout)
+	fake.FailOn(gocachextest.OpSet, errDown)
+
+	assert.ErrorIs(t, fake.Set(ctx, "k", []byte("v"), 0), errDown)
+	assert.ErrorIs(t, fake.Set(ctx, "k", []byte("v"), 0), errDown)
+
+	_, err := fake.Get(ctx, "k")
+	assert.ErrorIs(t, err, errTimeout)
+	_, err = fake.Get(ctx, "k")
+	assert.ErrorIs(t, err, backends.ErrNotFound, "FailNext only fails one call")
+
+	fake.FailOn(gocachextest.OpSet, nil)
+	require.NoError(t, fake.Set(ctx, "k", []byte("v"), 0))
+
+	fake.FailOn(gocachextest.OpHealth, errDown)
+	assert.ErrorIs(t, fake.Health(ctx), errDown)
+	fake.ClearFailures()
+	assert.NoError(t, fake.Health(ctx))
+}
+
+func TestBackendRecordsCalls(t *testing.T) {
+	ctx := context.Background()
+	fake := gocachextest.NewBackend(nil)
+	errDown := errors.New("down")
+
+	require.NoError(t, fake.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, fake.SetMulti(ctx, map[string][]byte{"c": nil, "b": nil}, 0))
+	_, _ = fake.Get(ctx, "missing")
+	fake.FailNext(gocachextest.OpIncrement, errDown)
+	_, _ = fake.Increment(ctx, "n", 2)
+
+	calls := fake.Calls()
+	require.Len(t, calls, 4)
+	assert.Equal(t, gocachextest.Call{Op: gocachextest.OpSet, Keys: []string{"a"}, Value: []byte("1"), TTL: time.Minute}, calls[0])
+	assert.Equal(t, []string{"b", "c"}, calls[1].Keys)
+	assert.ErrorIs(t, calls[2].Err, backends.ErrNotFound)
+	assert.Equal(t, int64(2), calls[3].Delta)
+	assert.ErrorIs(t, calls[3].Err, errDown)
+
+	assert.Len(t, fake.CallsTo(gocachextest.OpGet), 1)
+	fake.ResetCalls()
+	assert.Empty(t, fake.Calls())
+}
+
+func TestBackendGetSetKeepsExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := gocachextest.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	fake := gocachextest.NewBackend(clock)
+
+	require.NoError(t, fake.Set(ctx, "key", []byte("a"), time.Minute))
+	old, err := fake.GetSet(ctx, "key", []byte("b"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), old)
+
+	clock.Advance(time.Minute)
+	_, err = fake.Get(ctx, "key")
+	assert.ErrorIs(t, err, backends.ErrNotFound)
+
+	_, err = fake.GetSet(ctx, "key", []byte("c"))
+	assert.ErrorIs(t, err, backends.ErrNotFound)
+	ttl, err := fake.TTL(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), ttl)
+}
+
+func TestDeleteByPatternFallsBackToKeys(t *testing.T) {
+	ctx := context.Background()
+	fake := gocachextest.NewBackend(gocachextest.NewFakeClock(time.Now()))
+
+	cache, err := gocachex.NewWithBackend(config.Config{}, fake)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	for _, key := range []string{"products:1", "products:all", "users:1"} {
+		require.NoError(t, cache.Set(ctx, key, "v", time.Minute))
+	}
+
+	deleted, err := cache.DeleteByPattern(ctx, "products:*")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	keys, err := cache.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users:1"}, keys)
+	assert.Len(t, fake.CallsTo(gocachextest.OpDeleteMulti), 1)
+}
+
+func TestProbeMultiFallsBackToTTL(t *testing.T) {
+	ctx := context.Background()
+	fake := gocachextest.NewBackend(gocachextest.NewFakeClock(time.Now()))
+
+	cache, err := gocachex.NewWithBackend(config.Config{}, fake)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	require.NoError(t, cache.Set(ctx, "expiring", "v", time.Minute))
+	require.NoError(t, cache.Set(ctx, "persistent", "v", 0))
+
+	probes, err := cache.ProbeMulti(ctx, []string{"expiring", "persistent", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]backends.KeyProbe{
+		"expiring":   {Exists: true, TTL: time.Minute},
+		"persistent": {Exists: true, TTL: -1},
+		"missing":    {},
+	}, probes)
+}
+
+func TestBackendWithCacheClient(t *testing.T) {
+	ctx := context.Background()
+	clock := gocachextest.NewFakeClock(time.Now())
+	fake := gocachextest.NewBackend(clock)
+
+	cache, err := gocachex.NewWithBackend(config.Config{}, fake)
+	require.NoError(t, err)
+	defer cache.Close()
+
+	require.NoError(t, cache.Set(ctx, "session", "data", time.Minute))
+	value, err := cache.Get(ctx, "session")
+	require.NoError(t, err)
+	assert.Equal(t, "data", value)
+
+	clock.Advance(time.Minute)
+	_, err = cache.Get(ctx, "session")
+	assert.Error(t, err)
+
+	fake.FailOn(gocachextest.OpGet, errors.New("down"))
+	_, err = cache.Get(ctx, "session")
+	assert.ErrorContains(t, err, "down")
+
+	assert.Len(t, fake.CallsTo(gocachextest.OpSet), 1)
+}
@@ -0,0 +1,63 @@
+// Package gocachextest provides a fake cache backend for unit-testing code
+// that uses gocachex. The fake keeps entries in memory, expires them against
+// an injectable clock instead of real timers, can be told to fail any
+// operation, and records every call it receives.
+//
+//	clock := gocachextest.NewFakeClock(time.Now())
+//	fake := gocachextest.NewBackend(clock)
+//	cache, _ := gocachex.NewWithBackend(config.Config{}, fake)
+//
+//	cache.Set(ctx, "session", "data", time.Minute)
+//	clock.Advance(time.Minute)
+//	// cache.Get(ctx, "session") now misses
+package gocachextest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the fake backend the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock reads the system clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock is a Clock that only moves when told to. It is safe for
+// concurrent use.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a clock stopped at start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// Set moves the clock to t.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
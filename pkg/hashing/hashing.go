@@ -0,0 +1,69 @@
+// Package hashing provides the key hash functions shared by sharding and the
+// memory backend, so that both distribute keys the same way.
+package hashing
+
+import (
+	"crypto/md5"
+	"fmt"
+	"hash/crc32"
+)
+
+// Func maps a key to a 32-bit hash.
+type Func func(key string) uint32
+
+// Default is the hash function used when none is configured.
+var Default Func = CRC32
+
+// CRC32 hashes a key with CRC-32 (IEEE). It is fast and hardware accelerated
+// on most platforms.
+func CRC32(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+// FNV1a hashes a key with 32-bit FNV-1a. It is allocation free and works
+// well for short keys.
+func FNV1a(key string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	hash := uint32(offset32)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= prime32
+	}
+	return hash
+}
+
+// MD5 hashes a key with MD5 and keeps the first four bytes. It is slower than
+// the other functions but has the strongest avalanche behaviour.
+func MD5(key string) uint32 {
+	hash := md5.Sum([]byte(key))
+	return uint32(hash[0])<<24 | uint32(hash[1])<<16 | uint32(hash[2])<<8 | uint32(hash[3])
+}
+
+// New returns the hash function with the given name: "crc32", "fnv" or "md5".
+// An empty name returns Default.
+func New(name string) (Func, error) {
+	switch name {
+	case "":
+		return Default, nil
+	case "crc32":
+		return CRC32, nil
+	case "fnv":
+		return FNV1a, nil
+	case "md5":
+		return MD5, nil
+	default:
+		return nil, fmt.Errorf("unsupported hash function: %s", name)
+	}
+}
+
+// Index maps a key to a bucket in [0, buckets) using hash.
+func Index(hash Func, key string, buckets int) int {
+	if buckets <= 0 {
+		return 0
+	}
+	return int(hash(key) % uint32(buckets))
+}
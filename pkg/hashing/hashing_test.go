@@ -0,0 +1,55 @@
+package hashing
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashFuncsAreConsistent(t *testing.T) {
+	for _, name := range []string{"crc32", "fnv", "md5"} {
+		hash, err := New(name)
+		require.NoError(t, err)
+
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("user:%d", i)
+			assert.Equal(t, hash(key), hash(key), name)
+			assert.Equal(t, Index(hash, key, 7), Index(hash, key, 7), name)
+		}
+	}
+}
+
+func TestHashFuncsDistributeUniformly(t *testing.T) {
+	const (
+		buckets = 16
+		keys    = 100000
+	)
+
+	for _, name := range []string{"crc32", "fnv", "md5"} {
+		hash, err := New(name)
+		require.NoError(t, err)
+
+		counts := make([]int, buckets)
+		for i := 0; i < keys; i++ {
+			counts[Index(hash, fmt.Sprintf("user:%d", i), buckets)]++
+		}
+
+		mean := float64(keys) / buckets
+		for bucket, count := range counts {
+			assert.InDelta(t, mean, float64(count), mean*0.1, "%s bucket %d", name, bucket)
+		}
+	}
+}
+
+func TestNewHashFunc(t *testing.T) {
+	hash, err := New("")
+	require.NoError(t, err)
+	assert.Equal(t, Default("key"), hash("key"))
+
+	_, err = New("sha1")
+	assert.Error(t, err)
+
+	assert.Equal(t, 0, Index(FNV1a, "key", 0))
+}
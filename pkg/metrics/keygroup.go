@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	// defaultMaxKeyGroups is the group limit used when none is configured.
+	defaultMaxKeyGroups = 100
+
+	// UngroupedKeyGroup is the group label for keys the extractor cannot group.
+	UngroupedKeyGroup = "ungrouped"
+
+	// OtherKeyGroup is the group label used once the group limit is reached.
+	OtherKeyGroup = "other"
+)
+
+// KeyGroupFunc maps a cache key to a group label. It must return a value
+// drawn from a small set, never the key itself.
+type KeyGroupFunc func(key string) string
+
+// PrefixKeyGroup groups keys by the prefix before the first colon, so
+// "user:42" and "user:43" both belong to "user". Keys without a colon are
+// ungrouped.
+func PrefixKeyGroup(key string) string {
+	prefix, _, found := strings.Cut(key, ":")
+	if !found || prefix == "" {
+		return UngroupedKeyGroup
+	}
+	return prefix
+}
+
+// keyGroups bounds the number of distinct group labels, reporting any group
+// beyond the limit as OtherKeyGroup.
+type keyGroups struct {
+	mu      sync.RWMutex
+	extract KeyGroupFunc
+	limit   int
+	seen    map[string]struct{}
+}
+
+func newKeyGroups(limit int) *keyGroups {
+	if limit <= 0 {
+		limit = defaultMaxKeyGroups
+	}
+	return &keyGroups{
+		extract: PrefixKeyGroup,
+		limit:   limit,
+		seen:    make(map[string]struct{}),
+	}
+}
+
+// group returns the bounded group label for key.
+func (g *keyGroups) group(key string) string {
+	g.mu.RLock()
+	group := g.extract(key)
+	_, known := g.seen[group]
+	g.mu.RUnlock()
+
+	if known || group == UngroupedKeyGroup || group == OtherKeyGroup {
+		return group
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, known := g.seen[group]; known {
+		return group
+	}
+	if len(g.seen) >= g.limit {
+		return OtherKeyGroup
+	}
+	g.seen[group] = struct{}{}
+	return group
+}
+
+// setExtractor replaces the extractor and forgets previously seen groups.
+func (g *keyGroups) setExtractor(fn KeyGroupFunc) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.extract = fn
+	g.seen = make(map[string]struct{})
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// groupCounts returns the key group counter values by group label for op.
+func groupCounts(t *testing.T, c *Collector, operation string) map[string]float64 {
+	families, err := c.GetRegistry().Gather()
+	require.NoError(t, err)
+
+	counts := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "gocachex_cache_key_group_operations_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["operation"] == operation {
+				counts[labels["group"]] = metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return counts
+}
+
+func TestPrefixKeyGroup(t *testing.T) {
+	assert.Equal(t, "user", PrefixKeyGroup("user:42"))
+	assert.Equal(t, "user", PrefixKeyGroup("user:42:profile"))
+	assert.Equal(t, UngroupedKeyGroup, PrefixKeyGroup("plainkey"))
+	assert.Equal(t, UngroupedKeyGroup, PrefixKeyGroup(":leading"))
+}
+
+func TestRecordKeyGroup(t *testing.T) {
+	c := New(config.PrometheusConfig{Enabled: true, KeyGroups: true, MaxKeyGroups: 100})
+
+	keys := make([]string, 0, 1000)
+	for i := 0; i < 500; i++ {
+		keys = append(keys, fmt.Sprintf("user:%d", i), fmt.Sprintf("session-%d", i))
+	}
+	for _, key := range keys {
+		c.RecordKeyGroup("get", key)
+	}
+
+	assert.Equal(t, map[string]float64{
+		"user":            500,
+		UngroupedKeyGroup: 500,
+	}, groupCounts(t, c, "get"))
+}
+
+func TestRecordKeyGroupBoundsCardinality(t *testing.T) {
+	c := New(config.PrometheusConfig{Enabled: true, KeyGroups: true, MaxKeyGroups: 10})
+
+	// An extractor that returns the raw key would be unbounded
+	c.SetKeyGroupFunc(func(key string) string { return key })
+
+	keys := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("order-%d", i)
+		keys[key] = true
+		c.RecordKeyGroup("set", key)
+	}
+
+	counts := groupCounts(t, c, "set")
+	assert.LessOrEqual(t, len(counts), 11)
+	assert.Equal(t, float64(990), counts[OtherKeyGroup])
+
+	raw := 0
+	for group := range counts {
+		if keys[group] {
+			raw++
+		}
+	}
+	assert.LessOrEqual(t, raw, 10)
+}
+
+func TestRecordKeyGroupDisabled(t *testing.T) {
+	c := New(config.PrometheusConfig{Enabled: true})
+	c.RecordKeyGroup("get", "user:1")
+	assert.Empty(t, groupCounts(t, c, "get"))
+
+	var nilCollector *Collector
+	assert.NotPanics(t, func() { nilCollector.RecordKeyGroup("get", strings.Repeat("x", 10)) })
+}
@@ -2,9 +2,12 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/chmenegatti/gocachex/pkg/config"
@@ -19,6 +22,13 @@ type Collector struct {
 	// Operation metrics
 	operationsTotal   *prometheus.CounterVec
 	operationDuration *prometheus.HistogramVec
+	operationLatency  *prometheus.SummaryVec
+
+	// Compression metrics
+	compressionDuration *prometheus.HistogramVec
+
+	// Payload metrics
+	payloadSizeBytes *prometheus.HistogramVec
 
 	// Cache hit/miss metrics
 	cacheHitsTotal   *prometheus.CounterVec
@@ -28,18 +38,58 @@ type Collector struct {
 	cacheSizeBytes *prometheus.GaugeVec
 	cacheKeyCount  *prometheus.GaugeVec
 
+	// Clock metrics
+	clockSkewSeconds *prometheus.GaugeVec
+
 	// Connection metrics
 	activeConnections *prometheus.GaugeVec
 
 	// Error metrics
 	errorsTotal *prometheus.CounterVec
 
+	// Loader metrics
+	oversizedLoadsTotal prometheus.Counter
+
+	// Undecodable value metrics
+	undecodableEvictionsTotal prometheus.Counter
+
+	// Key group metrics
+	keyGroupOperationsTotal *prometheus.CounterVec
+	keyGroups               *keyGroups
+
 	// Registry
 	registry *prometheus.Registry
+
+	// Metrics HTTP server, set while it is running
+	serverMu sync.Mutex
+	server   *http.Server
+}
+
+// shutdownTimeout bounds how long Close waits for in-flight scrapes.
+const shutdownTimeout = 5 * time.Second
+
+// DefaultBuckets returns operation duration histogram buckets, in seconds,
+// suited to the typical latency of a backend: microseconds for memory and
+// milliseconds for network backends.
+func DefaultBuckets(backend string) []float64 {
+	switch backend {
+	case "memory":
+		return prometheus.ExponentialBuckets(0.000001, 2.5, 12) // 1µs to ~24ms
+	case "redis", "memcached":
+		return prometheus.ExponentialBuckets(0.0001, 2.5, 12) // 100µs to ~2.4s
+	default:
+		return prometheus.DefBuckets
+	}
 }
 
 // New creates a new metrics collector.
 func New(cfg config.PrometheusConfig) *Collector {
+	return NewForBackend(cfg, "")
+}
+
+// NewForBackend creates a new metrics collector whose operation duration
+// buckets are taken from cfg.Buckets for the backend, or DefaultBuckets.
+func NewForBackend(cfg config.PrometheusConfig, backend string) *Collector {
 	namespace := cfg.Namespace
 	if namespace == "" {
 		namespace = "gocachex"
@@ -55,6 +105,11 @@ func New(cfg config.PrometheusConfig) *Collector {
 		registry: prometheus.NewRegistry(),
 	}
 
+	buckets, ok := cfg.Buckets[backend]
+	if !ok || len(buckets) == 0 {
+		buckets = DefaultBuckets(backend)
+	}
+
 	// Operation metrics
 	collector.operationsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -72,11 +127,37 @@ func New(cfg config.PrometheusConfig) *Collector {
 			Subsystem: subsystem,
 			Name:      "operation_duration_seconds",
 			Help:      "Duration of cache operations in seconds",
-			Buckets:   prometheus.DefBuckets,
+			Buckets:   buckets,
 		},
 		[]string{"operation", "backend"},
 	)
 
+	if cfg.Summary {
+		collector.operationLatency = prometheus.NewSummaryVec(
+			prometheus.SummaryOpts{
+				Namespace:  namespace,
+				Subsystem:  subsystem,
+				Name:       "operation_latency_seconds",
+				Help:       "Latency percentiles of cache operations in seconds",
+				Objectives: map[float64]float64{0.5: 0.05, 0.95: 0.01, 0.99: 0.001},
+			},
+			[]string{"operation", "backend"},
+		)
+		collector.registry.MustRegister(collector.operationLatency)
+	}
+
+	// Compression metrics
+	collector.compressionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "compression_duration_seconds",
+			Help:      "CPU time spent compressing and decompressing entries in seconds",
+			Buckets:   prometheus.ExponentialBuckets(0.000001, 2.5, 12), // 1µs to ~24ms
+		},
+		[]string{"algorithm", "direction"},
+	)
+
 	// Hit/miss metrics
 	collector.cacheHitsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -119,6 +200,17 @@ func New(cfg config.PrometheusConfig) *Collector {
 		[]string{"backend", "level"},
 	)
 
+	// Clock metrics
+	collector.clockSkewSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "clock_skew_seconds",
+			Help:      "How far the clock a backend measures expirations against is ahead of the local clock, in seconds",
+		},
+		[]string{"backend", "level"},
+	)
+
 	// Connection metrics
 	collector.activeConnections = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -141,16 +233,68 @@ func New(cfg config.PrometheusConfig) *Collector {
 		[]string{"operation", "backend", "error_type"},
 	)
 
+	// Payload metrics
+	collector.payloadSizeBytes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "payload_size_bytes",
+			Help:      "Size in bytes of encoded values written to the backend, after serialization and compression",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 10), // 64B to 16MB
+		},
+		[]string{"backend"},
+	)
+
+	// Loader metrics
+	collector.oversizedLoadsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "oversized_loads_total",
+			Help:      "Total number of loaded values not cached for exceeding the maximum cacheable size",
+		},
+	)
+
+	// Undecodable value metrics
+	collector.undecodableEvictionsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "undecodable_evictions_total",
+			Help:      "Total number of stored values deleted for failing to decompress or deserialize",
+		},
+	)
+
+	// Key group metrics
+	collector.keyGroupOperationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "key_group_operations_total",
+			Help:      "Total number of cache operations by key group",
+		},
+		[]string{"operation", "group"},
+	)
+	if cfg.KeyGroups {
+		collector.keyGroups = newKeyGroups(cfg.MaxKeyGroups)
+	}
+
 	// Register metrics
 	collector.registry.MustRegister(
 		collector.operationsTotal,
 		collector.operationDuration,
+		collector.compressionDuration,
+		collector.payloadSizeBytes,
 		collector.cacheHitsTotal,
 		collector.cacheMissesTotal,
 		collector.cacheSizeBytes,
 		collector.cacheKeyCount,
+		collector.clockSkewSeconds,
 		collector.activeConnections,
 		collector.errorsTotal,
+		collector.oversizedLoadsTotal,
+		collector.undecodableEvictionsTotal,
+		collector.keyGroupOperationsTotal,
 	)
 
 	return collector
@@ -166,7 +310,7 @@ func (c *Collector) RecordOperation(operation string, duration time.Duration) {
 	status := "success"
 
 	c.operationsTotal.WithLabelValues(operation, backend, status).Inc()
-	c.operationDuration.WithLabelValues(operation, backend).Observe(duration.Seconds())
+	c.observeDuration(operation, backend, duration)
 }
 
 // RecordOperationWithBackend records a cache operation with backend and status.
@@ -176,7 +320,36 @@ func (c *Collector) RecordOperationWithBackend(operation, backend, status string
 	}
 
 	c.operationsTotal.WithLabelValues(operation, backend, status).Inc()
+	c.observeDuration(operation, backend, duration)
+}
+
+// observeDuration records an operation duration in the histogram and, when
+// enabled, the latency summary.
+func (c *Collector) observeDuration(operation, backend string, duration time.Duration) {
 	c.operationDuration.WithLabelValues(operation, backend).Observe(duration.Seconds())
+	if c.operationLatency != nil {
+		c.operationLatency.WithLabelValues(operation, backend).Observe(duration.Seconds())
+	}
+}
+
+// RecordCompression records the time spent compressing or decompressing one
+// entry. Direction is "compress" or "decompress".
+func (c *Collector) RecordCompression(algorithm, direction string, duration time.Duration) {
+	if c == nil {
+		return
+	}
+
+	c.compressionDuration.WithLabelValues(algorithm, direction).Observe(duration.Seconds())
+}
+
+// RecordPayloadSize records the size of an encoded value written to the
+// backend.
+func (c *Collector) RecordPayloadSize(backend string, sizeBytes int) {
+	if c == nil {
+		return
+	}
+
+	c.payloadSizeBytes.WithLabelValues(backend).Observe(float64(sizeBytes))
 }
 
 // RecordHit records a cache hit.
@@ -215,6 +388,15 @@ func (c *Collector) UpdateKeyCount(backend, level string, count int64) {
 	c.cacheKeyCount.WithLabelValues(backend, level).Set(float64(count))
 }
 
+// UpdateClockSkew updates the clock skew metric.
+func (c *Collector) UpdateClockSkew(backend, level string, skew time.Duration) {
+	if c == nil {
+		return
+	}
+
+	c.clockSkewSeconds.WithLabelValues(backend, level).Set(skew.Seconds())
+}
+
 // UpdateActiveConnections updates the active connections metric.
 func (c *Collector) UpdateActiveConnections(backend string, count int64) {
 	if c == nil {
@@ -233,7 +415,49 @@ func (c *Collector) RecordError(operation, backend, errorType string) {
 	c.errorsTotal.WithLabelValues(operation, backend, errorType).Inc()
 }
 
-// StartMetricsServer starts the Prometheus metrics HTTP server.
+// RecordOversizedLoad records a loaded value that was not cached for
+// exceeding the maximum cacheable size.
+func (c *Collector) RecordOversizedLoad() {
+	if c == nil {
+		return
+	}
+
+	c.oversizedLoadsTotal.Inc()
+}
+
+// RecordUndecodableEviction records a stored value deleted for failing to
+// decompress or deserialize.
+func (c *Collector) RecordUndecodableEviction() {
+	if c == nil {
+		return
+	}
+
+	c.undecodableEvictionsTotal.Inc()
+}
+
+// RecordKeyGroup records an operation against the group of key. The key
+// itself is never used as a label; it only feeds the key group extractor,
+// and the number of distinct groups is capped by MaxKeyGroups. It does
+// nothing unless key groups are enabled.
+func (c *Collector) RecordKeyGroup(operation, key string) {
+	if c == nil || c.keyGroups == nil {
+		return
+	}
+
+	c.keyGroupOperationsTotal.WithLabelValues(operation, c.keyGroups.group(key)).Inc()
+}
+
+// SetKeyGroupFunc replaces the key group extractor, PrefixKeyGroup by default.
+func (c *Collector) SetKeyGroupFunc(fn KeyGroupFunc) {
+	if c == nil || c.keyGroups == nil || fn == nil {
+		return
+	}
+
+	c.keyGroups.setExtractor(fn)
+}
+
+// StartMetricsServer starts the Prometheus metrics HTTP server, which runs
+// until Close.
 func (c *Collector) StartMetricsServer() error {
 	if c == nil {
 		return fmt.Errorf("metrics collector is nil")
@@ -257,14 +481,27 @@ func (c *Collector) StartMetricsServer() error {
 		EnableOpenMetrics: true,
 	})
 
-	http.Handle(path, handler)
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
 
-	addr := ":" + strconv.Itoa(port)
+	c.serverMu.Lock()
+	defer c.serverMu.Unlock()
+
+	if c.server != nil {
+		return fmt.Errorf("metrics server already started")
+	}
+
+	// Bind before returning so a busy port is reported to the caller
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return fmt.Errorf("failed to start metrics server: %w", err)
+	}
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	c.server = server
 	go func() {
-		if err := http.ListenAndServe(addr, nil); err != nil {
-			// Log error in production, for now just ignore
-			_ = err
-		}
+		// Serve returns http.ErrServerClosed once Close shuts the server down
+		_ = server.Serve(listener)
 	}()
 
 	return nil
@@ -278,8 +515,23 @@ func (c *Collector) GetRegistry() *prometheus.Registry {
 	return c.registry
 }
 
-// Close cleans up the metrics collector.
+// Close shuts down the metrics server, if started, waiting for in-flight
+// scrapes to finish.
 func (c *Collector) Close() error {
-	// Nothing to clean up for now
-	return nil
+	if c == nil {
+		return nil
+	}
+
+	c.serverMu.Lock()
+	server := c.server
+	c.server = nil
+	c.serverMu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
 }
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findMetric returns the first metric of the named family.
+func findMetric(t *testing.T, c *Collector, name string) *dto.Metric {
+	families, err := c.GetRegistry().Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() == name && len(family.GetMetric()) > 0 {
+			return family.GetMetric()[0]
+		}
+	}
+	return nil
+}
+
+func TestCustomBuckets(t *testing.T) {
+	c := NewForBackend(config.PrometheusConfig{
+		Enabled: true,
+		Buckets: map[string][]float64{
+			"memory": {0.00001, 0.0001, 0.001},
+		},
+	}, "memory")
+
+	c.RecordOperationWithBackend("get", "memory", "success", 5*time.Microsecond)
+	c.RecordOperationWithBackend("get", "memory", "success", 50*time.Microsecond)
+	c.RecordOperationWithBackend("get", "memory", "success", 500*time.Microsecond)
+	c.RecordOperationWithBackend("get", "memory", "success", 5*time.Millisecond)
+
+	metric := findMetric(t, c, "gocachex_cache_operation_duration_seconds")
+	require.NotNil(t, metric)
+
+	histogram := metric.GetHistogram()
+	assert.Equal(t, uint64(4), histogram.GetSampleCount())
+
+	buckets := histogram.GetBucket()
+	require.Len(t, buckets, 3)
+	for i, expected := range []struct {
+		bound float64
+		count uint64
+	}{
+		{0.00001, 1},
+		{0.0001, 2},
+		{0.001, 3},
+	} {
+		assert.Equal(t, expected.bound, buckets[i].GetUpperBound())
+		assert.Equal(t, expected.count, buckets[i].GetCumulativeCount())
+	}
+}
+
+func TestDefaultBucketsPerBackend(t *testing.T) {
+	memory := DefaultBuckets("memory")
+	redis := DefaultBuckets("redis")
+
+	assert.Less(t, memory[0], redis[0])
+	assert.Less(t, memory[0], 0.00001, "memory buckets should resolve microseconds")
+
+	c := NewForBackend(config.PrometheusConfig{Enabled: true}, "redis")
+	c.RecordOperationWithBackend("get", "redis", "success", time.Millisecond)
+
+	metric := findMetric(t, c, "gocachex_cache_operation_duration_seconds")
+	require.NotNil(t, metric)
+	assert.Len(t, metric.GetHistogram().GetBucket(), len(redis))
+}
+
+func TestLatencySummary(t *testing.T) {
+	c := NewForBackend(config.PrometheusConfig{Enabled: true}, "memory")
+	c.RecordOperationWithBackend("get", "memory", "success", time.Millisecond)
+	assert.Nil(t, findMetric(t, c, "gocachex_cache_operation_latency_seconds"))
+
+	c = NewForBackend(config.PrometheusConfig{Enabled: true, Summary: true}, "memory")
+	for i := 1; i <= 100; i++ {
+		c.RecordOperationWithBackend("get", "memory", "success", time.Duration(i)*time.Millisecond)
+	}
+
+	metric := findMetric(t, c, "gocachex_cache_operation_latency_seconds")
+	require.NotNil(t, metric)
+
+	quantiles := make(map[float64]float64)
+	for _, q := range metric.GetSummary().GetQuantile() {
+		quantiles[q.GetQuantile()] = q.GetValue()
+	}
+	assert.InDelta(t, 0.050, quantiles[0.5], 0.006)
+	assert.InDelta(t, 0.095, quantiles[0.95], 0.002)
+	assert.InDelta(t, 0.099, quantiles[0.99], 0.002)
+}
+
+func TestCompressionDuration(t *testing.T) {
+	c := New(config.PrometheusConfig{Enabled: true})
+	c.RecordCompression("gzip", "compress", 20*time.Microsecond)
+	c.RecordCompression("gzip", "compress", 40*time.Microsecond)
+
+	metric := findMetric(t, c, "gocachex_cache_compression_duration_seconds")
+	require.NotNil(t, metric)
+	assert.Equal(t, uint64(2), metric.GetHistogram().GetSampleCount())
+	assert.InDelta(t, 0.00006, metric.GetHistogram().GetSampleSum(), 1e-9)
+
+	labels := make(map[string]string)
+	for _, label := range metric.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	assert.Equal(t, map[string]string{"algorithm": "gzip", "direction": "compress"}, labels)
+
+	// A nil collector ignores observations
+	var nilCollector *Collector
+	nilCollector.RecordCompression("gzip", "compress", time.Microsecond)
+}
+
+func TestPayloadSize(t *testing.T) {
+	c := New(config.PrometheusConfig{Enabled: true})
+	c.RecordPayloadSize("redis", 100)
+	c.RecordPayloadSize("redis", 5000)
+
+	metric := findMetric(t, c, "gocachex_cache_payload_size_bytes")
+	require.NotNil(t, metric)
+	assert.Equal(t, uint64(2), metric.GetHistogram().GetSampleCount())
+	assert.Equal(t, float64(5100), metric.GetHistogram().GetSampleSum())
+
+	// A nil collector ignores observations
+	var nilCollector *Collector
+	nilCollector.RecordPayloadSize("redis", 100)
+}
+
+// freePort returns a TCP port that was free when checked.
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestMetricsServerClose(t *testing.T) {
+	port := freePort(t)
+	c := New(config.PrometheusConfig{Enabled: true, Port: port, Path: "/metrics"})
+	require.NoError(t, c.StartMetricsServer())
+	assert.Error(t, c.StartMetricsServer(), "the server is already running")
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	url := fmt.Sprintf("http://127.0.0.1:%d/metrics", port)
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, c.Close())
+	_, err = client.Get(url)
+	assert.Error(t, err, "the server is shut down")
+
+	// Closing again, or a nil collector, is a no-op
+	assert.NoError(t, c.Close())
+	var nilCollector *Collector
+	assert.NoError(t, nilCollector.Close())
+}
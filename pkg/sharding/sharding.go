@@ -2,13 +2,13 @@
 package sharding
 
 import (
-	"crypto/md5"
 	"fmt"
-	"hash/crc32"
+	"math"
 	"sort"
 
 	"github.com/chmenegatti/gocachex/pkg/backends"
 	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/chmenegatti/gocachex/pkg/hashing"
 )
 
 // Sharder provides data sharding functionality.
@@ -22,23 +22,59 @@ type Sharder interface {
 }
 
 // ConsistentHashSharder implements consistent hashing for data distribution.
+//
+// With a load factor set it implements consistent hashing with bounded
+// loads over the ring: each shard owns at most loadFactor times the mean
+// share of the hash space, and arcs of the ring that would take a shard over
+// that bound spill over to the next shards clockwise. Arcs are assigned
+// when shards are added or removed, so a key's shard depends only on the
+// ring and every sharder built from the same configuration agrees on it.
 type ConsistentHashSharder struct {
-	shards   []backends.Backend
-	replicas int
-	ring     map[uint32]int
-	keys     []uint32
+	shards     []backends.Backend
+	replicas   int
+	ring       map[uint32]int
+	keys       []uint32
+	segments   []ringSegment // Ownership of the ring, sorted by end
+	hash       hashing.Func
+	loadFactor float64
+}
+
+// ringSegment is a part of the ring, from just after the end of the
+// previous segment up to and including end, owned by shard.
+type ringSegment struct {
+	end   uint32
+	shard int
 }
 
 // NewConsistentHashSharder creates a new consistent hash sharder.
 func NewConsistentHashSharder(replicas int) *ConsistentHashSharder {
+	return NewConsistentHashSharderWithHash(replicas, hashing.Default)
+}
+
+// NewConsistentHashSharderWithHash creates a new consistent hash sharder
+// using the given hash function.
+func NewConsistentHashSharderWithHash(replicas int, hash hashing.Func) *ConsistentHashSharder {
+	if hash == nil {
+		hash = hashing.Default
+	}
 	return &ConsistentHashSharder{
 		shards:   make([]backends.Backend, 0),
 		replicas: replicas,
 		ring:     make(map[uint32]int),
 		keys:     make([]uint32, 0),
+		hash:     hash,
 	}
 }
 
+// NewBoundedConsistentHashSharder creates a consistent hash sharder with
+// bounded loads, where no shard owns more than loadFactor times the mean
+// share of the ring. loadFactor must be greater than 1.
+func NewBoundedConsistentHashSharder(replicas int, loadFactor float64, hash hashing.Func) *ConsistentHashSharder {
+	sharder := NewConsistentHashSharderWithHash(replicas, hash)
+	sharder.loadFactor = loadFactor
+	return sharder
+}
+
 // AddShard adds a new shard to the consistent hash ring.
 func (c *ConsistentHashSharder) AddShard(backend backends.Backend) error {
 	shardIndex := len(c.shards)
@@ -56,6 +92,8 @@ func (c *ConsistentHashSharder) AddShard(backend backends.Backend) error {
 		return c.keys[i] < c.keys[j]
 	})
 
+	c.assignArcs()
+
 	return nil
 }
 
@@ -90,33 +128,18 @@ func (c *ConsistentHashSharder) RemoveShard(index int) error {
 	// Remove shard from slice
 	c.shards = append(c.shards[:index], c.shards[index+1:]...)
 
+	c.assignArcs()
+
 	return nil
 }
 
 // GetShard returns the shard backend for a given key.
 func (c *ConsistentHashSharder) GetShard(key string) backends.Backend {
-	if len(c.shards) == 0 {
+	index := c.GetShardIndex(key)
+	if index < 0 {
 		return nil
 	}
-
-	if len(c.shards) == 1 {
-		return c.shards[0]
-	}
-
-	hash := c.hashKey(key)
-
-	// Find the first node >= hash
-	idx := sort.Search(len(c.keys), func(i int) bool {
-		return c.keys[i] >= hash
-	})
-
-	// If no node found, wrap around to the first node
-	if idx == len(c.keys) {
-		idx = 0
-	}
-
-	shardIndex := c.ring[c.keys[idx]]
-	return c.shards[shardIndex]
+	return c.shards[index]
 }
 
 // GetShardIndex returns the shard index for a given key.
@@ -131,17 +154,60 @@ func (c *ConsistentHashSharder) GetShardIndex(key string) int {
 
 	hash := c.hashKey(key)
 
-	// Find the first node >= hash
-	idx := sort.Search(len(c.keys), func(i int) bool {
-		return c.keys[i] >= hash
+	// Find the first segment ending at or after hash
+	idx := sort.Search(len(c.segments), func(i int) bool {
+		return c.segments[i].end >= hash
 	})
 
-	// If no node found, wrap around to the first node
-	if idx == len(c.keys) {
+	// If no segment found, wrap around to the first segment
+	if idx == len(c.segments) {
 		idx = 0
 	}
 
-	return c.ring[c.keys[idx]]
+	return c.segments[idx].shard
+}
+
+// assignArcs assigns the arc of the ring ending at each virtual node to the
+// node's shard. With a load factor, every shard owns at most loadFactor times
+// the mean share of the ring: arcs are assigned clockwise from the start of
+// the ring, and the part of an arc that does not fit in its shard is split
+// across the next shards clockwise with room for it.
+func (c *ConsistentHashSharder) assignArcs() {
+	c.segments = make([]ringSegment, 0, len(c.keys))
+	if c.loadFactor <= 0 || len(c.shards) <= 1 {
+		for _, key := range c.keys {
+			c.segments = append(c.segments, ringSegment{end: key, shard: c.ring[key]})
+		}
+		return
+	}
+
+	const ringSize = uint64(math.MaxUint32) + 1
+	capacity := uint64(math.Ceil(c.loadFactor * float64(ringSize) / float64(len(c.shards))))
+	owned := make([]uint64, len(c.shards))
+	for i, key := range c.keys {
+		// The arc ending at the first node wraps around from the last one
+		remaining := uint64(key - c.keys[(i+len(c.keys)-1)%len(c.keys)])
+		end := key
+
+		// The node's own shard keeps the part of the arc nearest the node.
+		// The shards have room for the whole ring, so the arc always fits.
+		for j := 0; remaining > 0 && j < len(c.keys); j++ {
+			shard := c.ring[c.keys[(i+j)%len(c.keys)]]
+			take := min(capacity-owned[shard], remaining)
+			if take == 0 {
+				continue
+			}
+
+			c.segments = append(c.segments, ringSegment{end: end, shard: shard})
+			owned[shard] += take
+			remaining -= take
+			end -= uint32(take)
+		}
+	}
+
+	sort.Slice(c.segments, func(i, j int) bool {
+		return c.segments[i].end < c.segments[j].end
+	})
 }
 
 // GetShards returns all shard backends.
@@ -156,18 +222,29 @@ func (c *ConsistentHashSharder) GetShardCount() int {
 
 // hashKey computes a hash for a given key.
 func (c *ConsistentHashSharder) hashKey(key string) uint32 {
-	return crc32.ChecksumIEEE([]byte(key))
+	return c.hash(key)
 }
 
 // HashSharder implements simple hash-based sharding.
 type HashSharder struct {
 	shards []backends.Backend
+	hash   hashing.Func
 }
 
 // NewHashSharder creates a new hash-based sharder.
 func NewHashSharder() *HashSharder {
+	return NewHashSharderWithHash(hashing.Default)
+}
+
+// NewHashSharderWithHash creates a new hash-based sharder using the given
+// hash function.
+func NewHashSharderWithHash(hash hashing.Func) *HashSharder {
+	if hash == nil {
+		hash = hashing.Default
+	}
 	return &HashSharder{
 		shards: make([]backends.Backend, 0),
+		hash:   hash,
 	}
 }
 
@@ -193,9 +270,7 @@ func (h *HashSharder) GetShard(key string) backends.Backend {
 		return nil
 	}
 
-	hash := h.hashKey(key)
-	index := int(hash) % len(h.shards)
-	return h.shards[index]
+	return h.shards[hashing.Index(h.hash, key, len(h.shards))]
 }
 
 // GetShardIndex returns the shard index for a given key.
@@ -204,8 +279,7 @@ func (h *HashSharder) GetShardIndex(key string) int {
 		return -1
 	}
 
-	hash := h.hashKey(key)
-	return int(hash) % len(h.shards)
+	return hashing.Index(h.hash, key, len(h.shards))
 }
 
 // GetShards returns all shard backends.
@@ -218,12 +292,6 @@ func (h *HashSharder) GetShardCount() int {
 	return len(h.shards)
 }
 
-// hashKey computes a hash for a given key.
-func (h *HashSharder) hashKey(key string) uint32 {
-	hash := md5.Sum([]byte(key))
-	return uint32(hash[0])<<24 | uint32(hash[1])<<16 | uint32(hash[2])<<8 | uint32(hash[3])
-}
-
 // RangeSharder implements range-based sharding.
 type RangeSharder struct {
 	shards []backends.Backend
@@ -305,30 +373,45 @@ func (r *RangeSharder) GetShardCount() int {
 
 // NewSharder creates a new sharder based on configuration.
 func NewSharder(cfg config.ShardingConfig) Sharder {
+	hash, err := hashing.New(cfg.HashFunction)
+	if err != nil {
+		hash = hashing.Default
+	}
+
 	switch cfg.Algorithm {
 	case "consistent":
 		replicas := cfg.Replicas
 		if replicas <= 0 {
 			replicas = 100 // Default replicas
 		}
-		return NewConsistentHashSharder(replicas)
+		if cfg.LoadFactor > 0 {
+			return NewBoundedConsistentHashSharder(replicas, cfg.LoadFactor, hash)
+		}
+		return NewConsistentHashSharderWithHash(replicas, hash)
 	case "hash":
-		return NewHashSharder()
+		return NewHashSharderWithHash(hash)
 	case "range":
 		return NewRangeSharder()
 	default:
-		return NewConsistentHashSharder(100)
+		if cfg.LoadFactor > 0 {
+			return NewBoundedConsistentHashSharder(100, cfg.LoadFactor, hash)
+		}
+		return NewConsistentHashSharderWithHash(100, hash)
 	}
 }
 
 // ShardKey is a helper function to determine which shard a key belongs to.
 func ShardKey(key string, shardCount int) int {
-	if shardCount <= 0 {
-		return 0
-	}
+	return hashing.Index(hashing.Default, key, shardCount)
+}
 
-	hash := crc32.ChecksumIEEE([]byte(key))
-	return int(hash) % shardCount
+// ShardKeyWithHash determines which shard a key belongs to using the given
+// hash function.
+func ShardKeyWithHash(key string, shardCount int, hash hashing.Func) int {
+	if hash == nil {
+		hash = hashing.Default
+	}
+	return hashing.Index(hash, key, shardCount)
 }
 
 // GenerateShardKey creates a shard-specific key.
@@ -0,0 +1,149 @@
+package sharding
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/chmenegatti/gocachex/pkg/hashing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMemoryShards(t *testing.T, n int) []backends.Backend {
+	shards := make([]backends.Backend, n)
+	for i := range shards {
+		backend, err := backends.NewMemoryBackend(config.MemoryConfig{CleanupInterval: time.Minute})
+		require.NoError(t, err)
+		t.Cleanup(func() { backend.Close() })
+		shards[i] = backend
+	}
+	return shards
+}
+
+func TestShardKeyUsesHashFunc(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		assert.Equal(t, ShardKey(key, 5), ShardKeyWithHash(key, 5, hashing.Default))
+		assert.Equal(t, int(hashing.FNV1a(key)%5), ShardKeyWithHash(key, 5, hashing.FNV1a))
+	}
+}
+
+func TestHashSharderWithConfiguredHash(t *testing.T) {
+	sharder := NewSharder(config.ShardingConfig{Algorithm: "hash", HashFunction: "fnv"})
+	for _, shard := range newMemoryShards(t, 4) {
+		require.NoError(t, sharder.AddShard(shard))
+	}
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		index := sharder.GetShardIndex(key)
+		assert.Equal(t, ShardKeyWithHash(key, 4, hashing.FNV1a), index)
+		assert.Same(t, sharder.GetShards()[index], sharder.GetShard(key))
+	}
+}
+
+// skewedRingHash clusters the virtual nodes of every shard but shard 0 in a
+// narrow band of the ring, so shard 0 owns almost all of it.
+func skewedRingHash(key string) uint32 {
+	h := hashing.CRC32(key)
+	if strings.HasPrefix(key, "shard-") && !strings.HasPrefix(key, "shard-0-") {
+		return h % 1000
+	}
+	return h
+}
+
+// ringShares returns the share of the hash space owned by each shard.
+func ringShares(c *ConsistentHashSharder) []float64 {
+	shares := make([]float64, len(c.shards))
+	for i, segment := range c.segments {
+		arc := segment.end - c.segments[(i+len(c.segments)-1)%len(c.segments)].end
+		shares[segment.shard] += float64(arc) / (float64(math.MaxUint32) + 1)
+	}
+	return shares
+}
+
+func TestBoundedLoadsUnderSkew(t *testing.T) {
+	const (
+		shardCount = 4
+		keyCount   = 10000
+		loadFactor = 1.25
+	)
+
+	unbounded := NewConsistentHashSharderWithHash(10, skewedRingHash)
+	bounded := NewBoundedConsistentHashSharder(10, loadFactor, skewedRingHash)
+	for _, shard := range newMemoryShards(t, shardCount) {
+		require.NoError(t, unbounded.AddShard(shard))
+		require.NoError(t, bounded.AddShard(shard))
+	}
+
+	limit := loadFactor * keyCount / shardCount
+	unboundedCounts := make([]int, shardCount)
+	boundedCounts := make([]int, shardCount)
+	for i := 0; i < keyCount; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		unboundedCounts[unbounded.GetShardIndex(key)]++
+		boundedCounts[bounded.GetShardIndex(key)]++
+	}
+
+	// The ring really is skewed without the bound
+	assert.Greater(t, float64(maxOf(unboundedCounts)), limit)
+
+	total := 0.0
+	for _, share := range ringShares(bounded) {
+		assert.LessOrEqual(t, share, loadFactor/shardCount)
+		total += share
+	}
+	assert.InDelta(t, 1, total, 1e-9)
+
+	// Keys follow the ring shares, up to hashing noise
+	for _, count := range boundedCounts {
+		assert.LessOrEqual(t, float64(count), limit*1.05)
+	}
+	assert.Equal(t, keyCount, sum(boundedCounts))
+}
+
+func TestBoundedLoadsPlacementIsDeterministic(t *testing.T) {
+	cfg := config.ShardingConfig{Algorithm: "consistent", LoadFactor: 1.25}
+	shards := newMemoryShards(t, 4)
+	first := NewSharder(cfg)
+	second := NewSharder(cfg)
+	for _, shard := range shards {
+		require.NoError(t, first.AddShard(shard))
+		require.NoError(t, second.AddShard(shard))
+	}
+
+	// Placements do not depend on lookup order or earlier lookups
+	const keyCount = 1000
+	indexes := make([]int, keyCount)
+	for i := range indexes {
+		indexes[i] = first.GetShardIndex(fmt.Sprintf("key:%d", i))
+	}
+	for i := keyCount - 1; i >= 0; i-- {
+		key := fmt.Sprintf("key:%d", i)
+		assert.Equal(t, indexes[i], second.GetShardIndex(key), key)
+		assert.Equal(t, indexes[i], first.GetShardIndex(key), key)
+	}
+}
+
+func maxOf(values []int) int {
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Default endpoints of a collector running on the local host.
+const (
+	defaultOTLPEndpoint   = "http://localhost:4318/v1/traces"
+	defaultZipkinEndpoint = "http://localhost:9411/api/v2/spans"
+)
+
+// newExporter creates the span exporter of the configured provider. Jaeger
+// accepts OTLP natively, so it shares the OTLP exporter.
+func newExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Provider {
+	case "otlp", "jaeger":
+		return newOTLPExporter(cfg)
+	case "zipkin":
+		endpoint, err := endpointURL(cfg.Endpoint, defaultZipkinEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		client := &http.Client{Transport: headerTransport{headers: cfg.Headers, next: http.DefaultTransport}}
+		return zipkin.New(endpoint.String(), zipkin.WithClient(client))
+	default:
+		return nil, fmt.Errorf("unsupported tracing provider: %s", cfg.Provider)
+	}
+}
+
+// newOTLPExporter creates an exporter sending spans over OTLP/HTTP.
+func newOTLPExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	endpoint, err := endpointURL(cfg.Endpoint, defaultOTLPEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(endpoint.Host),
+		otlptracehttp.WithHeaders(cfg.Headers),
+	}
+	if endpoint.Path != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(endpoint.Path))
+	}
+	if endpoint.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	// Connections are made on export, so this does not block
+	return otlptracehttp.New(context.Background(), opts...)
+}
+
+// endpointURL parses the configured endpoint, defaulting to fallback when
+// it is empty and to plain HTTP when it has no scheme.
+func endpointURL(endpoint, fallback string) (*url.URL, error) {
+	if endpoint == "" {
+		endpoint = fallback
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracing endpoint %q: %w", endpoint, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid tracing endpoint %q: missing host", endpoint)
+	}
+	return u, nil
+}
+
+// headerTransport adds headers to every request, for exporters that do not
+// take headers themselves.
+type headerTransport struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -4,42 +4,91 @@ package tracing
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/chmenegatti/gocachex/pkg/config"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // Tracer wraps OpenTelemetry tracing functionality.
 type Tracer struct {
-	config config.TracingConfig
-	tracer trace.Tracer
+	config   config.TracingConfig
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+
+	// sdk is the provider built from the configuration, which the tracer
+	// owns and shuts down on Close; nil when the global provider is used
+	sdk *sdktrace.TracerProvider
 }
 
-// New creates a new tracer instance.
+// flushTimeout bounds how long Close waits for pending spans to be exported.
+const flushTimeout = 5 * time.Second
+
+// New creates a new tracer instance. With a Provider configured, it builds
+// an OpenTelemetry SDK tracer provider that batches spans to the configured
+// exporter, sampling SampleRate of the traces; the provider is private to
+// the tracer rather than installed globally. Without one, spans are started
+// through the global tracer provider installed by the application.
 func New(cfg config.TracingConfig) (*Tracer, error) {
+	return newTracer(cfg)
+}
+
+// newTracer is New with extra options for the SDK tracer provider, such as
+// span processors recording spans in tests.
+func newTracer(cfg config.TracingConfig, opts ...sdktrace.TracerProviderOption) (*Tracer, error) {
 	if !cfg.Enabled {
 		return &Tracer{config: cfg}, nil
 	}
 
-	// Get the global tracer
-	tracer := otel.Tracer(cfg.ServiceName)
+	if cfg.Provider == "" {
+		// Get the global tracer
+		provider := otel.GetTracerProvider()
+
+		return &Tracer{
+			config:   cfg,
+			provider: provider,
+			tracer:   provider.Tracer(cfg.ServiceName),
+		}, nil
+	}
+
+	exporter, err := newExporter(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s exporter: %w", cfg.Provider, err)
+	}
+
+	sampleRate := cfg.SampleRate
+	if sampleRate == 0 {
+		sampleRate = 1
+	}
+
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRate))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName))),
+	}, opts...)
+	provider := sdktrace.NewTracerProvider(opts...)
 
 	return &Tracer{
-		config: cfg,
-		tracer: tracer,
+		config:   cfg,
+		provider: provider,
+		tracer:   provider.Tracer(cfg.ServiceName),
+		sdk:      provider,
 	}, nil
 }
 
 // StartSpan starts a new tracing span.
-func (t *Tracer) StartSpan(ctx context.Context, operationName string) (context.Context, trace.Span) {
+func (t *Tracer) StartSpan(ctx context.Context, operationName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
 	if t == nil || t.tracer == nil {
 		return ctx, trace.SpanFromContext(ctx)
 	}
 
-	return t.tracer.Start(ctx, operationName)
+	return t.tracer.Start(ctx, operationName, opts...)
 }
 
 // SpanFromContext returns the span from the context.
@@ -70,10 +119,27 @@ func (t *Tracer) RecordError(ctx context.Context, err error) {
 	}
 }
 
-// Close closes the tracer and flushes any pending spans.
+// Close exports pending spans. A provider built from the configuration is
+// shut down along with its exporter. The global provider is owned by the
+// application, so it is only flushed, if it supports flushing as the
+// OpenTelemetry SDK provider does.
 func (t *Tracer) Close() error {
-	// In a real implementation, you would flush/close the tracer provider
-	return nil
+	if t == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	if t.sdk != nil {
+		return t.sdk.Shutdown(ctx)
+	}
+
+	flusher, ok := t.provider.(interface{ ForceFlush(context.Context) error })
+	if !ok {
+		return nil
+	}
+	return flusher.ForceFlush(ctx)
 }
 
 // IsEnabled returns whether tracing is enabled.
@@ -152,7 +218,7 @@ func (t *Tracer) TraceBatchOperation(ctx context.Context, operation, backend str
 type NoOpTracer struct{}
 
 // StartSpan is a no-op implementation.
-func (n *NoOpTracer) StartSpan(ctx context.Context, operationName string) (context.Context, trace.Span) {
+func (n *NoOpTracer) StartSpan(ctx context.Context, operationName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
 	return ctx, trace.SpanFromContext(ctx)
 }
 
@@ -0,0 +1,161 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/chmenegatti/gocachex/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is an HTTP server recording the export requests it receives.
+type collector struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []exportRequest
+}
+
+type exportRequest struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		c.mu.Lock()
+		c.requests = append(c.requests, exportRequest{path: r.URL.Path, header: r.Header, body: body})
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func (c *collector) received() []exportRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]exportRequest(nil), c.requests...)
+}
+
+func TestSampleRate(t *testing.T) {
+	server := newCollector(t)
+
+	tests := []struct {
+		name       string
+		sampleRate float64
+		min, max   int
+	}{
+		{name: "default", sampleRate: 0, min: 1000, max: 1000},
+		{name: "all", sampleRate: 1, min: 1000, max: 1000},
+		{name: "quarter", sampleRate: 0.25, min: 150, max: 350},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tracer, err := newTracer(config.TracingConfig{
+				Enabled:     true,
+				Provider:    "otlp",
+				Endpoint:    server.URL,
+				ServiceName: "test",
+				SampleRate:  tt.sampleRate,
+			}, sdktrace.WithSpanProcessor(recorder))
+			require.NoError(t, err)
+			defer tracer.Close()
+
+			for i := 0; i < 1000; i++ {
+				_, span := tracer.StartSpan(context.Background(), "cache.get")
+				span.End()
+			}
+
+			ended := len(recorder.Ended())
+			assert.GreaterOrEqual(t, ended, tt.min)
+			assert.LessOrEqual(t, ended, tt.max)
+		})
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	for _, provider := range []string{"otlp", "jaeger"} {
+		t.Run(provider, func(t *testing.T) {
+			server := newCollector(t)
+			tracer, err := New(config.TracingConfig{
+				Enabled:     true,
+				Provider:    provider,
+				Endpoint:    server.URL + "/custom/traces",
+				ServiceName: "test",
+				Headers:     map[string]string{"X-Api-Key": "secret"},
+			})
+			require.NoError(t, err)
+
+			_, span := tracer.StartSpan(context.Background(), "cache.get")
+			span.End()
+
+			// Shutting down exports the batched span
+			require.NoError(t, tracer.Close())
+
+			requests := server.received()
+			require.Len(t, requests, 1)
+			assert.Equal(t, "/custom/traces", requests[0].path)
+			assert.Equal(t, "secret", requests[0].header.Get("X-Api-Key"))
+
+			var export coltracepb.ExportTraceServiceRequest
+			require.NoError(t, proto.Unmarshal(requests[0].body, &export))
+			require.Len(t, export.ResourceSpans, 1)
+			assert.Equal(t, "test", export.ResourceSpans[0].Resource.Attributes[0].Value.GetStringValue())
+			spans := export.ResourceSpans[0].ScopeSpans[0].Spans
+			require.Len(t, spans, 1)
+			assert.Equal(t, "cache.get", spans[0].Name)
+		})
+	}
+}
+
+func TestZipkinExporter(t *testing.T) {
+	server := newCollector(t)
+	tracer, err := New(config.TracingConfig{
+		Enabled:     true,
+		Provider:    "zipkin",
+		Endpoint:    server.URL + "/api/v2/spans",
+		ServiceName: "test",
+		Headers:     map[string]string{"X-Api-Key": "secret"},
+	})
+	require.NoError(t, err)
+
+	_, span := tracer.StartSpan(context.Background(), "cache.get")
+	span.End()
+	require.NoError(t, tracer.Close())
+
+	requests := server.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "/api/v2/spans", requests[0].path)
+	assert.Equal(t, "secret", requests[0].header.Get("X-Api-Key"))
+
+	var spans []struct {
+		Name          string `json:"name"`
+		LocalEndpoint struct {
+			ServiceName string `json:"serviceName"`
+		} `json:"localEndpoint"`
+	}
+	require.NoError(t, json.Unmarshal(requests[0].body, &spans))
+	require.Len(t, spans, 1)
+	assert.Equal(t, "cache.get", spans[0].Name)
+	assert.Equal(t, "test", spans[0].LocalEndpoint.ServiceName)
+}
+
+func TestUnsupportedProvider(t *testing.T) {
+	_, err := New(config.TracingConfig{Enabled: true, Provider: "datadog"})
+	assert.ErrorContains(t, err, "unsupported tracing provider")
+}
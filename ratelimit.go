@@ -0,0 +1,57 @@
+package gocachex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+)
+
+// RateLimitAllow records a request against key and reports whether it is
+// allowed: at most limit requests are allowed in any sliding window of the
+// given length. It also returns how many more requests the current window
+// allows. Rejected requests do not count against the limit, so a client
+// that keeps retrying recovers once its oldest allowed request leaves the
+// window.
+//
+// Limits are kept by the backend, in Redis as a sorted set per key, so every
+// client sharing the backend enforces the same limit:
+//
+//	allowed, remaining, err := cache.RateLimitAllow(ctx, "api:"+userID, 100, time.Minute)
+//
+// Rate limit keys live in their own namespace and do not collide with keys.
+func (c *CacheClient) RateLimitAllow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, error) {
+	// Start tracing span
+	ctx, span := c.startKeySpan(ctx, "cache.rate_limit_allow", key)
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return false, 0, ErrReadOnly
+	}
+
+	if limit <= 0 {
+		return false, 0, fmt.Errorf("rate limit must be positive, got %d", limit)
+	}
+	if window <= 0 {
+		return false, 0, fmt.Errorf("rate limit window must be positive, got %v", window)
+	}
+
+	backend, err := c.backendFor(key)
+	if err != nil {
+		return false, 0, err
+	}
+	limiter, ok := backend.(backends.RateLimiter)
+	if !ok {
+		return false, 0, fmt.Errorf("rate limiting not supported by backend")
+	}
+
+	var allowed bool
+	var remaining int
+	err = c.guard(func() error {
+		var err error
+		allowed, remaining, err = limiter.RateLimitAllow(ctx, key, limit, window)
+		return err
+	})
+	return allowed, remaining, err
+}
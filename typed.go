@@ -0,0 +1,113 @@
+package gocachex
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// GetTyped retrieves key from c and returns it as a T. When c is a
+// *CacheClient the stored bytes are deserialized directly into T with the
+// configured serializer, so a struct stored with SetTyped comes back as the
+// same struct type rather than a map:
+//
+//	product, err := gocachex.GetTyped[*Product](ctx, cache, "product:1")
+//
+// Pointer types are reconstructed from the stored value, whether it was
+// stored as a T or a *T. Other Cache implementations fall back to Get and a
+// type assertion.
+func GetTyped[T any](ctx context.Context, c Cache, key string) (T, error) {
+	var value T
+	if client, ok := c.(*CacheClient); ok {
+		err := client.getInto(ctx, key, &value)
+		return value, err
+	}
+
+	raw, err := c.Get(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	typed, ok := raw.(T)
+	if !ok {
+		return value, fmt.Errorf("cached value for key %s is %T, not %T", key, raw, value)
+	}
+	return typed, nil
+}
+
+// SetTyped stores value in c with the specified TTL. It is the typed
+// counterpart of GetTyped.
+func SetTyped[T any](ctx context.Context, c Cache, key string, value T, ttl time.Duration) error {
+	return c.Set(ctx, key, value, ttl)
+}
+
+// getInto retrieves key and decodes it into target, a pointer, without
+// going through interface{}.
+func (c *CacheClient) getInto(ctx context.Context, key string, target interface{}) (err error) {
+	// Start tracing span
+	ctx, span := c.startKeySpan(ctx, "cache.get", key)
+	defer span.End()
+
+	c.metrics.RecordKeyGroup("get", key)
+	defer func(start time.Time) { c.recordOperation(span, "get", start, err) }(time.Now())
+
+	// Hierarchical cache check
+	if c.config.Hierarchical {
+		return c.getIntoHierarchical(ctx, key, target)
+	}
+
+	data, err := c.getRaw(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := c.decodeInto(key, data, target); err != nil {
+		return c.evictUndecodable(ctx, key, err)
+	}
+	return nil
+}
+
+// getRaw fetches the stored bytes of key from a single or distributed
+// client.
+func (c *CacheClient) getRaw(ctx context.Context, key string) ([]byte, error) {
+	backend := c.backend
+	if c.config.Distributed {
+		shard, err := c.getShard(key)
+		if err != nil {
+			return nil, err
+		}
+		backend = shard
+	}
+
+	var data []byte
+	err := c.guard(func() error {
+		var err error
+		data, err = backend.Get(ctx, key)
+		return err
+	})
+	return data, err
+}
+
+// getIntoHierarchical decodes key from L1, falling back to L2 and promoting
+// the value to L1 like getHierarchical.
+func (c *CacheClient) getIntoHierarchical(ctx context.Context, key string, target interface{}) error {
+	l1, ok1 := c.l1Cache.(*CacheClient)
+	l2, ok2 := c.l2Cache.(*CacheClient)
+	if !ok1 || !ok2 {
+		return fmt.Errorf("typed reads not supported by cache tiers")
+	}
+
+	if err := l1.getInto(ctx, key, target); err == nil {
+		return nil
+	}
+	if err := l2.getInto(ctx, key, target); err != nil {
+		return err
+	}
+
+	l1TTL := c.config.L1.TTL
+	if l1TTL == 0 {
+		l1TTL = 5 * time.Minute // Default L1 TTL
+	}
+	_ = l1.Set(ctx, key, reflect.ValueOf(target).Elem().Interface(), l1TTL)
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package gocachex
+
+import (
+	"context"
+	"time"
+
+	"github.com/chmenegatti/gocachex/pkg/backends"
+)
+
+// defaultWaitPollInterval is used by GetWait when no poll interval is given.
+const defaultWaitPollInterval = 100 * time.Millisecond
+
+// GetWait blocks until key holds a value or ctx is done, checking for it
+// every pollInterval. When the backend can signal writes (Redis with
+// KeyspaceNotifications enabled), a write also wakes GetWait immediately;
+// polling continues as a safety net for lost notifications.
+func (c *CacheClient) GetWait(ctx context.Context, key string, pollInterval time.Duration) (interface{}, error) {
+	// Start tracing span
+	ctx, span := c.startKeySpan(ctx, "cache.get_wait", key)
+	defer span.End()
+
+	if pollInterval <= 0 {
+		pollInterval = defaultWaitPollInterval
+	}
+
+	// Wake on writes when the backend holding key can signal them
+	var events <-chan struct{}
+	backend, _ := c.backendFor(key)
+	if watcher, ok := backend.(backends.KeyWatcher); ok {
+		ch, stop, err := watcher.WatchKey(ctx, key)
+		if err == nil {
+			events = ch
+			defer stop()
+		}
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if value, err := c.Get(ctx, key); err == nil {
+			return value, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-events:
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package gocachex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LoaderRegistry maps cache keys to the loaders that produce their values.
+// It is safe for concurrent use.
+type LoaderRegistry struct {
+	mu      sync.RWMutex
+	loaders map[string]LoaderFunc
+}
+
+// NewLoaderRegistry creates an empty loader registry.
+func NewLoaderRegistry() *LoaderRegistry {
+	return &LoaderRegistry{loaders: make(map[string]LoaderFunc)}
+}
+
+// Register sets the loader for key, replacing any previous one.
+func (r *LoaderRegistry) Register(key string, loader LoaderFunc) {
+	r.mu.Lock()
+	r.loaders[key] = loader
+	r.mu.Unlock()
+}
+
+// Lookup returns the loader registered for key.
+func (r *LoaderRegistry) Lookup(key string) (LoaderFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	loader, ok := r.loaders[key]
+	return loader, ok
+}
+
+// Loaders is the registry that loads the critical keys listed in the
+// warmup configuration when a client is created.
+var Loaders = NewLoaderRegistry()
+
+// RegisterLoader registers loader for key in Loaders. Register loaders for
+// critical keys before calling New.
+func RegisterLoader(key string, loader LoaderFunc) {
+	Loaders.Register(key, loader)
+}
+
+// Warm loads keys with their loaders from registry and caches the results
+// with the given TTL. Keys are loaded concurrently and every loader is
+// bounded by ctx. It returns the joined errors of the keys that could not
+// be loaded, including keys without a registered loader.
+func (c *CacheClient) Warm(ctx context.Context, registry *LoaderRegistry, ttl time.Duration, keys ...string) error {
+	// Start tracing span
+	ctx, span := c.startSpan(ctx, "cache.warm")
+	defer span.End()
+
+	if c.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		loader, ok := registry.Lookup(key)
+		if !ok {
+			errs[i] = fmt.Errorf("no loader registered for key %s", key)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, key string, loader LoaderFunc) {
+			defer wg.Done()
+			value, err := loadWithContext(ctx, c.limitLoader(key, loader))
+			if err == nil {
+				err = c.Set(ctx, key, value, ttl)
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to warm key %s: %w", key, err)
+			}
+		}(i, key, loader)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// WarmFrom caches every item under the key derived by keyFn with the given
+// TTL, storing them in a single SetMulti batch. It is meant for result sets
+// loaded in bulk, such as database rows:
+//
+//	err := gocachex.WarmFrom(ctx, cache, products, func(p Product) string {
+//		return fmt.Sprintf("product:%d", p.ID)
+//	}, time.Hour)
+//
+// When two items derive the same key the later one is stored.
+func WarmFrom[T any](ctx context.Context, c Cache, items []T, keyFn func(T) string, ttl time.Duration) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	batch := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		key := keyFn(item)
+		if key == "" {
+			return fmt.Errorf("empty key derived for item %v", item)
+		}
+		batch[key] = item
+	}
+
+	return c.SetMulti(ctx, batch, ttl)
+}
+
+// warmCriticalKeys loads the configured critical keys from Loaders within
+// the warmup timeout.
+func (c *CacheClient) warmCriticalKeys() error {
+	warmup := c.config.Warmup
+	if len(warmup.CriticalKeys) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), warmup.Timeout)
+	defer cancel()
+
+	if err := c.Warm(ctx, Loaders, warmup.TTL, warmup.CriticalKeys...); err != nil {
+		return fmt.Errorf("failed to load critical keys: %w", err)
+	}
+	return nil
+}